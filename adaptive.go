@@ -0,0 +1,113 @@
+package openailb
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// AdaptiveConcurrency configures WithAdaptiveConcurrency.
+type AdaptiveConcurrency struct {
+	// InitialLimit is the limit each backend starts from (default 10),
+	// within MinLimit (default 1) and MaxLimit (default unbounded). A
+	// backend's MaxInFlight, if set, lowers MaxLimit.
+	InitialLimit int
+	MinLimit     int
+	MaxLimit     int
+	// LatencyThreshold, if set, is the latency above which a successful
+	// answer, or the first token of a stream, signals overload.
+	LatencyThreshold time.Duration
+	// Backoff is the factor applied to the limit on overload (default 0.9).
+	Backoff float64
+}
+
+// WithAdaptiveConcurrency adjusts the concurrency limit of each backend to
+// its observed capacity (AIMD): each success of a backend using at least
+// half its limit raises it by 1/limit, about 1 per limit's worth of
+// requests, and each timeout, 429, 5xx, network error or answer slower than
+// LatencyThreshold multiplies it by Backoff, once per round trip. Requests
+// over the limit are handled as with OpenaiClientConfig.MaxInFlight.
+// Client.Stats reports the current limits as MaxInFlight.
+func WithAdaptiveConcurrency(cfg AdaptiveConcurrency) LBOption {
+	return func(o *lbOptions) {
+		if cfg.MinLimit <= 0 {
+			cfg.MinLimit = 1
+		}
+		if cfg.MaxLimit <= 0 {
+			cfg.MaxLimit = math.MaxInt32
+		}
+		if cfg.InitialLimit <= 0 {
+			cfg.InitialLimit = 10
+		}
+		if cfg.Backoff <= 0 || cfg.Backoff >= 1 {
+			cfg.Backoff = 0.9
+		}
+		o.adaptive = &cfg
+	}
+}
+
+// aimdLimit is the adaptive concurrency limit of a backend.
+type aimdLimit struct {
+	cfg      *AdaptiveConcurrency
+	min, max float64
+
+	mu           sync.Mutex
+	limit        float64
+	lastDecrease time.Time
+}
+
+// newAIMDLimit returns the limit of a backend with the given MaxInFlight,
+// or nil without WithAdaptiveConcurrency.
+func newAIMDLimit(cfg *AdaptiveConcurrency, maxInFlight int) *aimdLimit {
+	if cfg == nil {
+		return nil
+	}
+	l := &aimdLimit{cfg: cfg, min: float64(cfg.MinLimit), max: float64(cfg.MaxLimit)}
+	if maxInFlight > 0 {
+		l.max = min(l.max, float64(maxInFlight))
+	}
+	l.min = min(l.min, l.max)
+	l.limit = max(l.min, min(l.max, float64(cfg.InitialLimit)))
+	return l
+}
+
+// adapt updates the backend's limit with the outcome of an attempt.
+func (c *SafeClient) adapt(now time.Time, r ResponseInfo) {
+	l := c.aimd
+	if l == nil {
+		return
+	}
+	latency := r.Latency
+	if r.Stream && r.TTFT > 0 {
+		latency = r.TTFT
+	}
+	var overloaded bool
+	switch r.Class {
+	case ClassTimeout, ClassRateLimited, ClassServer, ClassNetwork:
+		overloaded = true
+	case ClassOK:
+		overloaded = l.cfg.LatencyThreshold > 0 && latency > l.cfg.LatencyThreshold
+	default:
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if overloaded {
+		// The attempts sent before the last decrease saw the old limit.
+		if now.Add(-r.Latency).Before(l.lastDecrease) {
+			return
+		}
+		l.limit = max(l.min, l.limit*l.cfg.Backoff)
+		l.lastDecrease = now
+	} else {
+		// Raising a limit the backend doesn't use says nothing of its capacity.
+		if float64(c.active.Load()+1) < l.limit/2 {
+			return
+		}
+		l.limit = min(l.max, l.limit+1/l.limit)
+	}
+	if old := c.maxInFlight.Swap(int64(l.limit)); old < int64(l.limit) {
+		c.lb.slots.broadcast()
+	}
+}
@@ -0,0 +1,74 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestAdaptiveConcurrency(t *testing.T) {
+	t.Parallel()
+
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "ok"}}]}`))
+	}))
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{{Name: "b", APIKey: "k1", BaseURL: server.URL, MaxInFlight: 8}},
+		WithAdaptiveConcurrency(AdaptiveConcurrency{InitialLimit: 4, MinLimit: 1, Backoff: 0.5}),
+		WithCBSettings(tripAfter(100)))
+	limit := func() int64 { return client.Stats()["b"].MaxInFlight }
+	if got := limit(); got != 4 {
+		t.Fatalf("Expected the initial limit of 4, got %d", got)
+	}
+
+	// One request at a time uses less than half the limit: no evidence to raise it.
+	for i := 0; i < 10; i++ {
+		if _, err := client.Chat.Completions.New(context.Background(), chatParams("m")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := limit(); got != 4 {
+		t.Errorf("Expected an unused limit to stay at 4, got %d", got)
+	}
+
+	failing.Store(true)
+	for i := 0; i < 3; i++ {
+		_, _ = client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+	}
+	if got := limit(); got != 1 {
+		t.Errorf("Expected the 429s to halve the limit down to MinLimit, got %d", got)
+	}
+
+	failing.Store(false)
+	for i := 0; i < 50; i++ {
+		if _, err := client.Chat.Completions.New(context.Background(), chatParams("m")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Up to where one request at a time uses less than half the limit.
+	if got := limit(); got != 2 {
+		t.Errorf("Expected the limit to grow back to 2, got %d", got)
+	}
+}
+
+func TestAdaptiveConcurrencyBounds(t *testing.T) {
+	t.Parallel()
+
+	cfg := AdaptiveConcurrency{InitialLimit: 20, MinLimit: 12, MaxLimit: 100}
+	if l := newAIMDLimit(&cfg, 8); l.limit != 8 || l.min != 8 {
+		t.Errorf("Expected the backend's MaxInFlight to bound the limit, got %v within [%v, %v]", l.limit, l.min, l.max)
+	}
+	if l := newAIMDLimit(&cfg, 0); l.limit != 20 || l.max != 100 {
+		t.Errorf("Expected the initial limit within MaxLimit, got %v within [%v, %v]", l.limit, l.min, l.max)
+	}
+}
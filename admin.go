@@ -0,0 +1,214 @@
+package openailb
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AdminAuth authorizes a request to the AdminHandler; an error rejects it
+// with a 403 and the error's message.
+type AdminAuth func(r *http.Request) error
+
+// WithAdminAuth protects the AdminHandler with auth. Without it, the
+// AdminHandler rejects every request.
+func WithAdminAuth(auth AdminAuth) LBOption {
+	return func(o *lbOptions) {
+		o.adminAuth = auth
+	}
+}
+
+// AdminToken returns an AdminAuth accepting the requests that carry token
+// as an "Authorization: Bearer" header.
+func AdminToken(token string) AdminAuth {
+	return func(r *http.Request) error {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			return errors.New("invalid admin token")
+		}
+		return nil
+	}
+}
+
+// AdminHandler serves a JSON API to manage the pool at runtime, each request
+// authorized by WithAdminAuth:
+//
+//	GET    /backends                      the DebugBackend of every backend
+//	POST   /backends                      add the BackendConfig in the body
+//	GET    /backends/{name}               the DebugBackend of one backend
+//	DELETE /backends/{name}               RemoveBackend
+//	POST   /backends/{name}/drain         DrainBackend, ?timeout=30s by default; 504 if
+//	                                      requests were still in flight
+//	PUT    /backends/{name}/weight        SetWeight to the {"weight": 3} in the body
+//	POST   /backends/{name}/switchover    Switchover to the backend named by ?to=
+//	POST   /backends/{name}/trip          TripBreakers, with an optional ?reason=
+//	POST   /backends/{name}/reset         ResetBreakers
+//	POST   /backends/{name}/unhealthy     MarkUnhealthy, with an optional ?reason=
+//	POST   /backends/{name}/healthy       MarkHealthy
+//	POST   /backends/{name}/reinstate     Reinstate
+//
+// Errors are answered as {"error": "..."}. Changes are logged with the
+// WithLogger logger. Mount it under a prefix on an internal port:
+//
+//	mux.Handle("/admin/", http.StripPrefix("/admin", client.AdminHandler()))
+func (c Client) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /backends", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, c.DebugReport().Backends)
+	})
+	mux.HandleFunc("POST /backends", func(w http.ResponseWriter, r *http.Request) {
+		var b BackendConfig
+		dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&b); err != nil {
+			writeAdminError(w, http.StatusBadRequest, err)
+			return
+		}
+		// Validate as the sole backend; AddBackend checks the name isn't taken.
+		named := b
+		if named.Name == "" {
+			named.Name = "new"
+		}
+		if err := (&Config{Backends: []BackendConfig{named}}).Validate(); err != nil {
+			writeAdminError(w, http.StatusBadRequest, err)
+			return
+		}
+		cfg, err := b.ClientConfig()
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, err)
+			return
+		}
+		name, err := c.AddBackend(cfg)
+		if err != nil {
+			writeAdminError(w, http.StatusConflict, err)
+			return
+		}
+		c.adminLog(r, "add", name)
+		writeJSON(w, http.StatusCreated, map[string]string{"name": name})
+	})
+	mux.HandleFunc("GET /backends/{name}", func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		for _, b := range c.DebugReport().Backends {
+			if b.Name == name {
+				writeJSON(w, http.StatusOK, b)
+				return
+			}
+		}
+		writeAdminError(w, http.StatusNotFound, errors.New(`no backend named "`+name+`"`))
+	})
+	c.adminAction(mux, "DELETE /backends/{name}", "remove", func(r *http.Request, name string) error {
+		return c.RemoveBackend(name)
+	})
+	c.adminAction(mux, "POST /backends/{name}/drain", "drain", func(r *http.Request, name string) error {
+		timeout := 30 * time.Second
+		if t := r.URL.Query().Get("timeout"); t != "" {
+			var err error
+			if timeout, err = time.ParseDuration(t); err != nil || timeout <= 0 {
+				return errBadRequest{errors.New("timeout: must be a positive duration such as 30s")}
+			}
+		}
+		return c.DrainBackend(name, timeout)
+	})
+	c.adminAction(mux, "PUT /backends/{name}/weight", "set weight", func(r *http.Request, name string) error {
+		var body struct {
+			Weight *int `json:"weight"`
+		}
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<10)).Decode(&body); err != nil || body.Weight == nil || *body.Weight < 0 {
+			return errBadRequest{errors.New(`the body must be {"weight": n}, n >= 0`)}
+		}
+		return c.SetWeight(name, *body.Weight)
+	})
+	c.adminAction(mux, "POST /backends/{name}/switchover", "switch over", func(r *http.Request, name string) error {
+		to := r.URL.Query().Get("to")
+		if _, err := c.lb.clientByName(to); err != nil {
+			return errBadRequest{fmt.Errorf("to: %w", err)}
+		}
+		return c.Switchover(name, to)
+	})
+	c.adminAction(mux, "POST /backends/{name}/trip", "trip breakers", func(r *http.Request, name string) error {
+		reason := r.URL.Query().Get("reason")
+		if reason == "" {
+			reason = "tripped by an admin"
+		}
+		return c.TripBreakers(name, reason)
+	})
+	c.adminAction(mux, "POST /backends/{name}/reset", "reset breakers", func(r *http.Request, name string) error {
+		return c.ResetBreakers(name)
+	})
+	c.adminAction(mux, "POST /backends/{name}/unhealthy", "mark unhealthy", func(r *http.Request, name string) error {
+		reason := r.URL.Query().Get("reason")
+		if reason == "" {
+			reason = "marked down by an admin"
+		}
+		return c.MarkUnhealthy(name, reason)
+	})
+	c.adminAction(mux, "POST /backends/{name}/healthy", "mark healthy", func(r *http.Request, name string) error {
+		return c.MarkHealthy(name)
+	})
+	c.adminAction(mux, "POST /backends/{name}/reinstate", "reinstate", func(r *http.Request, name string) error {
+		return c.Reinstate(name)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		auth := c.lb.options.adminAuth
+		if auth == nil {
+			writeAdminError(w, http.StatusForbidden, errors.New("the admin API is disabled without WithAdminAuth"))
+			return
+		}
+		if err := auth(r); err != nil {
+			writeAdminError(w, http.StatusForbidden, err)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// errBadRequest marks an error as the request's fault.
+type errBadRequest struct{ error }
+
+// adminAction registers the action on the backend named in the path, which
+// answers 204 once done.
+func (c Client) adminAction(mux *http.ServeMux, pattern, action string, do func(r *http.Request, name string) error) {
+	mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if _, err := c.lb.clientByName(name); err != nil {
+			writeAdminError(w, http.StatusNotFound, err)
+			return
+		}
+		if err := do(r, name); err != nil {
+			code := http.StatusConflict
+			switch {
+			case errors.As(err, new(errBadRequest)):
+				code = http.StatusBadRequest
+			case errors.Is(err, context.DeadlineExceeded):
+				code = http.StatusGatewayTimeout
+			}
+			writeAdminError(w, code, err)
+			return
+		}
+		c.adminLog(r, action, name)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// adminLog logs a change made through the AdminHandler.
+func (c Client) adminLog(r *http.Request, action, backend string) {
+	c.lb.options.logger.InfoContext(r.Context(), "admin action", "action", action, "backend", backend, "remote_addr", r.RemoteAddr)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeAdminError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,107 @@
+package openailb
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminHandler(t *testing.T) {
+	t.Parallel()
+
+	a, b := newNamedServer(t, "a"), newNamedServer(t, "b")
+	defer a.Close()
+	defer b.Close()
+	client := NewClient([]OpenaiClientConfig{{Name: "a", APIKey: "k1", BaseURL: a.URL}}, WithAdminAuth(AdminToken("secret")))
+	admin := httptest.NewServer(http.StripPrefix("/admin", client.AdminHandler()))
+	defer admin.Close()
+
+	call := func(method, path, body, token string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(method, admin.URL+"/admin"+path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		out, _ := io.ReadAll(res.Body)
+		return res.StatusCode, strings.TrimSpace(string(out))
+	}
+
+	if code, body := call("GET", "/backends", "", "wrong"); code != http.StatusForbidden || body != `{"error":"invalid admin token"}` {
+		t.Errorf("Expected a wrong token to be rejected, got %d %s", code, body)
+	}
+	if code, _ := call("POST", "/backends", `{"name": "b", "api_key": "k2", "base_url": "`+b.URL+`", "weight": 2}`, "secret"); code != http.StatusCreated {
+		t.Fatalf("Expected the backend to be added, got %d", code)
+	}
+	if code, body := call("POST", "/backends", `{"name": "b", "api_key": "k2", "base_url": "`+b.URL+`"}`, "secret"); code != http.StatusConflict {
+		t.Errorf("Expected a taken name to conflict, got %d %s", code, body)
+	}
+	if code, body := call("POST", "/backends", `{"name": "c", "base_url": "`+b.URL+`"}`, "secret"); code != http.StatusBadRequest || !strings.Contains(body, "api_key: is required") {
+		t.Errorf("Expected an invalid backend to be refused, got %d %s", code, body)
+	}
+
+	code, body := call("GET", "/backends", "", "secret")
+	var backends []DebugBackend
+	if err := json.Unmarshal([]byte(body), &backends); code != http.StatusOK || err != nil || len(backends) != 2 || backends[1].Weight != 2 {
+		t.Fatalf("Expected both backends to be listed, got %d %s", code, body)
+	}
+
+	if code, _ := call("PUT", "/backends/b/weight", `{"weight": 0}`, "secret"); code != http.StatusNoContent {
+		t.Errorf("Expected the weight to be set, got %d", code)
+	}
+	if hits := countHits(t, client, 3); hits["a"] != 3 {
+		t.Errorf("Expected a weight of 0 to stop traffic, got %v", hits)
+	}
+	if code, _ := call("PUT", "/backends/b/weight", `{"weight": -1}`, "secret"); code != http.StatusBadRequest {
+		t.Errorf("Expected a negative weight to be refused, got %d", code)
+	}
+	if code, _ := call("POST", "/backends/a/switchover?to=b", "", "secret"); code != http.StatusNoContent {
+		t.Errorf("Expected the backends to be switched over, got %d", code)
+	}
+	if hits := countHits(t, client, 3); hits["b"] != 3 {
+		t.Errorf("Expected the switchover to move the traffic to b, got %v", hits)
+	}
+	if code, _ := call("POST", "/backends/b/switchover?to=nope", "", "secret"); code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown target to be refused, got %d", code)
+	}
+	if code, _ := call("POST", "/backends/b/switchover?to=a", "", "secret"); code != http.StatusNoContent {
+		t.Errorf("Expected the switchover to be reversed, got %d", code)
+	}
+
+	if code, _ := call("POST", "/backends/a/trip?reason=incident", "", "secret"); code != http.StatusNoContent {
+		t.Errorf("Expected the breakers to be tripped, got %d", code)
+	}
+	_, body = call("GET", "/backends/a", "", "secret")
+	if !strings.Contains(body, `"status":"breaker_open"`) || !strings.Contains(body, `"opened_by":"incident"`) {
+		t.Errorf("Expected the tripped breakers to be open, got %s", body)
+	}
+	if code, _ := call("POST", "/backends/a/reset", "", "secret"); code != http.StatusNoContent {
+		t.Errorf("Expected the breakers to be reset, got %d", code)
+	}
+	if _, body = call("GET", "/backends/a", "", "secret"); !strings.Contains(body, `"status":"healthy"`) {
+		t.Errorf("Expected the reset breakers to be closed, got %s", body)
+	}
+
+	if code, _ := call("POST", "/backends/b/drain?timeout=1s", "", "secret"); code != http.StatusNoContent {
+		t.Errorf("Expected the backend to be drained, got %d", code)
+	}
+	if code, _ := call("DELETE", "/backends/a", "", "secret"); code != http.StatusNoContent {
+		t.Errorf("Expected the backend to be removed, got %d", code)
+	}
+	if code, body := call("POST", "/backends/a/reset", "", "secret"); code != http.StatusNotFound || body != `{"error":"no backend named \"a\""}` {
+		t.Errorf("Expected an unknown backend to be reported, got %d %s", code, body)
+	}
+
+	disabled := httptest.NewRecorder()
+	NewClient(nil).AdminHandler().ServeHTTP(disabled, httptest.NewRequest("GET", "/backends", nil))
+	if disabled.Code != http.StatusForbidden {
+		t.Errorf("Expected the admin API to be disabled without WithAdminAuth, got %d", disabled.Code)
+	}
+}
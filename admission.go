@@ -0,0 +1,216 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrOverloaded is returned for the requests turned away by
+// WithMaxConcurrency: when its queue is full, or after waiting in it for
+// longer than its timeout.
+var ErrOverloaded = errors.New("overloaded")
+
+// WithMaxConcurrency caps the requests in flight across all backends at
+// limit, streams included, to protect both the process and the providers
+// during traffic spikes. The requests over the cap wait in a queue of up to
+// queueSize requests, highest WithPriority first and in arrival order within
+// a priority, each for at most queueTimeout (0 for as long as its context
+// allows); the others fail with ErrOverloaded at once. A request failing
+// over (WithFailover) keeps its slot rather than queueing again. A limit of
+// 0 removes the cap.
+func WithMaxConcurrency(limit, queueSize int, queueTimeout time.Duration) LBOption {
+	return func(o *lbOptions) {
+		if limit <= 0 {
+			o.admission = nil
+			return
+		}
+		o.admission = &admission{limit: limit, queueSize: queueSize, timeout: queueTimeout}
+	}
+}
+
+// admission is the queue of WithMaxConcurrency.
+type admission struct {
+	limit, queueSize int
+	timeout          time.Duration
+
+	mu     sync.Mutex
+	active int
+	queue  []*admissionWaiter
+}
+
+// admissionWaiter is a request waiting in the queue.
+type admissionWaiter struct {
+	priority Priority
+	tenant   string
+	round    int        // The tenant's requests of the same priority queued before it.
+	ready    chan error // Receives nil once the request holds a slot, or the error it was shed with.
+}
+
+// admit charges the request to its tenant's quota (WithTenantQuotas) and
+// waits for one of the slots of WithMaxConcurrency, if set, unless
+// WithLoadShedding or a spent WithBudget rejects it first. The returned func
+// hands them back.
+func (lb *LoadBalancer) admit(ctx context.Context) (func(), error) {
+	start := time.Now()
+	shedding := lb.options.shedding
+	if shedding != nil && PriorityFromContext(ctx) == PriorityLow && shedding.shed(start) {
+		return nil, backpressure(ErrOverloaded, 0, "shedding low-priority requests")
+	}
+	if b := lb.options.budget; b != nil && b.Reject && b.exceeded(start) {
+		return nil, backpressure(ErrBudgetExceeded, b.resetIn(start), fmt.Sprintf("%v spent this %s period", b.Limit, b.Period))
+	}
+	t, tokens := lb.tenantOf(ctx), tokenEstimateFrom(ctx)
+	leave, err := t.take(start, tokens)
+	if err != nil {
+		return nil, err
+	}
+	admitted, err := lb.options.admission.wait(ctx)
+	if err != nil {
+		leave()
+		t.refund(time.Now(), tokens)
+		return nil, err
+	}
+	done := shedding.admitted(time.Since(start))
+	return func() {
+		done()
+		admitted()
+		leave()
+	}, nil
+}
+
+// sharedRelease calls release once each of its holders handed it back, so
+// that a request's admission outlives all of its attempts.
+type sharedRelease struct {
+	mu      sync.Mutex
+	holders int
+	release func()
+}
+
+// hold adds a holder and returns the func handing it back, once.
+func (s *sharedRelease) hold() func() {
+	s.mu.Lock()
+	s.holders++
+	s.mu.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			s.holders--
+			last := s.holders == 0
+			s.mu.Unlock()
+			if last {
+				s.release()
+			}
+		})
+	}
+}
+
+// wait waits for a slot, if a is set, and returns the func handing it back.
+func (a *admission) wait(ctx context.Context) (func(), error) {
+	if a == nil {
+		return func() {}, nil
+	}
+	w := &admissionWaiter{priority: PriorityFromContext(ctx), tenant: TenantFromContext(ctx), ready: make(chan error, 1)}
+	a.mu.Lock()
+	if a.active < a.limit && len(a.queue) == 0 {
+		a.active++
+		a.mu.Unlock()
+		return sync.OnceFunc(a.release), nil
+	}
+	if !a.enqueue(w) {
+		a.mu.Unlock()
+		return nil, backpressure(ErrOverloaded, 0, fmt.Sprintf("%d requests in flight and %d queued", a.limit, a.queueSize))
+	}
+	a.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if a.timeout > 0 {
+		timer := time.NewTimer(a.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var err error
+	select {
+	case err = <-w.ready:
+		if err != nil {
+			return nil, err
+		}
+		return sync.OnceFunc(a.release), nil
+	case <-timeout:
+		err = backpressure(ErrOverloaded, 0, fmt.Sprintf("queued for %s", a.timeout))
+	case <-ctx.Done():
+		err = fmt.Errorf("queued: %w", ctx.Err())
+	}
+	if !a.leave(w) {
+		if <-w.ready == nil {
+			// Granted meanwhile; pass the slot on.
+			a.release()
+		}
+	}
+	return nil, err
+}
+
+// enqueue queues w behind the requests of a higher priority, taking turns
+// with the other tenants within its own: a tenant's nth queued request goes
+// behind the others' nth. In a full queue, w takes the place of the last
+// request if that one comes after it, which is shed. It reports false if w
+// has no place. a.mu must be held.
+func (a *admission) enqueue(w *admissionWaiter) bool {
+	for _, q := range a.queue {
+		if q.priority == w.priority && q.tenant == w.tenant {
+			w.round++
+		}
+	}
+	if len(a.queue) >= a.queueSize {
+		last := len(a.queue) - 1
+		if last < 0 || !w.before(a.queue[last]) {
+			return false
+		}
+		shed := a.queue[last]
+		a.queue = a.queue[:last]
+		shed.ready <- backpressure(ErrOverloaded, 0, "shed from a full queue")
+	}
+	i := len(a.queue)
+	for i > 0 && w.before(a.queue[i-1]) {
+		i--
+	}
+	a.queue = slices.Insert(a.queue, i, w)
+	return true
+}
+
+// before reports whether w is to be served before q.
+func (w *admissionWaiter) before(q *admissionWaiter) bool {
+	if w.priority != q.priority {
+		return w.priority > q.priority
+	}
+	return w.round < q.round
+}
+
+// release hands a slot to the first request in the queue, or frees it.
+func (a *admission) release() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.queue) > 0 {
+		w := a.queue[0]
+		a.queue = a.queue[1:]
+		w.ready <- nil
+		return
+	}
+	a.active--
+}
+
+// leave takes w out of the queue. It reports false if w was granted a slot
+// or shed.
+func (a *admission) leave(w *admissionWaiter) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if i := slices.Index(a.queue, w); i >= 0 {
+		a.queue = slices.Delete(a.queue, i, i+1)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMaxConcurrency(t *testing.T) {
+	t.Parallel()
+
+	arrived, release := make(chan struct{}, 4), make(chan struct{})
+	slow := newBlockingServer(arrived, release)
+	defer slow.Close()
+	defer close(release)
+
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: slow.URL},
+		{APIKey: "k2", BaseURL: slow.URL},
+	}, WithMaxConcurrency(1, 1, 0))
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := client.Chat.Completions.New(context.Background(), chatParams("m"))
+			errs <- err
+		}()
+	}
+	<-arrived
+	select {
+	case <-arrived:
+		t.Fatal("Expected the second request to wait in the queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m")); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("Expected ErrOverloaded with the queue full, got %v", err)
+	}
+
+	release <- struct{}{}
+	<-arrived
+	release <- struct{}{}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+	if a := client.lb.options.admission; a.active != 0 || len(a.queue) != 0 {
+		t.Errorf("Expected every slot to be handed back, got %d held and %d queued", a.active, len(a.queue))
+	}
+}
+
+func TestMaxConcurrencyQueueTimeout(t *testing.T) {
+	t.Parallel()
+
+	arrived, release := make(chan struct{}, 4), make(chan struct{})
+	slow := newBlockingServer(arrived, release)
+	defer slow.Close()
+	defer close(release)
+
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: slow.URL}}, WithMaxConcurrency(1, 4, 20*time.Millisecond))
+	errs := make(chan error, 1)
+	go func() {
+		_, err := client.Chat.Completions.New(context.Background(), chatParams("m"))
+		errs <- err
+	}()
+	<-arrived
+
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m")); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("Expected ErrOverloaded after the queue timeout, got %v", err)
+	}
+	stream := client.Chat.Completions.NewStreaming(context.Background(), chatParams("m"))
+	if stream.Next() || !errors.Is(stream.Err(), ErrOverloaded) {
+		t.Errorf("Expected a stream failing with ErrOverloaded, got %v", stream.Err())
+	}
+	var backpressure *BackpressureError
+	if !errors.As(stream.Err(), &backpressure) || backpressure.StatusCode() != http.StatusServiceUnavailable {
+		t.Errorf("Expected the stream's error to be a 503 BackpressureError, got %v", stream.Err())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.Chat.Completions.New(ctx, chatParams("m")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the queued request to give up with its context, got %v", err)
+	}
+
+	release <- struct{}{}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if a := client.lb.options.admission; a.active != 0 || len(a.queue) != 0 {
+		t.Errorf("Expected every slot to be handed back, got %d held and %d queued", a.active, len(a.queue))
+	}
+}
@@ -0,0 +1,134 @@
+package openailb
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// UsageAlert warns (WithUsageAlerts) as the spend or the tokens of the
+// requests it matches cross fractions of its limits within a period, e.g.
+// before a Budget or a quota kicks in.
+type UsageAlert struct {
+	// Name identifies the alert in its UsageAlertEvents.
+	Name string `json:"name" yaml:"name"`
+	// Backend, Model (as requested) and Tenant (WithTenant) select the
+	// requests counted; empty ones match all.
+	Backend string `json:"backend,omitempty" yaml:"backend,omitempty"`
+	Model   string `json:"model,omitempty" yaml:"model,omitempty"`
+	Tenant  string `json:"tenant,omitempty" yaml:"tenant,omitempty"`
+	// Spend and Tokens are the limits per Period, at least one of them set.
+	Spend  float64      `json:"spend,omitempty" yaml:"spend,omitempty"`
+	Tokens int64        `json:"tokens,omitempty" yaml:"tokens,omitempty"`
+	Period BudgetPeriod `json:"period" yaml:"period"`
+	// Thresholds are the fractions of the limits that fire the alert
+	// (default 0.5, 0.8 and 1).
+	Thresholds []float64 `json:"thresholds,omitempty" yaml:"thresholds,omitempty"`
+}
+
+// UsageAlertEvent is a UsageAlert crossing one of its thresholds. It is
+// delivered once per threshold and period.
+type UsageAlertEvent struct {
+	Alert string `json:"alert"`
+	// Metric is "spend" or "tokens".
+	Metric    string    `json:"metric"`
+	Threshold float64   `json:"threshold"`
+	Used      float64   `json:"used"`
+	Limit     float64   `json:"limit"`
+	At        time.Time `json:"at"`
+}
+
+// WithUsageAlerts fires alerts as usage crosses their thresholds, to the
+// Hooks.OnUsageAlert hooks, the WithNotifier webhook and the logger.
+func WithUsageAlerts(alerts ...UsageAlert) LBOption {
+	return func(o *lbOptions) {
+		for _, a := range alerts {
+			if len(a.Thresholds) == 0 {
+				a.Thresholds = []float64{0.5, 0.8, 1}
+			}
+			o.alerts = append(o.alerts, &alertState{UsageAlert: a})
+		}
+	}
+}
+
+// validate checks a, to be reported under field.
+func (a *UsageAlert) validate(field string) []error {
+	var errs []error
+	if a.Name == "" {
+		errs = append(errs, fmt.Errorf("%s.name: is required", field))
+	}
+	if a.Spend < 0 || a.Tokens < 0 || (a.Spend == 0 && a.Tokens == 0) {
+		errs = append(errs, fmt.Errorf("%s: a positive spend or tokens limit is required", field))
+	}
+	if err := a.Period.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("%s.period: %w", field, err))
+	}
+	for i, t := range a.Thresholds {
+		if t <= 0 {
+			errs = append(errs, fmt.Errorf("%s.thresholds[%d]: must be positive, got %v", field, i, t))
+		}
+	}
+	return errs
+}
+
+// alertState is the usage of the current period against a UsageAlert.
+type alertState struct {
+	UsageAlert
+
+	mu     sync.Mutex
+	start  time.Time // Of the current period.
+	spend  float64
+	tokens int64
+}
+
+// matches reports whether r counts towards the alert.
+func (s *alertState) matches(r ResponseInfo) bool {
+	return (s.Backend == "" || s.Backend == r.Backend) &&
+		(s.Model == "" || s.Model == r.Model) &&
+		(s.Tenant == "" || s.Tenant == r.Tenant)
+}
+
+// record adds r, finished at now, and returns the thresholds it crossed.
+func (s *alertState) record(now time.Time, r ResponseInfo) []UsageAlertEvent {
+	tokens := r.PromptTokens + r.CompletionTokens
+	if (r.Cost <= 0 && tokens <= 0) || !s.matches(r) {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if start := periodStart(s.Period, now); !start.Equal(s.start) {
+		s.start, s.spend, s.tokens = start, 0, 0
+	}
+	var fired []UsageAlertEvent
+	crossed := func(metric string, before, after, limit float64) {
+		for _, t := range s.Thresholds {
+			if limit > 0 && before < t*limit && after >= t*limit {
+				fired = append(fired, UsageAlertEvent{Alert: s.Name, Metric: metric, Threshold: t, Used: after, Limit: limit, At: now})
+			}
+		}
+	}
+	crossed("spend", s.spend, s.spend+r.Cost, s.Spend)
+	crossed("tokens", float64(s.tokens), float64(s.tokens+tokens), float64(s.Tokens))
+	s.spend += r.Cost
+	s.tokens += tokens
+	return fired
+}
+
+// checkAlerts counts r, finished at now, towards the alerts and delivers
+// those it fired.
+func (lb *LoadBalancer) checkAlerts(now time.Time, r ResponseInfo) {
+	for _, s := range lb.options.alerts {
+		for _, ev := range s.record(now, r) {
+			lb.options.logger.Warn("usage alert",
+				"alert", ev.Alert, "metric", ev.Metric, "threshold", ev.Threshold, "used", ev.Used, "limit", ev.Limit)
+			for _, h := range lb.options.hooks {
+				if h.OnUsageAlert != nil {
+					h.OnUsageAlert(ev)
+				}
+			}
+			if n := lb.options.notifier; n != nil {
+				go n.alert(ev)
+			}
+		}
+	}
+}
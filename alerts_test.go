@@ -0,0 +1,88 @@
+package openailb
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUsageAlerts(t *testing.T) {
+	t.Parallel()
+
+	posts := make(chan []byte, 10)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		posts <- body
+	}))
+	defer webhook.Close()
+	backend := newUsageServer("ok")
+	defer backend.Close()
+
+	var mu sync.Mutex
+	var fired []UsageAlertEvent
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: backend.URL, Prices: budgetPrices}},
+		WithUsageAlerts(
+			UsageAlert{Name: "team-a spend", Tenant: "a", Spend: 0.02, Period: BudgetDaily},
+			UsageAlert{Name: "tokens", Tokens: 3000, Period: BudgetMonthly, Thresholds: []float64{1}},
+		),
+		WithNotifier(Notifier{URL: webhook.URL}),
+		WithHooks(Hooks{OnUsageAlert: func(ev UsageAlertEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			fired = append(fired, ev)
+		}}))
+
+	// 0.006 and 1500 tokens a request.
+	if _, err := client.Chat.Completions.New(WithTenant(context.Background(), "b"), chatParams("m")); err != nil {
+		t.Fatal(err)
+	}
+	a := WithTenant(context.Background(), "a")
+	for i := 0; i < 4; i++ {
+		if _, err := client.Chat.Completions.New(a, chatParams("m")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var got []string
+	for _, ev := range fired {
+		got = append(got, ev.Alert+"@"+ev.Metric)
+	}
+	want := []string{"tokens@tokens", "team-a spend@spend", "team-a spend@spend", "team-a spend@spend"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected alerts %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expected alerts %v, got %v", want, got)
+		}
+	}
+	if ev := fired[1]; ev.Threshold != 0.5 || !near(ev.Used, 0.012) || ev.Limit != 0.02 {
+		t.Errorf("Expected the 50%% alert at 0.012 of 0.02, got %+v", ev)
+	}
+	if ev := fired[3]; ev.Threshold != 1 || !near(ev.Used, 0.024) {
+		t.Errorf("Expected the 100%% alert at 0.024, got %+v", ev)
+	}
+
+	seen := make(map[float64]bool)
+	for len(seen) < 3 {
+		select {
+		case body := <-posts:
+			var ev UsageAlertEvent
+			if err := json.Unmarshal(body, &ev); err != nil {
+				t.Fatal(err)
+			}
+			if ev.Alert == "team-a spend" {
+				seen[ev.Threshold] = true
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected the alerts to be posted to the webhook, got %v", seen)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package openailb
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// WithModelAlias defines alias as a model callers may request, served by
+// the backends named in models, each as the model it maps the backend's name
+// to, e.g.
+//
+//	WithModelAlias("fast-chat", map[string]string{"openai": "gpt-4o-mini", "vllm": "llama-3.1-8b"})
+//
+// Requests for alias go to those backends only, balanced and failing over
+// among them as usual; the alias takes precedence over their ModelMap. A
+// WithPools pool serves the alias if it lists it.
+func WithModelAlias(alias string, models map[string]string) LBOption {
+	return func(o *lbOptions) {
+		if o.aliases == nil {
+			o.aliases = make(map[string]map[string]string)
+		}
+		o.aliases[alias] = models
+	}
+}
+
+// aliasTarget returns the model the backend serves alias as, reporting
+// false if alias isn't one. The model is empty if the backend doesn't
+// serve it.
+func (c *SafeClient) aliasTarget(alias string) (string, bool) {
+	models, ok := c.lb.options.aliases[alias]
+	if !ok {
+		return "", false
+	}
+	return models[c.Name], true
+}
+
+// validateAliases checks that the aliases of opts name backends of configs.
+func validateAliases(configs []OpenaiClientConfig, opts []LBOption) error {
+	var options lbOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	names := make(map[string]bool, len(configs))
+	for i, cfg := range configs {
+		names[backendName(cfg, i)] = true
+	}
+	aliases := make([]string, 0, len(options.aliases))
+	for alias := range options.aliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	var errs []error
+	for _, alias := range aliases {
+		for _, name := range sortedKeys(options.aliases[alias]) {
+			if !names[name] {
+				errs = append(errs, fmt.Errorf("aliases[%q]: no backend named %q", alias, name))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
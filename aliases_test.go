@@ -0,0 +1,43 @@
+package openailb
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestModelAlias(t *testing.T) {
+	t.Parallel()
+
+	server := newModelEchoServer(t)
+	defer server.Close()
+	configs := []OpenaiClientConfig{
+		{Name: "openai", APIKey: "k1", BaseURL: server.URL},
+		{Name: "vllm", APIKey: "k2", BaseURL: server.URL},
+		{Name: "other", APIKey: "k3", BaseURL: server.URL},
+	}
+	client, err := New(configs, WithModelAlias("fast-chat", map[string]string{"openai": "gpt-4o-mini", "vllm": "llama-3.1-8b"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	models := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		resp, err := client.Chat.Completions.New(context.Background(), chatParams("fast-chat"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		models[resp.Choices[0].Message.Content]++
+	}
+	if models["gpt-4o-mini"] != 2 || models["llama-3.1-8b"] != 2 {
+		t.Errorf("Expected the alias to be balanced over its backends as their models, got %v", models)
+	}
+
+	_, err = New(configs, WithModelAlias("fast-chat", map[string]string{"missing": "m"}))
+	if err == nil || !strings.Contains(err.Error(), `no backend named "missing"`) {
+		t.Errorf("Expected the unknown backend to be reported, got %v", err)
+	}
+	if _, err := NewClient(configs, WithModelAlias("fast-chat", map[string]string{"missing": "m"})).Chat.Completions.New(context.Background(), chatParams("fast-chat")); err == nil || !strings.Contains(err.Error(), "no backend serves the alias") {
+		t.Errorf("Expected an alias without backends to fail, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package openailb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// Audit configures the audit log (WithAudit): a summary of every backend
+// attempt, written to Writer, for teams that must account for every LLM call.
+type Audit struct {
+	Writer AuditWriter
+	// SampleRate is the fraction of attempts recorded, from 0 to 1 (default 1).
+	SampleRate float64
+	// Content says how prompts and completions are recorded (default AuditOmit).
+	Content AuditContent
+	// MaxContentLength is where AuditTruncate cuts the content (default 256 bytes).
+	MaxContentLength int
+	// Redact, if set, edits every record before it is written, e.g. to blank
+	// the tag or mask parts of the prompt.
+	Redact func(*AuditRecord)
+}
+
+// withDefaults fills in the zero fields.
+func (a Audit) withDefaults() Audit {
+	if a.SampleRate <= 0 {
+		a.SampleRate = 1
+	}
+	if a.MaxContentLength <= 0 {
+		a.MaxContentLength = 256
+	}
+	return a
+}
+
+// AuditContent says how the audit log records prompts and completions.
+type AuditContent int
+
+const (
+	// AuditOmit leaves the content out.
+	AuditOmit AuditContent = iota
+	// AuditTruncate records the first MaxContentLength bytes of the content.
+	AuditTruncate
+	// AuditHash records the hex SHA-256 of the content, so identical prompts
+	// can be matched without storing them.
+	AuditHash
+	// AuditFull records the content as is.
+	AuditFull
+)
+
+// AuditRecord summarizes one backend attempt.
+type AuditRecord struct {
+	Time             time.Time     `json:"time"`
+	Backend          string        `json:"backend"`
+	Service          ServiceType   `json:"service"`
+	Model            string        `json:"model"`
+	MappedModel      string        `json:"mapped_model"`
+	Tag              string        `json:"tag,omitempty"`
+	Attempt          int           `json:"attempt"`
+	Stream           bool          `json:"stream,omitempty"`
+	Latency          time.Duration `json:"latency"`
+	PromptTokens     int64         `json:"prompt_tokens"`
+	CompletionTokens int64         `json:"completion_tokens"`
+	Cost             float64       `json:"cost,omitempty"`
+	Class            ErrorClass    `json:"class"`
+	Error            string        `json:"error,omitempty"`
+	// Prompt and Completion are recorded as set by Audit.Content. The prompt
+	// is the text of the request's messages or input; streamed completions
+	// are not recorded.
+	Prompt     string `json:"prompt,omitempty"`
+	Completion string `json:"completion,omitempty"`
+}
+
+// AuditWriter stores audit records. It must be safe for concurrent use, and is
+// called on the request path.
+type AuditWriter interface {
+	WriteAudit(r AuditRecord) error
+}
+
+// jsonAuditWriter writes records as JSON lines.
+type jsonAuditWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONAuditWriter returns an AuditWriter writing one JSON object per line to w.
+func NewJSONAuditWriter(w io.Writer) AuditWriter {
+	return &jsonAuditWriter{enc: json.NewEncoder(w)}
+}
+
+func (w *jsonAuditWriter) WriteAudit(r AuditRecord) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.enc.Encode(r)
+}
+
+// audit writes the record of a finished attempt, if it is sampled.
+func (lb *LoadBalancer) audit(ctx context.Context, capture *attemptCapture, r ResponseInfo, res any) {
+	cfg := lb.options.audit
+	if cfg == nil || rand.Float64() >= cfg.SampleRate {
+		return
+	}
+	rec := AuditRecord{
+		Time:             time.Now(),
+		Backend:          r.Backend,
+		Service:          r.Service,
+		Model:            r.Model,
+		MappedModel:      r.MappedModel,
+		Tag:              r.Tag,
+		Attempt:          r.Attempt,
+		Stream:           r.Stream,
+		Latency:          r.Latency,
+		PromptTokens:     r.PromptTokens,
+		CompletionTokens: r.CompletionTokens,
+		Cost:             r.Cost,
+		Class:            r.Class,
+	}
+	if r.Err != nil {
+		rec.Error = r.Err.Error()
+	}
+	if cfg.Content != AuditOmit {
+		rec.Prompt = cfg.content(promptOf(capture.body))
+		rec.Completion = cfg.content(completionOf(res))
+	}
+	if cfg.Redact != nil {
+		cfg.Redact(&rec)
+	}
+	if err := cfg.Writer.WriteAudit(rec); err != nil {
+		lb.options.logger.WarnContext(ctx, "audit record not written", "backend", r.Backend, "error", err)
+	}
+}
+
+// content renders s as configured by a.Content.
+func (a *Audit) content(s string) string {
+	if s == "" {
+		return ""
+	}
+	switch a.Content {
+	case AuditTruncate:
+		if len(s) > a.MaxContentLength {
+			return s[:a.MaxContentLength]
+		}
+	case AuditHash:
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+	return s
+}
+
+// promptOf returns the text of a JSON request body: its messages for chat
+// completions, its input or prompt otherwise, or the body itself.
+func promptOf(body []byte) string {
+	var req struct {
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+		Input  json.RawMessage `json:"input"`
+		Prompt json.RawMessage `json:"prompt"`
+	}
+	if len(body) == 0 || json.Unmarshal(body, &req) != nil {
+		return string(body)
+	}
+	switch {
+	case len(req.Messages) > 0:
+		var b strings.Builder
+		for i, m := range req.Messages {
+			if i > 0 {
+				b.WriteByte('\n')
+			}
+			b.WriteString(m.Role)
+			b.WriteString(": ")
+			b.WriteString(textOf(m.Content))
+		}
+		return b.String()
+	case len(req.Input) > 0:
+		return textOf(req.Input)
+	case len(req.Prompt) > 0:
+		return textOf(req.Prompt)
+	}
+	return string(body)
+}
+
+// textOf returns a JSON string as is, and any other JSON value as JSON.
+func textOf(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	return string(raw)
+}
+
+// completionOf returns the generated text of a response.
+func completionOf(res any) string {
+	switch r := res.(type) {
+	case *openai.ChatCompletion:
+		if r != nil && len(r.Choices) > 0 {
+			return r.Choices[0].Message.Content
+		}
+	case *responses.Response:
+		if r != nil {
+			return r.OutputText()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package openailb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// recordingAuditWriter keeps the records written to it.
+type recordingAuditWriter struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (w *recordingAuditWriter) WriteAudit(r AuditRecord) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.records = append(w.records, r)
+	return nil
+}
+
+func TestAudit(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "Hello there"}}], "usage": {"prompt_tokens": 5, "completion_tokens": 2}}`))
+	}))
+	defer server.Close()
+	configs := []OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL, ModelMap: map[string]string{"m": "mapped"}}}
+	params := openai.ChatCompletionNewParams{
+		Model:    "m",
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("a secret prompt")},
+	}
+
+	writer := &recordingAuditWriter{}
+	client := NewClient(configs, WithAudit(Audit{
+		Writer:           writer,
+		Content:          AuditTruncate,
+		MaxContentLength: 8,
+		Redact:           func(r *AuditRecord) { r.Tag = "" },
+	}))
+	if _, err := client.Chat.Completions.New(WithTag(context.Background(), "team-a"), params); err != nil {
+		t.Fatal(err)
+	}
+	if len(writer.records) != 1 {
+		t.Fatalf("Expected one audit record, got %d", len(writer.records))
+	}
+	rec := writer.records[0]
+	if rec.Backend != "Client-0" || rec.Model != "m" || rec.MappedModel != "mapped" || rec.PromptTokens != 5 || rec.CompletionTokens != 2 || rec.Class != ClassOK {
+		t.Errorf("Unexpected audit record %+v", rec)
+	}
+	if rec.Prompt != "user: a " || rec.Completion != "Hello th" {
+		t.Errorf("Expected truncated content, got %q and %q", rec.Prompt, rec.Completion)
+	}
+	if rec.Tag != "" {
+		t.Errorf("Expected the tag to be redacted, got %q", rec.Tag)
+	}
+
+	// Hashed content, as JSON lines.
+	var buf bytes.Buffer
+	client = NewClient(configs, WithAudit(Audit{Writer: NewJSONAuditWriter(&buf), Content: AuditHash}))
+	if _, err := client.Chat.Completions.New(context.Background(), params); err != nil {
+		t.Fatal(err)
+	}
+	var line AuditRecord
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	if len(line.Prompt) != 64 || strings.Contains(buf.String(), "secret") {
+		t.Errorf("Expected a hashed prompt, got %s", buf.String())
+	}
+
+	// Nothing is sampled at a tiny rate.
+	writer = &recordingAuditWriter{}
+	client = NewClient(configs, WithAudit(Audit{Writer: writer, SampleRate: 1e-12}))
+	for i := 0; i < 10; i++ {
+		if _, err := client.Chat.Completions.New(context.Background(), params); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(writer.records) != 0 {
+		t.Errorf("Expected no sampled records, got %d", len(writer.records))
+	}
+}
@@ -0,0 +1,182 @@
+// Package awssmlb resolves openailb API key references from AWS Secrets
+// Manager. A reference is a secret's name or ARN, optionally followed by the
+// key holding the API key in a JSON secret: "prod/openai#api_key". It calls
+// GetSecretValue over HTTP, signing the requests with Signature Version 4.
+//
+//	client, err := openailb.New([]openailb.OpenaiClientConfig{
+//		{APIKeyRef: "prod/openai#api_key", BaseURL: "https://api.openai.com/v1"},
+//	}, openailb.WithSecretProvider(&awssmlb.Provider{Region: "us-east-1"}, 0))
+package awssmlb
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Provider is an openailb.SecretProvider over AWS Secrets Manager. The
+// credentials default to the standard environment variables, e.g. those an
+// ECS task or a Lambda function is given.
+type Provider struct {
+	// Region is the secrets' region (default $AWS_REGION, then
+	// $AWS_DEFAULT_REGION).
+	Region string
+	// Endpoint, if set, replaces the regional endpoint, e.g. a VPC endpoint.
+	Endpoint string
+	// AccessKeyID, SecretAccessKey and SessionToken sign the requests
+	// (default $AWS_ACCESS_KEY_ID, $AWS_SECRET_ACCESS_KEY and
+	// $AWS_SESSION_TOKEN).
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// HTTPClient sends the requests (default http.DefaultClient).
+	HTTPClient *http.Client
+}
+
+// credentials signs requests.
+type credentials struct {
+	accessKeyID, secretAccessKey, sessionToken string
+}
+
+// Secret implements openailb.SecretProvider. ref is "secret-id" for a
+// plaintext secret, or "secret-id#key" for a key of a JSON secret.
+func (p *Provider) Secret(ctx context.Context, ref string) (string, error) {
+	id, key, _ := strings.Cut(ref, "#")
+	if id == "" {
+		return "", fmt.Errorf("awssmlb: %q has no secret ID", ref)
+	}
+	region := envDefault(p.Region, "AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	creds := credentials{
+		accessKeyID:     envDefault(p.AccessKeyID, "AWS_ACCESS_KEY_ID"),
+		secretAccessKey: envDefault(p.SecretAccessKey, "AWS_SECRET_ACCESS_KEY"),
+		sessionToken:    envDefault(p.SessionToken, "AWS_SESSION_TOKEN"),
+	}
+	if region == "" || creds.accessKeyID == "" || creds.secretAccessKey == "" {
+		return "", fmt.Errorf("awssmlb: a region and credentials are required")
+	}
+	endpoint := p.Endpoint
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + region + ".amazonaws.com"
+	}
+
+	body, _ := json.Marshal(map[string]string{"SecretId": id})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	sign(req, body, creds, region, "secretsmanager", time.Now())
+	httpClient := p.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return "", fmt.Errorf("awssmlb: get %s: %s: %s", id, res.Status, strings.TrimSpace(string(msg)))
+	}
+	var secret struct {
+		SecretString *string
+	}
+	if err := json.NewDecoder(res.Body).Decode(&secret); err != nil {
+		return "", fmt.Errorf("awssmlb: get %s: %w", id, err)
+	}
+	if secret.SecretString == nil {
+		return "", fmt.Errorf("awssmlb: %s is a binary secret", id)
+	}
+	if key == "" {
+		return *secret.SecretString, nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(*secret.SecretString), &fields); err != nil {
+		return "", fmt.Errorf("awssmlb: %s is not a JSON secret with keys: %w", id, err)
+	}
+	value, ok := fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("awssmlb: %s has no string key %q", id, key)
+	}
+	return value, nil
+}
+
+// sign adds the Signature Version 4 headers to req, signing its host and
+// headers and body.
+func sign(req *http.Request, body []byte, creds credentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hashHex(body),
+	}, "\n")
+
+	scope := amzDate[:8] + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hashHex([]byte(canonicalRequest))
+	key := []byte("AWS4" + creds.secretAccessKey)
+	for _, part := range []string{amzDate[:8], region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.accessKeyID, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))))
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// envDefault returns value, or else the environment variable key.
+func envDefault(value, key string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(key)
+}
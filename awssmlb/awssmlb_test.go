@@ -0,0 +1,64 @@
+package awssmlb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSign checks the signer against the get-vanilla case of the AWS
+// Signature Version 4 test suite.
+func TestSign(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	creds := credentials{accessKeyID: "AKIDEXAMPLE", secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	sign(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestProvider(t *testing.T) {
+	secrets := map[string]string{
+		"plain":       "sk-plain",
+		"prod/openai": `{"api_key": "sk-json", "org": "org-1"}`,
+	}
+	sm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" || r.Header.Get("X-Amz-Security-Token") != "session" ||
+			!strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/secretsmanager/aws4_request") {
+			http.Error(w, `{"__type": "UnrecognizedClientException"}`, http.StatusBadRequest)
+			return
+		}
+		var req struct{ SecretId string }
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		secret, ok := secrets[req.SecretId]
+		if !ok {
+			http.Error(w, `{"__type": "ResourceNotFoundException"}`, http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"SecretString": secret})
+	}))
+	defer sm.Close()
+
+	p := &Provider{Region: "eu-west-1", Endpoint: sm.URL, AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session"}
+	for ref, want := range map[string]string{"plain": "sk-plain", "prod/openai#api_key": "sk-json"} {
+		got, err := p.Secret(context.Background(), ref)
+		if err != nil || got != want {
+			t.Errorf("Secret(%q) = %q, %v; want %q", ref, got, err, want)
+		}
+	}
+	for ref, want := range map[string]string{
+		"prod/openai#missing": `has no string key "missing"`,
+		"plain#api_key":       "plain is not a JSON secret",
+		"nope":                "ResourceNotFoundException",
+	} {
+		if _, err := p.Secret(context.Background(), ref); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Secret(%q): expected an error containing %q, got %v", ref, want, err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package openailb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+// AzureAPIVersion is the default api-version of Azure backends.
+const AzureAPIVersion = "2024-10-21"
+
+// Azure makes a backend an Azure OpenAI resource (OpenaiClientConfig.Azure),
+// so it can share a pool with openai.com. The backend's BaseURL is then the
+// resource endpoint, e.g. https://my-resource.openai.azure.com, and its
+// ModelMap maps the requested models to deployment names; unmapped models go
+// to the deployment of the same name. The APIKey is sent as the api-key header.
+type Azure struct {
+	// APIVersion is the api-version of every request (default AzureAPIVersion).
+	APIVersion string `json:"api_version,omitempty" yaml:"api_version,omitempty"`
+}
+
+// azureDeploymentRoutes are the endpoints Azure serves per deployment, as
+// openai/deployments/{deployment}/{route}, relative to the resource endpoint.
+var azureDeploymentRoutes = map[string]bool{
+	"chat/completions":     true,
+	"completions":          true,
+	"embeddings":           true,
+	"audio/speech":         true,
+	"audio/transcriptions": true,
+	"audio/translations":   true,
+	"images/generations":   true,
+	"images/edits":         true,
+}
+
+// options returns the request options that point a client at the resource
+// at endpoint.
+func (a Azure) options(endpoint, apiKey string) []option.RequestOption {
+	version := a.APIVersion
+	if version == "" {
+		version = AzureAPIVersion
+	}
+	base := strings.TrimSuffix(endpoint, "/") + "/openai/"
+	prefix := "/openai/"
+	if u, err := url.Parse(base); err == nil {
+		prefix = u.Path
+	}
+	return []option.RequestOption{
+		option.WithBaseURL(base),
+		option.WithHeaderDel("Authorization"),
+		option.WithHeader("Api-Key", apiKey),
+		option.WithQuery("api-version", version),
+		option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+			route, ok := strings.CutPrefix(req.URL.Path, prefix)
+			if !ok || !azureDeploymentRoutes[route] {
+				return next(req)
+			}
+			deployment, err := requestModel(req)
+			if err != nil {
+				return nil, err
+			}
+			if deployment == "" {
+				return nil, errors.New("azure: the request has no model to pick a deployment by")
+			}
+			req.URL.Path = prefix + "deployments/" + deployment + "/" + route
+			req.URL.RawPath = prefix + "deployments/" + url.PathEscape(deployment) + "/" + route
+			return next(req)
+		}),
+	}
+}
+
+// requestModel returns the model field of a JSON or multipart request body,
+// leaving the body readable.
+func requestModel(req *http.Request) (string, error) {
+	if req.Body == nil {
+		return "", nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+
+	mediaType, params, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		var fields struct {
+			Model string `json:"model"`
+		}
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return "", err
+		}
+		return fields.Model, nil
+	}
+	form := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := form.NextPart()
+		if errors.Is(err, io.EOF) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if part.FormName() == "model" {
+			model, err := io.ReadAll(part)
+			return string(model), err
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestAzureBackend(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var requests []string
+	azure := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.RequestURI())
+		mu.Unlock()
+		if r.Header.Get("Api-Key") != "azure-key" || r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/audio/transcriptions") {
+			_, _ = w.Write([]byte(`{"text": "azure"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "azure"}}]}`))
+	}))
+	defer azure.Close()
+
+	client, err := New([]OpenaiClientConfig{{
+		APIKey:   "azure-key",
+		BaseURL:  azure.URL,
+		Azure:    &Azure{},
+		ModelMap: map[string]string{"gpt-4o": "prod-gpt4o"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Chat.Completions.New(context.Background(), chatParams("gpt-4o"), option.WithMaxRetries(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content := resp.Choices[0].Message.Content; content != "azure" {
+		t.Errorf("Expected the Azure answer, got %s", content)
+	}
+	_, err = client.Chat.Completions.New(context.Background(), chatParams("gpt-4o-mini"), option.WithMaxRetries(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Audio.Transcriptions.New(context.Background(), openai.AudioTranscriptionNewParams{
+		Model: "whisper",
+		File:  strings.NewReader("audio"),
+	}, option.WithMaxRetries(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.lb.backends()[0].ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{
+		"/openai/deployments/prod-gpt4o/chat/completions?api-version=" + AzureAPIVersion,
+		"/openai/deployments/gpt-4o-mini/chat/completions?api-version=" + AzureAPIVersion,
+		"/openai/deployments/whisper/audio/transcriptions?api-version=" + AzureAPIVersion,
+		"/openai/models?api-version=" + AzureAPIVersion,
+	}
+	if strings.Join(requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected the requests\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(requests, "\n"))
+	}
+}
+
+func TestAzureSharesPool(t *testing.T) {
+	t.Parallel()
+
+	var paths sync.Map
+	azure := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths.Store(r.URL.Path, r.URL.Query().Get("api-version"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "azure"}}]}`))
+	}))
+	defer azure.Close()
+	openaiServer := newNamedServer(t, "openai")
+	defer openaiServer.Close()
+
+	path := writeConfig(t, "lb.yaml", `
+backends:
+  - api_key: k1
+    base_url: `+openaiServer.URL+`
+  - api_key: k2
+    base_url: `+azure.URL+`/
+    azure: {api_version: 2025-01-01-preview}
+    model_map: {m: east}
+  - name: azure-west
+    api_key: k2
+    base_url: `+azure.URL+`
+    azure: {}
+    model_map: {m: west}
+`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClientFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits := countHits(t, client, 3); hits["openai"] != 1 || hits["azure"] != 2 {
+		t.Errorf("Expected each backend to serve once, got %v", hits)
+	}
+	for path, version := range map[string]string{
+		"/openai/deployments/east/chat/completions": "2025-01-01-preview",
+		"/openai/deployments/west/chat/completions": AzureAPIVersion,
+	} {
+		if got, ok := paths.Load(path); !ok || got != version {
+			t.Errorf("Expected a request to %s with api-version %s, got %v", path, version, got)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package openailb
+
+import "context"
+
+// Backend identifies the backend serving a request attempt.
+type Backend struct {
+	Name    string
+	BaseURL string
+	// Model is the model sent to the backend, after model mapping.
+	Model string
+	// Attempt is 1 for the first try, 2 for the first failover, ...
+	Attempt int
+}
+
+type backendKey struct{}
+
+// withBackend returns ctx carrying the backend of a.
+func withBackend(ctx context.Context, a attempt) context.Context {
+	return context.WithValue(ctx, backendKey{}, Backend{
+		Name:    a.client.Name,
+		BaseURL: a.client.BaseURL,
+		Model:   a.model,
+		Attempt: a.number,
+	})
+}
+
+// BackendFromContext returns the backend serving the request that ctx belongs
+// to. The LB sets it on the context passed to the underlying client, so HTTP
+// middleware (option.WithMiddleware) and loggers can tag their records with
+// the serving backend:
+//
+//	option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+//		backend, _ := openailb.BackendFromContext(req.Context())
+//		log.Printf("%s %s via %s", req.Method, req.URL.Path, backend.Name)
+//		return next(req)
+//	})
+func BackendFromContext(ctx context.Context) (Backend, bool) {
+	b, ok := ctx.Value(backendKey{}).(Backend)
+	return b, ok
+}
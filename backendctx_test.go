@@ -0,0 +1,34 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestBackendFromContext(t *testing.T) {
+	t.Parallel()
+
+	server := newNamedServer(t, "ok")
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL, ModelMap: map[string]string{"m": "mapped"}}})
+
+	var got Backend
+	var found bool
+	tag := option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		got, found = BackendFromContext(req.Context())
+		return next(req)
+	})
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m"), tag); err != nil {
+		t.Fatal(err)
+	}
+	want := Backend{Name: "Client-0", BaseURL: server.URL, Model: "mapped", Attempt: 1}
+	if !found || got != want {
+		t.Errorf("Expected %+v in the request context, got %+v", want, got)
+	}
+	if _, ok := BackendFromContext(context.Background()); ok {
+		t.Error("Expected no backend outside of a request")
+	}
+}
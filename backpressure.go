@@ -0,0 +1,94 @@
+package openailb
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrRateLimited is returned when the backends that could take a
+	// request are all at their RPM or TPM limit.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrAllBackendsDown is returned when no backend may take a request: their
+	// breakers are open, or they are ejected, quarantined or unhealthy.
+	ErrAllBackendsDown = errors.New("all backends are down")
+)
+
+// defaultRetryAfter is the retry hint of the rejections with nothing better
+// to go by, e.g. a full WithMaxConcurrency queue.
+const defaultRetryAfter = time.Second
+
+// BackpressureError is returned for the requests the client turns away
+// rather than sends, to protect itself, the backends or a budget. It wraps
+// ErrOverloaded, ErrRateLimited, ErrTenantQuota, ErrBudgetExceeded or
+// ErrAllBackendsDown, for errors.Is, and hints at when to try again, so an
+// HTTP server in front of the client can answer with StatusCode and a
+// Retry-After header instead of a 500.
+type BackpressureError struct {
+	Err        error
+	Detail     string
+	retryAfter time.Duration
+}
+
+// backpressure returns the BackpressureError wrapping err, suggesting to
+// retry after retryAfter, or defaultRetryAfter if unknown.
+func backpressure(err error, retryAfter time.Duration, detail string) *BackpressureError {
+	if retryAfter <= 0 {
+		retryAfter = defaultRetryAfter
+	}
+	return &BackpressureError{Err: err, Detail: detail, retryAfter: retryAfter}
+}
+
+func (e *BackpressureError) Error() string {
+	if e.Detail == "" {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + ": " + e.Detail
+}
+
+func (e *BackpressureError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfter is how long to wait before the request may be taken, e.g. until
+// a breaker's cooldown ends, a rate limit refills or a budget period starts.
+// It is an estimate; the wait may be shorter or longer.
+func (e *BackpressureError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// StatusCode is the HTTP status to answer the request with: 429 Too Many
+// Requests when the caller is over a limit, 503 Service Unavailable when
+// the client or the backends are.
+func (e *BackpressureError) StatusCode() int {
+	switch {
+	case errors.Is(e.Err, ErrRateLimited), errors.Is(e.Err, ErrTenantQuota), errors.Is(e.Err, ErrBudgetExceeded):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusServiceUnavailable
+	}
+}
+
+// RetryAfter returns the hint of the BackpressureError in err's chain, if
+// any, e.g. for a Retry-After header.
+func RetryAfter(err error) (time.Duration, bool) {
+	var bp *BackpressureError
+	if !errors.As(err, &bp) {
+		return 0, false
+	}
+	return bp.RetryAfter(), true
+}
+
+// soonest returns the shorter of the positive durations a and b, or 0 if
+// neither is.
+func soonest(a, b time.Duration) time.Duration {
+	switch {
+	case a <= 0:
+		return max(b, 0)
+	case b <= 0:
+		return a
+	default:
+		return min(a, b)
+	}
+}
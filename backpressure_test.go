@@ -0,0 +1,114 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker/v2"
+)
+
+func TestBackpressureAllBackendsDown(t *testing.T) {
+	t.Parallel()
+
+	server := newNamedServer(t, "ok")
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{{Name: "only", APIKey: "k1", BaseURL: server.URL}})
+	if err := client.TripBreakers("only", "incident"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := client.Chat.Completions.New(context.Background(), chatParams("m"))
+	var bp *BackpressureError
+	if !errors.As(err, &bp) || !errors.Is(err, ErrAllBackendsDown) {
+		t.Fatalf("Expected ErrAllBackendsDown, got %v", err)
+	}
+	if bp.StatusCode() != http.StatusServiceUnavailable {
+		t.Errorf("Expected a 503, got %d", bp.StatusCode())
+	}
+	if wait, ok := RetryAfter(err); !ok || wait < time.Second || wait > defaultCBSettings.Timeout {
+		t.Errorf("Expected to retry once the breaker cools down, got %s", wait)
+	}
+}
+
+func TestBackpressureBreakerRejects(t *testing.T) {
+	t.Parallel()
+
+	server := newNamedServer(t, "ok")
+	defer server.Close()
+	settings := tripAfter(1)
+	settings.Timeout = 20 * time.Millisecond
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL}}, WithCBSettings(settings))
+	breaker := client.lb.backends()[0].Breaker(ServiceChat)
+	failed, err := breaker.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed(false)
+	time.Sleep(30 * time.Millisecond)
+	// Half-open, with its only probe taken: the backend is picked, but its
+	// breaker turns the request away.
+	if _, err := breaker.Allow(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Chat.Completions.New(context.Background(), chatParams("m"))
+	var bp *BackpressureError
+	if !errors.As(err, &bp) || !errors.Is(err, ErrAllBackendsDown) || !errors.Is(err, gobreaker.ErrTooManyRequests) {
+		t.Fatalf("Expected ErrAllBackendsDown wrapping the breaker's error, got %v", err)
+	}
+	if bp.StatusCode() != http.StatusServiceUnavailable {
+		t.Errorf("Expected a 503, got %d", bp.StatusCode())
+	}
+	if wait, ok := RetryAfter(err); !ok || wait <= 0 {
+		t.Errorf("Expected a retry hint, got %s", wait)
+	}
+}
+
+func TestBackpressureRateLimited(t *testing.T) {
+	t.Parallel()
+
+	server := newNamedServer(t, "ok")
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL, RPM: 1}})
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m")); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := client.Chat.Completions.New(context.Background(), chatParams("m"))
+	var bp *BackpressureError
+	if !errors.As(err, &bp) || !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Expected ErrRateLimited, got %v", err)
+	}
+	if bp.StatusCode() != http.StatusTooManyRequests {
+		t.Errorf("Expected a 429, got %d", bp.StatusCode())
+	}
+	if wait := bp.RetryAfter(); wait < 50*time.Second || wait > time.Minute {
+		t.Errorf("Expected to retry once the RPM refills, got %s", wait)
+	}
+}
+
+func TestBackpressureTenantQuota(t *testing.T) {
+	t.Parallel()
+
+	server := newNamedServer(t, "ok")
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL}}, WithTenantQuotas(TenantQuota{RPM: 6}, nil))
+	ctx := WithTenant(context.Background(), "search")
+	if _, err := client.Chat.Completions.New(ctx, chatParams("m")); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := client.Chat.Completions.New(ctx, chatParams("m"))
+	if !errors.Is(err, ErrTenantQuota) {
+		t.Fatalf("Expected ErrTenantQuota, got %v", err)
+	}
+	if wait, ok := RetryAfter(err); !ok || wait < 5*time.Second || wait > 10*time.Second {
+		t.Errorf("Expected to retry once the tenant's RPM refills, got %s", wait)
+	}
+	if _, ok := RetryAfter(context.Canceled); ok {
+		t.Error("Expected no hint for other errors")
+	}
+}
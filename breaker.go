@@ -0,0 +1,312 @@
+package openailb
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sony/gobreaker/v2"
+)
+
+// defaultBreakerTimeout is gobreaker's open-state duration when Settings.Timeout is unset.
+const defaultBreakerTimeout = 60 * time.Second
+
+// Breaker is the circuit breaker guarding one backend for one service (and
+// model, with WithPerModelBreakers).
+type Breaker interface {
+	// Allow reports whether a request may proceed; a non-nil error rejects
+	// it. Otherwise done must be called exactly once with the request's
+	// outcome, and settles that request only, whatever was allowed since.
+	Allow() (done func(success bool), err error)
+	State() gobreaker.State
+}
+
+// BreakerFactory builds the breaker named name (e.g. "Client-0/chat") from the
+// backend's effective circuit breaker settings.
+type BreakerFactory func(name string, settings gobreaker.Settings) Breaker
+
+// NewGobreaker is the default BreakerFactory, backed by sony/gobreaker.
+func NewGobreaker(name string, settings gobreaker.Settings) Breaker {
+	settings.Name = name
+	return &gobreakerBreaker{cb: gobreaker.NewTwoStepCircuitBreaker[any](settings)}
+}
+
+// gobreakerBreaker adapts gobreaker's two-step breaker to the Breaker
+// interface. Its done callbacks already carry the generation they were issued
+// in, so results of a past generation are ignored as gobreaker intends.
+type gobreakerBreaker struct {
+	cb *gobreaker.TwoStepCircuitBreaker[any]
+}
+
+func (b *gobreakerBreaker) Allow() (func(success bool), error) {
+	return b.cb.Allow()
+}
+
+func (b *gobreakerBreaker) State() gobreaker.State {
+	return b.cb.State()
+}
+
+// trackedBreaker wraps the Breaker built by the factory with the counters,
+// transitions and cooldowns the LB keeps regardless of the implementation.
+type trackedBreaker struct {
+	Breaker
+	name    string
+	key     breakerKey
+	timeout time.Duration // How long the breaker stays open, used to compute cooldowns.
+	owner   *SafeClient
+	probing atomic.Bool // Whether a synthetic probe is in flight.
+
+	mu        sync.Mutex
+	counts    gobreaker.Counts // Counts of the current state, like gobreaker's.
+	state     gobreaker.State  // Last observed state.
+	openedAt  time.Time
+	openedBy  string    // Why the breaker is open: the backend's last error, unless set when opening it.
+	closedAt  time.Time // When the breaker last recovered from open/half-open.
+	holdUntil time.Time // Report open until then regardless of the wrapped breaker (restored cooldowns).
+	adopting  bool      // Whether a peer's signal is opening the breaker (WithBreakerSignals).
+}
+
+func (b *trackedBreaker) Allow() (func(success bool), error) {
+	if b.held() {
+		return nil, gobreaker.ErrOpenState
+	}
+	done, err := b.Breaker.Allow()
+	if err != nil {
+		b.observe()
+		return nil, err
+	}
+	b.mu.Lock()
+	b.counts.Requests++
+	b.mu.Unlock()
+	b.observe()
+	return func(success bool) {
+		done(success)
+		b.record(success)
+	}, nil
+}
+
+// record counts the outcome of an allowed request.
+func (b *trackedBreaker) record(success bool) {
+	b.mu.Lock()
+	if success {
+		b.counts.TotalSuccesses++
+		b.counts.ConsecutiveSuccesses++
+		b.counts.ConsecutiveFailures = 0
+	} else {
+		b.counts.TotalFailures++
+		b.counts.ConsecutiveFailures++
+		b.counts.ConsecutiveSuccesses = 0
+	}
+	b.mu.Unlock()
+	b.observe()
+}
+
+func (b *trackedBreaker) State() gobreaker.State {
+	if b.held() {
+		return gobreaker.StateOpen
+	}
+	return b.Breaker.State()
+}
+
+func (b *trackedBreaker) held() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Now().Before(b.holdUntil)
+}
+
+// observe detects state transitions of the wrapped breaker and reports them.
+func (b *trackedBreaker) observe() {
+	to := b.State()
+	var lastError string
+	if to == gobreaker.StateOpen {
+		lastError, _, _ = b.owner.lastErr()
+	}
+
+	b.mu.Lock()
+	from := b.state
+	if from == to {
+		b.mu.Unlock()
+		return
+	}
+	b.state = to
+	b.counts = gobreaker.Counts{}
+	switch to {
+	case gobreaker.StateOpen:
+		b.openedAt = time.Now()
+		// Those opening it on purpose (tripBreakers, adopt) give their reason.
+		if b.openedBy == "" {
+			b.openedBy = lastError
+		}
+	case gobreaker.StateClosed:
+		b.closedAt = time.Now()
+		b.openedBy = ""
+	default:
+		b.openedBy = ""
+	}
+	b.mu.Unlock()
+
+	b.owner.lb.onBreakerStateChange(b, from, to)
+}
+
+// cooldownUntil is when an open breaker is expected to let a probe through.
+func (b *trackedBreaker) cooldownUntil() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != gobreaker.StateOpen {
+		return time.Time{}
+	}
+	until := b.openedAt.Add(b.timeout)
+	if b.holdUntil.After(until) {
+		until = b.holdUntil
+	}
+	return until
+}
+
+// breakerKey identifies one breaker on a backend. model is empty unless
+// per-model breakers are enabled and the call targets a specific model.
+type breakerKey struct {
+	svc   ServiceType
+	model string
+}
+
+// Breaker returns the circuit breaker guarding svc on this backend.
+func (c *SafeClient) Breaker(svc ServiceType) Breaker {
+	return c.breakerFor(svc, "")
+}
+
+// ModelBreaker returns the circuit breaker guarding model on svc. Without
+// WithPerModelBreakers this is the same breaker as Breaker(svc).
+func (c *SafeClient) ModelBreaker(svc ServiceType, model string) Breaker {
+	return c.breakerFor(svc, model)
+}
+
+// breakerKey returns the key of the breaker guarding svc and model.
+func (c *SafeClient) breakerKey(svc ServiceType, model string) breakerKey {
+	key := breakerKey{svc: svc}
+	if c.lb.options.perModelBreakers {
+		key.model = model
+	}
+	return key
+}
+
+// breakerFor returns (creating on first use) the breaker for svc and model.
+func (c *SafeClient) breakerFor(svc ServiceType, model string) *trackedBreaker {
+	key := c.breakerKey(svc, model)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.breakers[key]
+	if !ok {
+		b = c.newBreaker(key)
+		if snap, ok := c.lb.restored[b.name]; ok {
+			b.restore(snap)
+		}
+		c.breakers[key] = b
+	}
+	return b
+}
+
+// newBreaker builds a closed breaker for key.
+func (c *SafeClient) newBreaker(key breakerKey) *trackedBreaker {
+	name := fmt.Sprintf("%s/%s", c.Name, key.svc)
+	if key.model != "" {
+		name += "/" + key.model
+	}
+	settings := c.breakerSettings
+	if settings.Timeout <= 0 {
+		settings.Timeout = defaultBreakerTimeout
+	}
+	// Spread the open periods so breakers opened by a shared outage don't
+	// all half-open, and re-fail, in lockstep.
+	settings.Timeout = jitter(settings.Timeout, c.lb.options.breakerJitter)
+	return &trackedBreaker{
+		Breaker: c.lb.options.breakerFactory(name, settings),
+		name:    name,
+		key:     key,
+		timeout: settings.Timeout,
+		owner:   c,
+	}
+}
+
+// tripBreakers opens every breaker of the backend for its timeout, whatever
+// the wrapped breakers make of the traffic.
+func (c *SafeClient) tripBreakers(reason string) {
+	for _, svc := range serviceTypes {
+		c.breakerFor(svc, "")
+	}
+	for _, b := range c.breakerList() {
+		b.mu.Lock()
+		b.holdUntil = time.Now().Add(b.timeout)
+		b.openedBy = reason
+		b.mu.Unlock()
+		b.observe()
+	}
+}
+
+// resetBreakers closes every breaker of the backend, replacing each with a
+// fresh one.
+func (c *SafeClient) resetBreakers() {
+	type change struct {
+		breaker *trackedBreaker
+		from    gobreaker.State
+	}
+	var changes []change
+	c.mu.Lock()
+	for key, old := range c.breakers {
+		b := c.newBreaker(key)
+		c.breakers[key] = b
+		if from := old.State(); from != gobreaker.StateClosed {
+			// Recovering, as far as WithSlowStart is concerned.
+			b.closedAt = time.Now()
+			changes = append(changes, change{b, from})
+		}
+	}
+	c.mu.Unlock()
+	for _, ch := range changes {
+		c.lb.onBreakerStateChange(ch.breaker, ch.from, gobreaker.StateClosed)
+	}
+}
+
+// breakerList returns the breakers created so far, in no particular order.
+func (c *SafeClient) breakerList() []*trackedBreaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	list := make([]*trackedBreaker, 0, len(c.breakers))
+	for _, b := range c.breakers {
+		list = append(list, b)
+	}
+	return list
+}
+
+// jitter returns d scaled by a random factor in [1-fraction, 1+fraction].
+func jitter(d time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + fraction*(2*rand.Float64()-1)))
+}
+
+// TripBreakers opens every breaker of the backend called name for its
+// timeout, e.g. to take it out of rotation during a provider incident the
+// breakers haven't noticed yet. reason is reported as what opened them.
+func (c Client) TripBreakers(name, reason string) error {
+	backend, err := c.lb.clientByName(name)
+	if err != nil {
+		return err
+	}
+	backend.tripBreakers(reason)
+	return nil
+}
+
+// ResetBreakers closes every breaker of the backend called name, e.g. once
+// an outage it was cut off for is known to be over.
+func (c Client) ResetBreakers(name string) error {
+	backend, err := c.lb.clientByName(name)
+	if err != nil {
+		return err
+	}
+	backend.resetBreakers()
+	return nil
+}
@@ -0,0 +1,306 @@
+package openailb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/sony/gobreaker/v2"
+)
+
+// newModelServer returns a server that fails every chat completion for failModel.
+func newModelServer(t *testing.T, failModel string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Model == failModel {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "Hello"}}]}`))
+	}))
+}
+
+func chatParams(model string) openai.ChatCompletionNewParams {
+	return openai.ChatCompletionNewParams{
+		Model:    model,
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("test")},
+	}
+}
+
+// tripAfter returns breaker settings that open after n consecutive failures.
+func tripAfter(n uint32) gobreaker.Settings {
+	return gobreaker.Settings{
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= n
+		},
+	}
+}
+
+func TestPerModelBreakers(t *testing.T) {
+	t.Parallel()
+
+	server := newModelServer(t, "gpt-4o")
+	defer server.Close()
+
+	client := NewClient([]OpenaiClientConfig{{APIKey: "mock-key", BaseURL: server.URL}},
+		WithCBSettings(tripAfter(1)), WithPerModelBreakers())
+
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("gpt-4o"), option.WithMaxRetries(0)); err == nil {
+		t.Fatal("Expected gpt-4o to fail")
+	}
+
+	backend := client.Chat.Completions.lb.backends()[0]
+	if state := backend.ModelBreaker(ServiceChat, "gpt-4o").State(); state != gobreaker.StateOpen {
+		t.Fatalf("Expected gpt-4o breaker to be open, got %s", state)
+	}
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("gpt-4o-mini")); err != nil {
+		t.Fatalf("gpt-4o-mini should still be routed to the backend: %v", err)
+	}
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("gpt-4o")); err == nil {
+		t.Fatal("gpt-4o should be rejected while its breaker is open")
+	}
+}
+
+func TestErrorRateBreaker(t *testing.T) {
+	t.Parallel()
+
+	server := newModelServer(t, "bad")
+	defer server.Close()
+
+	// Interleaved traffic never produces 3 consecutive failures, but 50% of it
+	// fails. Settings given afterwards keep the error rate.
+	client := NewClient([]OpenaiClientConfig{{APIKey: "mock-key", BaseURL: server.URL}},
+		WithErrorRateBreaker(0.5, time.Minute, 4), WithCBSettings(tripAfter(3)))
+
+	for i := 0; i < 4; i++ {
+		model := "good"
+		if i%2 == 1 {
+			model = "bad"
+		}
+		_, _ = client.Chat.Completions.New(context.Background(), chatParams(model), option.WithMaxRetries(0))
+	}
+
+	backend := client.Chat.Completions.lb.backends()[0]
+	if state := backend.Breaker(ServiceChat).State(); state != gobreaker.StateOpen {
+		t.Fatalf("Expected breaker to open at 50%% errors over the window, got %s", state)
+	}
+}
+
+func TestLatencyBreaker(t *testing.T) {
+	t.Parallel()
+
+	slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "Hello"}}]}`))
+	}))
+	defer slowServer.Close()
+
+	client := NewClient([]OpenaiClientConfig{{APIKey: "mock-key", BaseURL: slowServer.URL}},
+		WithCBSettings(tripAfter(2)), WithLatencyBreaker(95, 10*time.Millisecond, time.Minute, 1))
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Chat.Completions.New(context.Background(), chatParams("m")); err != nil {
+			t.Fatalf("Slow request %d should still return its response: %v", i, err)
+		}
+	}
+
+	backend := client.Chat.Completions.lb.backends()[0]
+	if state := backend.Breaker(ServiceChat).State(); state != gobreaker.StateOpen {
+		t.Fatalf("Expected slow backend's breaker to be open, got %s", state)
+	}
+}
+
+// countingBreaker is a Breaker that opens once it has seen open failures.
+type countingBreaker struct {
+	open                int
+	successes, failures int
+}
+
+func (b *countingBreaker) Allow() (func(success bool), error) {
+	if b.State() == gobreaker.StateOpen {
+		return nil, gobreaker.ErrOpenState
+	}
+	return func(success bool) {
+		if success {
+			b.successes++
+		} else {
+			b.failures++
+		}
+	}, nil
+}
+
+func (b *countingBreaker) State() gobreaker.State {
+	if b.failures >= b.open {
+		return gobreaker.StateOpen
+	}
+	return gobreaker.StateClosed
+}
+
+func TestBreakerFactory(t *testing.T) {
+	t.Parallel()
+
+	server := newModelServer(t, "bad")
+	defer server.Close()
+
+	var names []string
+	breakers := map[string]*countingBreaker{}
+	factory := func(name string, _ gobreaker.Settings) Breaker {
+		names = append(names, name)
+		breakers[name] = &countingBreaker{open: 1}
+		return breakers[name]
+	}
+	client := NewClient([]OpenaiClientConfig{{APIKey: "mock-key", BaseURL: server.URL}}, WithBreakerFactory(factory))
+
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("good")); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("bad"), option.WithMaxRetries(0)); err == nil {
+		t.Fatal("Expected the bad model to fail")
+	}
+
+	b := breakers["Client-0/chat"]
+	if len(names) != 1 || b == nil {
+		t.Fatalf("Expected one breaker named Client-0/chat, got %v", names)
+	}
+	if b.successes != 1 || b.failures != 1 {
+		t.Errorf("Expected 1 success and 1 failure, got %d and %d", b.successes, b.failures)
+	}
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("good")); err == nil {
+		t.Error("Expected the custom breaker to reject requests once open")
+	}
+}
+
+func TestBreakerJitter(t *testing.T) {
+	t.Parallel()
+
+	var timeouts []time.Duration
+	factory := func(name string, settings gobreaker.Settings) Breaker {
+		timeouts = append(timeouts, settings.Timeout)
+		return NewGobreaker(name, settings)
+	}
+	configs := make([]OpenaiClientConfig, 20)
+	client := NewClient(configs, WithBreakerFactory(factory), WithBreakerJitter(0.5))
+	for _, backend := range client.Chat.Completions.lb.backends() {
+		backend.Breaker(ServiceChat)
+	}
+
+	distinct := map[time.Duration]bool{}
+	for _, timeout := range timeouts {
+		if timeout < 15*time.Second || timeout > 45*time.Second {
+			t.Errorf("Timeout %s outside of 30s ±50%%", timeout)
+		}
+		distinct[timeout] = true
+	}
+	if len(distinct) < 2 {
+		t.Errorf("Expected jittered timeouts to differ, got %v", timeouts)
+	}
+}
+
+func TestHalfOpenProbes(t *testing.T) {
+	t.Parallel()
+
+	settings := tripAfter(1)
+	settings.Timeout = 20 * time.Millisecond
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", HalfOpenProbes: 3},
+		{APIKey: "k2"},
+	}, WithCBSettings(settings))
+
+	for i, want := range []int{3, 1} {
+		breaker := client.Chat.Completions.lb.backends()[i].Breaker(ServiceChat)
+		done, err := breaker.Allow()
+		if err != nil {
+			t.Fatalf("Backend %d: Allow failed: %v", i, err)
+		}
+		done(false)
+		time.Sleep(30 * time.Millisecond)
+
+		allowed := 0
+		for {
+			if _, err := breaker.Allow(); err != nil {
+				break
+			}
+			allowed++
+		}
+		if allowed != want {
+			t.Errorf("Backend %d: expected %d half-open probes, got %d", i, want, allowed)
+		}
+	}
+}
+
+func TestBreakerSettlesOwnRequest(t *testing.T) {
+	t.Parallel()
+
+	settings := tripAfter(1)
+	settings.Timeout = 20 * time.Millisecond
+	breaker := NewGobreaker("b", settings)
+
+	slow, err := breaker.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed, _ := breaker.Allow()
+	failed(false)
+	time.Sleep(30 * time.Millisecond)
+
+	// The half-open probe settles itself, not the request of the closed
+	// generation still in flight.
+	probe, err := breaker.Allow()
+	if err != nil {
+		t.Fatalf("Expected a half-open probe, got %v", err)
+	}
+	probe(true)
+	if state := breaker.State(); state != gobreaker.StateClosed {
+		t.Fatalf("Expected the probe's success to close the breaker, got %s", state)
+	}
+	slow(false)
+	if state := breaker.State(); state != gobreaker.StateClosed {
+		t.Errorf("Expected the stale failure to be ignored, got %s", state)
+	}
+}
+
+func TestIsSuccessful(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"finish_reason": "content_filter", "message": {"content": ""}}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient([]OpenaiClientConfig{{APIKey: "mock-key", BaseURL: server.URL}},
+		WithCBSettings(tripAfter(1)),
+		WithIsSuccessful(func(res any, err error) bool {
+			if completion, ok := res.(*openai.ChatCompletion); ok && err == nil {
+				return completion.Choices[0].FinishReason != "content_filter"
+			}
+			return DefaultIsSuccessful(res, err)
+		}))
+
+	res, err := client.Chat.Completions.New(context.Background(), chatParams("gpt-4o"))
+	if err != nil {
+		t.Fatalf("The filtered completion should still be returned: %v", err)
+	}
+	if res.Choices[0].FinishReason != "content_filter" {
+		t.Fatalf("Expected the filtered completion, got %q", res.Choices[0].FinishReason)
+	}
+
+	backend := client.Chat.Completions.lb.backends()[0]
+	if state := backend.Breaker(ServiceChat).State(); state != gobreaker.StateOpen {
+		t.Fatalf("Expected the breaker to open on a filtered completion, got %s", state)
+	}
+	if health := client.Health()[0]; health.LastError == "" {
+		t.Fatal("Expected the rejected response to be recorded as the last error")
+	}
+}
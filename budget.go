@@ -0,0 +1,179 @@
+package openailb
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded is returned for the requests that no backend may serve
+// within its Budget and the one of WithBudget.
+var ErrBudgetExceeded = errors.New("spend budget exceeded")
+
+// ErrBudgetExhausted is an alias of ErrBudgetExceeded.
+var ErrBudgetExhausted = ErrBudgetExceeded
+
+// BudgetPeriod is the calendar period of a Budget, in UTC.
+type BudgetPeriod string
+
+const (
+	BudgetDaily   BudgetPeriod = "daily"
+	BudgetMonthly BudgetPeriod = "monthly"
+)
+
+// Budget is a spend ceiling per calendar day or month, in the currency of
+// the prices set with WithPricing and OpenaiClientConfig.Prices.
+type Budget struct {
+	Limit  float64      `json:"limit" yaml:"limit"`
+	Period BudgetPeriod `json:"period" yaml:"period"`
+	// Reject, for WithBudget, fails every request with ErrBudgetExceeded once
+	// the ceiling is hit, instead of only keeping them off the paid backends.
+	Reject bool `json:"reject,omitempty" yaml:"reject,omitempty"`
+}
+
+// WithBudget sets a spend ceiling across all backends. Once it is hit, the
+// backends with a price for the requested model stop taking requests until
+// the next period, while free (e.g. self-hosted) ones carry on, unless
+// b.Reject is set. Requests already sent may overshoot the ceiling, and
+// nothing is spent without prices. The spending, like that against the
+// backends' own budgets, is kept in memory only: a restart counts the
+// period from zero, and replicas each enforce the whole ceiling on their own.
+func WithBudget(b Budget) LBOption {
+	return func(o *lbOptions) {
+		o.budget = newSpendBudget(&b)
+	}
+}
+
+// validate checks b, to be reported under field.
+func (b *Budget) validate(field string) []error {
+	var errs []error
+	if b.Limit <= 0 {
+		errs = append(errs, fmt.Errorf("%s.limit: must be positive, got %v", field, b.Limit))
+	}
+	if err := b.Period.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("%s.period: %w", field, err))
+	}
+	return errs
+}
+
+func (p BudgetPeriod) validate() error {
+	if p != BudgetDaily && p != BudgetMonthly {
+		return fmt.Errorf("must be %q or %q, got %q", BudgetDaily, BudgetMonthly, p)
+	}
+	return nil
+}
+
+// spendBudget is the spending of the current period against a Budget.
+type spendBudget struct {
+	Budget
+
+	mu    sync.Mutex
+	start time.Time // Of the current period.
+	spent float64
+}
+
+// newSpendBudget returns the spending against b, or nil without a ceiling.
+func newSpendBudget(b *Budget) *spendBudget {
+	if b == nil || b.Limit <= 0 {
+		return nil
+	}
+	return &spendBudget{Budget: *b}
+}
+
+// periodStart returns the start of the period p that now falls in.
+func periodStart(p BudgetPeriod, now time.Time) time.Time {
+	y, m, d := now.UTC().Date()
+	if p == BudgetMonthly {
+		d = 1
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// periodEnd returns the end of the period p that now falls in.
+func periodEnd(p BudgetPeriod, now time.Time) time.Time {
+	if p == BudgetMonthly {
+		return periodStart(p, now).AddDate(0, 1, 0)
+	}
+	return periodStart(p, now).AddDate(0, 0, 1)
+}
+
+// roll starts a new period if now is past the current one. s.mu must be held.
+func (s *spendBudget) roll(now time.Time) {
+	if start := periodStart(s.Period, now); !start.Equal(s.start) {
+		s.start, s.spent = start, 0
+	}
+}
+
+// exceeded reports whether the ceiling was hit in the period of now.
+func (s *spendBudget) exceeded(now time.Time) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roll(now)
+	return s.spent >= s.Limit
+}
+
+// resetIn returns how long from now until the ceiling no longer applies: the
+// rest of the period if it was hit, 0 otherwise.
+func (s *spendBudget) resetIn(now time.Time) time.Duration {
+	if !s.exceeded(now) {
+		return 0
+	}
+	return periodEnd(s.Period, now).Sub(now)
+}
+
+// spend adds cost at now. It reports whether this hit the ceiling, and
+// returns the amount spent in the period.
+func (s *spendBudget) spend(now time.Time, cost float64) (hit bool, spent float64) {
+	if s == nil || cost <= 0 {
+		return false, 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roll(now)
+	before := s.spent
+	s.spent += cost
+	return before < s.Limit && s.spent >= s.Limit, s.spent
+}
+
+// paid reports whether the backend has a price for model, as mapped for it.
+func (c *SafeClient) paid(model string) bool {
+	p := c.priceOf(mapModel(c, model))
+	return p.Prompt > 0 || p.Completion > 0
+}
+
+// overBudget reports whether the backend may not take a request for model
+// at now: its own budget is spent, or the one of WithBudget and it is paid.
+func (c *SafeClient) overBudget(now time.Time, model string) bool {
+	return c.budget.exceeded(now) || (c.lb.options.budget.exceeded(now) && c.paid(model))
+}
+
+// budgetResetIn returns how long from now until the backend is within its
+// budgets again for model.
+func (c *SafeClient) budgetResetIn(now time.Time, model string) time.Duration {
+	wait := c.budget.resetIn(now)
+	if c.paid(model) {
+		wait = max(wait, c.lb.options.budget.resetIn(now))
+	}
+	return wait
+}
+
+// spend charges an attempt's cost to the budgets, announcing the ceilings
+// it hit.
+func (lb *LoadBalancer) spend(now time.Time, c *SafeClient, cost float64) {
+	if hit, spent := c.budget.spend(now, cost); hit {
+		lb.budgetExceeded(c.Name, spent, c.budget.Budget)
+	}
+	if hit, spent := lb.options.budget.spend(now, cost); hit {
+		lb.budgetExceeded("", spent, lb.options.budget.Budget)
+	}
+}
+
+func (lb *LoadBalancer) budgetExceeded(backend string, spent float64, b Budget) {
+	lb.options.logger.Warn("spend budget exceeded",
+		"backend", backend, "spent", spent, "limit", b.Limit, "period", b.Period)
+	lb.events.publish(Event{Kind: EventBudgetExceeded, Backend: backend, Spent: spent, Limit: b.Limit})
+}
@@ -0,0 +1,92 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newUsageServer answers name with 1000 prompt and 500 completion tokens.
+func newUsageServer(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "` + name + `"}}], "usage": {"prompt_tokens": 1000, "completion_tokens": 500}}`))
+	}))
+}
+
+// At these prices, each request of a usage server costs 0.006.
+var budgetPrices = map[string]Price{"m": {Prompt: 2, Completion: 8}}
+
+func TestBackendBudget(t *testing.T) {
+	t.Parallel()
+
+	paid, spare := newUsageServer("paid"), newUsageServer("spare")
+	defer paid.Close()
+	defer spare.Close()
+	client := NewClient([]OpenaiClientConfig{
+		{Name: "paid", APIKey: "k1", BaseURL: paid.URL, Weight: 100, Prices: budgetPrices, Budget: &Budget{Limit: 0.01, Period: BudgetDaily}},
+		{Name: "spare", APIKey: "k2", BaseURL: spare.URL},
+	})
+	events := client.Events()
+
+	if hits := countHits(t, client, 4); hits["paid"] != 2 || hits["spare"] != 2 {
+		t.Errorf("Expected the backend to stop taking requests once over its budget, got %v", hits)
+	}
+	select {
+	case ev := <-events:
+		for ev.Kind != EventBudgetExceeded {
+			ev = <-events
+		}
+		if ev.Backend != "paid" || !near(ev.Spent, 0.012) || ev.Limit != 0.01 {
+			t.Errorf("Expected the event to report the backend's spending, got %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected an EventBudgetExceeded")
+	}
+}
+
+func TestGlobalBudget(t *testing.T) {
+	t.Parallel()
+
+	paid, free := newUsageServer("paid"), newUsageServer("free")
+	defer paid.Close()
+	defer free.Close()
+	configs := []OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: paid.URL, Weight: 100, Prices: budgetPrices},
+		{APIKey: "k2", BaseURL: free.URL},
+	}
+
+	client := NewClient(configs, WithBudget(Budget{Limit: 0.005, Period: BudgetMonthly}))
+	if hits := countHits(t, client, 3); hits["paid"] != 1 || hits["free"] != 2 {
+		t.Errorf("Expected only the free backend to take requests over the budget, got %v", hits)
+	}
+
+	strict := NewClient(configs, WithBudget(Budget{Limit: 0.005, Period: BudgetMonthly, Reject: true}))
+	if hits := countHits(t, strict, 1); hits["paid"] != 1 {
+		t.Fatalf("Expected the paid backend to take the first request, got %v", hits)
+	}
+	if _, err := strict.Chat.Completions.New(context.Background(), chatParams("m")); !errors.Is(err, ErrBudgetExceeded) || !errors.Is(err, ErrBudgetExhausted) {
+		t.Errorf("Expected ErrBudgetExceeded over a rejecting budget, got %v", err)
+	}
+}
+
+func TestBudgetPeriods(t *testing.T) {
+	t.Parallel()
+
+	s := newSpendBudget(&Budget{Limit: 1, Period: BudgetDaily})
+	day := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+	if hit, _ := s.spend(day, 1); !hit || !s.exceeded(day) {
+		t.Error("Expected the budget to be exceeded")
+	}
+	if s.exceeded(day.Add(time.Hour)) {
+		t.Error("Expected a new day to start a new period")
+	}
+	m := newSpendBudget(&Budget{Limit: 1, Period: BudgetMonthly})
+	m.spend(day, 1)
+	if !m.exceeded(day.Add(-20*24*time.Hour)) || m.exceeded(day.Add(time.Hour)) {
+		t.Error("Expected the monthly budget to last until the next month")
+	}
+}
@@ -0,0 +1,351 @@
+package openailb
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// Cache stores the responses of WithResponseCache, e.g. in Redis
+// (redisstore.Cache) to share them across replicas. Implementations must be
+// safe for concurrent use.
+type Cache interface {
+	// Get returns the value stored under key, reporting false if there is
+	// none or it expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes key.
+	Delete(ctx context.Context, key string) error
+}
+
+// ResponseCache configures WithResponseCache. Zero fields take the defaults
+// noted below.
+type ResponseCache struct {
+	// TTL is how long a response is served from the cache (default 5m).
+	TTL time.Duration
+	// Store keeps the responses (default an in-memory LRU cache bounded by
+	// MaxEntries and MaxBytes).
+	Store Cache
+	// MaxEntries caps the responses kept in memory, the least recently used
+	// going first (default 1000).
+	MaxEntries int
+	// MaxBytes, if set, caps the total size of the responses kept in memory.
+	MaxBytes int64
+	// AnyTemperature caches the requests at any temperature too, not only
+	// the deterministic ones, so repeated prompts get the same answer.
+	AnyTemperature bool
+	// StaleFor, if set, keeps the answer to every chat completion, at any
+	// temperature, for StaleFor, to answer the identical requests with when
+	// every backend is down (ErrAllBackendsDown) or over budget
+	// (ErrBudgetExceeded), instead of failing: for the product surfaces
+	// where a slightly stale answer beats an error page. Such answers have
+	// RouteInfo.Stale set.
+	StaleFor time.Duration
+}
+
+// withDefaults fills in the zero fields.
+func (c ResponseCache) withDefaults() ResponseCache {
+	if c.TTL <= 0 {
+		c.TTL = 5 * time.Minute
+	}
+	if c.MaxEntries <= 0 {
+		c.MaxEntries = 1000
+	}
+	return c
+}
+
+// CacheStats counts the lookups of the response cache (Client.CacheStats).
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+	// Stale counts the stale answers served during outages (StaleFor).
+	Stale int64
+}
+
+// CacheMetricsSink is implemented by the MetricsSinks that also count the
+// lookups of the response cache (WithResponseCache), like promlb's.
+type CacheMetricsSink interface {
+	CacheLookup(svc ServiceType, model string, hit bool)
+}
+
+// WithResponseCache answers the chat completions identical to one answered
+// within cfg.TTL from the cache, without calling a backend. Requests are
+// identical when their JSON bodies are, model, messages and sampling
+// parameters included, as requested, before model mapping. Only the
+// deterministic requests, at temperature 0, are cached unless
+// cfg.AnyTemperature is set; streams are never cached. See cfg.StaleFor to
+// answer from the cache during outages as well. A cached answer has
+// RouteInfo.Cached set and no backend. Hits and misses are counted in
+// Client.CacheStats and reported to the CacheMetricsSinks. When the store
+// fails, requests go to the backends as on a miss.
+func WithResponseCache(cfg ResponseCache) LBOption {
+	return func(o *lbOptions) {
+		cfg = cfg.withDefaults()
+		store := cfg.Store
+		if store == nil {
+			store = newLRUCache(cfg.MaxEntries, cfg.MaxBytes)
+		}
+		o.cache = &responseCache{cfg: cfg, store: store}
+	}
+}
+
+// CacheStats returns the hits and misses of the response cache so far, or
+// zeros without WithResponseCache.
+func (c Client) CacheStats() CacheStats {
+	cache := c.lb.options.cache
+	if cache == nil {
+		return CacheStats{}
+	}
+	return CacheStats{Hits: cache.hits.Load(), Misses: cache.misses.Load(), Stale: cache.stale.Load()}
+}
+
+// responseCache is the cache of WithResponseCache.
+type responseCache struct {
+	cfg                 ResponseCache
+	store               Cache
+	hits, misses, stale atomic.Int64
+}
+
+// keeps reports whether the answer to params goes through the cache.
+func (c *responseCache) keeps(params openai.ChatCompletionNewParams) bool {
+	return c != nil && (c.caches(params) || c.cfg.StaleFor > 0)
+}
+
+// caches reports whether the answer to params may be served fresh from the
+// cache.
+func (c *responseCache) caches(params openai.ChatCompletionNewParams) bool {
+	return c.cfg.AnyTemperature || (params.Temperature.Valid() && params.Temperature.Value == 0)
+}
+
+// outage reports whether err says that no backend could take the request,
+// for StaleFor.
+func outage(err error) bool {
+	return errors.Is(err, ErrAllBackendsDown) || errors.Is(err, ErrBudgetExceeded)
+}
+
+// completion answers params from the cache, or with fetch, caching its
+// answer, and with a stale answer if fetch found every backend out.
+func (c *responseCache) completion(ctx context.Context, lb *LoadBalancer, params openai.ChatCompletionNewParams, opts []option.RequestOption,
+	fetch func(context.Context, openai.ChatCompletionNewParams, ...option.RequestOption) (*openai.ChatCompletion, error)) (*openai.ChatCompletion, error) {
+	key, err := cacheKey(params)
+	if err != nil {
+		return fetch(ctx, params, opts...)
+	}
+	fresh := c.caches(params)
+	if fresh {
+		if body, ok := c.get(ctx, lb, key); ok {
+			res, err := replayCompletion(ctx, body, params, opts, false)
+			if err == nil {
+				c.hits.Add(1)
+				lb.cacheLookup(ServiceChat, params.Model, true)
+				return res, nil
+			}
+			lb.options.logger.Warn("cached response unreadable", "key", key, "error", err)
+			c.delete(ctx, lb, key)
+		}
+		c.misses.Add(1)
+		lb.cacheLookup(ServiceChat, params.Model, false)
+	}
+	res, err := fetch(ctx, params, opts...)
+	if err == nil && res.RawJSON() != "" {
+		if fresh {
+			c.set(ctx, lb, key, []byte(res.RawJSON()), c.cfg.TTL)
+		}
+		if c.cfg.StaleFor > 0 {
+			c.set(ctx, lb, staleKey(key), []byte(res.RawJSON()), c.cfg.StaleFor)
+		}
+		return res, nil
+	}
+	if c.cfg.StaleFor > 0 && outage(err) {
+		if body, ok := c.get(ctx, lb, staleKey(key)); ok {
+			if stale, staleErr := replayCompletion(ctx, body, params, opts, true); staleErr == nil {
+				c.stale.Add(1)
+				lb.options.logger.Warn("serving a stale response", "model", params.Model, "error", err)
+				return stale, nil
+			}
+		}
+	}
+	return res, err
+}
+
+// staleKey returns the key of the stale copy of the answer under key.
+func staleKey(key string) string {
+	return "stale:" + key
+}
+
+// get looks key up in the store, a failure counting as a miss.
+func (c *responseCache) get(ctx context.Context, lb *LoadBalancer, key string) ([]byte, bool) {
+	ctx, cancel := context.WithTimeout(ctx, sharedTimeout)
+	defer cancel()
+	body, ok, err := c.store.Get(ctx, key)
+	if err != nil {
+		lb.options.logger.Warn("response cache lookup failed", "error", err)
+		return nil, false
+	}
+	return body, ok
+}
+
+// set stores body under key in the store for ttl.
+func (c *responseCache) set(ctx context.Context, lb *LoadBalancer, key string, body []byte, ttl time.Duration) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sharedTimeout)
+	defer cancel()
+	if err := c.store.Set(ctx, key, body, ttl); err != nil {
+		lb.options.logger.Warn("response not cached", "error", err)
+	}
+}
+
+// delete removes key from the store.
+func (c *responseCache) delete(ctx context.Context, lb *LoadBalancer, key string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sharedTimeout)
+	defer cancel()
+	if err := c.store.Delete(ctx, key); err != nil {
+		lb.options.logger.Warn("cached response not deleted", "error", err)
+	}
+}
+
+// cacheKey returns the key of the answer to params: the hash of its body.
+func cacheKey(params openai.ChatCompletionNewParams) (string, error) {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return "chat:" + hex.EncodeToString(sum[:]), nil
+}
+
+// cacheLookup reports a lookup of the response cache to the sinks counting them.
+func (lb *LoadBalancer) cacheLookup(svc ServiceType, model string, hit bool) {
+	sinks, ok := lb.options.metrics.(multiSink)
+	if !ok {
+		sinks = multiSink{lb.options.metrics}
+	}
+	for _, s := range sinks {
+		if s, ok := s.(CacheMetricsSink); ok {
+			s.CacheLookup(svc, model, hit)
+		}
+	}
+}
+
+type replayKey struct{}
+
+// replayClient answers every request with the body in its context, so a
+// cached answer goes through the SDK, and the caller's request options,
+// like a fresh one.
+var replayClient = openai.NewClient(
+	option.WithAPIKey("cache"),
+	option.WithBaseURL("http://cache.invalid/"),
+	option.WithMaxRetries(0),
+	option.WithHTTPClient(&http.Client{Transport: replayTransport{}}),
+)
+
+type replayTransport struct{}
+
+func (replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, _ := req.Context().Value(replayKey{}).([]byte)
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// replayCompletion returns the cached answer body to params, marked Cached,
+// and Stale if stale, in its RouteInfo.
+func replayCompletion(ctx context.Context, body []byte, params openai.ChatCompletionNewParams, opts []option.RequestOption, stale bool) (*openai.ChatCompletion, error) {
+	ctx, route := withRoute(ctx, params.Model)
+	route.cached, route.stale = true, stale
+	defer route.finish()
+	return replayClient.Chat.Completions.New(context.WithValue(ctx, replayKey{}, body), params, opts...)
+}
+
+// lruCache is the in-memory Cache, keeping the most recently used entries,
+// up to maxEntries and maxBytes of values.
+type lruCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	bytes      int64
+	order      *list.List // Of *lruEntry, the most recently used first.
+	entries    map[string]*list.Element
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func newLRUCache(maxEntries int, maxBytes int64) *lruCache {
+	return &lruCache{maxEntries: maxEntries, maxBytes: maxBytes, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// Get returns the value of key, unless missing or expired.
+func (c *lruCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := e.Value.(*lruEntry)
+	if !time.Now().Before(entry.expires) {
+		c.remove(e)
+		return nil, false, nil
+	}
+	c.order.MoveToFront(e)
+	return entry.value, true, nil
+}
+
+// Set stores value under key for ttl, evicting the least recently used
+// entries over the limits.
+func (c *lruCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if c.maxBytes > 0 && int64(len(value)) > c.maxBytes {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, expires: time.Now().Add(ttl)})
+	c.bytes += int64(len(value))
+	for c.order.Len() > c.maxEntries || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+// Delete removes key.
+func (c *lruCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+	return nil
+}
+
+func (c *lruCache) remove(e *list.Element) {
+	entry := c.order.Remove(e).(*lruEntry)
+	delete(c.entries, entry.key)
+	c.bytes -= int64(len(entry.value))
+}
@@ -0,0 +1,189 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+func TestResponseCache(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": "chatcmpl-1", "choices": [{"message": {"content": "cached"}}]}`))
+	}))
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{{Name: "openai", APIKey: "k1", BaseURL: server.URL}}, WithResponseCache(ResponseCache{TTL: time.Minute}))
+
+	params := chatParams("m")
+	params.Temperature = openai.Float(0)
+	var first, second RouteInfo
+	if _, err := client.Chat.Completions.New(context.Background(), params, WithRouteInfo(&first)); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Chat.Completions.New(context.Background(), params, WithRouteInfo(&second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 1 || resp.ID != "chatcmpl-1" || resp.Choices[0].Message.Content != "cached" {
+		t.Errorf("Expected the second request to be answered from the cache, got %d calls and %+v", calls.Load(), resp)
+	}
+	if first.Cached || first.Backend != "openai" || !second.Cached || second.Backend != "" {
+		t.Errorf("Expected only the second answer to be marked cached, got %+v and %+v", first, second)
+	}
+
+	other := chatParams("m")
+	other.Temperature = openai.Float(0.7)
+	for i := 0; i < 2; i++ {
+		if _, err := client.Chat.Completions.New(context.Background(), other); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected the nondeterministic requests to reach the backend, got %d calls", calls.Load())
+	}
+	if stats := client.CacheStats(); stats != (CacheStats{Hits: 1, Misses: 1}) {
+		t.Errorf("Expected one hit and one miss, got %+v", stats)
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cache := newLRUCache(2, 10)
+	cache.Set(ctx, "a", []byte("aaa"), time.Minute)
+	cache.Set(ctx, "b", []byte("bbb"), time.Minute)
+	cache.Get(ctx, "a")
+	cache.Set(ctx, "c", []byte("ccc"), time.Minute)
+	if _, ok, _ := cache.Get(ctx, "b"); ok {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+	if _, ok, _ := cache.Get(ctx, "a"); !ok {
+		t.Error("Expected the recently used entry to be kept")
+	}
+
+	cache.Set(ctx, "d", []byte("dddddddd"), time.Minute)
+	if _, ok, _ := cache.Get(ctx, "a"); ok || cache.bytes > 10 {
+		t.Errorf("Expected entries to be evicted down to the byte limit, got %d bytes", cache.bytes)
+	}
+	cache.Set(ctx, "e", []byte("eeeeeeeeeeee"), time.Minute)
+	if _, ok, _ := cache.Get(ctx, "e"); ok {
+		t.Error("Expected a value over the byte limit not to be cached")
+	}
+
+	cache.Set(ctx, "f", []byte("f"), -time.Second)
+	if _, ok, _ := cache.Get(ctx, "f"); ok {
+		t.Error("Expected an expired entry to be missed")
+	}
+}
+
+// mapCache is a Cache in a map, recording the TTLs it was given.
+type mapCache struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+	ttls    []time.Duration
+}
+
+func (c *mapCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.entries[key]
+	return value, ok, nil
+}
+
+func (c *mapCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = value
+	c.ttls = append(c.ttls, ttl)
+	return nil
+}
+
+func (c *mapCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+	return nil
+}
+
+func TestResponseCacheStore(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "fresh"}}]}`))
+	}))
+	defer server.Close()
+	store := &mapCache{entries: make(map[string][]byte)}
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL}}, WithResponseCache(ResponseCache{TTL: time.Hour, Store: store}))
+
+	params := chatParams("m")
+	params.Temperature = openai.Float(0)
+	key, err := cacheKey(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.entries[key] = []byte("not json")
+	resp, err := client.Chat.Completions.New(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 1 || resp.Choices[0].Message.Content != "fresh" {
+		t.Errorf("Expected an unreadable entry to be fetched again, got %d calls and %+v", calls.Load(), resp)
+	}
+	if _, err := client.Chat.Completions.New(context.Background(), params); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected the second request to be answered from the store, got %d calls", calls.Load())
+	}
+	if len(store.ttls) != 1 || store.ttls[0] != time.Hour {
+		t.Errorf("Expected the answer to be stored once for the TTL, got %v", store.ttls)
+	}
+}
+
+func TestResponseCacheServeStale(t *testing.T) {
+	t.Parallel()
+
+	server := newNamedServer(t, "fresh")
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{{Name: "openai", APIKey: "k1", BaseURL: server.URL}}, WithResponseCache(ResponseCache{StaleFor: time.Hour}))
+
+	params := chatParams("m")
+	params.Temperature = openai.Float(0.7)
+	if _, err := client.Chat.Completions.New(context.Background(), params); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.MarkUnhealthy("openai", "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+
+	var info RouteInfo
+	resp, err := client.Chat.Completions.New(context.Background(), params, WithRouteInfo(&info))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Choices[0].Message.Content != "fresh" || !info.Stale || !info.Cached {
+		t.Errorf("Expected the last answer to be served stale and marked so, got %+v and %+v", resp, info)
+	}
+	other := chatParams("other")
+	if _, err := client.Chat.Completions.New(context.Background(), other); !errors.Is(err, ErrAllBackendsDown) {
+		t.Errorf("Expected a request never answered to fail, got %v", err)
+	}
+	if stats := client.CacheStats(); stats != (CacheStats{Stale: 1}) {
+		t.Errorf("Expected one stale answer and no fresh lookups, got %+v", stats)
+	}
+}
@@ -0,0 +1,55 @@
+package openailb
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+type captureKey struct{}
+
+// attemptCapture holds what captureAttempt saw of an attempt's HTTP exchange.
+type attemptCapture struct {
+	keepBody   bool   // Whether to keep the request body, for WithAudit.
+	body       []byte // The JSON request body.
+	rateLimits *RateLimits
+}
+
+// withCapture returns a context in which captureAttempt records the attempt
+// into the returned capture.
+func (lb *LoadBalancer) withCapture(ctx context.Context) (context.Context, *attemptCapture) {
+	capture := &attemptCapture{keepBody: lb.options.audit != nil}
+	return context.WithValue(ctx, captureKey{}, capture), capture
+}
+
+// captureFrom returns the capture of withCapture in ctx.
+func captureFrom(ctx context.Context) *attemptCapture {
+	capture, _ := ctx.Value(captureKey{}).(*attemptCapture)
+	return capture
+}
+
+// captureAttempt is the backend middleware recording the rate limits a
+// backend announced and, for the audit log, a copy of JSON request bodies.
+// Multipart uploads are left alone.
+func captureAttempt(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	capture := captureFrom(req.Context())
+	if capture == nil {
+		return next(req)
+	}
+	if capture.keepBody && req.GetBody != nil && strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		if body, err := req.GetBody(); err == nil {
+			capture.body, _ = io.ReadAll(body)
+			body.Close()
+		}
+	}
+	res, err := next(req)
+	if res != nil {
+		if limits, ok := parseRateLimits(res.Header); ok {
+			capture.rateLimits = &limits
+		}
+	}
+	return res, err
+}
@@ -0,0 +1,79 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrClosed is returned for requests, and by AddBackend, once the client is
+// closed.
+var ErrClosed = errors.New("client is closed")
+
+// Close shuts the client down: new requests fail with ErrClosed at once, and
+// discovery stops. It then waits until ctx is done for the requests in flight,
+// streams included, and the removed backends still draining; stops the health
+// checks and other background work of the backends; saves the breaker state
+// to the WithStateStore store, if any; and closes the idle connections. If
+// ctx is done first, Close still stops everything but returns an error
+// wrapping ctx's. Closing a closed client returns ErrClosed.
+func (c Client) Close(ctx context.Context) error {
+	lb := c.lb
+	lb.poolMu.Lock()
+	if lb.closed.Swap(true) {
+		lb.poolMu.Unlock()
+		return ErrClosed
+	}
+	lb.poolMu.Unlock()
+	lb.closeDone()
+	lb.options.logger.Info("closing")
+
+	drained := true
+	for _, backend := range lb.backends() {
+		drained = backend.waitIdle(ctx) && drained
+	}
+	background := make(chan struct{})
+	go func() {
+		lb.background.Wait()
+		close(background)
+	}()
+	select {
+	case <-background:
+	case <-ctx.Done():
+		select {
+		case <-background:
+		default:
+			drained = false
+		}
+	}
+
+	var errs []error
+	var inFlight int64
+	for _, backend := range lb.backends() {
+		inFlight += backend.stats.inFlight.Load()
+		backend.retire()
+	}
+	if !drained {
+		errs = append(errs, fmt.Errorf("%d requests still in flight: %w", inFlight, ctx.Err()))
+	}
+	if lb.options.stateStore != nil {
+		// Even past ctx's deadline: the state outlives the requests.
+		saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), stateStoreTimeout)
+		defer cancel()
+		if err := lb.saveState(saveCtx); err != nil {
+			errs = append(errs, fmt.Errorf("save state: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// untilClosed returns a copy of ctx that is also canceled when the client
+// is closed.
+func (lb *LoadBalancer) untilClosed(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(lb.done, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
@@ -0,0 +1,108 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestClose(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	arrived := make(chan struct{}, 1)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "slow"}}]}`))
+	}))
+	defer slow.Close()
+	fast := newNamedServer(t, "fast")
+	defer fast.Close()
+
+	client := NewClient([]OpenaiClientConfig{{Name: "slow", APIKey: "k1", BaseURL: slow.URL, Labels: map[string]string{"speed": "slow"}}})
+	d := &fakeDiscovery{updates: make(chan []OpenaiClientConfig, 1)}
+	d.updates <- []OpenaiClientConfig{{Name: "fast", APIKey: "k2", BaseURL: fast.URL}}
+	if err := client.Discover(context.Background(), d); err != nil {
+		t.Fatal(err)
+	}
+	backends := client.lb.backends()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Chat.Completions.New(WithLabelSelector(context.Background(), map[string]string{"speed": "slow"}), chatParams("m"))
+		done <- err
+	}()
+	<-arrived
+	closed := make(chan error, 1)
+	go func() { closed <- client.Close(context.Background()) }()
+	if !waitFor(t, time.Second, client.lb.closed.Load) {
+		t.Fatal("Expected the client to be closing")
+	}
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected new requests to be refused once closing, got %v", err)
+	}
+	if _, err := client.AddBackend(OpenaiClientConfig{APIKey: "k3", BaseURL: fast.URL}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected AddBackend to be refused, got %v", err)
+	}
+	select {
+	case err := <-closed:
+		t.Fatalf("Expected Close to wait for the in-flight request, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("Expected the in-flight request to complete, got %v", err)
+	}
+	if err := <-closed; err != nil {
+		t.Errorf("Expected a clean close, got %v", err)
+	}
+	for _, backend := range backends {
+		select {
+		case <-backend.stop:
+		default:
+			t.Errorf("Expected %s to be stopped", backend.Name)
+		}
+	}
+	if err := client.Close(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected closing twice to fail, got %v", err)
+	}
+}
+
+func TestCloseTimeout(t *testing.T) {
+	t.Parallel()
+
+	hung := make(chan struct{})
+	arrived := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-hung
+	}))
+	defer server.Close()
+	defer close(hung)
+
+	client := NewClient([]OpenaiClientConfig{{Name: "stuck", APIKey: "k1", BaseURL: server.URL}})
+	go func() {
+		_, _ = client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+	}()
+	<-arrived
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := client.Close(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "1 requests still in flight") {
+		t.Errorf("Expected the requests left to be reported, got %v", err)
+	}
+	select {
+	case <-client.lb.backends()[0].stop:
+	default:
+		t.Error("Expected the backend to be stopped anyway")
+	}
+}
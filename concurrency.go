@@ -0,0 +1,87 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// errAtCapacity is returned by nextClient when the backends left are all at
+// their MaxInFlight.
+var errAtCapacity = errors.New("all clients are unavailable or at their MaxInFlight limit")
+
+// full reports whether the backend has as many requests as it may hold.
+func (c *SafeClient) full() bool {
+	limit := c.maxInFlight.Load()
+	return limit > 0 && c.active.Load() >= limit
+}
+
+// acquire takes one of the backend's request slots, to be handed back by the
+// returned func, which may be called more than once.
+func (c *SafeClient) acquire() func() {
+	c.active.Add(1)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.active.Add(-1)
+			c.lb.slots.broadcast()
+		})
+	}
+}
+
+// slotSignal wakes the requests waiting for a backend's slot to free up.
+type slotSignal struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+// wait returns a channel closed at the next broadcast.
+func (s *slotSignal) wait() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ch == nil {
+		s.ch = make(chan struct{})
+	}
+	return s.ch
+}
+
+func (s *slotSignal) broadcast() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ch != nil {
+		close(s.ch)
+		s.ch = nil
+	}
+}
+
+// pick is nextClient, waiting while the backends it could use are all at
+// their MaxInFlight until one frees a slot or ctx is done, and skipping those
+// out of shared budget (WithRateLimiter). The slot is taken; the returned func
+// hands it back. The request must have been admitted (admit) already.
+func (lb *LoadBalancer) pick(ctx context.Context, svc ServiceType, model string, tokens int64, tried map[*SafeClient]bool) (*SafeClient, func(), error) {
+	for {
+		freed := lb.slots.wait()
+		safeClient, release, err := lb.nextClient(svc, model, tokens, LabelSelectorFromContext(ctx), tried)
+		if err == nil && !lb.takeShared(ctx, safeClient, tokens) {
+			// Used up by the other replicas; its local buckets now say so.
+			release()
+			continue
+		}
+		if err == nil {
+			return safeClient, release, nil
+		}
+		if !errors.Is(err, errAtCapacity) {
+			return nil, nil, err
+		}
+		select {
+		case <-freed:
+			continue
+		case <-ctx.Done():
+			err = fmt.Errorf("%w: %w", err, ctx.Err())
+		case <-lb.done.Done():
+			err = ErrClosed
+		}
+		return nil, nil, err
+	}
+}
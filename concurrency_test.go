@@ -0,0 +1,94 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newBlockingServer answers "slow" once release is closed, signaling each
+// request on arrived first.
+func newBlockingServer(arrived chan<- struct{}, release <-chan struct{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "slow"}}]}`))
+	}))
+}
+
+func TestMaxInFlight(t *testing.T) {
+	t.Parallel()
+
+	arrived, release := make(chan struct{}, 4), make(chan struct{})
+	slow := newBlockingServer(arrived, release)
+	defer slow.Close()
+	defer close(release)
+	spare := newNamedServer(t, "spare")
+	defer spare.Close()
+
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: slow.URL, Weight: 10, MaxInFlight: 1},
+		{APIKey: "k2", BaseURL: spare.URL},
+	})
+	errs := make(chan error, 1)
+	go func() {
+		_, err := client.Chat.Completions.New(context.Background(), chatParams("m"))
+		errs <- err
+	}()
+	<-arrived
+	if hits := countHits(t, client, 3); hits["spare"] != 3 {
+		t.Errorf("Expected the excess traffic to go to the spare backend, got %v", hits)
+	}
+	release <- struct{}{}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if active := client.lb.backends()[0].active.Load(); active != 0 {
+		t.Errorf("Expected every slot to be handed back, got %d held", active)
+	}
+}
+
+func TestMaxInFlightQueueing(t *testing.T) {
+	t.Parallel()
+
+	arrived, release := make(chan struct{}, 4), make(chan struct{})
+	slow := newBlockingServer(arrived, release)
+	defer slow.Close()
+	defer close(release)
+
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: slow.URL, MaxInFlight: 1}})
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := client.Chat.Completions.New(context.Background(), chatParams("m"))
+			errs <- err
+		}()
+	}
+	<-arrived
+	select {
+	case <-arrived:
+		t.Fatal("Expected the second request to wait for the slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := client.Chat.Completions.New(ctx, chatParams("m"))
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "MaxInFlight") {
+		t.Errorf("Expected the queued request to give up with its context, got %v", err)
+	}
+
+	release <- struct{}{}
+	<-arrived
+	release <- struct{}{}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
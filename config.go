@@ -0,0 +1,772 @@
+package openailb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+	"github.com/sony/gobreaker/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the file form of a pool: its backends and the LB-wide options, as
+// loaded by LoadConfig from YAML or JSON. Durations are strings such as "30s".
+// Options without a file form, like hooks or metrics sinks, are passed to
+// NewClientFromConfig in code.
+//
+//	backends:
+//	  - api_key: sk-...
+//	    base_url: https://api.openai.com/v1/
+//	    weight: 2
+//	  - api_key: unused
+//	    base_url: http://vllm:8000/v1/
+//	    model_map: {gpt-4o: llama-3.1-70b}
+//	breaker:
+//	  consecutive_failures: 5
+//	  timeout: 1m
+type Config struct {
+	Backends []BackendConfig `json:"backends" yaml:"backends"`
+
+	// Breaker sets the breakers of every backend (WithCBSettings).
+	Breaker          *BreakerConfig `json:"breaker,omitempty" yaml:"breaker,omitempty"`
+	PerModelBreakers bool           `json:"per_model_breakers,omitempty" yaml:"per_model_breakers,omitempty"`
+	BreakerJitter    float64        `json:"breaker_jitter,omitempty" yaml:"breaker_jitter,omitempty"`
+	AuthQuarantine   bool           `json:"auth_quarantine,omitempty" yaml:"auth_quarantine,omitempty"`
+	// SystemPrompt is injected into every chat completion (WithSystemPrompt).
+	SystemPrompt string `json:"system_prompt,omitempty" yaml:"system_prompt,omitempty"`
+	// RewriteResponseModel reports the requested models in responses
+	// (WithResponseModelRewrite).
+	RewriteResponseModel bool               `json:"rewrite_response_model,omitempty" yaml:"rewrite_response_model,omitempty"`
+	HealthCheck          *HealthCheckConfig `json:"health_check,omitempty" yaml:"health_check,omitempty"`
+	DNSRefresh           Duration           `json:"dns_refresh,omitempty" yaml:"dns_refresh,omitempty"`
+	SlowStart            *SlowStartConfig   `json:"slow_start,omitempty" yaml:"slow_start,omitempty"`
+	Pricing              map[string]Price   `json:"pricing,omitempty" yaml:"pricing,omitempty"`
+	// Budget is the spend ceiling across all backends (WithBudget).
+	Budget *Budget `json:"budget,omitempty" yaml:"budget,omitempty"`
+	// Alerts warn as usage nears its limits (WithUsageAlerts).
+	Alerts []UsageAlert `json:"alerts,omitempty" yaml:"alerts,omitempty"`
+	// Aliases maps model aliases to the model each backend, by name, serves
+	// them as (WithModelAlias).
+	Aliases map[string]map[string]string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	// ContextWindows, if set, even empty, looks up the backends' context
+	// windows by model (WithContextWindows), with these entries on top of the
+	// well-known ones.
+	ContextWindows map[string]int `json:"context_windows,omitempty" yaml:"context_windows,omitempty"`
+	// ValidateModels checks the mapped models against the backends' /models
+	// lists at startup (WithModelValidation).
+	ValidateModels bool `json:"validate_models,omitempty" yaml:"validate_models,omitempty"`
+	// StrictModels rejects the models no backend maps (WithStrictModels).
+	StrictModels bool `json:"strict_models,omitempty" yaml:"strict_models,omitempty"`
+	// TruncateHistory trims chat completions to the context window of their
+	// backend (WithHistoryTruncation).
+	TruncateHistory bool `json:"truncate_history,omitempty" yaml:"truncate_history,omitempty"`
+	// ResponseCache caches deterministic chat completions (WithResponseCache).
+	ResponseCache *ResponseCacheConfig `json:"response_cache,omitempty" yaml:"response_cache,omitempty"`
+	// Pools splits the backends into pools by model (WithPools).
+	Pools []PoolConfig `json:"pools,omitempty" yaml:"pools,omitempty"`
+}
+
+// PoolConfig is the file form of a Pool, its options overriding the LB-wide ones.
+type PoolConfig struct {
+	Name        string             `json:"name" yaml:"name"`
+	Models      []string           `json:"models" yaml:"models"`
+	Breaker     *BreakerConfig     `json:"breaker,omitempty" yaml:"breaker,omitempty"`
+	HealthCheck *HealthCheckConfig `json:"health_check,omitempty" yaml:"health_check,omitempty"`
+}
+
+// pool returns the Pool of p.
+func (p PoolConfig) pool() Pool {
+	pool := Pool{Name: p.Name, Models: p.Models}
+	if p.Breaker != nil {
+		pool.Options = append(pool.Options, WithCBSettings(p.Breaker.settings()))
+	}
+	if p.HealthCheck != nil {
+		pool.Options = append(pool.Options, WithHealthChecks(p.HealthCheck.healthCheck()))
+	}
+	return pool
+}
+
+// BackendConfig is the file form of an OpenaiClientConfig.
+type BackendConfig struct {
+	Name     string            `json:"name,omitempty" yaml:"name,omitempty"`
+	APIKey   string            `json:"api_key" yaml:"api_key"`
+	BaseURL  string            `json:"base_url" yaml:"base_url"`
+	Weight   int               `json:"weight,omitempty" yaml:"weight,omitempty"`
+	ModelMap map[string]string `json:"model_map,omitempty" yaml:"model_map,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	// Pool names the entry of Config.Pools the backend belongs to.
+	Pool string `json:"pool,omitempty" yaml:"pool,omitempty"`
+	// APIKeyRef replaces api_key with a reference resolved by the
+	// WithSecretProvider passed to NewClientFromConfig.
+	APIKeyRef string `json:"api_key_ref,omitempty" yaml:"api_key_ref,omitempty"`
+	// Azure makes the backend an Azure OpenAI resource, with base_url its
+	// endpoint and model_map mapping models to deployment names.
+	Azure *Azure `json:"azure,omitempty" yaml:"azure,omitempty"`
+	// Breaker replaces Config.Breaker for this backend.
+	Breaker *BreakerConfig `json:"breaker,omitempty" yaml:"breaker,omitempty"`
+	// HealthCheck overrides the non-zero fields of Config.HealthCheck.
+	HealthCheck *HealthCheckConfig `json:"health_check,omitempty" yaml:"health_check,omitempty"`
+	Prices      map[string]Price   `json:"prices,omitempty" yaml:"prices,omitempty"`
+	// Params adapts the chat completions sent to the backend.
+	Params *ParamPolicy `json:"params,omitempty" yaml:"params,omitempty"`
+	// SystemPrompt is injected into the chat completions sent to the backend.
+	SystemPrompt string `json:"system_prompt,omitempty" yaml:"system_prompt,omitempty"`
+	// ExtraBody adds vendor fields to the requests sent to the backend.
+	ExtraBody map[string]any `json:"extra_body,omitempty" yaml:"extra_body,omitempty"`
+	// Headers and Query are sent with every call to this backend, e.g. a
+	// gateway's auth header; Organization and Project set the OpenAI ones.
+	Headers      map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Query        map[string]string `json:"query,omitempty" yaml:"query,omitempty"`
+	Organization string            `json:"organization,omitempty" yaml:"organization,omitempty"`
+	Project      string            `json:"project,omitempty" yaml:"project,omitempty"`
+	// ProxyURL, DialTimeout and TLS configure the backend's HTTP transport.
+	ProxyURL    string     `json:"proxy_url,omitempty" yaml:"proxy_url,omitempty"`
+	DialTimeout Duration   `json:"dial_timeout,omitempty" yaml:"dial_timeout,omitempty"`
+	TLS         *TLSConfig `json:"tls,omitempty" yaml:"tls,omitempty"`
+	// RequestTimeout bounds each attempt on the backend.
+	RequestTimeout Duration `json:"request_timeout,omitempty" yaml:"request_timeout,omitempty"`
+	// RPM and RPMBurst cap the requests per minute sent to the backend.
+	RPM      int `json:"rpm,omitempty" yaml:"rpm,omitempty"`
+	RPMBurst int `json:"rpm_burst,omitempty" yaml:"rpm_burst,omitempty"`
+	// TPM caps the tokens per minute sent to the backend.
+	TPM int `json:"tpm,omitempty" yaml:"tpm,omitempty"`
+	// ContextWindow is the most tokens the backend's models take per request.
+	ContextWindow int `json:"context_window,omitempty" yaml:"context_window,omitempty"`
+	// MaxInFlight caps the requests in flight on the backend.
+	MaxInFlight int `json:"max_in_flight,omitempty" yaml:"max_in_flight,omitempty"`
+	// Budget is the backend's spend ceiling.
+	Budget *Budget `json:"budget,omitempty" yaml:"budget,omitempty"`
+}
+
+// requestOptions returns the request options of the backend's headers,
+// query parameters, organization and project.
+func (b BackendConfig) requestOptions() []option.RequestOption {
+	var opts []option.RequestOption
+	for _, key := range sortedKeys(b.Headers) {
+		opts = append(opts, option.WithHeader(key, b.Headers[key]))
+	}
+	for _, key := range sortedKeys(b.Query) {
+		opts = append(opts, option.WithQuery(key, b.Query[key]))
+	}
+	if b.Organization != "" {
+		opts = append(opts, option.WithOrganization(b.Organization))
+	}
+	if b.Project != "" {
+		opts = append(opts, option.WithProject(b.Project))
+	}
+	return opts
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// BreakerConfig is the file form of the breaker settings.
+type BreakerConfig struct {
+	// ConsecutiveFailures trips the breaker after that many failures in a row (default 3).
+	ConsecutiveFailures uint32 `json:"consecutive_failures,omitempty" yaml:"consecutive_failures,omitempty"`
+	// Timeout is how long the breaker stays open (default 30s).
+	Timeout Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// Interval is how often a closed breaker clears its counts (default never).
+	Interval       Duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+	HalfOpenProbes uint32   `json:"half_open_probes,omitempty" yaml:"half_open_probes,omitempty"`
+}
+
+// settings returns the gobreaker settings of b, with the defaults filled in.
+func (b BreakerConfig) settings() gobreaker.Settings {
+	failures := b.ConsecutiveFailures
+	if failures == 0 {
+		failures = 3
+	}
+	st := defaultCBSettings
+	st.ReadyToTrip = func(counts gobreaker.Counts) bool { return counts.ConsecutiveFailures >= failures }
+	if b.Timeout > 0 {
+		st.Timeout = time.Duration(b.Timeout)
+	}
+	st.Interval = time.Duration(b.Interval)
+	st.MaxRequests = b.HalfOpenProbes
+	return st
+}
+
+// HealthCheckConfig is the file form of a HealthCheck.
+type HealthCheckConfig struct {
+	Interval           Duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+	Timeout            Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	Method             string   `json:"method,omitempty" yaml:"method,omitempty"`
+	Path               string   `json:"path,omitempty" yaml:"path,omitempty"`
+	Model              string   `json:"model,omitempty" yaml:"model,omitempty"`
+	UnhealthyThreshold int      `json:"unhealthy_threshold,omitempty" yaml:"unhealthy_threshold,omitempty"`
+	HealthyThreshold   int      `json:"healthy_threshold,omitempty" yaml:"healthy_threshold,omitempty"`
+}
+
+func (h HealthCheckConfig) healthCheck() HealthCheck {
+	return HealthCheck{
+		Interval:           time.Duration(h.Interval),
+		Timeout:            time.Duration(h.Timeout),
+		Method:             h.Method,
+		Path:               h.Path,
+		Model:              h.Model,
+		UnhealthyThreshold: h.UnhealthyThreshold,
+		HealthyThreshold:   h.HealthyThreshold,
+	}
+}
+
+// SlowStartConfig is the file form of WithSlowStart.
+type SlowStartConfig struct {
+	Window          Duration `json:"window" yaml:"window"`
+	InitialFraction float64  `json:"initial_fraction" yaml:"initial_fraction"`
+}
+
+// ResponseCacheConfig is the file form of WithResponseCache.
+type ResponseCacheConfig struct {
+	TTL            Duration `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	MaxEntries     int      `json:"max_entries,omitempty" yaml:"max_entries,omitempty"`
+	MaxBytes       int64    `json:"max_bytes,omitempty" yaml:"max_bytes,omitempty"`
+	AnyTemperature bool     `json:"any_temperature,omitempty" yaml:"any_temperature,omitempty"`
+	StaleFor       Duration `json:"stale_for,omitempty" yaml:"stale_for,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "1m30s" in config files.
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q (use e.g. \"30s\" or \"2m\")", text)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// LoadConfig reads and validates the config file at path, as YAML or, with a
+// .json extension, as JSON. Unknown fields are errors, so typos don't go
+// unnoticed.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := parseConfig(data, strings.EqualFold(filepath.Ext(path), ".json"))
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// parseConfig strictly decodes a YAML or JSON config.
+func parseConfig(data []byte, isJSON bool) (*Config, error) {
+	var cfg Config
+	var err error
+	if isJSON {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&cfg)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&cfg)
+	}
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("config is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate reports every problem of the config at once, each prefixed with
+// the path of the offending field, e.g. "backends[1].base_url".
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(field, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if len(c.Backends) == 0 {
+		fail("backends", "at least one backend is required")
+	}
+	pools := make(map[string]int, len(c.Pools))
+	for i, p := range c.Pools {
+		field := fmt.Sprintf("pools[%d]", i)
+		if first, ok := pools[p.Name]; ok {
+			fail(field+".name", "%q is already the name of pools[%d]", p.Name, first)
+		} else if p.Name == "" {
+			fail(field+".name", "is required")
+		} else {
+			pools[p.Name] = i
+		}
+		if len(p.Models) == 0 {
+			fail(field+".models", "at least one model is required")
+		}
+		if p.Breaker != nil {
+			errs = append(errs, p.Breaker.validate(field+".breaker")...)
+		}
+		if p.HealthCheck != nil {
+			errs = append(errs, p.HealthCheck.validate(field+".health_check")...)
+		}
+	}
+	configs := make([]OpenaiClientConfig, len(c.Backends))
+	names := make(map[string]int, len(c.Backends))
+	for i, b := range c.Backends {
+		field := fmt.Sprintf("backends[%d]", i)
+		configs[i] = b.clientConfig()
+		names[backendName(configs[i], i)] = i
+		if _, ok := pools[b.Pool]; b.Pool != "" && !ok {
+			fail(field+".pool", "no pool named %q in pools", b.Pool)
+		}
+		if b.Breaker != nil {
+			errs = append(errs, b.Breaker.validate(field+".breaker")...)
+		}
+		if b.HealthCheck != nil {
+			errs = append(errs, b.HealthCheck.validate(field+".health_check")...)
+		}
+		if b.TLS != nil {
+			if _, err := b.TLS.tlsConfig(); err != nil {
+				fail(field+".tls", "%v", err)
+			}
+		}
+	}
+	errs = append(errs, validateBackends(configs, "backends", configFileFields)...)
+
+	for alias, models := range c.Aliases {
+		for _, name := range sortedKeys(models) {
+			if _, ok := names[name]; !ok {
+				fail(fmt.Sprintf("aliases.%s", alias), "no backend named %q", name)
+			}
+			if models[name] == "" {
+				fail(fmt.Sprintf("aliases.%s.%s", alias, name), "must not be empty")
+			}
+		}
+	}
+	if rc := c.ResponseCache; rc != nil {
+		if rc.TTL < 0 {
+			fail("response_cache.ttl", "must not be negative, got %s", time.Duration(rc.TTL))
+		}
+		if rc.MaxEntries < 0 {
+			fail("response_cache.max_entries", "must not be negative, got %d", rc.MaxEntries)
+		}
+		if rc.MaxBytes < 0 {
+			fail("response_cache.max_bytes", "must not be negative, got %d", rc.MaxBytes)
+		}
+		if rc.StaleFor < 0 {
+			fail("response_cache.stale_for", "must not be negative, got %s", time.Duration(rc.StaleFor))
+		}
+	}
+	for model, window := range c.ContextWindows {
+		if window <= 0 {
+			fail(fmt.Sprintf("context_windows.%s", model), "must be positive, got %d", window)
+		}
+	}
+	if c.Breaker != nil {
+		errs = append(errs, c.Breaker.validate("breaker")...)
+	}
+	if c.BreakerJitter < 0 || c.BreakerJitter >= 1 {
+		fail("breaker_jitter", "must be in [0, 1), got %v", c.BreakerJitter)
+	}
+	if c.HealthCheck != nil {
+		errs = append(errs, c.HealthCheck.validate("health_check")...)
+	}
+	if c.DNSRefresh < 0 {
+		fail("dns_refresh", "must not be negative, got %s", time.Duration(c.DNSRefresh))
+	}
+	if s := c.SlowStart; s != nil {
+		if s.Window <= 0 {
+			fail("slow_start.window", "must be positive, got %s", time.Duration(s.Window))
+		}
+		if s.InitialFraction < 0 || s.InitialFraction > 1 {
+			fail("slow_start.initial_fraction", "must be in [0, 1], got %v", s.InitialFraction)
+		}
+	}
+	errs = append(errs, validatePrices("pricing", c.Pricing)...)
+	if c.Budget != nil {
+		errs = append(errs, c.Budget.validate("budget")...)
+	}
+	for i := range c.Alerts {
+		errs = append(errs, c.Alerts[i].validate(fmt.Sprintf("alerts[%d]", i))...)
+	}
+	return errors.Join(errs...)
+}
+
+// validateProxyURL checks that u is empty or an absolute proxy URL.
+func validateProxyURL(u string) error {
+	if u == "" {
+		return nil
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return fmt.Errorf("%q is not a URL: %v", u, err)
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return fmt.Errorf("%q must be an absolute URL, e.g. http://proxy:3128", u)
+	}
+	return nil
+}
+
+// validateBaseURL checks that u is an absolute http(s) URL.
+func validateBaseURL(u string) error {
+	if u == "" {
+		return errors.New("is required")
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return fmt.Errorf("%q is not a URL: %v", u, err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%q must be an absolute http or https URL, e.g. https://api.openai.com/v1/", u)
+	}
+	return nil
+}
+
+func (b *BreakerConfig) validate(field string) []error {
+	var errs []error
+	if b.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("%s.timeout: must not be negative, got %s", field, time.Duration(b.Timeout)))
+	}
+	if b.Interval < 0 {
+		errs = append(errs, fmt.Errorf("%s.interval: must not be negative, got %s", field, time.Duration(b.Interval)))
+	}
+	return errs
+}
+
+func (h *HealthCheckConfig) validate(field string) []error {
+	var errs []error
+	if h.Interval < 0 {
+		errs = append(errs, fmt.Errorf("%s.interval: must not be negative, got %s", field, time.Duration(h.Interval)))
+	}
+	if h.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("%s.timeout: must not be negative, got %s", field, time.Duration(h.Timeout)))
+	}
+	if h.Method != "" && h.Method != http.MethodGet && h.Method != http.MethodPost {
+		errs = append(errs, fmt.Errorf("%s.method: must be GET or POST, got %q", field, h.Method))
+	}
+	if h.UnhealthyThreshold < 0 || h.HealthyThreshold < 0 {
+		errs = append(errs, fmt.Errorf("%s: thresholds must not be negative", field))
+	}
+	return errs
+}
+
+func validatePrices(field string, prices map[string]Price) []error {
+	var errs []error
+	for model, p := range prices {
+		if p.Prompt < 0 || p.Completion < 0 {
+			errs = append(errs, fmt.Errorf("%s[%q]: prices must not be negative", field, model))
+		}
+	}
+	return errs
+}
+
+// backendConfigs returns the OpenaiClientConfigs of the backends, loading
+// their TLS files.
+func (c *Config) backendConfigs() ([]OpenaiClientConfig, error) {
+	configs := make([]OpenaiClientConfig, 0, len(c.Backends))
+	for i, b := range c.Backends {
+		cfg, err := b.ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("backends[%d].%w", i, err)
+		}
+		configs = append(configs, cfg)
+	}
+	return configs, nil
+}
+
+// ClientConfig returns the OpenaiClientConfig of the backend, loading its TLS
+// files, e.g. for Client.AddBackend. It doesn't validate b; Config.Validate does.
+func (b BackendConfig) ClientConfig() (OpenaiClientConfig, error) {
+	cfg := b.clientConfig()
+	if b.TLS != nil {
+		tlsConfig, err := b.TLS.tlsConfig()
+		if err != nil {
+			return OpenaiClientConfig{}, fmt.Errorf("tls: %w", err)
+		}
+		cfg.TLSConfig = tlsConfig
+	}
+	return cfg, nil
+}
+
+// clientConfig is ClientConfig without the TLS files.
+func (b BackendConfig) clientConfig() OpenaiClientConfig {
+	cfg := OpenaiClientConfig{
+		Name:           b.Name,
+		APIKey:         b.APIKey,
+		APIKeyRef:      b.APIKeyRef,
+		BaseURL:        b.BaseURL,
+		ModelMap:       b.ModelMap,
+		Labels:         b.Labels,
+		Weight:         b.Weight,
+		Pool:           b.Pool,
+		Azure:          b.Azure,
+		Prices:         b.Prices,
+		Params:         b.Params,
+		SystemPrompt:   b.SystemPrompt,
+		ExtraBody:      b.ExtraBody,
+		RequestOptions: b.requestOptions(),
+		ProxyURL:       b.ProxyURL,
+		DialTimeout:    time.Duration(b.DialTimeout),
+		RequestTimeout: time.Duration(b.RequestTimeout),
+		RPM:            b.RPM,
+		RPMBurst:       b.RPMBurst,
+		TPM:            b.TPM,
+		ContextWindow:  b.ContextWindow,
+		MaxInFlight:    b.MaxInFlight,
+		Budget:         b.Budget,
+	}
+	if b.Breaker != nil {
+		st := b.Breaker.settings()
+		cfg.CBSettings = &st
+	}
+	if b.HealthCheck != nil {
+		check := b.HealthCheck.healthCheck()
+		cfg.HealthCheck = &check
+	}
+	return cfg
+}
+
+// options returns the LB options set by the config.
+func (c *Config) options() []LBOption {
+	var opts []LBOption
+	if c.Breaker != nil {
+		opts = append(opts, WithCBSettings(c.Breaker.settings()))
+	}
+	if c.PerModelBreakers {
+		opts = append(opts, WithPerModelBreakers())
+	}
+	if c.BreakerJitter > 0 {
+		opts = append(opts, WithBreakerJitter(c.BreakerJitter))
+	}
+	if c.AuthQuarantine {
+		opts = append(opts, WithAuthQuarantine())
+	}
+	if c.SystemPrompt != "" {
+		opts = append(opts, WithSystemPrompt(c.SystemPrompt))
+	}
+	if c.RewriteResponseModel {
+		opts = append(opts, WithResponseModelRewrite())
+	}
+	if c.HealthCheck != nil {
+		opts = append(opts, WithHealthChecks(c.HealthCheck.healthCheck()))
+	}
+	if c.DNSRefresh > 0 {
+		opts = append(opts, WithDNSRefresh(time.Duration(c.DNSRefresh)))
+	}
+	if s := c.SlowStart; s != nil {
+		opts = append(opts, WithSlowStart(time.Duration(s.Window), s.InitialFraction))
+	}
+	if len(c.Pricing) > 0 {
+		opts = append(opts, WithPricing(c.Pricing))
+	}
+	if c.Budget != nil {
+		opts = append(opts, WithBudget(*c.Budget))
+	}
+	if len(c.Alerts) > 0 {
+		opts = append(opts, WithUsageAlerts(c.Alerts...))
+	}
+	for alias, models := range c.Aliases {
+		opts = append(opts, WithModelAlias(alias, models))
+	}
+	if c.ContextWindows != nil {
+		opts = append(opts, WithContextWindows(c.ContextWindows))
+	}
+	if c.TruncateHistory {
+		opts = append(opts, WithHistoryTruncation())
+	}
+	if c.StrictModels {
+		opts = append(opts, WithStrictModels())
+	}
+	if c.ValidateModels {
+		opts = append(opts, WithModelValidation())
+	}
+	if rc := c.ResponseCache; rc != nil {
+		opts = append(opts, WithResponseCache(ResponseCache{
+			TTL:            time.Duration(rc.TTL),
+			MaxEntries:     rc.MaxEntries,
+			MaxBytes:       rc.MaxBytes,
+			AnyTemperature: rc.AnyTemperature,
+			StaleFor:       time.Duration(rc.StaleFor),
+		}))
+	}
+	for _, p := range c.Pools {
+		opts = append(opts, WithPools(p.pool()))
+	}
+	return opts
+}
+
+// NewClientFromConfig validates cfg and builds its pool. opts are applied
+// after the options of cfg, e.g. for a logger or hooks.
+func NewClientFromConfig(cfg *Config, opts ...LBOption) (Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return Client{}, err
+	}
+	configs, err := cfg.backendConfigs()
+	if err != nil {
+		return Client{}, err
+	}
+	return New(configs, append(cfg.options(), opts...)...)
+}
+
+// New is NewClient, but validates configs up front instead of failing at
+// request time: it returns an error for an empty pool, a missing API key, a
+// malformed base URL, a negative weight, a duplicate name, the same backend
+// configured twice, an unknown pool or an alias naming an unknown backend,
+// naming every offending config.
+func New(configs []OpenaiClientConfig, opts ...LBOption) (Client, error) {
+	if err := ValidateConfigs(configs); err != nil {
+		return Client{}, err
+	}
+	if err := validatePools(configs, opts); err != nil {
+		return Client{}, err
+	}
+	if err := validateAliases(configs, opts); err != nil {
+		return Client{}, err
+	}
+	return NewClient(configs, opts...), nil
+}
+
+// ValidateConfigs reports every problem of configs at once, without
+// contacting the backends (see Client.Validate for that).
+func ValidateConfigs(configs []OpenaiClientConfig) error {
+	if len(configs) == 0 {
+		return errors.New("no backends configured")
+	}
+	return errors.Join(validateBackends(configs, "configs", nil)...)
+}
+
+// fieldNames maps the names of OpenaiClientConfig fields to those the
+// errors give them; fields it lacks keep their own.
+type fieldNames map[string]string
+
+func (n fieldNames) of(field string) string {
+	if name, ok := n[field]; ok {
+		return name
+	}
+	return field
+}
+
+// configFileFields names the fields as config files do.
+var configFileFields = fieldNames{
+	"Name":           "name",
+	"APIKey":         "api_key",
+	"APIKeyRef":      "api_key_ref",
+	"BaseURL":        "base_url",
+	"Weight":         "weight",
+	"ModelMap":       "model_map",
+	"Prices":         "prices",
+	"ProxyURL":       "proxy_url",
+	"DialTimeout":    "dial_timeout",
+	"RequestTimeout": "request_timeout",
+	"RPM":            "rpm",
+	"RPMBurst":       "rpm_burst",
+	"TPM":            "tpm",
+	"ContextWindow":  "context_window",
+	"MaxInFlight":    "max_in_flight",
+	"Budget":         "budget",
+	"Params":         "params",
+	"ExtraBody":      "extra_body",
+}
+
+// validateBackends reports the problems of the backends listed under list,
+// naming their fields with names. Config.Validate and ValidateConfigs share
+// it, so that files and code are held to the same rules.
+func validateBackends(configs []OpenaiClientConfig, list string, names fieldNames) []error {
+	var errs []error
+	seen := make([]OpenaiClientConfig, 0, len(configs)) // Without their Name and Weight.
+	taken := make(map[string]int, len(configs))
+	for i, cfg := range configs {
+		at := fmt.Sprintf("%s[%d]", list, i)
+		fail := func(name, format string, args ...any) {
+			errs = append(errs, fmt.Errorf("%s.%s: %s", at, names.of(name), fmt.Sprintf(format, args...)))
+		}
+		name := backendName(cfg, i)
+		if first, ok := taken[name]; ok {
+			fail("Name", "%q is already the name of %s[%d]", name, list, first)
+		} else {
+			taken[name] = i
+		}
+		switch {
+		case cfg.APIKey == "" && cfg.APIKeyRef == "":
+			fail("APIKey", "is required, or %s (use any placeholder for servers without auth)", names.of("APIKeyRef"))
+		case cfg.APIKey != "" && cfg.APIKeyRef != "":
+			fail("APIKeyRef", "must not be set with %s", names.of("APIKey"))
+		}
+		if err := validateBaseURL(cfg.BaseURL); err != nil {
+			fail("BaseURL", "%v", err)
+		}
+		if cfg.Weight < 0 {
+			fail("Weight", "must not be negative, got %d", cfg.Weight)
+		}
+		for _, err := range validateModelMap(cfg.ModelMap) {
+			fail("ModelMap", "%v", err)
+		}
+		errs = append(errs, validatePrices(at+"."+names.of("Prices"), cfg.Prices)...)
+		if err := validateProxyURL(cfg.ProxyURL); err != nil {
+			fail("ProxyURL", "%v", err)
+		}
+		if cfg.DialTimeout < 0 {
+			fail("DialTimeout", "must not be negative, got %s", cfg.DialTimeout)
+		}
+		if cfg.RequestTimeout < 0 {
+			fail("RequestTimeout", "must not be negative, got %s", cfg.RequestTimeout)
+		}
+		if cfg.RPM < 0 {
+			fail("RPM", "must not be negative, got %d", cfg.RPM)
+		}
+		if cfg.RPMBurst < 0 {
+			fail("RPMBurst", "must not be negative, got %d", cfg.RPMBurst)
+		}
+		if cfg.TPM < 0 {
+			fail("TPM", "must not be negative, got %d", cfg.TPM)
+		}
+		if cfg.ContextWindow < 0 {
+			fail("ContextWindow", "must not be negative, got %d", cfg.ContextWindow)
+		}
+		if cfg.MaxInFlight < 0 {
+			fail("MaxInFlight", "must not be negative, got %d", cfg.MaxInFlight)
+		}
+		if cfg.Budget != nil {
+			errs = append(errs, cfg.Budget.validate(at+"."+names.of("Budget"))...)
+		}
+		if cfg.Params != nil {
+			errs = append(errs, cfg.Params.validate(at+"."+names.of("Params"))...)
+		}
+		if _, ok := cfg.ExtraBody[""]; ok {
+			fail("ExtraBody", "has an empty field name")
+		}
+		if cfg.HTTPClient != nil && (cfg.ProxyURL != "" || cfg.TLSConfig != nil || cfg.DialTimeout > 0) {
+			errs = append(errs, fmt.Errorf("%s: ProxyURL, TLSConfig and DialTimeout have no effect with an HTTPClient; configure its transport instead", at))
+		}
+		// Backends sharing an endpoint and key may still differ, like Azure
+		// deployments on one resource or backends of separate pools; only
+		// those differing in nothing but their name and weight are the same.
+		id := cfg
+		id.Name, id.Weight, id.BaseURL = "", 0, strings.TrimSuffix(cfg.BaseURL, "/")
+		if first := slices.IndexFunc(seen, func(s OpenaiClientConfig) bool { return reflect.DeepEqual(s, id) }); first >= 0 && cfg.BaseURL != "" {
+			errs = append(errs, fmt.Errorf("%s: same config as %s[%d]; raise its %s instead", at, list, first, names.of("Weight")))
+		}
+		seen = append(seen, id)
+	}
+	return errs
+}
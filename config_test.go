@@ -0,0 +1,219 @@
+package openailb
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+
+	server := newNamedServer(t, "ok")
+	defer server.Close()
+	path := writeConfig(t, "lb.yaml", `
+backends:
+  - api_key: k1
+    base_url: `+server.URL+`
+    weight: 2
+    model_map: {m: mapped}
+    request_timeout: 2m
+    breaker:
+      consecutive_failures: 1
+      timeout: 1m
+breaker:
+  consecutive_failures: 5
+  timeout: 10s
+pricing:
+  mapped: {prompt: 2, completion: 8}
+`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := cfg.Backends[0]
+	if b.Weight != 2 || b.ModelMap["m"] != "mapped" || time.Duration(b.Breaker.Timeout) != time.Minute {
+		t.Errorf("Unexpected backend %+v", b)
+	}
+	if time.Duration(cfg.Breaker.Timeout) != 10*time.Second || cfg.Pricing["mapped"].Completion != 8 {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+
+	client, err := NewClientFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits := countHits(t, client, 1); hits["ok"] != 1 {
+		t.Errorf("Expected the configured backend to serve, got %v", hits)
+	}
+	backend := client.lb.backends()[0]
+	if backend.weight.Load() != 2 || backend.breakerSettings.Timeout != time.Minute || backend.requestTimeout != 2*time.Minute {
+		t.Errorf("Expected the backend config to apply, got weight %v, breaker timeout %s and request timeout %s",
+			backend.weight.Load(), backend.breakerSettings.Timeout, backend.requestTimeout)
+	}
+
+	jsonPath := writeConfig(t, "lb.json", `{"backends": [{"api_key": "k1", "base_url": "`+server.URL+`"}], "dns_refresh": "30s"}`)
+	if cfg, err := LoadConfig(jsonPath); err != nil || time.Duration(cfg.DNSRefresh) != 30*time.Second {
+		t.Errorf("Expected the JSON config to load, got %+v, %v", cfg, err)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		file, content string
+		want          []string
+	}{
+		"empty":              {"lb.yaml", "", []string{"config is empty"}},
+		"unknown field":      {"lb.yaml", "backends:\n  - api_key: k\n    base_url: http://x\n    wieght: 2\n", []string{"field wieght not found"}},
+		"unknown JSON field": {"lb.json", `{"backend": []}`, []string{`unknown field "backend"`}},
+		"bad duration":       {"lb.yaml", "backends: []\nbreaker: {timeout: 30}\n", []string{`invalid duration "30"`}},
+		"no backends":        {"lb.yaml", "per_model_breakers: true\n", []string{"backends: at least one backend is required"}},
+		"bad backends": {"lb.yaml", `
+backends:
+  - base_url: api.openai.com/v1
+  - api_key: k
+    base_url: https://api.openai.com/v1/
+    weight: -1
+    request_timeout: -1s
+    rpm: -5
+    budget: {limit: 0, period: daily}
+    params: {min_temperature: 1, max_temperature: 0.5}
+  - api_key: k2
+    base_url: https://api.openai.com/v1
+  - api_key: k2
+    base_url: https://api.openai.com/v1/
+    weight: 3
+health_check: {method: HEAD}
+breaker_jitter: 2
+context_windows: {llama-3.1-70b: 0}
+budget: {limit: 100, period: weekly}
+alerts:
+  - name: spend
+    period: daily
+`, []string{
+			"backends[0].api_key: is required",
+			`backends[0].base_url: "api.openai.com/v1" must be an absolute http or https URL`,
+			"backends[1].weight: must not be negative, got -1",
+			"backends[1].request_timeout: must not be negative, got -1s",
+			"backends[1].rpm: must not be negative, got -5",
+			"backends[1].budget.limit: must be positive, got 0",
+			"backends[1].params.min_temperature: must not exceed max_temperature, got 1 > 0.5",
+			"backends[3]: same config as backends[2]; raise its weight instead",
+			`budget.period: must be "daily" or "monthly", got "weekly"`,
+			"alerts[0]: a positive spend or tokens limit is required",
+			`health_check.method: must be GET or POST, got "HEAD"`,
+			"breaker_jitter: must be in [0, 1), got 2",
+			"context_windows.llama-3.1-70b: must be positive, got 0",
+		}},
+		"bad pools": {"lb.yaml", `
+backends:
+  - api_key: k
+    base_url: https://api.openai.com/v1/
+    pool: large
+pools:
+  - name: small
+  - name: small
+    models: [gpt-4o-mini]
+`, []string{
+			`backends[0].pool: no pool named "large" in pools`,
+			"pools[0].models: at least one model is required",
+			`pools[1].name: "small" is already the name of pools[0]`,
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := LoadConfig(writeConfig(t, tc.file, tc.content))
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected %q in the error, got:\n%v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestNewValidatesConfigs(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New(nil); err == nil || err.Error() != "no backends configured" {
+		t.Errorf("Expected an empty pool to fail, got %v", err)
+	}
+	_, err := New([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: "https://api.openai.com/v1/"},
+		{BaseURL: "://bad"},
+		{APIKey: "k1", BaseURL: "https://api.openai.com/v1", Weight: -2, RequestTimeout: -time.Second, Budget: &Budget{Limit: 10}},
+		{Name: "again", APIKey: "k1", BaseURL: "https://api.openai.com/v1", Weight: 2},
+	})
+	for _, want := range []string{
+		"configs[1].APIKey: is required",
+		`configs[1].BaseURL: "://bad" is not a URL`,
+		"configs[2].Weight: must not be negative, got -2",
+		"configs[2].RequestTimeout: must not be negative, got -1s",
+		`configs[2].Budget.period: must be "daily" or "monthly", got ""`,
+		"configs[3]: same config as configs[0]; raise its Weight instead",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in the error, got:\n%v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "configs[2]: same config") {
+		t.Errorf("Expected configs differing in more than their weight to be separate backends, got:\n%v", err)
+	}
+	err = ValidateConfigs([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: "https://api.openai.com/v1"},
+		{APIKey: "k1", BaseURL: "https://api.openai.com/v1", ModelMap: map[string]string{"fast": "gpt-4o-mini"}},
+		{APIKey: "k1", BaseURL: "https://api.openai.com/v1", Labels: map[string]string{"team": "search"}},
+		{APIKey: "k1", BaseURL: "https://api.openai.com/v1", Pool: "batch"},
+	})
+	if err != nil {
+		t.Errorf("Expected backends sharing an endpoint and key to be allowed, got %v", err)
+	}
+
+	server := newNamedServer(t, "ok")
+	defer server.Close()
+	client, err := New([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits := countHits(t, client, 1); hits["ok"] != 1 {
+		t.Errorf("Expected the validated client to serve, got %v", hits)
+	}
+}
+
+func TestConfigRequestOptions(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := parseConfig([]byte(`
+backends:
+  - api_key: k1
+    base_url: http://localhost:8000/v1/
+    headers: {Helicone-Auth: Bearer h}
+    project: proj-1
+`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs, err := cfg.backendConfigs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts := configs[0].RequestOptions; len(opts) != 2 {
+		t.Errorf("Expected a header and a project option, got %d options", len(opts))
+	}
+}
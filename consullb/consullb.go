@@ -0,0 +1,192 @@
+// Package consullb discovers openailb backends from the Consul catalog: one
+// backend per passing instance of a service. It uses Consul's HTTP API with
+// blocking queries, so changes apply as soon as Consul sees them.
+//
+//	err := client.Discover(ctx, &consullb.Discovery{
+//		Service:  "vllm",
+//		Template: openailb.OpenaiClientConfig{APIKey: "none", BaseURL: "http://vllm/v1"},
+//	})
+package consullb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	openailb "github.com/hi2code/openai-go-lb"
+)
+
+// DefaultAddress is the address of the local Consul agent.
+const DefaultAddress = "http://127.0.0.1:8500"
+
+// retryInterval is the wait before querying again after a failed query.
+const retryInterval = 5 * time.Second
+
+// waitTime is how long a blocking query waits for a change.
+const waitTime = 5 * time.Minute
+
+// Discovery is an openailb.Discovery over the passing instances of a Consul
+// service.
+type Discovery struct {
+	// Address is the Consul agent's URL (default DefaultAddress).
+	Address string
+	// Token, if set, is the ACL token sent with the queries.
+	Token string
+	// Datacenter, if set, is the datacenter queried instead of the agent's.
+	Datacenter string
+	// Service is the name of the service; Tag, if set, keeps only the
+	// instances carrying it.
+	Service string
+	Tag     string
+	// Template is the config of every backend, e.g. the shared API key and
+	// model map. The scheme and path of its BaseURL, e.g. "http://vllm/v1",
+	// are kept and the host replaced by each instance's address and port.
+	// The instance's service meta is added to Template.Labels. The backends
+	// are named after the instance, prefixed with Template.Name or else the
+	// service: "vllm@10.0.0.5:8000".
+	Template openailb.OpenaiClientConfig
+	// HTTPClient sends the queries (default http.DefaultClient).
+	HTTPClient *http.Client
+	// Logger, if set, receives failed queries, which are retried.
+	Logger *slog.Logger
+}
+
+type serviceEntry struct {
+	Node struct {
+		Address string
+	}
+	Service struct {
+		Address string
+		Port    int
+		Meta    map[string]string
+	}
+}
+
+// Watch implements openailb.Discovery. A failed first query is returned;
+// later ones are retried.
+func (d *Discovery) Watch(ctx context.Context, update func([]openailb.OpenaiClientConfig)) error {
+	base, err := url.Parse(d.Template.BaseURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") {
+		return fmt.Errorf("consullb: Template.BaseURL: %q must be an absolute http or https URL", d.Template.BaseURL)
+	}
+	if d.Service == "" {
+		return fmt.Errorf("consullb: Service is required")
+	}
+
+	index := ""
+	for first := true; ; first = false {
+		entries, next, err := d.query(ctx, index)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			if first {
+				return err
+			}
+			if d.Logger != nil {
+				d.Logger.Warn("consullb: query failed", "service", d.Service, "error", err)
+			}
+			select {
+			case <-time.After(retryInterval):
+			case <-ctx.Done():
+				return nil
+			}
+			continue
+		}
+		if first || next != index {
+			update(d.backends(base, entries))
+		}
+		index = next
+	}
+}
+
+// query returns the passing instances of the service once they changed since
+// index, or the blocking query timed out, and the index to wait on next.
+func (d *Discovery) query(ctx context.Context, index string) ([]serviceEntry, string, error) {
+	address := d.Address
+	if address == "" {
+		address = DefaultAddress
+	}
+	q := url.Values{"passing": {"true"}}
+	if d.Tag != "" {
+		q.Set("tag", d.Tag)
+	}
+	if d.Datacenter != "" {
+		q.Set("dc", d.Datacenter)
+	}
+	if index != "" {
+		q.Set("index", index)
+		q.Set("wait", fmt.Sprintf("%ds", int(waitTime.Seconds())))
+	}
+	u := strings.TrimSuffix(address, "/") + "/v1/health/service/" + url.PathEscape(d.Service) + "?" + q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if d.Token != "" {
+		req.Header.Set("X-Consul-Token", d.Token)
+	}
+	httpClient := d.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, "", fmt.Errorf("consullb: query %s: %s: %s", d.Service, res.Status, strings.TrimSpace(string(body)))
+	}
+	var entries []serviceEntry
+	if err := json.NewDecoder(res.Body).Decode(&entries); err != nil {
+		return nil, "", fmt.Errorf("consullb: query %s: %w", d.Service, err)
+	}
+	next := res.Header.Get("X-Consul-Index")
+	if n, err := strconv.ParseUint(next, 10, 64); err != nil || n == 0 {
+		// Consul requires a positive index; reset rather than spin.
+		next = "1"
+	}
+	return entries, next, nil
+}
+
+// backends returns the sorted backend configs of entries.
+func (d *Discovery) backends(base *url.URL, entries []serviceEntry) []openailb.OpenaiClientConfig {
+	prefix := d.Template.Name
+	if prefix == "" {
+		prefix = d.Service
+	}
+	configs := make([]openailb.OpenaiClientConfig, 0, len(entries))
+	for _, e := range entries {
+		addr := e.Service.Address
+		if addr == "" {
+			addr = e.Node.Address
+		}
+		endpoint := net.JoinHostPort(addr, strconv.Itoa(e.Service.Port))
+		cfg := d.Template
+		u := *base
+		u.Host = endpoint
+		cfg.Name, cfg.BaseURL = prefix+"@"+endpoint, u.String()
+		if len(e.Service.Meta) > 0 {
+			cfg.Labels = maps.Clone(d.Template.Labels)
+			if cfg.Labels == nil {
+				cfg.Labels = make(map[string]string, len(e.Service.Meta))
+			}
+			maps.Copy(cfg.Labels, e.Service.Meta)
+		}
+		configs = append(configs, cfg)
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Name < configs[j].Name })
+	return configs
+}
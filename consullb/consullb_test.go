@@ -0,0 +1,86 @@
+package consullb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	openailb "github.com/hi2code/openai-go-lb"
+)
+
+func instance(addr string, port int, meta map[string]string) map[string]any {
+	return map[string]any{
+		"Node":    map[string]any{"Address": "10.0.0.100"},
+		"Service": map[string]any{"Address": addr, "Port": port, "Meta": meta},
+	}
+}
+
+func TestDiscovery(t *testing.T) {
+	changes := make(chan []any, 1)
+	consul := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health/service/vllm" || r.URL.Query().Get("passing") != "true" || r.Header.Get("X-Consul-Token") != "acl" {
+			http.Error(w, "unexpected request", http.StatusForbidden)
+			return
+		}
+		entries := []any{instance("10.0.0.1", 8000, map[string]string{"gpu": "a100"})}
+		index := "1"
+		if r.URL.Query().Get("index") != "" {
+			select {
+			case entries = <-changes:
+				index = "2"
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Header().Set("X-Consul-Index", index)
+		_ = json.NewEncoder(w).Encode(entries)
+	}))
+	defer consul.Close()
+
+	client := openailb.NewClient(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := client.Discover(ctx, &Discovery{
+		Address:  consul.URL,
+		Token:    "acl",
+		Service:  "vllm",
+		Template: openailb.OpenaiClientConfig{APIKey: "none", BaseURL: "http://vllm/v1", Labels: map[string]string{"env": "prod"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	health := client.Health()
+	if len(health) != 1 || health[0].Name != "vllm@10.0.0.1:8000" || health[0].BaseURL != "http://10.0.0.1:8000/v1" {
+		t.Fatalf("Expected the passing instance to be added, got %+v", health)
+	}
+	if labels := health[0].Labels; labels["gpu"] != "a100" || labels["env"] != "prod" {
+		t.Errorf("Expected the service meta to be added to the labels, got %v", labels)
+	}
+
+	changes <- []any{instance("", 8000, nil), instance("10.0.0.2", 8001, nil)}
+	deadline := time.Now().Add(2 * time.Second)
+	var names []string
+	for time.Now().Before(deadline) {
+		names = names[:0]
+		for _, h := range client.Health() {
+			names = append(names, h.Name)
+		}
+		if strings.Join(names, ",") == "vllm@10.0.0.100:8000,vllm@10.0.0.2:8001" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := strings.Join(names, ","); got != "vllm@10.0.0.100:8000,vllm@10.0.0.2:8001" {
+		t.Errorf("Expected the instances to follow Consul, got %s", got)
+	}
+
+	err = openailb.NewClient(nil).Discover(ctx, &Discovery{Address: consul.URL, Service: "vllm", Template: openailb.OpenaiClientConfig{BaseURL: "http://vllm/v1"}})
+	if err == nil || !strings.Contains(err.Error(), fmt.Sprint(http.StatusForbidden)) {
+		t.Errorf("Expected the failed first query to be returned, got %v", err)
+	}
+}
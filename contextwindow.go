@@ -0,0 +1,117 @@
+package openailb
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// knownContextWindows are the context windows, in tokens, of well-known
+// models, used by WithContextWindows.
+var knownContextWindows = map[string]int{
+	"gpt-3.5-turbo": 16385,
+	"gpt-4":         8192,
+	"gpt-4-32k":     32768,
+	"gpt-4-turbo":   128000,
+	"gpt-4o":        128000,
+	"gpt-4o-mini":   128000,
+	"gpt-4.1":       1047576,
+	"gpt-4.1-mini":  1047576,
+	"gpt-4.1-nano":  1047576,
+	"gpt-5":         400000,
+	"gpt-5-mini":    400000,
+	"gpt-5-nano":    400000,
+	"o1":            200000,
+	"o1-mini":       128000,
+	"o3":            200000,
+	"o3-mini":       200000,
+	"o4-mini":       200000,
+}
+
+// WithContextWindows looks up the context window of the backends without an
+// OpenaiClientConfig.ContextWindow by the model they are sent, in a table of
+// well-known models extended, or overridden, by windows, e.g.
+// {"llama-3.1-70b": 131072}. A model missing from the table is tried
+// without its trailing "-" parts, so "gpt-4o-2024-08-06" gets the window
+// of "gpt-4o"; models still not found have no window. Requests too large
+// for a backend's window go to the others, as with ContextWindow.
+func WithContextWindows(windows map[string]int) LBOption {
+	return func(o *lbOptions) {
+		table := make(map[string]int, len(knownContextWindows)+len(windows))
+		for model, window := range knownContextWindows {
+			table[model] = window
+		}
+		for model, window := range windows {
+			table[model] = window
+		}
+		o.contextWindows = table
+	}
+}
+
+// WithHistoryTruncation trims the oldest messages of the chat completions
+// too large for the context window of the backend they are sent to, so they
+// fit instead of failing with a context_length_exceeded error. System and
+// developer messages and the last message are kept, along with the call
+// that tool results ending the history answer, and the tool results
+// following a dropped assistant message are dropped with it. Context
+// windows then no longer steer requests away from a backend.
+func WithHistoryTruncation() LBOption {
+	return func(o *lbOptions) {
+		o.truncateHistory = true
+	}
+}
+
+// contextWindow returns the context window of the backend for model, as
+// sent to it, or 0 if unknown.
+func (c *SafeClient) contextWindow(model string) int64 {
+	if c.config.ContextWindow > 0 {
+		return int64(c.config.ContextWindow)
+	}
+	table := c.lb.options.contextWindows
+	for table != nil {
+		if window, ok := table[model]; ok {
+			return int64(window)
+		}
+		i := strings.LastIndexByte(model, '-')
+		if i < 0 {
+			break
+		}
+		model = model[:i]
+	}
+	return 0
+}
+
+// truncateHistory returns params with the oldest messages dropped until the
+// estimate of the request fits window. The caller's messages are left alone.
+func truncateHistory(params openai.ChatCompletionNewParams, window int64) (openai.ChatCompletionNewParams, int) {
+	tokens := estimateChatTokens(params)
+	if window <= 0 || tokens <= window {
+		return params, 0
+	}
+	messages, dropped := slices.Clone(params.Messages), 0
+	for tokens > window {
+		i := slices.IndexFunc(messages, func(m openai.ChatCompletionMessageParamUnion) bool {
+			return m.OfSystem == nil && m.OfDeveloper == nil
+		})
+		if i < 0 {
+			break
+		}
+		// A message goes with the tool results answering it.
+		j := i + 1
+		for j < len(messages) && messages[j].OfTool != nil {
+			j++
+		}
+		if j == len(messages) {
+			// Keep the last turn, and the call its tool results answer.
+			break
+		}
+		for _, m := range messages[i:j] {
+			tokens -= estimateTokens(m, 0)
+		}
+		messages = slices.Delete(messages, i, j)
+		dropped += j - i
+	}
+	params.Messages = messages
+	return params, dropped
+}
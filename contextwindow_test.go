@@ -0,0 +1,117 @@
+package openailb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+)
+
+func TestContextWindows(t *testing.T) {
+	t.Parallel()
+
+	small, large := newNamedServer(t, "small"), newNamedServer(t, "large")
+	defer small.Close()
+	defer large.Close()
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: small.URL, Weight: 100, ModelMap: map[string]string{"gpt-4o": "llama-3.1-8b-instruct"}},
+		{APIKey: "k2", BaseURL: large.URL},
+		{APIKey: "k3", BaseURL: large.URL, ContextWindow: 500},
+	}, WithContextWindows(map[string]int{"llama-3.1-8b": 1000, "gpt-4": 10000}))
+
+	backends := client.lb.backends()
+	for _, tc := range []struct {
+		backend int
+		model   string
+		want    int64
+	}{
+		{0, "llama-3.1-8b-instruct", 1000},
+		{1, "gpt-4o-2024-08-06", 128000},
+		{1, "gpt-4-0613", 10000},
+		{1, "unknown", 0},
+		{2, "gpt-4o", 500},
+	} {
+		if got := backends[tc.backend].contextWindow(tc.model); got != tc.want {
+			t.Errorf("Expected a window of %d for %q on backend %d, got %d", tc.want, tc.model, tc.backend, got)
+		}
+	}
+
+	params := chatParams("gpt-4o")
+	params.Messages = []openai.ChatCompletionMessageParamUnion{openai.UserMessage(strings.Repeat("word ", 2000))}
+	resp, err := client.Chat.Completions.New(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Choices[0].Message.Content; got != "large" {
+		t.Errorf("Expected the request to go to the backend whose model's window it fits, got %q", got)
+	}
+}
+
+func TestHistoryTruncation(t *testing.T) {
+	t.Parallel()
+
+	sent := make(chan []map[string]any, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []map[string]any `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		sent <- body.Messages
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "ok"}}]}`))
+	}))
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL, ContextWindow: 500}}, WithHistoryTruncation())
+
+	long := strings.Repeat("word ", 600)
+	params := chatParams("m")
+	params.Messages = []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage("Be brief."),
+		openai.UserMessage(long),
+		{OfAssistant: &openai.ChatCompletionAssistantMessageParam{ToolCalls: []openai.ChatCompletionMessageToolCallUnionParam{{
+			OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{ID: "call_1", Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{Name: "lookup", Arguments: "{}"}},
+		}}}},
+		openai.ToolMessage(long, "call_1"),
+		openai.AssistantMessage("Found it."),
+		openai.UserMessage("And now?"),
+	}
+	if _, err := client.Chat.Completions.New(context.Background(), params); err != nil {
+		t.Fatal(err)
+	}
+
+	var roles []string
+	for _, m := range <-sent {
+		roles = append(roles, m["role"].(string))
+	}
+	if got := strings.Join(roles, ","); got != "system,assistant,user" {
+		t.Errorf("Expected the oldest turns to be dropped with their tool results, got %s", got)
+	}
+	if len(params.Messages) != 6 {
+		t.Errorf("Expected the caller's messages to be left alone, got %d", len(params.Messages))
+	}
+}
+
+func TestHistoryTruncationKeepsTrailingToolResults(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("word ", 600)
+	params := chatParams("m")
+	params.Messages = []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage("Be brief."),
+		{OfAssistant: &openai.ChatCompletionAssistantMessageParam{ToolCalls: []openai.ChatCompletionMessageToolCallUnionParam{
+			{OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{ID: "call_1", Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{Name: "lookup", Arguments: "{}"}}},
+			{OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{ID: "call_2", Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{Name: "lookup", Arguments: "{}"}}},
+		}}},
+		openai.ToolMessage(long, "call_1"),
+		openai.ToolMessage(long, "call_2"),
+	}
+
+	truncated, dropped := truncateHistory(params, 500)
+	if dropped != 0 || len(truncated.Messages) != 4 {
+		t.Errorf("Expected the tool results to keep the call they answer, got %d messages, %d dropped", len(truncated.Messages), dropped)
+	}
+}
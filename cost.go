@@ -0,0 +1,112 @@
+package openailb
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Price is what a model costs per million tokens, in any currency.
+type Price struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+}
+
+// cost returns what the tokens cost at p.
+func (p Price) cost(promptTokens, completionTokens int64) float64 {
+	return (float64(promptTokens)*p.Prompt + float64(completionTokens)*p.Completion) / 1e6
+}
+
+// Usage is the token usage and cost of a set of requests. Errors counts the
+// requests that didn't end with ClassOK.
+type Usage struct {
+	Requests         int64   `json:"requests"`
+	Errors           int64   `json:"errors"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+func (u *Usage) add(r ResponseInfo) {
+	u.Requests++
+	if r.Class != ClassOK {
+		u.Errors++
+	}
+	u.PromptTokens += r.PromptTokens
+	u.CompletionTokens += r.CompletionTokens
+	u.Cost += r.Cost
+}
+
+// usageLedger accumulates a backend's usage per model and per caller tag.
+type usageLedger struct {
+	mu      sync.Mutex
+	total   Usage
+	byModel map[string]*Usage
+	byTag   map[string]*Usage
+	history usageHistory
+}
+
+// record adds r, finished at now, under its mapped model and its tag.
+func (l *usageLedger) record(now time.Time, r ResponseInfo) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.total.add(r)
+	l.history.add(now, r)
+	if l.byModel == nil {
+		l.byModel = make(map[string]*Usage)
+		l.byTag = make(map[string]*Usage)
+	}
+	usageFor(l.byModel, r.MappedModel).add(r)
+	if r.Tag != "" {
+		usageFor(l.byTag, r.Tag).add(r)
+	}
+}
+
+// usageFor returns (creating on first use) the entry for key.
+func usageFor(m map[string]*Usage, key string) *Usage {
+	u, ok := m[key]
+	if !ok {
+		u = &Usage{}
+		m[key] = u
+	}
+	return u
+}
+
+// snapshot returns copies of the totals and of the per-model and per-tag usage.
+func (l *usageLedger) snapshot() (total Usage, byModel, byTag map[string]Usage) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.total, copyUsage(l.byModel), copyUsage(l.byTag)
+}
+
+func copyUsage(m map[string]*Usage) map[string]Usage {
+	out := make(map[string]Usage, len(m))
+	for k, u := range m {
+		out[k] = *u
+	}
+	return out
+}
+
+// priceOf returns the price of model (as sent to the backend): the backend's
+// own Prices first, then WithPricing.
+func (c *SafeClient) priceOf(model string) Price {
+	if p, ok := c.prices[model]; ok {
+		return p
+	}
+	return c.lb.options.prices[model]
+}
+
+type tagKey struct{}
+
+// WithTag tags the requests made with ctx, e.g. with the calling team or
+// feature, so their usage and cost are accounted separately in
+// BackendStats.Tags and reported to the hooks.
+func WithTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, tagKey{}, tag)
+}
+
+// TagFromContext returns the tag set with WithTag, or "".
+func TagFromContext(ctx context.Context) string {
+	tag, _ := ctx.Value(tagKey{}).(string)
+	return tag
+}
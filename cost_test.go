@@ -0,0 +1,74 @@
+package openailb
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+)
+
+func TestCostAccounting(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), `"stream":true`) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: {\"choices\": [{\"delta\": {\"content\": \"Hi\"}}]}\n\n" +
+				"data: {\"choices\": [], \"usage\": {\"prompt_tokens\": 100, \"completion_tokens\": 50}}\n\ndata: [DONE]\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "Hello"}}], "usage": {"prompt_tokens": 1000, "completion_tokens": 500}}`))
+	}))
+	defer server.Close()
+
+	var costs []float64
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: server.URL, ModelMap: map[string]string{"m": "mapped"}},
+	}, WithPricing(map[string]Price{"mapped": {Prompt: 2, Completion: 8}}), WithHooks(Hooks{
+		OnResponse: func(r ResponseInfo) { costs = append(costs, r.Cost) },
+	}))
+
+	ctx := WithTag(context.Background(), "team-a")
+	if _, err := client.Chat.Completions.New(ctx, chatParams("m")); err != nil {
+		t.Fatal(err)
+	}
+	stream := client.Chat.Completions.NewStreaming(context.Background(), openai.ChatCompletionNewParams{
+		Model:         "m",
+		Messages:      []openai.ChatCompletionMessageParamUnion{openai.UserMessage("test")},
+		StreamOptions: openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)},
+	})
+	for stream.Next() {
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	stream.Close()
+
+	// 1000*2/1M + 500*8/1M for the completion, 100*2/1M + 50*8/1M for the stream.
+	const completionCost, streamCost = 0.006, 0.0006
+	if len(costs) != 2 || !near(costs[0], completionCost) || !near(costs[1], streamCost) {
+		t.Errorf("Expected hook costs [%v %v], got %v", completionCost, streamCost, costs)
+	}
+
+	stats := client.Stats()["Client-0"]
+	if !near(stats.Cost, completionCost+streamCost) {
+		t.Errorf("Expected a total cost of %v, got %v", completionCost+streamCost, stats.Cost)
+	}
+	if u := stats.Models["mapped"]; u.Requests != 2 || u.PromptTokens != 1100 || u.CompletionTokens != 550 {
+		t.Errorf("Unexpected usage for the mapped model: %+v", u)
+	}
+	if u := stats.Tags["team-a"]; u.Requests != 1 || !near(u.Cost, completionCost) {
+		t.Errorf("Unexpected usage for the tag: %+v", u)
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-12
+}
@@ -0,0 +1,105 @@
+package openailb
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DebugBackend is one backend's row of the debug dashboard.
+type DebugBackend struct {
+	BackendHealth
+	Stats BackendStats `json:"stats"`
+}
+
+// DebugReport is the JSON body served by Client.DebugHandler.
+type DebugReport struct {
+	At       time.Time      `json:"at"`
+	Backends []DebugBackend `json:"backends"`
+}
+
+// DebugReport returns the figures of the debug dashboard: each backend's
+// health snapshot together with its runtime stats.
+func (c Client) DebugReport() DebugReport {
+	stats := c.Stats()
+	report := DebugReport{At: time.Now()}
+	for _, h := range c.Health() {
+		report.Backends = append(report.Backends, DebugBackend{BackendHealth: h, Stats: stats[h.Name]})
+	}
+	return report
+}
+
+// DebugHandler serves a small dashboard of the backends' health, weights,
+// breaker states, last errors and live request rates, for quick inspection
+// without a metrics stack. It serves HTML that refreshes itself, or the
+// DebugReport as JSON with ?format=json or an Accept: application/json header.
+// It exposes base URLs and error messages, so mount it on an internal port:
+//
+//	mux.Handle("/debug/openailb", client.DebugHandler())
+func (c Client) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.DebugReport()
+		w.Header().Set("Cache-Control", "no-store")
+		if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(report)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = debugPage.Execute(w, report)
+	})
+}
+
+var debugPage = template.Must(template.New("debug").Funcs(template.FuncMap{
+	"ms":      func(d time.Duration) string { return d.Round(time.Millisecond).String() },
+	"percent": func(f float64) string { return formatFloat(f*100) + "%" },
+	"float":   formatFloat,
+	"when": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return time.Since(t).Round(time.Second).String() + " ago"
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="5">
+<title>openailb</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+.healthy { color: green; } .degraded, .partial { color: darkorange; }
+.breaker_open, .ejected, .quarantined, .unhealthy { color: red; }
+</style>
+</head>
+<body>
+<h1>openailb backends</h1>
+<p>{{.At.Format "2006-01-02 15:04:05"}} &middot; <a href="?format=json">JSON</a></p>
+<table>
+<tr><th>Backend</th><th>Status</th><th>Weight</th><th>Breakers</th><th>RPS</th><th>Errors</th><th>p50 / p95 / p99</th><th>In flight</th><th>Requests</th><th>Last error</th></tr>
+{{range .Backends}}<tr>
+<td>{{.Name}}<br><small>{{.BaseURL}}</small></td>
+<td class="{{.Status}}">{{.Status}}{{with .UnhealthyReason}}<br><small>{{.}}</small>{{end}}{{with .DegradedReason}}<br><small>{{.}}</small>{{end}}{{with .QuarantineReason}}<br><small>{{.}}</small>{{end}}</td>
+<td>{{float .EffectiveWeight}} / {{float .Weight}}</td>
+<td>{{range .Breakers}}{{.Name}}: {{.State}}<br>{{end}}</td>
+<td>{{float .Stats.RPS}}</td>
+<td>{{percent .Stats.ErrorRate}}</td>
+<td>{{ms .Stats.P50}} / {{ms .Stats.P95}} / {{ms .Stats.P99}}</td>
+<td>{{.Stats.InFlight}}</td>
+<td>{{.Stats.Requests}} ({{.Stats.Failures}} failed)</td>
+<td>{{with .LastErrorClass}}[{{.}}] {{end}}{{.LastError}}{{with when .LastErrorAt}}<br><small>{{.}}</small>{{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// formatFloat formats f with at most two decimals.
+func formatFloat(f float64) string {
+	return strings.TrimSuffix(strings.TrimRight(strconv.FormatFloat(f, 'f', 2, 64), "0"), ".")
+}
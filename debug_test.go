@@ -0,0 +1,43 @@
+package openailb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDebugHandler(t *testing.T) {
+	t.Parallel()
+
+	server := newNamedServer(t, "ok")
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL}})
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m")); err != nil {
+		t.Fatal(err)
+	}
+	handler := client.DebugHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/openailb", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Expected HTML, got %s", ct)
+	}
+	for _, want := range []string{"Client-0", server.URL, "Client-0/chat: closed", `class="healthy"`, "1 (0 failed)"} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("Missing %q in:\n%s", want, rec.Body.String())
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/openailb?format=json", nil))
+	var report DebugReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Backends) != 1 || report.Backends[0].Name != "Client-0" || report.Backends[0].Stats.Requests != 1 {
+		t.Errorf("Unexpected JSON report %+v", report)
+	}
+}
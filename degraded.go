@@ -0,0 +1,76 @@
+package openailb
+
+import (
+	"fmt"
+	"time"
+)
+
+// Degradation configures the degraded state (WithDegradation). A backend whose
+// recent error rate or mean latency is elevated, though not bad enough to trip
+// its breakers, is degraded: it keeps receiving a reduced share of traffic
+// instead of being either fully in or fully out of rotation. Zero fields take
+// the defaults noted below.
+type Degradation struct {
+	// Window is the rolling period the figures cover (default 1m).
+	Window time.Duration
+	// MinRequests is the traffic a backend needs within Window to be judged (default 10).
+	MinRequests int64
+	// ErrorRate degrades backends whose share of failed calls reaches it (default 0.05).
+	ErrorRate float64
+	// Latency degrades backends whose mean latency reaches it. Zero disables it.
+	Latency time.Duration
+	// LatencyPercentile, if set, compares that percentile of the latency
+	// (e.g. 95 for p95) with Latency instead of the mean.
+	LatencyPercentile float64
+	// Weight scales the weight of degraded backends, e.g. 0.25 to send them a
+	// quarter of their share (default 0.25).
+	Weight float64
+}
+
+// withDefaults fills in the zero fields.
+func (d Degradation) withDefaults() Degradation {
+	if d.Window <= 0 {
+		d.Window = time.Minute
+	}
+	if d.MinRequests <= 0 {
+		d.MinRequests = 10
+	}
+	if d.ErrorRate <= 0 {
+		d.ErrorRate = 0.05
+	}
+	if d.Weight <= 0 {
+		d.Weight = 0.25
+	}
+	return d
+}
+
+// degraded reports whether the backend is degraded, and why.
+func (c *SafeClient) degraded(now time.Time) (bool, string) {
+	cfg := c.lb.options.degradation
+	if cfg == nil {
+		return false, ""
+	}
+	requests, failures, latency := c.degradeCounter.stats(now)
+	if requests < cfg.MinRequests {
+		return false, ""
+	}
+	if rate := float64(failures) / float64(requests); rate >= cfg.ErrorRate {
+		return true, fmt.Sprintf("error rate %.1f%% over the last %s", rate*100, cfg.Window)
+	}
+	if cfg.Latency > 0 && cfg.LatencyPercentile > 0 {
+		if p := c.degradeLatency.window(now).quantile(cfg.LatencyPercentile); p >= cfg.Latency {
+			return true, fmt.Sprintf("p%g latency %s over the last %s", cfg.LatencyPercentile, p.Round(time.Millisecond), cfg.Window)
+		}
+	} else if mean := latency / time.Duration(requests); cfg.Latency > 0 && mean >= cfg.Latency {
+		return true, fmt.Sprintf("mean latency %s over the last %s", mean.Round(time.Millisecond), cfg.Window)
+	}
+	return false, ""
+}
+
+// degradedFactor scales the backend's weight down while it is degraded.
+func (c *SafeClient) degradedFactor() float64 {
+	if degraded, _ := c.degraded(time.Now()); degraded {
+		return c.lb.options.degradation.Weight
+	}
+	return 1
+}
@@ -0,0 +1,73 @@
+package openailb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDegradedBackend(t *testing.T) {
+	t.Parallel()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "slow"}}]}`))
+	}))
+	defer slow.Close()
+	fast := newNamedServer(t, "fast")
+	defer fast.Close()
+
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: slow.URL},
+		{APIKey: "k2", BaseURL: fast.URL},
+	}, WithDegradation(Degradation{Latency: 10 * time.Millisecond, MinRequests: 2}))
+
+	// Warm up until the slow backend has enough samples to be judged.
+	countHits(t, client, 4)
+	health := client.Health()
+	if health[0].Status != StatusDegraded || health[0].DegradedReason == "" {
+		t.Fatalf("Expected the slow backend to be degraded, got %s %q", health[0].Status, health[0].DegradedReason)
+	}
+	if health[1].Status != StatusHealthy {
+		t.Errorf("Expected the fast backend to be healthy, got %s", health[1].Status)
+	}
+
+	// A degraded backend keeps a quarter of its share: 1 in 5 requests.
+	hits := countHits(t, client, 20)
+	if hits["slow"] == 0 || hits["slow"] > 5 {
+		t.Errorf("Expected the degraded backend to get a reduced share, got %v", hits)
+	}
+}
+
+func TestDegradedLatencyPercentile(t *testing.T) {
+	t.Parallel()
+
+	// Every fifth request is slow: the mean stays low, the tail doesn't.
+	var n atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n.Add(1)%5 == 0 {
+			time.Sleep(30 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "ok"}}]}`))
+	}))
+	defer server.Close()
+
+	mean := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL}},
+		WithDegradation(Degradation{Latency: 15 * time.Millisecond, MinRequests: 10}))
+	countHits(t, mean, 10)
+	if status := mean.Health()[0].Status; status != StatusHealthy {
+		t.Errorf("Expected a healthy mean latency, got %s", status)
+	}
+
+	tail := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL}},
+		WithDegradation(Degradation{Latency: 15 * time.Millisecond, LatencyPercentile: 95, MinRequests: 10}))
+	countHits(t, tail, 10)
+	if h := tail.Health()[0]; h.Status != StatusDegraded || !strings.HasPrefix(h.DegradedReason, "p95 latency") {
+		t.Errorf("Expected the p95 latency to degrade the backend, got %s %q", h.Status, h.DegradedReason)
+	}
+}
@@ -0,0 +1,68 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Discovery is a source of backends, e.g. a service registry, that
+// Client.Discover keeps the pool in step with. The consullb and etcdlb
+// packages implement it for Consul and etcd.
+type Discovery interface {
+	// Watch calls update with the complete set of backends, first as soon as
+	// it is known and then after every change, until ctx is done. Backends
+	// are told apart by name, so each must be named, e.g. after its endpoint.
+	// update is never called concurrently. Watch returns once ctx is done, or
+	// with the error that stopped it.
+	Watch(ctx context.Context, update func([]OpenaiClientConfig)) error
+}
+
+// Discover keeps the backends of d in the pool: backends that appear are
+// added, and those that disappear are removed, letting their in-flight
+// requests finish. A backend whose config changes under the same name is
+// kept as it is; d must remove it first to replace it. Discover returns once
+// the first set of backends is applied, or with the error of d.Watch if it
+// stops before. Canceling ctx, or closing the client, stops the watch,
+// leaving the discovered backends in the pool.
+func (c Client) Discover(ctx context.Context, d Discovery) error {
+	ctx, cancel := c.lb.untilClosed(ctx)
+	var owned map[string]bool
+	var once sync.Once
+	applied := make(chan struct{})
+	stopped := make(chan error, 1)
+	c.lb.background.Add(1)
+	go func() {
+		defer c.lb.background.Done()
+		defer cancel()
+		stopped <- d.Watch(ctx, func(configs []OpenaiClientConfig) {
+			owned = c.syncBackends(owned, configs)
+			once.Do(func() { close(applied) })
+		})
+	}()
+
+	select {
+	case <-applied:
+		go func() {
+			if err := <-stopped; err != nil && ctx.Err() == nil {
+				c.lb.options.logger.Warn("discovery stopped", "error", err)
+			}
+		}()
+		return nil
+	case err := <-stopped:
+		select {
+		case <-applied:
+			if err != nil && ctx.Err() == nil {
+				c.lb.options.logger.Warn("discovery stopped", "error", err)
+			}
+			return nil
+		default:
+		}
+		if err == nil {
+			err = errors.New("discovery stopped before finding any backends")
+		}
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
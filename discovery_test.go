@@ -0,0 +1,61 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeDiscovery sends the sets of backends it is given.
+type fakeDiscovery struct {
+	updates chan []OpenaiClientConfig
+	err     error
+}
+
+func (d *fakeDiscovery) Watch(ctx context.Context, update func([]OpenaiClientConfig)) error {
+	if d.err != nil {
+		return d.err
+	}
+	for {
+		select {
+		case configs := <-d.updates:
+			update(configs)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	t.Parallel()
+
+	east, west := newNamedServer(t, "east"), newNamedServer(t, "west")
+	defer east.Close()
+	defer west.Close()
+
+	client := NewClient([]OpenaiClientConfig{{Name: "static", APIKey: "k0", BaseURL: west.URL}})
+	d := &fakeDiscovery{updates: make(chan []OpenaiClientConfig, 1)}
+	d.updates <- []OpenaiClientConfig{{Name: "east", APIKey: "k1", BaseURL: east.URL}, {APIKey: "unnamed", BaseURL: east.URL}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := client.Discover(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(client.lb.backends()); n != 2 {
+		t.Fatalf("Expected the named backend to be added, got %d backends", n)
+	}
+
+	d.updates <- []OpenaiClientConfig{{Name: "west", APIKey: "k2", BaseURL: west.URL}}
+	if !waitFor(t, time.Second, func() bool {
+		backends := client.lb.backends()
+		return len(backends) == 2 && backends[0].Name == "static" && backends[1].Name == "west"
+	}) {
+		t.Fatal("Expected the discovered backends to be swapped, keeping the static one")
+	}
+
+	d.err = errors.New("registry unreachable")
+	if err := NewClient(nil).Discover(ctx, d); err == nil || err.Error() != "registry unreachable" {
+		t.Errorf("Expected the watch error, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"sort"
+	"time"
+)
+
+// runDNSRefresh re-resolves the backend's hostname every interval.
+func (c *SafeClient) runDNSRefresh(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		c.refreshDNS()
+		select {
+		case <-ticker.C:
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// refreshDNS resolves the backend's hostname. A failed lookup counts as a
+// failed health check. When the addresses changed, idle connections to the old
+// ones are closed so new requests dial the current addresses.
+func (c *SafeClient) refreshDNS() {
+	u, err := url.Parse(c.BaseURL)
+	if err != nil || u.Hostname() == "" || net.ParseIP(u.Hostname()) != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.healthCheck.Timeout)
+	defer cancel()
+	addrs, err := c.lb.options.lookupHost(ctx, u.Hostname())
+	if err != nil {
+		c.recordCheck(fmt.Errorf("resolve %s: %w", u.Hostname(), err))
+		return
+	}
+	sort.Strings(addrs)
+
+	c.mu.Lock()
+	previous := c.addrs
+	c.addrs = addrs
+	changed := previous != nil && !slices.Equal(previous, addrs)
+	if changed {
+		c.emitHealth(HealthEvent{Kind: HealthEndpointChanged, Reason: fmt.Sprintf("%s resolved to %v, was %v", u.Hostname(), addrs, previous)})
+	}
+	c.mu.Unlock()
+
+	if changed {
+		c.httpClient.CloseIdleConnections()
+	}
+	if !c.checked {
+		// Without active checks, resolving again is what brings the backend back.
+		c.recordCheck(nil)
+	}
+}
+
+// isDialError reports whether err means no connection could be established,
+// e.g. because the backend's address went away.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
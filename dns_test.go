@@ -0,0 +1,62 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDNSRefresh(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	addrs, lookupErr := []string{"10.0.0.1"}, error(nil)
+	setDNS := func(a []string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		addrs, lookupErr = a, err
+	}
+	fakeLookup := func(o *lbOptions) {
+		o.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return addrs, lookupErr
+		}
+	}
+
+	events := make(chan HealthEvent, 100)
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: "http://gateway.internal/v1"}},
+		WithDNSRefresh(10*time.Millisecond), fakeLookup,
+		WithOnHealthChange(func(ev HealthEvent) { events <- ev }))
+
+	if !waitFor(t, time.Second, func() bool { return len(client.Health()[0].Addresses) == 1 }) {
+		t.Fatal("Expected the hostname to be resolved")
+	}
+
+	setDNS(nil, errors.New("no such host"))
+	if !waitFor(t, time.Second, func() bool { return client.Health()[0].Status == StatusUnhealthy }) {
+		t.Fatal("Expected failed lookups to mark the backend unhealthy")
+	}
+
+	setDNS([]string{"10.0.0.2"}, nil)
+	if !waitFor(t, time.Second, func() bool { return client.Health()[0].Status == StatusHealthy }) {
+		t.Fatal("Expected the backend to recover once it resolves again")
+	}
+	if got := client.Health()[0].Addresses; len(got) != 1 || got[0] != "10.0.0.2" {
+		t.Errorf("Expected the new address, got %v", got)
+	}
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Kind == HealthEndpointChanged {
+				return
+			}
+		case <-timeout:
+			t.Fatal("Expected an endpoint_changed event")
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dnsLookupTimeout bounds each lookup of a DNSDiscovery.
+const dnsLookupTimeout = 5 * time.Second
+
+// DNSDiscovery discovers backends from DNS (Client.DiscoverDNS), e.g. the pods
+// of an autoscaled vLLM fleet behind a headless Kubernetes Service.
+type DNSDiscovery struct {
+	// SRV is the SRV record listing the endpoints, e.g.
+	// "_http._tcp.vllm.default.svc.cluster.local".
+	SRV string
+	// Host, used when SRV is empty, is resolved to its A/AAAA records, e.g.
+	// "vllm.default.svc.cluster.local"; every address gets Template's port.
+	Host string
+	// Template is the config of every discovered backend: the shared API key,
+	// model map, timeouts and so on. The scheme and path of its BaseURL, e.g.
+	// "http://vllm:8000/v1", are kept and the host replaced by each endpoint.
+	// The backends are named after the endpoint, prefixed with Template.Name
+	// or else the record: "vllm@10.0.0.5:8000".
+	Template OpenaiClientConfig
+	// Interval is the time between lookups (default 30s).
+	Interval time.Duration
+}
+
+// DiscoverDNS keeps one backend per endpoint d resolves to in the pool:
+// endpoints that appear are added, and those that disappear are removed,
+// letting their in-flight requests finish. The first lookup happens before
+// DiscoverDNS returns and its error is returned; later failed lookups keep
+// the current backends. Canceling ctx, or closing the client, stops the
+// lookups, leaving the discovered backends in the pool.
+func (c Client) DiscoverDNS(ctx context.Context, d DNSDiscovery) error {
+	record := d.SRV
+	if record == "" {
+		record = d.Host
+	}
+	if record == "" {
+		return errors.New("DNSDiscovery: SRV or Host is required")
+	}
+	base, err := url.Parse(d.Template.BaseURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") {
+		return fmt.Errorf("DNSDiscovery.Template.BaseURL: %q must be an absolute http or https URL", d.Template.BaseURL)
+	}
+	interval := d.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	prefix := d.Template.Name
+	if prefix == "" {
+		prefix = record
+	}
+
+	resolve := func() ([]OpenaiClientConfig, error) {
+		ctx, cancel := context.WithTimeout(ctx, dnsLookupTimeout)
+		defer cancel()
+		endpoints, err := c.lb.lookupEndpoints(ctx, d, base.Port())
+		if err != nil {
+			return nil, fmt.Errorf("resolve %s: %w", record, err)
+		}
+		if len(endpoints) == 0 {
+			return nil, fmt.Errorf("resolve %s: no endpoints", record)
+		}
+		configs := make([]OpenaiClientConfig, 0, len(endpoints))
+		for _, endpoint := range endpoints {
+			cfg := d.Template
+			u := *base
+			u.Host = endpoint
+			cfg.Name, cfg.BaseURL = prefix+"@"+endpoint, u.String()
+			configs = append(configs, cfg)
+		}
+		return configs, nil
+	}
+
+	ctx, cancel := c.lb.untilClosed(ctx)
+	configs, err := resolve()
+	if err != nil {
+		cancel()
+		return err
+	}
+	owned := c.syncBackends(nil, configs)
+	c.lb.background.Add(1)
+	go func() {
+		defer c.lb.background.Done()
+		defer cancel()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			configs, err := resolve()
+			if err != nil {
+				if ctx.Err() == nil {
+					c.lb.options.logger.Warn("DNS discovery failed; keeping the current backends", "record", record, "error", err)
+				}
+				continue
+			}
+			owned = c.syncBackends(owned, configs)
+		}
+	}()
+	return nil
+}
+
+// lookupEndpoints returns the sorted host:port endpoints of d. port is the
+// Template's, used for A/AAAA records.
+func (lb *LoadBalancer) lookupEndpoints(ctx context.Context, d DNSDiscovery, port string) ([]string, error) {
+	var endpoints []string
+	if d.SRV != "" {
+		_, records, err := lb.options.lookupSRV(ctx, "", "", d.SRV)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range records {
+			endpoints = append(endpoints, net.JoinHostPort(strings.TrimSuffix(r.Target, "."), strconv.Itoa(int(r.Port))))
+		}
+	} else {
+		addrs, err := lb.options.lookupHost(ctx, d.Host)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			if port != "" {
+				endpoints = append(endpoints, net.JoinHostPort(addr, port))
+			} else if strings.Contains(addr, ":") {
+				endpoints = append(endpoints, "["+addr+"]")
+			} else {
+				endpoints = append(endpoints, addr)
+			}
+		}
+	}
+	sort.Strings(endpoints)
+	return endpoints, nil
+}
@@ -0,0 +1,121 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDiscoverDNS(t *testing.T) {
+	t.Parallel()
+
+	first, second := newNamedServer(t, "first"), newNamedServer(t, "second")
+	defer first.Close()
+	defer second.Close()
+	srv := func(serverURL string) *net.SRV {
+		u, _ := url.Parse(serverURL)
+		port, _ := strconv.Atoi(u.Port())
+		return &net.SRV{Target: "127.0.0.1.", Port: uint16(port)}
+	}
+
+	var mu sync.Mutex
+	records, lookupErr := []*net.SRV{srv(first.URL)}, error(nil)
+	setSRV := func(r []*net.SRV, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		records, lookupErr = r, err
+	}
+	fakeLookup := func(o *lbOptions) {
+		o.lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return name, records, lookupErr
+		}
+	}
+
+	client := NewClient(nil, fakeLookup)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := client.DiscoverDNS(ctx, DNSDiscovery{
+		SRV:      "_http._tcp.vllm.default.svc.cluster.local",
+		Template: OpenaiClientConfig{Name: "vllm", APIKey: "shared", BaseURL: "http://vllm/v1"},
+		Interval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backends := client.lb.backends()
+	if len(backends) != 1 || backends[0].Name != "vllm@"+first.Listener.Addr().String() {
+		t.Fatalf("Expected the first endpoint to be discovered, got %d backends", len(backends))
+	}
+	if hits := countHits(t, client, 1); hits["first"] != 1 {
+		t.Errorf("Expected the discovered backend to serve, got %v", hits)
+	}
+
+	setSRV([]*net.SRV{srv(first.URL), srv(second.URL)}, nil)
+	if !waitFor(t, time.Second, func() bool { return len(client.lb.backends()) == 2 }) {
+		t.Fatal("Expected the new endpoint to be added")
+	}
+
+	// A failed lookup keeps the pool as it is.
+	setSRV(nil, errors.New("no such host"))
+	time.Sleep(50 * time.Millisecond)
+	if n := len(client.lb.backends()); n != 2 {
+		t.Fatalf("Expected a failed lookup to keep the backends, got %d", n)
+	}
+
+	setSRV([]*net.SRV{srv(second.URL)}, nil)
+	if !waitFor(t, time.Second, func() bool { return len(client.lb.backends()) == 1 }) {
+		t.Fatal("Expected the gone endpoint to be removed")
+	}
+	if hits := countHits(t, client, 2); hits["second"] != 2 {
+		t.Errorf("Expected the remaining backend to serve, got %v", hits)
+	}
+
+	setSRV(nil, errors.New("no such host"))
+	if err := NewClient(nil, fakeLookup).DiscoverDNS(ctx, DNSDiscovery{SRV: "_http._tcp.gone", Template: OpenaiClientConfig{BaseURL: "http://vllm/v1"}}); err == nil {
+		t.Error("Expected the first failed lookup to be returned")
+	}
+}
+
+func TestDiscoverDNSHost(t *testing.T) {
+	t.Parallel()
+
+	fakeLookup := func(o *lbOptions) {
+		o.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+			return []string{"10.0.0.2", "10.0.0.1", "fd00::1"}, nil
+		}
+	}
+	client := NewClient(nil, fakeLookup)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := client.DiscoverDNS(ctx, DNSDiscovery{
+		Host:     "vllm.default.svc.cluster.local",
+		Template: OpenaiClientConfig{APIKey: "shared", BaseURL: "http://vllm:8000/v1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var urls []string
+	for _, b := range client.lb.backends() {
+		urls = append(urls, b.Name+" "+b.BaseURL)
+	}
+	want := []string{
+		"vllm.default.svc.cluster.local@10.0.0.1:8000 http://10.0.0.1:8000/v1",
+		"vllm.default.svc.cluster.local@10.0.0.2:8000 http://10.0.0.2:8000/v1",
+		"vllm.default.svc.cluster.local@[fd00::1]:8000 http://[fd00::1]:8000/v1",
+	}
+	if len(urls) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, urls)
+	}
+	for i := range want {
+		if urls[i] != want[i] {
+			t.Errorf("Expected %s, got %s", want[i], urls[i])
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package openailb
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// LBEmbeddingsService mimics openai.EmbeddingService.
+type LBEmbeddingsService struct {
+	lb *LoadBalancer
+}
+
+// New creates an embedding vector on the next backend whose embeddings breaker is closed.
+// With WithEmbeddingSharding, large input arrays are split across backends.
+func (s *LBEmbeddingsService) New(ctx context.Context, params openai.EmbeddingNewParams, opts ...option.RequestOption) (*openai.CreateEmbeddingResponse, error) {
+	shards := splitEmbeddingInput(params.Input, s.lb.route(params.Model).options.embeddingShardSize)
+	if len(shards) <= 1 {
+		return s.newSingle(ctx, params, opts...)
+	}
+	return s.newSharded(ctx, params, shards, opts...)
+}
+
+func (s *LBEmbeddingsService) newSingle(ctx context.Context, params openai.EmbeddingNewParams, opts ...option.RequestOption) (*openai.CreateEmbeddingResponse, error) {
+	ctx = s.lb.withTokenEstimate(ctx, func() int64 { return estimateTokens(params.Input, 0) })
+	return execute(ctx, s.lb, ServiceEmbeddings, params.Model, func(ctx context.Context, safeClient *SafeClient) (*openai.CreateEmbeddingResponse, error) {
+		p := params
+		p.Model = mapModel(safeClient, p.Model)
+		return safeClient.OpenAIClient().Embeddings.New(ctx, p, opts...)
+	})
+}
+
+// newSharded dispatches the shards concurrently, at most as many at a time as
+// there are backends, each balanced like any other request, and merges the
+// results, offsetting every vector's index by its shard's position. Nothing
+// keeps two shards off the same backend.
+func (s *LBEmbeddingsService) newSharded(ctx context.Context, params openai.EmbeddingNewParams, shards []embeddingShard, opts ...option.RequestOption) (*openai.CreateEmbeddingResponse, error) {
+	ctx, cancel := context.WithCancel(withSharedRoute(ctx))
+	defer cancel()
+
+	results := make([]*openai.CreateEmbeddingResponse, len(shards))
+	errs := make([]error, len(shards))
+	sem := make(chan struct{}, max(len(s.lb.backends()), 1))
+
+	var wg sync.WaitGroup
+	for i, shard := range shards {
+		wg.Add(1)
+		go func(i int, shard embeddingShard) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			p := params
+			p.Input = shard.input
+			results[i], errs[i] = s.newSingle(ctx, p, opts...)
+			if errs[i] != nil {
+				// One failed shard fails the whole request; stop the others early.
+				cancel()
+			}
+		}(i, shard)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	merged := *results[0]
+	merged.Data = nil
+	merged.Usage = openai.CreateEmbeddingResponseUsage{}
+	for i, res := range results {
+		for _, emb := range res.Data {
+			emb.Index += int64(shards[i].offset)
+			merged.Data = append(merged.Data, emb)
+		}
+		merged.Usage.PromptTokens += res.Usage.PromptTokens
+		merged.Usage.TotalTokens += res.Usage.TotalTokens
+	}
+	sort.Slice(merged.Data, func(a, b int) bool { return merged.Data[a].Index < merged.Data[b].Index })
+	return &merged, nil
+}
+
+// embeddingShard is a slice of the original input and the position of its first element.
+type embeddingShard struct {
+	input  openai.EmbeddingNewParamsInputUnion
+	offset int
+}
+
+// splitEmbeddingInput splits array inputs into shards of at most size elements.
+// Single string and single token array inputs are never split.
+func splitEmbeddingInput(input openai.EmbeddingNewParamsInputUnion, size int) []embeddingShard {
+	if size <= 0 {
+		return nil
+	}
+
+	var shards []embeddingShard
+	switch {
+	case len(input.OfArrayOfStrings) > size:
+		for start := 0; start < len(input.OfArrayOfStrings); start += size {
+			end := min(start+size, len(input.OfArrayOfStrings))
+			shards = append(shards, embeddingShard{
+				input:  openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: input.OfArrayOfStrings[start:end]},
+				offset: start,
+			})
+		}
+	case len(input.OfArrayOfTokenArrays) > size:
+		for start := 0; start < len(input.OfArrayOfTokenArrays); start += size {
+			end := min(start+size, len(input.OfArrayOfTokenArrays))
+			shards = append(shards, embeddingShard{
+				input:  openai.EmbeddingNewParamsInputUnion{OfArrayOfTokenArrays: input.OfArrayOfTokenArrays[start:end]},
+				offset: start,
+			})
+		}
+	}
+	return shards
+}
@@ -0,0 +1,80 @@
+package openailb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// newEmbeddingServer returns a server that embeds each input string as [len(input), serverID].
+func newEmbeddingServer(t *testing.T, serverID float64, hits *int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		var body struct {
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var data []string
+		for i, in := range body.Input {
+			data = append(data, fmt.Sprintf(`{"object":"embedding","index":%d,"embedding":[%d,%v]}`, i, len(in), serverID))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"object":"list","model":"m","data":[%s],"usage":{"prompt_tokens":%d,"total_tokens":%d}}`,
+			strings.Join(data, ","), len(body.Input), len(body.Input))
+	}))
+}
+
+func TestEmbeddingsSharding(t *testing.T) {
+	t.Parallel()
+
+	var hits1, hits2 int32
+	server1 := newEmbeddingServer(t, 1, &hits1)
+	defer server1.Close()
+	server2 := newEmbeddingServer(t, 2, &hits2)
+	defer server2.Close()
+
+	configs := []OpenaiClientConfig{
+		{APIKey: "mock-key-1", BaseURL: server1.URL},
+		{APIKey: "mock-key-2", BaseURL: server2.URL},
+	}
+	client := NewClient(configs, WithEmbeddingSharding(2))
+
+	inputs := []string{"a", "bb", "ccc", "dddd", "eeeee"}
+	var info RouteInfo
+	resp, err := client.Embeddings.New(context.Background(), openai.EmbeddingNewParams{
+		Model: "m",
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: inputs},
+	}, WithRouteInfo(&info))
+	if err != nil {
+		t.Fatalf("Sharded embeddings request failed: %v", err)
+	}
+	if len(info.Attempts) != 1 || info.Backend == "" {
+		t.Errorf("Expected the route of one shard, got %+v", info)
+	}
+
+	if len(resp.Data) != len(inputs) {
+		t.Fatalf("Expected %d vectors, got %d", len(inputs), len(resp.Data))
+	}
+	for i, emb := range resp.Data {
+		if emb.Index != int64(i) || emb.Embedding[0] != float64(len(inputs[i])) {
+			t.Errorf("Vector %d out of order: index %d, embedding %v", i, emb.Index, emb.Embedding)
+		}
+	}
+	if resp.Usage.PromptTokens != int64(len(inputs)) {
+		t.Errorf("Expected merged prompt tokens %d, got %d", len(inputs), resp.Usage.PromptTokens)
+	}
+	if atomic.LoadInt32(&hits1) == 0 || atomic.LoadInt32(&hits2) == 0 {
+		t.Errorf("Expected shards on both backends, got %d and %d", hits1, hits2)
+	}
+}
@@ -0,0 +1,81 @@
+package openailb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// BackendsEnv is the environment variable NewClientFromEnv reads the pool from.
+const BackendsEnv = "OPENAI_LB_BACKENDS"
+
+// NewClientFromEnv builds the pool from the BackendsEnv environment variable,
+// for deployments where mounting a config file is awkward. It holds either a
+// JSON list of BackendConfig:
+//
+//	OPENAI_LB_BACKENDS='[{"api_key": "sk-1", "base_url": "https://api.openai.com/v1/", "weight": 2}]'
+//
+// or entries of key, base URL and optional weight separated by semicolons or
+// newlines:
+//
+//	OPENAI_LB_BACKENDS='sk-1,https://api.openai.com/v1/,2;sk-2,http://vllm:8000/v1/'
+//
+// The backends are validated like a Config; opts configure the LB.
+func NewClientFromEnv(opts ...LBOption) (Client, error) {
+	value, ok := os.LookupEnv(BackendsEnv)
+	if !ok {
+		return Client{}, fmt.Errorf("%s is not set", BackendsEnv)
+	}
+	backends, err := parseBackends(value)
+	if err != nil {
+		return Client{}, fmt.Errorf("%s: %w", BackendsEnv, err)
+	}
+	client, err := NewClientFromConfig(&Config{Backends: backends}, opts...)
+	if err != nil {
+		return Client{}, fmt.Errorf("%s: %w", BackendsEnv, err)
+	}
+	return client, nil
+}
+
+// parseBackends parses the JSON or delimited form of BackendsEnv.
+func parseBackends(value string) ([]BackendConfig, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "[") {
+		var backends []BackendConfig
+		dec := json.NewDecoder(strings.NewReader(value))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&backends); err != nil {
+			return nil, err
+		}
+		return backends, nil
+	}
+
+	var backends []BackendConfig
+	entries := strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == '\n' })
+	for i, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		fields := strings.Split(entry, ",")
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("entry %d: want key,base_url[,weight], got %d fields", i, len(fields))
+		}
+		b := BackendConfig{APIKey: strings.TrimSpace(fields[0]), BaseURL: strings.TrimSpace(fields[1])}
+		if len(fields) == 3 {
+			weight, err := strconv.Atoi(strings.TrimSpace(fields[2]))
+			if err != nil {
+				return nil, fmt.Errorf("entry %d: weight %q is not an integer", i, fields[2])
+			}
+			b.Weight = weight
+		}
+		backends = append(backends, b)
+	}
+	if len(backends) == 0 {
+		return nil, errors.New("no backends")
+	}
+	return backends, nil
+}
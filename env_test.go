@@ -0,0 +1,47 @@
+package openailb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewClientFromEnv(t *testing.T) {
+	a := newNamedServer(t, "a")
+	defer a.Close()
+	b := newNamedServer(t, "b")
+	defer b.Close()
+
+	t.Setenv(BackendsEnv, "k1,"+a.URL+",2;\n k2, "+b.URL+" ")
+	client, err := NewClientFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits := countHits(t, client, 3); hits["a"] != 2 || hits["b"] != 1 {
+		t.Errorf("Expected a 2:1 split, got %v", hits)
+	}
+
+	t.Setenv(BackendsEnv, `[{"api_key": "k1", "base_url": "`+a.URL+`", "model_map": {"m": "mapped"}}]`)
+	client, err = NewClientFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backend := client.lb.backends()[0]; backend.ModelMap["m"] != "mapped" {
+		t.Errorf("Expected the JSON model map to apply, got %v", backend.ModelMap)
+	}
+}
+
+func TestNewClientFromEnvErrors(t *testing.T) {
+	for value, want := range map[string]string{
+		"":                               "no backends",
+		"k1":                             "entry 0: want key,base_url[,weight], got 1 fields",
+		"k1,http://a,two":                `entry 0: weight "two" is not an integer`,
+		"k1,localhost:8000":              `backends[0].base_url: "localhost:8000" must be an absolute http or https URL`,
+		`[{"api_key": "k", "url": "x"}]`: `unknown field "url"`,
+	} {
+		t.Setenv(BackendsEnv, value)
+		_, err := NewClientFromEnv()
+		if err == nil || !strings.Contains(err.Error(), want) || !strings.HasPrefix(err.Error(), BackendsEnv) {
+			t.Errorf("%q: expected an error with %q, got %v", value, want, err)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+// Package etcdlb discovers openailb backends registered in etcd: one backend
+// per key under a prefix, whose value is the backend in the JSON format of an
+// openailb config file. It uses etcd's v3 JSON gateway and watches the prefix,
+// so changes apply as soon as they are written.
+//
+//	// etcdctl put /openai/backends/east '{"api_key": "sk-1", "base_url": "https://api.openai.com/v1"}'
+//	err := client.Discover(ctx, &etcdlb.Discovery{Prefix: "/openai/backends/"})
+package etcdlb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	openailb "github.com/hi2code/openai-go-lb"
+)
+
+// DefaultEndpoint is the address of a local etcd member.
+const DefaultEndpoint = "http://127.0.0.1:2379"
+
+// retryInterval is the wait before reading the prefix again after a failure.
+const retryInterval = 5 * time.Second
+
+// Discovery is an openailb.Discovery over the keys under an etcd prefix.
+type Discovery struct {
+	// Endpoint is the URL of an etcd member (default DefaultEndpoint).
+	Endpoint string
+	// Prefix is the key prefix of the backends, e.g. "/openai/backends/".
+	// A backend is named after its key without the prefix unless its value
+	// names it.
+	Prefix string
+	// HTTPClient sends the requests (default http.DefaultClient), e.g. with
+	// the client certificate of an mTLS-protected cluster.
+	HTTPClient *http.Client
+	// Logger, if set, receives values that don't parse or validate, which are
+	// skipped, and failed reads and watches, which are retried.
+	Logger *slog.Logger
+}
+
+type keyValue struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// Watch implements openailb.Discovery. A failed first read is returned;
+// later failures are retried. A changed value replaces its backend.
+func (d *Discovery) Watch(ctx context.Context, update func([]openailb.OpenaiClientConfig)) error {
+	if d.Prefix == "" {
+		return fmt.Errorf("etcdlb: Prefix is required")
+	}
+	for first := true; ; first = false {
+		values, revision, err := d.rangePrefix(ctx)
+		if err == nil {
+			update(d.backends(values))
+			err = d.watch(ctx, revision, values, update)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if first && values == nil {
+			return err
+		}
+		if d.Logger != nil {
+			d.Logger.Warn("etcdlb: watch failed; reading the prefix again", "prefix", d.Prefix, "error", err)
+		}
+		select {
+		case <-time.After(retryInterval):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// post sends body as JSON to the gateway endpoint at path.
+func (d *Discovery) post(ctx context.Context, path string, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := d.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	httpClient := d.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, fmt.Errorf("etcdlb: %s: %s: %s", path, res.Status, strings.TrimSpace(string(msg)))
+	}
+	return res, nil
+}
+
+// rangeEnd returns the end of the key range of the prefix.
+func (d *Discovery) rangeEnd() []byte {
+	end := []byte(d.Prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return []byte{0}
+}
+
+// rangePrefix returns the values under the prefix by key, and the revision
+// they were read at.
+func (d *Discovery) rangePrefix(ctx context.Context) (map[string][]byte, string, error) {
+	res, err := d.post(ctx, "/v3/kv/range", map[string]any{"key": []byte(d.Prefix), "range_end": d.rangeEnd()})
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+	var r struct {
+		Header struct {
+			Revision string `json:"revision"`
+		} `json:"header"`
+		KVs []keyValue `json:"kvs"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, "", fmt.Errorf("etcdlb: range %s: %w", d.Prefix, err)
+	}
+	values := make(map[string][]byte, len(r.KVs))
+	for _, kv := range r.KVs {
+		values[string(kv.Key)] = kv.Value
+	}
+	return values, r.Header.Revision, nil
+}
+
+// watch applies the changes under the prefix after revision to values,
+// calling update after each, until the watch ends.
+func (d *Discovery) watch(ctx context.Context, revision string, values map[string][]byte, update func([]openailb.OpenaiClientConfig)) error {
+	var start int64
+	fmt.Sscan(revision, &start)
+	res, err := d.post(ctx, "/v3/watch", map[string]any{"create_request": map[string]any{
+		"key":            []byte(d.Prefix),
+		"range_end":      d.rangeEnd(),
+		"start_revision": start + 1,
+	}})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	dec := json.NewDecoder(bufio.NewReader(res.Body))
+	for {
+		var msg struct {
+			Result struct {
+				Canceled     bool   `json:"canceled"`
+				CancelReason string `json:"cancel_reason"`
+				Events       []struct {
+					Type string   `json:"type"` // Omitted for PUT.
+					KV   keyValue `json:"kv"`
+				} `json:"events"`
+			} `json:"result"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := dec.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("etcdlb: watch %s ended", d.Prefix)
+			}
+			return err
+		}
+		if msg.Error != nil {
+			return fmt.Errorf("etcdlb: watch %s: %s", d.Prefix, msg.Error.Message)
+		}
+		if msg.Result.Canceled {
+			// E.g. compacted past the revision watched from.
+			return fmt.Errorf("etcdlb: watch %s canceled: %s", d.Prefix, msg.Result.CancelReason)
+		}
+		for _, ev := range msg.Result.Events {
+			key := string(ev.KV.Key)
+			if ev.Type == "DELETE" {
+				delete(values, key)
+				continue
+			}
+			if old, ok := values[key]; ok && !bytes.Equal(old, ev.KV.Value) {
+				// The pool keeps a backend whose config changed; remove it first.
+				delete(values, key)
+				update(d.backends(values))
+			}
+			values[key] = ev.KV.Value
+		}
+		if len(msg.Result.Events) > 0 {
+			update(d.backends(values))
+		}
+	}
+}
+
+// backends returns the backend configs of values, sorted by name. Values
+// that don't parse or validate are skipped.
+func (d *Discovery) backends(values map[string][]byte) []openailb.OpenaiClientConfig {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	configs := make([]openailb.OpenaiClientConfig, 0, len(keys))
+	for _, key := range keys {
+		cfg, err := parseBackend(strings.TrimPrefix(key, d.Prefix), values[key])
+		if err != nil {
+			if d.Logger != nil {
+				d.Logger.Warn("etcdlb: backend skipped", "key", key, "error", err)
+			}
+			continue
+		}
+		configs = append(configs, cfg)
+	}
+	return configs
+}
+
+// parseBackend parses and validates a backend, naming it name unless it is named.
+func parseBackend(name string, value []byte) (openailb.OpenaiClientConfig, error) {
+	var b openailb.BackendConfig
+	dec := json.NewDecoder(bytes.NewReader(value))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&b); err != nil {
+		return openailb.OpenaiClientConfig{}, err
+	}
+	if b.Name == "" {
+		b.Name = name
+	}
+	if err := (&openailb.Config{Backends: []openailb.BackendConfig{b}}).Validate(); err != nil {
+		return openailb.OpenaiClientConfig{}, err
+	}
+	return b.ClientConfig()
+}
@@ -0,0 +1,87 @@
+package etcdlb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	openailb "github.com/hi2code/openai-go-lb"
+)
+
+func TestDiscovery(t *testing.T) {
+	events := make(chan []any, 4)
+	etcd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		switch r.URL.Path {
+		case "/v3/kv/range":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"header": map[string]any{"revision": "7"},
+				"kvs": []any{
+					map[string]any{"key": []byte("/openai/backends/east"), "value": []byte(`{"api_key": "k1", "base_url": "http://east/v1"}`)},
+					map[string]any{"key": []byte("/openai/backends/bad"), "value": []byte(`{"base_url": "http://bad/v1"}`)},
+				},
+			})
+		case "/v3/watch":
+			if create, _ := req["create_request"].(map[string]any); create["start_revision"] != float64(8) {
+				http.Error(w, "unexpected start revision", http.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"result": map[string]any{"created": true}})
+			w.(http.Flusher).Flush()
+			for {
+				select {
+				case evs := <-events:
+					_ = json.NewEncoder(w).Encode(map[string]any{"result": map[string]any{"events": evs}})
+					w.(http.Flusher).Flush()
+				case <-r.Context().Done():
+					return
+				}
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer etcd.Close()
+
+	client := openailb.NewClient(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := client.Discover(ctx, &Discovery{Endpoint: etcd.URL, Prefix: "/openai/backends/"}); err != nil {
+		t.Fatal(err)
+	}
+	waitForBackends(t, client, "east http://east/v1")
+
+	events <- []any{
+		map[string]any{"kv": map[string]any{"key": []byte("/openai/backends/west"), "value": []byte(`{"api_key": "k2", "base_url": "http://west/v1"}`)}},
+		map[string]any{"type": "DELETE", "kv": map[string]any{"key": []byte("/openai/backends/bad")}},
+	}
+	waitForBackends(t, client, "east http://east/v1", "west http://west/v1")
+
+	events <- []any{
+		map[string]any{"kv": map[string]any{"key": []byte("/openai/backends/east"), "value": []byte(`{"api_key": "k1", "base_url": "http://east-2/v1"}`)}},
+		map[string]any{"type": "DELETE", "kv": map[string]any{"key": []byte("/openai/backends/west")}},
+	}
+	waitForBackends(t, client, "east http://east-2/v1")
+}
+
+func waitForBackends(t *testing.T, client openailb.Client, want ...string) {
+	t.Helper()
+	var got []string
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		got = got[:0]
+		for _, h := range client.Health() {
+			got = append(got, h.Name+" "+h.BaseURL)
+		}
+		if strings.Join(got, ",") == strings.Join(want, ",") {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Expected the backends %v, got %v", want, got)
+}
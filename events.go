@@ -0,0 +1,117 @@
+package openailb
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventKind says what an Event is about.
+type EventKind string
+
+const (
+	// EventRouteSelected means a backend was picked for a request attempt.
+	EventRouteSelected EventKind = "route_selected"
+	// EventFailover means a failed attempt is retried on another backend (WithFailover).
+	EventFailover EventKind = "failover"
+	// EventBreakerStateChange means a breaker changed state.
+	EventBreakerStateChange EventKind = "breaker_state_change"
+	// EventHealthProbe is the result of an active health check or a synthetic probe.
+	EventHealthProbe EventKind = "health_probe"
+	// EventCooldownStarted means a breaker opened; it lets a probe through at Until.
+	EventCooldownStarted EventKind = "cooldown_started"
+	// EventBudgetExceeded means a spend ceiling was hit: Backend's Budget, or
+	// the one of WithBudget if Backend is empty.
+	EventBudgetExceeded EventKind = "budget_exceeded"
+)
+
+// Event is a lifecycle event of the LB, delivered by Client.Events. Which
+// fields are set depends on Kind.
+type Event struct {
+	Kind    EventKind
+	At      time.Time
+	Backend string
+
+	// Service, Model (requested), MappedModel and Attempt describe the request
+	// of EventRouteSelected and EventFailover.
+	Service     ServiceType
+	Model       string
+	MappedModel string
+	Attempt     int
+
+	// Breaker, From and To are set for EventBreakerStateChange and
+	// EventCooldownStarted. Breaker is also set for the EventHealthProbe of a
+	// synthetic probe (WithSyntheticProbes).
+	Breaker string
+	From    string
+	To      string
+	// Until is when the cooldown of EventCooldownStarted ends.
+	Until time.Time
+
+	// Spent and Limit are the period's spending and ceiling for
+	// EventBudgetExceeded.
+	Spent float64
+	Limit float64
+
+	// Err is the failed attempt's error for EventFailover, and the probe's
+	// error (nil if it passed) for EventHealthProbe.
+	Err error
+}
+
+// eventsBuffer is the buffer of each Events channel.
+const eventsBuffer = 256
+
+// eventBus fans events out to the Events channels.
+type eventBus struct {
+	active atomic.Bool // Whether anyone subscribed; keeps publish cheap otherwise.
+
+	mu   sync.Mutex
+	subs []chan Event
+}
+
+func (b *eventBus) subscribe() <-chan Event {
+	ch := make(chan Event, eventsBuffer)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, ch)
+	b.active.Store(true)
+	return ch
+}
+
+// publish delivers ev to every subscriber whose buffer has room; it never blocks.
+func (b *eventBus) publish(ev Event) {
+	if !b.active.Load() {
+		return
+	}
+	if ev.At.IsZero() {
+		ev.At = time.Now()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// Events returns a new channel receiving the LB's lifecycle events: routing
+// decisions, failovers, breaker transitions, cooldowns and health probes.
+// Events are dropped, not queued, while the channel's buffer is full, so a slow
+// reader never holds up requests. Each call returns a separate channel.
+func (c Client) Events() <-chan Event {
+	return c.lb.events.subscribe()
+}
+
+// attemptEvent returns an event of kind about a.
+func attemptEvent(kind EventKind, a attempt) Event {
+	return Event{
+		Kind:        kind,
+		Backend:     a.client.Name,
+		Service:     a.breaker.key.svc,
+		Model:       a.requested,
+		MappedModel: a.model,
+		Attempt:     a.number,
+	}
+}
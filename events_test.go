@@ -0,0 +1,57 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+
+	failServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failServer.Close()
+	okServer := newNamedServer(t, "ok")
+	defer okServer.Close()
+
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: failServer.URL},
+		{APIKey: "k2", BaseURL: okServer.URL},
+	}, WithFailover(2), WithCBSettings(tripAfter(1)))
+	events := client.Events()
+
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0)); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[EventKind]Event)
+	timeout := time.After(time.Second)
+	for len(seen) < 4 {
+		select {
+		case ev := <-events:
+			if _, ok := seen[ev.Kind]; !ok {
+				seen[ev.Kind] = ev
+			}
+		case <-timeout:
+			t.Fatalf("Missing events, got %v", seen)
+		}
+	}
+	if ev := seen[EventRouteSelected]; ev.Backend != "Client-0" || ev.Service != ServiceChat || ev.Model != "m" || ev.Attempt != 1 {
+		t.Errorf("Unexpected route event %+v", ev)
+	}
+	if ev := seen[EventFailover]; ev.Backend != "Client-0" || ev.Err == nil {
+		t.Errorf("Unexpected failover event %+v", ev)
+	}
+	if ev := seen[EventBreakerStateChange]; ev.Breaker != "Client-0/chat" || ev.From != "closed" || ev.To != "open" {
+		t.Errorf("Unexpected breaker event %+v", ev)
+	}
+	if ev := seen[EventCooldownStarted]; ev.Breaker != "Client-0/chat" || !ev.Until.After(ev.At) {
+		t.Errorf("Unexpected cooldown event %+v", ev)
+	}
+}
@@ -0,0 +1,48 @@
+package openailb
+
+import (
+	"expvar"
+	"fmt"
+)
+
+// expvarBackend is one backend's entry in the published expvar.
+type expvarBackend struct {
+	Status   BackendStatus `json:"status"`
+	Requests int64         `json:"requests"`
+	Failures int64         `json:"failures"`
+	InFlight int64         `json:"in_flight"`
+	// Breakers maps each breaker's name to its state.
+	Breakers map[string]string `json:"breakers"`
+}
+
+// PublishExpvar publishes the LB's per-backend counters (requests, failures,
+// in-flight requests, status and breaker states) with expvar under prefix, so
+// /debug/vars and the tooling built on it pick them up. The value is computed
+// whenever it is read. It fails if prefix is already published.
+func (c Client) PublishExpvar(prefix string) error {
+	if expvar.Get(prefix) != nil {
+		return fmt.Errorf("expvar %q is already published", prefix)
+	}
+	expvar.Publish(prefix, expvar.Func(func() any { return c.expvars() }))
+	return nil
+}
+
+func (c Client) expvars() map[string]expvarBackend {
+	stats := c.Stats()
+	vars := make(map[string]expvarBackend, len(c.lb.backends()))
+	for _, h := range c.Health() {
+		s := stats[h.Name]
+		v := expvarBackend{
+			Status:   h.Status,
+			Requests: s.Requests,
+			Failures: s.Failures,
+			InFlight: s.InFlight,
+			Breakers: make(map[string]string, len(h.Breakers)),
+		}
+		for _, b := range h.Breakers {
+			v.Breakers[b.Name] = b.State
+		}
+		vars[h.Name] = v
+	}
+	return vars
+}
@@ -0,0 +1,37 @@
+package openailb
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"testing"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	t.Parallel()
+
+	server := newNamedServer(t, "ok")
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL}})
+	if err := client.PublishExpvar("openailb_test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PublishExpvar("openailb_test"); err == nil {
+		t.Error("Expected publishing the same prefix twice to fail")
+	}
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m")); err != nil {
+		t.Fatal(err)
+	}
+
+	var vars map[string]expvarBackend
+	if err := json.Unmarshal([]byte(expvar.Get("openailb_test").String()), &vars); err != nil {
+		t.Fatal(err)
+	}
+	backend := vars["Client-0"]
+	if backend.Requests != 1 || backend.Failures != 0 || backend.Status != StatusHealthy {
+		t.Errorf("Unexpected expvar %+v", backend)
+	}
+	if state := backend.Breakers["Client-0/chat"]; state != "closed" {
+		t.Errorf("Expected the chat breaker to be closed, got %q", state)
+	}
+}
@@ -0,0 +1,180 @@
+package openailb
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/sony/gobreaker/v2"
+)
+
+// BackendStatus summarizes whether a backend is taking traffic.
+type BackendStatus string
+
+const (
+	// StatusHealthy means every breaker of the backend is closed or half-open.
+	StatusHealthy BackendStatus = "healthy"
+	// StatusDegraded means the backend serves, but with elevated errors or
+	// latency, and gets a reduced share of traffic (WithDegradation).
+	StatusDegraded BackendStatus = "degraded"
+	// StatusPartial means the backend serves chat, but some of its other
+	// breakers (other services, or models with WithPerModelBreakers) are
+	// open, as listed in BackendHealth.OpenBreakers.
+	StatusPartial BackendStatus = "partial"
+	// StatusBreakerOpen means the backend's chat breaker is open.
+	StatusBreakerOpen BackendStatus = "breaker_open"
+	// StatusEjected means outlier detection took the backend out of rotation.
+	StatusEjected BackendStatus = "ejected"
+	// StatusQuarantined means the backend rejected its key and waits for Reinstate.
+	StatusQuarantined BackendStatus = "quarantined"
+	// StatusUnhealthy means the active health checks (WithHealthChecks) are
+	// failing, or the backend was marked down with MarkUnhealthy.
+	StatusUnhealthy BackendStatus = "unhealthy"
+)
+
+// BackendHealth is a point-in-time view of one backend, as returned by Client.Health.
+type BackendHealth struct {
+	Name    string            `json:"name"`
+	BaseURL string            `json:"base_url"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Pool    string            `json:"pool,omitempty"`
+	Status  BackendStatus     `json:"status"`
+	// Weight is the configured weight; EffectiveWeight is the chat weight after
+	// slow start and error-budget adjustments.
+	Weight          float64 `json:"weight"`
+	EffectiveWeight float64 `json:"effective_weight"`
+	// Breakers lists the breakers created so far, sorted by name, and
+	// OpenBreakers the names of those open.
+	Breakers         []BreakerSnapshot `json:"breakers"`
+	OpenBreakers     []string          `json:"open_breakers,omitempty"`
+	LastError        string            `json:"last_error,omitempty"`
+	LastErrorAt      time.Time         `json:"last_error_at,omitempty"`
+	LastErrorClass   ErrorClass        `json:"last_error_class,omitempty"`
+	QuarantineReason string            `json:"quarantine_reason,omitempty"`
+	EjectedUntil     time.Time         `json:"ejected_until,omitempty"`
+	LastCheckAt      time.Time         `json:"last_check_at,omitempty"`
+	UnhealthyReason  string            `json:"unhealthy_reason,omitempty"`
+	Addresses        []string          `json:"addresses,omitempty"` // Resolved with WithDNSRefresh.
+	DegradedReason   string            `json:"degraded_reason,omitempty"`
+}
+
+// Health returns a snapshot of every backend in the pool, in configuration order.
+func (c Client) Health() []BackendHealth {
+	lb := c.lb
+	now := time.Now()
+
+	health := make([]BackendHealth, 0, len(lb.backends()))
+	for _, backend := range lb.backends() {
+		health = append(health, backend.health(now))
+	}
+	return health
+}
+
+// HealthReport is the JSON body served by Client.HealthHandler.
+type HealthReport struct {
+	// Status is "ok" if at least one backend is healthy, degraded or partial,
+	// "unavailable" otherwise.
+	Status   string          `json:"status"`
+	Backends []BackendHealth `json:"backends"`
+}
+
+// HealthHandler serves the Health snapshot as a JSON HealthReport, with status
+// 200 if at least one backend is healthy, degraded or partial and 503
+// otherwise, so it can be mounted as a liveness or readiness endpoint:
+//
+//	mux.Handle("/readyz", client.HealthHandler())
+func (c Client) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := HealthReport{Status: "unavailable", Backends: c.Health()}
+		code := http.StatusServiceUnavailable
+		for _, h := range report.Backends {
+			if h.Status == StatusHealthy || h.Status == StatusDegraded || h.Status == StatusPartial {
+				report.Status = "ok"
+				code = http.StatusOK
+				break
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// health builds the backend's snapshot.
+func (c *SafeClient) health(now time.Time) BackendHealth {
+	h := BackendHealth{
+		Name:            c.Name,
+		BaseURL:         c.BaseURL,
+		Labels:          c.Labels,
+		Pool:            c.lb.pool,
+		Status:          StatusHealthy,
+		Weight:          c.weight.Load(),
+		EffectiveWeight: c.effectiveWeight(c.breakerFor(ServiceChat, "")),
+	}
+	if degraded, reason := c.degraded(now); degraded {
+		h.Status = StatusDegraded
+		h.DegradedReason = reason
+	}
+
+	chatOpen := false
+	for _, b := range c.breakerList() {
+		snap := b.snapshot()
+		h.Breakers = append(h.Breakers, snap)
+		if snap.State == gobreaker.StateOpen.String() {
+			h.OpenBreakers = append(h.OpenBreakers, snap.Name)
+			chatOpen = chatOpen || b.key == breakerKey{svc: ServiceChat}
+		}
+	}
+	sort.Slice(h.Breakers, func(i, j int) bool { return h.Breakers[i].Name < h.Breakers[j].Name })
+	sort.Strings(h.OpenBreakers)
+	switch {
+	case chatOpen:
+		h.Status = StatusBreakerOpen
+	case len(h.OpenBreakers) > 0 && h.Status == StatusHealthy:
+		h.Status = StatusPartial
+	}
+
+	c.lb.mu.Lock()
+	if c.ejected(now) {
+		h.Status = StatusEjected
+		h.EjectedUntil = c.outlier.ejectedUntil
+	}
+	c.lb.mu.Unlock()
+
+	c.mu.Lock()
+	h.LastError = c.lastError
+	h.LastErrorAt = c.lastErrorAt
+	h.LastErrorClass = c.lastErrorClass
+	h.LastCheckAt = c.checks.lastCheckAt
+	h.Addresses = c.addrs
+	if unhealthy, reason := c.unhealthyLocked(); unhealthy {
+		h.Status = StatusUnhealthy
+		h.UnhealthyReason = reason
+	}
+	if !c.quarantinedAt.IsZero() {
+		h.Status = StatusQuarantined
+		h.QuarantineReason = c.quarantineReason
+	}
+	c.mu.Unlock()
+
+	return h
+}
+
+// recordError remembers err, of class, as the backend's most recent failure.
+func (c *SafeClient) recordError(err error, class ErrorClass) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastError = err.Error()
+	c.lastErrorAt = time.Now()
+	c.lastErrorClass = class
+}
+
+// lastErr returns the backend's most recent failure, its class and when it happened.
+func (c *SafeClient) lastErr() (string, ErrorClass, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastError, c.lastErrorClass, c.lastErrorAt
+}
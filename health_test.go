@@ -0,0 +1,103 @@
+package openailb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openai/openai-go/v3/option"
+	"github.com/sony/gobreaker/v2"
+)
+
+func TestHealthSnapshot(t *testing.T) {
+	t.Parallel()
+
+	failServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failServer.Close()
+	okServer := newNamedServer(t, "ok")
+	defer okServer.Close()
+
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "fail-key", BaseURL: failServer.URL},
+		{APIKey: "ok-key", BaseURL: okServer.URL, Weight: 2},
+	}, WithCBSettings(tripAfter(1)))
+
+	for i := 0; i < 2; i++ {
+		_, _ = client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+	}
+
+	health := client.Health()
+	if len(health) != 2 {
+		t.Fatalf("Expected 2 backends, got %d", len(health))
+	}
+
+	failed := health[0]
+	if failed.Name != "Client-0" || failed.Status != StatusBreakerOpen {
+		t.Errorf("Expected Client-0 to be breaker_open, got %s %s", failed.Name, failed.Status)
+	}
+	if failed.LastError == "" || failed.LastErrorAt.IsZero() || failed.LastErrorClass != ClassServer {
+		t.Errorf("Expected the last error to be captured and classified, got %q (%s)", failed.LastError, failed.LastErrorClass)
+	}
+	chat := failed.Breakers[0]
+	if chat.Name != "Client-0/chat" || chat.State != gobreaker.StateOpen.String() || chat.CooldownUntil.IsZero() {
+		t.Errorf("Unexpected chat breaker snapshot: %+v", chat)
+	}
+	if chat.OpenedBy != failed.LastError {
+		t.Errorf("Expected the breaker to record the error that opened it, got %q", chat.OpenedBy)
+	}
+	if stats := client.Stats()["Client-0"]; stats.LastError != failed.LastError || stats.LastErrorClass != ClassServer {
+		t.Errorf("Expected the last error in the stats, got %q (%s)", stats.LastError, stats.LastErrorClass)
+	}
+
+	ok := health[1]
+	if ok.Status != StatusHealthy || ok.Weight != 2 || ok.EffectiveWeight != 2 || ok.LastError != "" {
+		t.Errorf("Unexpected snapshot for the healthy backend: %+v", ok)
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	t.Parallel()
+
+	failServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failServer.Close()
+
+	client := NewClient([]OpenaiClientConfig{{APIKey: "fail-key", BaseURL: failServer.URL}},
+		WithCBSettings(tripAfter(1)))
+
+	get := func() (int, HealthReport) {
+		rec := httptest.NewRecorder()
+		client.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		var report HealthReport
+		if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+			t.Fatalf("Invalid JSON body: %v", err)
+		}
+		return rec.Code, report
+	}
+
+	if code, report := get(); code != http.StatusOK || report.Status != "ok" || len(report.Backends) != 1 {
+		t.Errorf("Expected 200 ok before any failure, got %d %+v", code, report)
+	}
+
+	// An open embeddings breaker leaves chat served.
+	done, err := client.lb.backends()[0].Breaker(ServiceEmbeddings).Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done(false)
+	if code, report := get(); code != http.StatusOK || report.Backends[0].Status != StatusPartial ||
+		len(report.Backends[0].OpenBreakers) != 1 || report.Backends[0].OpenBreakers[0] != "Client-0/embeddings" {
+		t.Errorf("Expected 200 with the backend partial, got %d %+v", code, report)
+	}
+
+	_, _ = client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+	if code, report := get(); code != http.StatusServiceUnavailable || report.Status != "unavailable" ||
+		report.Backends[0].Status != StatusBreakerOpen {
+		t.Errorf("Expected 503 with the open breaker reported, got %d %+v", code, report)
+	}
+}
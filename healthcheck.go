@@ -0,0 +1,308 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// HealthCheck configures active health checks (WithHealthChecks). Every
+// Interval each backend is checked in the background, independently of user
+// traffic, so a dead backend leaves rotation before user requests fail on it.
+// Zero fields take the defaults noted below.
+type HealthCheck struct {
+	// Interval is the time between checks of a backend (default 10s).
+	Interval time.Duration
+	// Timeout bounds each check (default 5s).
+	Timeout time.Duration
+	// Method is the HTTP method of the check (default GET). With POST, the
+	// check sends a one-token chat completion for Model to Path.
+	Method string
+	// Path is the endpoint checked, relative to the backend's BaseURL (default
+	// "models"). CompletionCheckPath sends a tiny chat completion for Model
+	// instead, for gateways without a cheap GET endpoint.
+	Path string
+	// Model is the (requested, pre-mapping) model of the check completion.
+	Model string
+	// UnhealthyThreshold is how many checks in a row must fail before the
+	// backend leaves rotation (default 2).
+	UnhealthyThreshold int
+	// HealthyThreshold is how many checks in a row must pass before an
+	// unhealthy backend returns to rotation (default 1).
+	HealthyThreshold int
+}
+
+// withDefaults fills in the zero fields.
+func (h HealthCheck) withDefaults() HealthCheck {
+	if h.Interval <= 0 {
+		h.Interval = 10 * time.Second
+	}
+	if h.Timeout <= 0 {
+		h.Timeout = 5 * time.Second
+	}
+	if h.Path == "" {
+		h.Path = "models"
+	}
+	if h.Path == CompletionCheckPath && h.Method == "" {
+		h.Method = http.MethodPost
+	}
+	if h.Method == "" {
+		h.Method = http.MethodGet
+	}
+	if h.UnhealthyThreshold <= 0 {
+		h.UnhealthyThreshold = 2
+	}
+	if h.HealthyThreshold <= 0 {
+		h.HealthyThreshold = 1
+	}
+	return h
+}
+
+// override returns h with the non-zero fields of o.
+func (h HealthCheck) override(o HealthCheck) HealthCheck {
+	if o.Interval > 0 {
+		h.Interval = o.Interval
+	}
+	if o.Timeout > 0 {
+		h.Timeout = o.Timeout
+	}
+	if o.Method != "" {
+		h.Method = o.Method
+	}
+	if o.Path != "" {
+		h.Path = o.Path
+		if o.Method == "" {
+			// Let the method follow the new path's default.
+			h.Method = ""
+		}
+	}
+	if o.Model != "" {
+		h.Model = o.Model
+	}
+	if o.UnhealthyThreshold > 0 {
+		h.UnhealthyThreshold = o.UnhealthyThreshold
+	}
+	if o.HealthyThreshold > 0 {
+		h.HealthyThreshold = o.HealthyThreshold
+	}
+	return h
+}
+
+// CompletionCheckPath as a HealthCheck path checks with a one-token chat
+// completion rather than a GET. Unlike a GET, it is billed by most providers.
+const CompletionCheckPath = "chat/completions"
+
+// checkState is a backend's health as seen by the active checks, guarded by SafeClient.mu.
+type checkState struct {
+	unhealthySince time.Time // Non-zero while the checks consider the backend down.
+	reason         string    // Why the checks consider it down.
+	failures       int       // Consecutive failed checks.
+	passes         int       // Consecutive passed checks.
+	lastCheckAt    time.Time
+}
+
+// healthCheckFor resolves a backend's check: the LB-wide config with the
+// backend's overrides applied. It reports whether the backend is checked at all.
+func healthCheckFor(lbWide *HealthCheck, cfg OpenaiClientConfig) (HealthCheck, bool) {
+	var check HealthCheck
+	if lbWide != nil {
+		check = *lbWide
+	}
+	if cfg.HealthCheck != nil {
+		check = check.override(*cfg.HealthCheck)
+	}
+	if cfg.HealthCheckPath != "" {
+		check = check.override(HealthCheck{Path: cfg.HealthCheckPath})
+	}
+	return check.withDefaults(), lbWide != nil || cfg.HealthCheck != nil
+}
+
+// startHealthChecks checks each backend in the background at its own interval,
+// and re-resolves its hostname with WithDNSRefresh.
+func (lb *LoadBalancer) startHealthChecks() {
+	for _, c := range lb.backends() {
+		c.startHealthChecks()
+	}
+}
+
+// startHealthChecks starts the background checks of one backend, until c.stop is closed.
+func (c *SafeClient) startHealthChecks() {
+	if c.checked {
+		go c.runHealthChecks()
+	}
+	if interval := c.lb.options.dnsRefresh; interval > 0 {
+		go c.runDNSRefresh(interval)
+	}
+	if c.secret != nil && c.lb.options.secrets != nil {
+		go c.runSecretRefresh(c.lb.options.secretRefresh)
+	}
+}
+
+// runHealthChecks checks the backend every interval.
+func (c *SafeClient) runHealthChecks() {
+	ticker := time.NewTicker(c.healthCheck.Interval)
+	defer ticker.Stop()
+	for {
+		// A rejected key won't start working again on its own.
+		if quarantined, _ := c.Quarantined(); !quarantined {
+			err := c.check()
+			c.lb.events.publish(Event{Kind: EventHealthProbe, Backend: c.Name, Err: err})
+			c.recordCheck(err)
+		}
+		select {
+		case <-ticker.C:
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// check sends one check request to the backend, bypassing its breakers.
+func (c *SafeClient) check() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.healthCheck.Timeout)
+	defer cancel()
+	err := c.ping(ctx)
+	if isDialError(err) {
+		// Pooled connections may point at an address that is gone; redial.
+		c.httpClient.CloseIdleConnections()
+	}
+	return pingFailure(err)
+}
+
+// pingFailure returns the error of a ping that shows the backend is down.
+// The backend answered a rejected check request, e.g. with a 400, so that
+// doesn't count.
+func pingFailure(err error) error {
+	if err != nil && !isFatalError(err) {
+		return nil
+	}
+	return err
+}
+
+// ping sends the backend's check request: a one-token completion for POST,
+// a plain request to the check path otherwise.
+func (c *SafeClient) ping(ctx context.Context) error {
+	check := c.healthCheck
+	if check.Method == http.MethodPost {
+		var res *openai.ChatCompletion
+		return c.OpenAIClient().Post(ctx, check.Path, openai.ChatCompletionNewParams{
+			Model:               mapModel(c, check.Model),
+			Messages:            []openai.ChatCompletionMessageParamUnion{openai.UserMessage("ping")},
+			MaxCompletionTokens: openai.Int(1),
+		}, &res, option.WithMaxRetries(0))
+	}
+	var res *http.Response
+	err := c.OpenAIClient().Execute(ctx, check.Method, check.Path, nil, &res, option.WithMaxRetries(0))
+	if res != nil {
+		res.Body.Close()
+	}
+	return err
+}
+
+// recordCheck updates the backend's health with a check result.
+func (c *SafeClient) recordCheck(err error) {
+	cfg := c.healthCheck
+	if err != nil {
+		c.recordError(err, classify(err, false))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	c.checks.lastCheckAt = now
+	if err != nil {
+		c.emitHealth(HealthEvent{Kind: HealthCheckFailed, At: now, Reason: err.Error()})
+		c.checks.failures++
+		c.checks.passes = 0
+		if c.checks.failures >= cfg.UnhealthyThreshold {
+			if c.checks.unhealthySince.IsZero() {
+				c.checks.unhealthySince = now
+				c.emitHealth(HealthEvent{Kind: HealthUnhealthy, At: now, Reason: "health check failed: " + err.Error()})
+			}
+			c.checks.reason = "health check failed: " + err.Error()
+		}
+		return
+	}
+	c.lb.readyOnce.Do(func() { close(c.lb.ready) })
+	c.checks.passes++
+	c.checks.failures = 0
+	if c.checks.passes >= cfg.HealthyThreshold && !c.checks.unhealthySince.IsZero() {
+		c.checks.unhealthySince = time.Time{}
+		c.checks.reason = ""
+		c.emitHealth(HealthEvent{Kind: HealthRecovered, At: now, Reason: "health check passed"})
+	}
+}
+
+// unhealthyLocked reports whether the backend was marked unhealthy, by the active
+// checks or by MarkUnhealthy, and why. Callers hold c.mu.
+func (c *SafeClient) unhealthyLocked() (bool, string) {
+	if !c.markedDownAt.IsZero() {
+		return true, c.markedDownReason
+	}
+	return !c.checks.unhealthySince.IsZero(), c.checks.reason
+}
+
+// unhealthy reports whether the backend is out of rotation for being unhealthy.
+func (c *SafeClient) unhealthy() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	unhealthy, _ := c.unhealthyLocked()
+	return unhealthy
+}
+
+// MarkUnhealthy takes a backend out of rotation until MarkHealthy is called,
+// e.g. when an orchestrator knows about upstream maintenance before the LB can
+// observe failures. The reason is reported by Client.Health.
+func (c Client) MarkUnhealthy(name, reason string) error {
+	backend, err := c.lb.clientByName(name)
+	if err != nil {
+		return err
+	}
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	backend.markedDownAt = time.Now()
+	backend.markedDownReason = reason
+	backend.emitHealth(HealthEvent{Kind: HealthUnhealthy, At: backend.markedDownAt, Reason: reason})
+	return nil
+}
+
+// MarkHealthy puts a backend back into rotation, lifting MarkUnhealthy and
+// resetting its health-check state. Failing health checks take it out again.
+func (c Client) MarkHealthy(name string) error {
+	backend, err := c.lb.clientByName(name)
+	if err != nil {
+		return err
+	}
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	if unhealthy, _ := backend.unhealthyLocked(); unhealthy {
+		backend.emitHealth(HealthEvent{Kind: HealthRecovered, Reason: "marked healthy"})
+	}
+	backend.markedDownAt = time.Time{}
+	backend.markedDownReason = ""
+	backend.checks = checkState{lastCheckAt: backend.checks.lastCheckAt}
+	return nil
+}
+
+// WaitReady blocks until a health check passed on at least one backend, e.g. to
+// hold back a Kubernetes readiness probe until the LB can serve completions.
+// It requires WithHealthChecks.
+func (c Client) WaitReady(ctx context.Context) error {
+	checked := false
+	for _, backend := range c.lb.backends() {
+		checked = checked || backend.checked
+	}
+	if !checked {
+		return errors.New("WaitReady requires WithHealthChecks")
+	}
+	select {
+	case <-c.lb.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
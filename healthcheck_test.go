@@ -0,0 +1,229 @@
+package openailb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds or the timeout expires.
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return cond()
+}
+
+func TestActiveHealthChecks(t *testing.T) {
+	t.Parallel()
+
+	var down atomic.Bool
+	down.Store(true)
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "flaky"}}]}`))
+	}))
+	defer flaky.Close()
+	steady := newNamedServer(t, "steady")
+	defer steady.Close()
+
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: flaky.URL},
+		{APIKey: "k2", BaseURL: steady.URL},
+	}, WithHealthChecks(HealthCheck{Interval: 10 * time.Millisecond, Model: "m"}))
+
+	if !waitFor(t, time.Second, func() bool { return client.Health()[0].Status == StatusUnhealthy }) {
+		t.Fatalf("Expected the failing backend to be marked unhealthy, got %s", client.Health()[0].Status)
+	}
+	// No user request has to fail on the unhealthy backend.
+	if hits := countHits(t, client, 4); hits["steady"] != 4 {
+		t.Errorf("Expected all requests on the steady backend, got %v", hits)
+	}
+
+	down.Store(false)
+	if !waitFor(t, time.Second, func() bool { return client.Health()[0].Status == StatusHealthy }) {
+		t.Fatalf("Expected the backend to recover, got %s", client.Health()[0].Status)
+	}
+	if hits := countHits(t, client, 4); hits["flaky"] == 0 {
+		t.Errorf("Expected the recovered backend back in rotation, got %v", hits)
+	}
+}
+
+func TestHealthCheckPath(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	checked := make(map[string]string) // backend -> "METHOD path"
+	newServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			checked[name] = r.Method + " " + r.URL.Path
+			mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"object": "list", "data": [], "choices": [{"message": {"content": "ok"}}]}`))
+		}))
+	}
+	models := newServer("models")
+	defer models.Close()
+	gateway := newServer("gateway")
+	defer gateway.Close()
+
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: models.URL},
+		{APIKey: "k2", BaseURL: gateway.URL, HealthCheckPath: CompletionCheckPath},
+	}, WithHealthChecks(HealthCheck{Interval: 10 * time.Millisecond, Model: "m"}))
+
+	if !waitFor(t, time.Second, func() bool {
+		health := client.Health()
+		return !health[0].LastCheckAt.IsZero() && !health[1].LastCheckAt.IsZero()
+	}) {
+		t.Fatal("Expected both backends to be checked")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := checked["models"]; got != "GET /models" {
+		t.Errorf("Expected GET /models by default, got %q", got)
+	}
+	if got := checked["gateway"]; got != "POST /chat/completions" {
+		t.Errorf("Expected the override to send a completion, got %q", got)
+	}
+	for i, h := range client.Health() {
+		if h.Status != StatusHealthy {
+			t.Errorf("Expected backend %d to be healthy, got %s", i, h.Status)
+		}
+	}
+}
+
+func TestWaitReady(t *testing.T) {
+	t.Parallel()
+
+	var up atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object": "list", "data": []}`))
+	}))
+	defer server.Close()
+
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL}},
+		WithHealthChecks(HealthCheck{Interval: 10 * time.Millisecond}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if err := client.WaitReady(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Expected WaitReady to block while every check fails, got %v", err)
+	}
+
+	up.Store(true)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := client.WaitReady(ctx); err != nil {
+		t.Fatalf("Expected WaitReady to return once a check passed, got %v", err)
+	}
+
+	if err := NewClient(nil).WaitReady(context.Background()); err == nil {
+		t.Error("Expected WaitReady to fail without health checks")
+	}
+}
+
+func TestMarkUnhealthy(t *testing.T) {
+	t.Parallel()
+
+	first := newNamedServer(t, "first")
+	defer first.Close()
+	second := newNamedServer(t, "second")
+	defer second.Close()
+
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: first.URL},
+		{APIKey: "k2", BaseURL: second.URL},
+	})
+
+	if err := client.MarkUnhealthy("Client-0", "upstream maintenance"); err != nil {
+		t.Fatal(err)
+	}
+	if h := client.Health()[0]; h.Status != StatusUnhealthy || h.UnhealthyReason != "upstream maintenance" {
+		t.Errorf("Expected the reason in the snapshot, got %s %q", h.Status, h.UnhealthyReason)
+	}
+	if hits := countHits(t, client, 4); hits["second"] != 4 {
+		t.Errorf("Expected all requests on the remaining backend, got %v", hits)
+	}
+
+	if err := client.MarkHealthy("Client-0"); err != nil {
+		t.Fatal(err)
+	}
+	if hits := countHits(t, client, 4); hits["first"] != 2 {
+		t.Errorf("Expected the backend back in rotation, got %v", hits)
+	}
+	if err := client.MarkUnhealthy("Client-9", "typo"); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+}
+
+func TestPerBackendHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	checked := make(map[string]string) // backend -> "METHOD path model"
+	newServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Model string `json:"model"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			mu.Lock()
+			checked[name] = strings.TrimSpace(r.Method + " " + r.URL.Path + " " + body.Model)
+			mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "ok"}}]}`))
+		}))
+	}
+	vllm := newServer("vllm")
+	defer vllm.Close()
+	azure := newServer("azure")
+	defer azure.Close()
+
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: vllm.URL, HealthCheck: &HealthCheck{Path: "health"}},
+		{APIKey: "k2", BaseURL: azure.URL, HealthCheck: &HealthCheck{
+			Method: http.MethodPost,
+			Path:   "openai/deployments/gpt-4o/chat/completions",
+			Model:  "gpt-4o",
+		}},
+	}, WithHealthChecks(HealthCheck{Interval: 10 * time.Millisecond}))
+
+	if !waitFor(t, time.Second, func() bool {
+		health := client.Health()
+		return !health[0].LastCheckAt.IsZero() && !health[1].LastCheckAt.IsZero()
+	}) {
+		t.Fatal("Expected both backends to be checked")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := checked["vllm"]; got != "GET /health" {
+		t.Errorf("Expected the vLLM override, got %q", got)
+	}
+	if got := checked["azure"]; got != "POST /openai/deployments/gpt-4o/chat/completions gpt-4o" {
+		t.Errorf("Expected the deployment-scoped completion, got %q", got)
+	}
+}
@@ -0,0 +1,129 @@
+package openailb
+
+import (
+	"sync"
+	"time"
+)
+
+// HealthEventKind says what happened to a backend.
+type HealthEventKind string
+
+const (
+	// HealthCheckFailed means an active health check failed.
+	HealthCheckFailed HealthEventKind = "check_failed"
+	// HealthUnhealthy means the backend left rotation for failing its health
+	// checks or being marked down with MarkUnhealthy.
+	HealthUnhealthy HealthEventKind = "unhealthy"
+	// HealthRecovered means an unhealthy or quarantined backend is back in rotation.
+	HealthRecovered HealthEventKind = "recovered"
+	// HealthQuarantined means the backend was quarantined by WithAuthQuarantine.
+	HealthQuarantined HealthEventKind = "quarantined"
+	// HealthEjected means outlier detection ejected the backend until Until.
+	HealthEjected HealthEventKind = "ejected"
+	// HealthEndpointChanged means the backend's hostname resolved to new
+	// addresses (WithDNSRefresh).
+	HealthEndpointChanged HealthEventKind = "endpoint_changed"
+	// HealthBreakerStateChange means one of the backend's breakers changed state.
+	HealthBreakerStateChange HealthEventKind = "breaker_state_change"
+	// HealthModelsMissing means models the backend is mapped to are missing
+	// from its /models list (WithModelValidation).
+	HealthModelsMissing HealthEventKind = "models_missing"
+)
+
+// HealthEvent describes a health transition of one backend (WithOnHealthChange).
+type HealthEvent struct {
+	Backend string          `json:"backend"`
+	Kind    HealthEventKind `json:"kind"`
+	At      time.Time       `json:"at"`
+	Reason  string          `json:"reason,omitempty"`
+	// Breaker, From and To are set for HealthBreakerStateChange, e.g.
+	// "Client-0/chat" going from "closed" to "open".
+	Breaker string `json:"breaker,omitempty"`
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
+	// Until is set for HealthEjected.
+	Until time.Time `json:"until,omitempty"`
+}
+
+// emitHealth logs ev and queues it for the WithOnHealthChange hook and the
+// notifier. Events are often raised while holding locks, so those run later,
+// in order, on the goroutine of deliverHealthEvents.
+func (c *SafeClient) emitHealth(ev HealthEvent) {
+	ev.Backend = c.Name
+	if ev.At.IsZero() {
+		ev.At = time.Now()
+	}
+	c.lb.logHealth(ev)
+	hook, n := c.lb.options.onHealthChange, c.lb.options.notifier
+	if hook == nil && n == nil {
+		return
+	}
+	c.lb.healthEvents.push(healthDelivery{ev: ev, hook: hook, notifier: n})
+}
+
+// healthQueue holds the health events not yet delivered, in the order they
+// were raised.
+type healthQueue struct {
+	mu      sync.Mutex
+	pending []healthDelivery
+	wake    chan struct{} // Signaled when pending grows.
+}
+
+type healthDelivery struct {
+	ev       HealthEvent
+	hook     func(HealthEvent)
+	notifier *notifier
+}
+
+// push queues d without blocking.
+func (q *healthQueue) push(d healthDelivery) {
+	q.mu.Lock()
+	q.pending = append(q.pending, d)
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the queued deliveries and empties the queue.
+func (q *healthQueue) take() []healthDelivery {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	pending := q.pending
+	q.pending = nil
+	return pending
+}
+
+// deliverHealthEvents hands the queued health events to the hook and the
+// notifier, one at a time, until the client is closed, if either is set.
+func (lb *LoadBalancer) deliverHealthEvents() {
+	if lb.options.onHealthChange == nil && lb.options.notifier == nil {
+		return
+	}
+	lb.background.Add(1)
+	go func() {
+		defer lb.background.Done()
+		for {
+			select {
+			case <-lb.healthEvents.wake:
+			case <-lb.done.Done():
+				// Those raised while closing still go out.
+				deliver(lb.healthEvents.take())
+				return
+			}
+			deliver(lb.healthEvents.take())
+		}
+	}()
+}
+
+func deliver(deliveries []healthDelivery) {
+	for _, d := range deliveries {
+		if d.hook != nil {
+			d.hook(d.ev)
+		}
+		if d.notifier != nil {
+			d.notifier.notify(d.ev)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestOnHealthChange(t *testing.T) {
+	t.Parallel()
+
+	var down atomic.Bool
+	down.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object": "list", "data": []}`))
+	}))
+	defer server.Close()
+
+	events := make(chan HealthEvent, 100)
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL}},
+		WithCBSettings(tripAfter(1)),
+		WithHealthChecks(HealthCheck{Interval: 10 * time.Millisecond, UnhealthyThreshold: 1}),
+		WithOnHealthChange(func(ev HealthEvent) { events <- ev }))
+
+	// next returns the next event of kind, skipping the others.
+	next := func(kind HealthEventKind) HealthEvent {
+		t.Helper()
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case ev := <-events:
+				if ev.Kind == kind {
+					return ev
+				}
+			case <-timeout:
+				t.Fatalf("No %s event", kind)
+			}
+		}
+	}
+
+	if ev := next(HealthUnhealthy); ev.Backend != "Client-0" || ev.Reason == "" || ev.At.IsZero() {
+		t.Errorf("Unexpected unhealthy event: %+v", ev)
+	}
+	down.Store(false)
+	next(HealthRecovered)
+
+	down.Store(true)
+	_, _ = client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+	if ev := next(HealthBreakerStateChange); ev.Breaker != "Client-0/chat" || ev.From != "closed" || ev.To != "open" {
+		t.Errorf("Unexpected breaker event: %+v", ev)
+	}
+}
+
+func TestHealthEventOrder(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var states []string
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1"}}, WithOnHealthChange(func(ev HealthEvent) {
+		// A slow hook must not let the later events overtake the earlier ones.
+		time.Sleep(time.Millisecond)
+		if ev.Breaker == "Client-0/chat" {
+			mu.Lock()
+			states = append(states, ev.To)
+			mu.Unlock()
+		}
+	}))
+	for i := 0; i < 5; i++ {
+		if err := client.TripBreakers("Client-0", "incident"); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.ResetBreakers("Client-0"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Close waits for the queued events to be delivered.
+	if err := client.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(states) != 10 {
+		t.Fatalf("Expected 10 breaker transitions, got %v", states)
+	}
+	for i, state := range states {
+		if want := []string{"open", "closed"}[i%2]; state != want {
+			t.Fatalf("Expected the transitions in order, got %v", states)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package openailb
+
+import (
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// Latencies are kept in log-linear histograms, in the manner of HDR
+// histograms: every power of two of microseconds is split into
+// histogramSubBuckets buckets, so any percentile is accurate to within 1/64 of
+// its value, in constant memory whatever the traffic.
+const (
+	histogramSubBits    = 5
+	histogramSubBuckets = 1 << histogramSubBits
+	// histogramMaxShift caps the range at 2^37µs, about 38 hours.
+	histogramMaxShift = 31
+	histogramBuckets  = histogramSubBuckets * (histogramMaxShift + 2)
+)
+
+// histogram counts latencies in log-linear buckets.
+type histogram struct {
+	counts [histogramBuckets]uint32
+	total  uint64
+}
+
+// histogramIndex returns the bucket of v microseconds.
+func histogramIndex(v uint64) int {
+	if v < 2*histogramSubBuckets {
+		return int(v)
+	}
+	shift := bits.Len64(v) - histogramSubBits - 1
+	if shift > histogramMaxShift {
+		return histogramBuckets - 1
+	}
+	return histogramSubBuckets*shift + int(v>>shift)
+}
+
+// histogramValue returns the midpoint of bucket i, in microseconds.
+func histogramValue(i int) uint64 {
+	if i < 2*histogramSubBuckets {
+		return uint64(i)
+	}
+	shift := i/histogramSubBuckets - 1
+	lower := uint64(i-histogramSubBuckets*shift) << shift
+	return lower + (uint64(1)<<shift)/2
+}
+
+func (h *histogram) record(d time.Duration) {
+	h.counts[histogramIndex(uint64(max(d.Microseconds(), 0)))]++
+	h.total++
+}
+
+func (h *histogram) merge(o *histogram) {
+	for i, n := range o.counts {
+		h.counts[i] += n
+	}
+	h.total += o.total
+}
+
+// quantile returns the p-th percentile (0-100), or 0 if h is empty.
+func (h *histogram) quantile(p float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	rank := uint64(float64(h.total-1)*p/100) + 1
+	var seen uint64
+	for i, n := range h.counts {
+		seen += uint64(n)
+		if seen >= rank {
+			return time.Duration(histogramValue(i)) * time.Microsecond
+		}
+	}
+	return 0
+}
+
+// histogramSlices is how many histograms a rolling window is split into.
+const histogramSlices = 6
+
+// rollingHistogram is a latency histogram over a rolling time window.
+type rollingHistogram struct {
+	mu     sync.Mutex
+	slice  time.Duration
+	ids    [histogramSlices]int64
+	slices [histogramSlices]histogram
+}
+
+func newRollingHistogram(window time.Duration) *rollingHistogram {
+	return &rollingHistogram{slice: max(window/histogramSlices, time.Millisecond)}
+}
+
+// observe records a latency of d at now.
+func (r *rollingHistogram) observe(now time.Time, d time.Duration) {
+	id := now.UnixNano() / int64(r.slice)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := id % histogramSlices
+	if r.ids[i] != id {
+		r.ids[i] = id
+		r.slices[i] = histogram{}
+	}
+	r.slices[i].record(d)
+}
+
+// window returns the histogram of the window ending at now.
+func (r *rollingHistogram) window(now time.Time) *histogram {
+	id := now.UnixNano() / int64(r.slice)
+
+	var h histogram
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.slices {
+		if r.ids[i] > id-histogramSlices && r.ids[i] <= id {
+			h.merge(&r.slices[i])
+		}
+	}
+	return &h
+}
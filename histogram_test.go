@@ -0,0 +1,53 @@
+package openailb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistogramIndex(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range []uint64{0, 1, 63, 64, 65, 1000, 123456, 1 << 30} {
+		got := histogramValue(histogramIndex(v))
+		if diff := float64(got) - float64(v); diff < -float64(v)/64-1 || diff > float64(v)/64+1 {
+			t.Errorf("Value %d landed in the bucket of %d", v, got)
+		}
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	t.Parallel()
+
+	var h histogram
+	if h.quantile(50) != 0 {
+		t.Error("Expected 0 for an empty histogram")
+	}
+	for i := 1; i <= 1000; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+	for _, tc := range []struct {
+		p    float64
+		want time.Duration
+	}{{50, 500 * time.Millisecond}, {95, 950 * time.Millisecond}, {99, 990 * time.Millisecond}, {100, time.Second}} {
+		got := h.quantile(tc.p)
+		if diff := got - tc.want; diff < -tc.want/50 || diff > tc.want/50 {
+			t.Errorf("p%v: expected about %s, got %s", tc.p, tc.want, got)
+		}
+	}
+}
+
+func TestRollingHistogram(t *testing.T) {
+	t.Parallel()
+
+	r := newRollingHistogram(time.Minute)
+	start := time.Now()
+	r.observe(start, time.Second)
+	r.observe(start.Add(30*time.Second), 2*time.Second)
+	if n := r.window(start.Add(30 * time.Second)).total; n != 2 {
+		t.Errorf("Expected 2 samples in the window, got %d", n)
+	}
+	if h := r.window(start.Add(80 * time.Second)); h.total != 1 || h.quantile(50) < 1900*time.Millisecond {
+		t.Errorf("Expected only the later sample to remain, got %d samples, p50 %s", h.total, h.quantile(50))
+	}
+}
@@ -0,0 +1,106 @@
+package openailb
+
+import "time"
+
+// Hooks are callbacks at the lifecycle points of every backend attempt
+// (WithHooks), a single place to plug in logging, billing or custom metrics.
+// They run on the request's goroutine, so they should return quickly. Nil
+// callbacks are skipped.
+type Hooks struct {
+	// OnRequest is called when an attempt is sent to its backend.
+	OnRequest func(RequestInfo)
+	// OnResponse is called when an attempt succeeded.
+	OnResponse func(ResponseInfo)
+	// OnError is called when an attempt failed, including attempts turned away
+	// by the backend's open breaker, which never reached OnRequest.
+	OnError func(ResponseInfo)
+	// OnFailover is called when a failed attempt is retried on another backend
+	// (WithFailover), after OnError for it.
+	OnFailover func(ResponseInfo)
+	// OnUsageAlert is called when a UsageAlert crosses a threshold (WithUsageAlerts).
+	OnUsageAlert func(UsageAlertEvent)
+}
+
+// RequestInfo summarizes one attempt of a request on one backend.
+type RequestInfo struct {
+	Backend string
+	Service ServiceType
+	// Model is the requested model; MappedModel the one sent to the backend.
+	Model       string
+	MappedModel string
+	// Attempt is 1 for the first try, 2 for the first failover, ...
+	Attempt int
+	Stream  bool
+	// Tag is the caller's tag, set with WithTag.
+	Tag string
+	// Tenant is the caller's tenant, set with WithTenant.
+	Tenant string
+	// Labels are the backend's OpenaiClientConfig.Labels.
+	Labels map[string]string
+}
+
+// ResponseInfo describes how an attempt ended.
+type ResponseInfo struct {
+	RequestInfo
+	Latency time.Duration
+	// TTFT is the time to the first streamed chunk; zero for non-streaming requests.
+	TTFT             time.Duration
+	PromptTokens     int64
+	CompletionTokens int64
+	// Cost is what the tokens cost at the configured prices (WithPricing).
+	Cost  float64
+	Class ErrorClass
+	// Err is the attempt's error; nil for a response that WithIsSuccessful
+	// classified as a failure.
+	Err error
+	// RateLimits are the quotas announced by the backend, if it sent any.
+	RateLimits *RateLimits
+}
+
+// info returns the hooks' summary of a.
+func (a attempt) info() RequestInfo {
+	return RequestInfo{
+		Backend:     a.client.Name,
+		Service:     a.breaker.key.svc,
+		Model:       a.requested,
+		MappedModel: a.model,
+		Attempt:     a.number,
+		Stream:      a.stream,
+		Tag:         a.tag,
+		Tenant:      a.tenant,
+		Labels:      a.client.Labels,
+	}
+}
+
+func (lb *LoadBalancer) hookRequest(a attempt) {
+	if len(lb.options.hooks) == 0 {
+		return
+	}
+	info := a.info()
+	for _, h := range lb.options.hooks {
+		if h.OnRequest != nil {
+			h.OnRequest(info)
+		}
+	}
+}
+
+// hookDone calls OnResponse or OnError for a finished attempt.
+func (lb *LoadBalancer) hookDone(r ResponseInfo) {
+	for _, h := range lb.options.hooks {
+		if r.Class == ClassOK {
+			if h.OnResponse != nil {
+				h.OnResponse(r)
+			}
+		} else if h.OnError != nil {
+			h.OnError(r)
+		}
+	}
+}
+
+func (lb *LoadBalancer) hookFailover(r ResponseInfo) {
+	for _, h := range lb.options.hooks {
+		if h.OnFailover != nil {
+			h.OnFailover(r)
+		}
+	}
+}
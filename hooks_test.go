@@ -0,0 +1,137 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestHooks(t *testing.T) {
+	t.Parallel()
+
+	failServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failServer.Close()
+	okServer := newNamedServer(t, "ok")
+	defer okServer.Close()
+
+	var mu sync.Mutex
+	var calls []string
+	var responses []ResponseInfo
+	record := func(name string) func(ResponseInfo) {
+		return func(r ResponseInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, name+":"+r.Backend)
+			responses = append(responses, r)
+		}
+	}
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: failServer.URL},
+		{APIKey: "k2", BaseURL: okServer.URL, ModelMap: map[string]string{"m": "mapped"}},
+	}, WithFailover(2), WithHooks(Hooks{
+		OnRequest: func(r RequestInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, "request:"+r.Backend)
+		},
+		OnResponse: record("response"),
+		OnError:    record("error"),
+		OnFailover: record("failover"),
+	}))
+
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0)); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"request:Client-0", "error:Client-0", "failover:Client-0", "request:Client-1", "response:Client-1"}
+	if len(calls) != len(want) {
+		t.Fatalf("Expected hook calls %v, got %v", want, calls)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("Expected hook calls %v, got %v", want, calls)
+		}
+	}
+
+	if failed := responses[0]; failed.Class != ClassServer || failed.Err == nil || failed.Attempt != 1 {
+		t.Errorf("Expected a server error on attempt 1, got %+v", failed)
+	}
+	ok := responses[2]
+	if ok.Class != ClassOK || ok.Attempt != 2 || ok.Model != "m" || ok.MappedModel != "mapped" || ok.Service != ServiceChat {
+		t.Errorf("Unexpected response info %+v", ok)
+	}
+	if ok.Latency <= 0 {
+		t.Error("Expected the response latency to be set")
+	}
+}
+
+func TestHooksStreamRetried(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first stream succeeds on the SDK's retry; the second never does.
+		if n := hits.Add(1); n != 2 {
+			w.Header().Set("Retry-After-Ms", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\": [{\"delta\": {\"content\": \"Hi\"}}]}\n\ndata: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var calls []string
+	var responses []ResponseInfo
+	record := func(name string) func(ResponseInfo) {
+		return func(r ResponseInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, name)
+			responses = append(responses, r)
+		}
+	}
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL}}, WithHooks(Hooks{
+		OnRequest: func(RequestInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, "request")
+		},
+		OnResponse: record("response"),
+		OnError:    record("error"),
+	}))
+
+	stream := client.Chat.Completions.NewStreaming(context.Background(), chatParams("m"), option.WithMaxRetries(1))
+	for stream.Next() {
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	stream = client.Chat.Completions.NewStreaming(context.Background(), chatParams("m"), option.WithMaxRetries(1))
+	if stream.Err() == nil {
+		t.Fatal("Expected the second stream to fail")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"request", "response", "request", "error"}; !slices.Equal(calls, want) {
+		t.Fatalf("Expected hook calls %v, got %v", want, calls)
+	}
+	if failed := responses[1]; failed.Class != ClassServer || failed.Err == nil {
+		t.Errorf("Expected a server error, got %+v", failed)
+	}
+	if got := hits.Load(); got != 4 {
+		t.Errorf("Expected 4 requests with the SDK's retries, got %d", got)
+	}
+}
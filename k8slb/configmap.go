@@ -0,0 +1,92 @@
+package k8slb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	openailb "github.com/hi2code/openai-go-lb"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigMapKey is the ConfigMap key read by default.
+const DefaultConfigMapKey = "backends.yaml"
+
+// ConfigMap selects the ConfigMap listing the backends to balance over.
+type ConfigMap struct {
+	// Name is the name of the ConfigMap in the Cluster's namespace.
+	Name string
+	// Key is the entry holding the backends (default DefaultConfigMapKey): a
+	// YAML or JSON list in the format of the backends of an openailb config
+	// file. Every backend must be named.
+	Key string
+}
+
+type configMap struct {
+	Metadata metadata          `json:"metadata"`
+	Data     map[string]string `json:"data"`
+}
+
+func (m configMap) name() string { return m.Metadata.Name }
+
+// WatchConfigMap keeps the backends listed in the ConfigMap in the pool of
+// client: listed backends are added, changed ones replaced, and unlisted or
+// deleted ones removed, letting their in-flight requests finish. A list that
+// doesn't parse or validate leaves the pool as it is. The ConfigMap is read
+// before WatchConfigMap returns, and an error reading or parsing it is
+// returned. Canceling ctx stops the watch, leaving the backends in the pool.
+func (c *Cluster) WatchConfigMap(ctx context.Context, client openailb.Client, m ConfigMap) error {
+	if m.Name == "" {
+		return errors.New("k8slb: ConfigMap.Name is required")
+	}
+	key := m.Key
+	if key == "" {
+		key = DefaultConfigMapKey
+	}
+
+	p := newPool(client, c)
+	path := "/api/v1/namespaces/" + url.PathEscape(c.Namespace) + "/configmaps"
+	query := url.Values{"fieldSelector": {"metadata.name=" + m.Name}}
+	return follow(ctx, c, path, query, func(maps map[string]configMap) error {
+		want, err := parseBackends(maps[m.Name], key)
+		if err != nil {
+			return fmt.Errorf("k8slb: ConfigMap %s: %w", m.Name, err)
+		}
+		p.sync(want)
+		return nil
+	})
+}
+
+// parseBackends returns the backends listed under key of m.
+func parseBackends(m configMap, key string) (map[string]desired, error) {
+	data, ok := m.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("no key %q", key)
+	}
+	var backends []openailb.BackendConfig
+	dec := yaml.NewDecoder(bytes.NewReader([]byte(data)))
+	dec.KnownFields(true)
+	if err := dec.Decode(&backends); err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	if err := (&openailb.Config{Backends: backends}).Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	want := make(map[string]desired, len(backends))
+	for i, b := range backends {
+		if b.Name == "" {
+			return nil, fmt.Errorf("%s: backends[%d].name: is required", key, i)
+		}
+		if _, ok := want[b.Name]; ok {
+			return nil, fmt.Errorf("%s: backends[%d].name: %q is listed twice", key, i, b.Name)
+		}
+		cfg, err := b.ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("%s: backends[%d].%w", key, i, err)
+		}
+		want[b.Name] = desired{spec: b, config: cfg}
+	}
+	return want, nil
+}
@@ -0,0 +1,110 @@
+package k8slb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	openailb "github.com/hi2code/openai-go-lb"
+)
+
+// EndpointSlices selects the endpoints of a Service to balance over.
+type EndpointSlices struct {
+	// Service is the name of the Service in the Cluster's namespace.
+	Service string
+	// Port is the name of the Service port to use; with one port it may be
+	// empty.
+	Port string
+	// Template is the config of every backend, e.g. the shared API key and
+	// model map. The scheme and path of its BaseURL, e.g. "http://vllm/v1",
+	// are kept and the host replaced by each endpoint's address and port.
+	// The backends are named after the endpoint, prefixed with Template.Name
+	// or else the Service: "vllm@10.0.0.5:8000".
+	Template openailb.OpenaiClientConfig
+}
+
+type endpointSlice struct {
+	Metadata  metadata `json:"metadata"`
+	Endpoints []struct {
+		Addresses  []string `json:"addresses"`
+		Conditions struct {
+			Ready       *bool `json:"ready"`
+			Terminating *bool `json:"terminating"`
+		} `json:"conditions"`
+	} `json:"endpoints"`
+	Ports []struct {
+		Name *string `json:"name"`
+		Port *int32  `json:"port"`
+	} `json:"ports"`
+}
+
+func (s endpointSlice) name() string { return s.Metadata.Name }
+
+// port returns the slice's port called name, or its only port for "".
+func (s endpointSlice) port(name string) (int32, bool) {
+	for _, p := range s.Ports {
+		if p.Port == nil {
+			continue
+		}
+		portName := ""
+		if p.Name != nil {
+			portName = *p.Name
+		}
+		if portName == name || (name == "" && len(s.Ports) == 1) {
+			return *p.Port, true
+		}
+	}
+	return 0, false
+}
+
+// WatchEndpointSlices keeps one backend per ready endpoint of the Service in
+// the pool of client: endpoints that become ready are added, and those that
+// go away or stop being ready are removed, letting their in-flight requests
+// finish. The first list happens before WatchEndpointSlices returns and its
+// error is returned. Canceling ctx stops the watch, leaving the backends in
+// the pool.
+func (c *Cluster) WatchEndpointSlices(ctx context.Context, client openailb.Client, s EndpointSlices) error {
+	if s.Service == "" {
+		return errors.New("k8slb: EndpointSlices.Service is required")
+	}
+	base, err := url.Parse(s.Template.BaseURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") {
+		return fmt.Errorf("k8slb: EndpointSlices.Template.BaseURL: %q must be an absolute http or https URL", s.Template.BaseURL)
+	}
+	prefix := s.Template.Name
+	if prefix == "" {
+		prefix = s.Service
+	}
+
+	p := newPool(client, c)
+	path := "/apis/discovery.k8s.io/v1/namespaces/" + url.PathEscape(c.Namespace) + "/endpointslices"
+	query := url.Values{"labelSelector": {"kubernetes.io/service-name=" + s.Service}}
+	return follow(ctx, c, path, query, func(slices map[string]endpointSlice) error {
+		want := make(map[string]desired)
+		for _, slice := range slices {
+			port, ok := slice.port(s.Port)
+			if !ok {
+				continue
+			}
+			for _, e := range slice.Endpoints {
+				// A nil condition means unknown, which Kubernetes treats as ready.
+				if (e.Conditions.Ready != nil && !*e.Conditions.Ready) || (e.Conditions.Terminating != nil && *e.Conditions.Terminating) {
+					continue
+				}
+				for _, addr := range e.Addresses {
+					endpoint := net.JoinHostPort(addr, strconv.Itoa(int(port)))
+					cfg := s.Template
+					u := *base
+					u.Host = endpoint
+					cfg.BaseURL = u.String()
+					want[prefix+"@"+endpoint] = desired{spec: cfg.BaseURL, config: cfg}
+				}
+			}
+		}
+		p.sync(want)
+		return nil
+	})
+}
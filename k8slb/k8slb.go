@@ -0,0 +1,312 @@
+// Package k8slb keeps an openailb pool in step with Kubernetes: the ready
+// endpoints of a Service's EndpointSlices, or the backends listed in a
+// ConfigMap. It talks to the API server directly, without client-go.
+//
+//	cluster, err := k8slb.InCluster()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	client := openailb.NewClient(nil)
+//	err = cluster.WatchEndpointSlices(ctx, client, k8slb.EndpointSlices{
+//		Service:  "vllm",
+//		Port:     "http",
+//		Template: openailb.OpenaiClientConfig{APIKey: "none", BaseURL: "http://vllm/v1"},
+//	})
+//
+// The service account needs get, list and watch on the watched resource.
+package k8slb
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	openailb "github.com/hi2code/openai-go-lb"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// retryInterval is the wait before listing again after a failed list or watch.
+const retryInterval = 5 * time.Second
+
+// watchTimeout is how long the API server keeps a watch open; the objects are
+// listed again after each.
+const watchTimeout = 5 * time.Minute
+
+// Cluster is a connection to a Kubernetes API server.
+type Cluster struct {
+	// Server is the API server's URL, e.g. https://kubernetes.default.svc.
+	Server string
+	// Namespace is the namespace of the watched objects.
+	Namespace string
+	// TokenFile, if set, holds the bearer token. It is read for every
+	// request, so rotated service account tokens are picked up.
+	TokenFile string
+	// HTTPClient sends the requests (default http.DefaultClient).
+	HTTPClient *http.Client
+	// Logger, if set, receives failed lists and watches, which are retried,
+	// and backends that couldn't be applied.
+	Logger *slog.Logger
+}
+
+// InCluster returns the Cluster the process runs in, authenticated as its
+// service account and watching its namespace.
+func InCluster() (*Cluster, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("k8slb: not running in a cluster: KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are unset")
+	}
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("k8slb: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(ca) {
+		return nil, errors.New("k8slb: no certificate found in " + serviceAccountDir + "/ca.crt")
+	}
+	namespace, err := os.ReadFile(serviceAccountDir + "/namespace")
+	if err != nil {
+		return nil, fmt.Errorf("k8slb: %w", err)
+	}
+	return &Cluster{
+		Server:     "https://" + net.JoinHostPort(host, port),
+		Namespace:  strings.TrimSpace(string(namespace)),
+		TokenFile:  serviceAccountDir + "/token",
+		HTTPClient: &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}},
+	}, nil
+}
+
+// get sends a GET for path and query to the API server.
+func (c *Cluster) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.Server, "/")+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.TokenFile != "" {
+		token, err := os.ReadFile(c.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	req.Header.Set("Accept", "application/json")
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, fmt.Errorf("GET %s: %s: %s", path, res.Status, strings.TrimSpace(string(body)))
+	}
+	return res, nil
+}
+
+func (c *Cluster) warn(msg string, args ...any) {
+	if c.Logger != nil {
+		c.Logger.Warn(msg, args...)
+	}
+}
+
+// object is a Kubernetes object of the watched kind.
+type object interface {
+	name() string
+}
+
+type metadata struct {
+	Name            string `json:"name"`
+	ResourceVersion string `json:"resourceVersion"`
+}
+
+// follow lists the objects at path matching query and calls sync with them,
+// then watches them in the background until ctx is done, calling sync after
+// every change. Only the first list's and sync's errors are returned; later
+// failures are logged, and failed lists and watches retried.
+func follow[T object](ctx context.Context, c *Cluster, path string, query url.Values, sync func(map[string]T) error) error {
+	objects, version, err := list[T](ctx, c, path, query)
+	if err != nil {
+		return err
+	}
+	if err := sync(objects); err != nil {
+		return err
+	}
+	apply := func(objects map[string]T) {
+		if err := sync(objects); err != nil {
+			c.warn("k8slb: change not applied", "path", path, "error", err)
+		}
+	}
+	go func() {
+		for {
+			err := watch(ctx, c, path, query, version, objects, apply)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				c.warn("k8slb: watch failed; listing again", "path", path, "error", err)
+				select {
+				case <-time.After(retryInterval):
+				case <-ctx.Done():
+					return
+				}
+			}
+			// Changes may have been missed since the watch ended.
+			relisted, relistedVersion, err := list[T](ctx, c, path, query)
+			if err != nil {
+				c.warn("k8slb: list failed", "path", path, "error", err)
+				continue
+			}
+			objects, version = relisted, relistedVersion
+			apply(objects)
+		}
+	}()
+	return nil
+}
+
+// list returns the objects at path by name, and the list's resource version.
+func list[T object](ctx context.Context, c *Cluster, path string, query url.Values) (map[string]T, string, error) {
+	res, err := c.get(ctx, path, query)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+	var l struct {
+		Metadata metadata `json:"metadata"`
+		Items    []T      `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&l); err != nil {
+		return nil, "", fmt.Errorf("GET %s: %w", path, err)
+	}
+	objects := make(map[string]T, len(l.Items))
+	for _, o := range l.Items {
+		objects[o.name()] = o
+	}
+	return objects, l.Metadata.ResourceVersion, nil
+}
+
+// watch applies the changes to objects after version to it, calling sync
+// after each, until the watch ends.
+func watch[T object](ctx context.Context, c *Cluster, path string, query url.Values, version string, objects map[string]T, sync func(map[string]T)) error {
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	q.Set("watch", "true")
+	q.Set("resourceVersion", version)
+	q.Set("timeoutSeconds", fmt.Sprint(int(watchTimeout.Seconds())))
+	res, err := c.get(ctx, path, q)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	dec := json.NewDecoder(res.Body)
+	for {
+		var ev struct {
+			Type   string          `json:"type"`
+			Object json.RawMessage `json:"object"`
+		}
+		if err := dec.Decode(&ev); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if ev.Type == "ERROR" {
+			// Typically 410 Gone: the version is too old to watch from.
+			return fmt.Errorf("watch %s: %s", path, ev.Object)
+		}
+		var o T
+		if err := json.Unmarshal(ev.Object, &o); err != nil {
+			return fmt.Errorf("watch %s: %w", path, err)
+		}
+		switch ev.Type {
+		case "ADDED", "MODIFIED":
+			objects[o.name()] = o
+		case "DELETED":
+			delete(objects, o.name())
+		default:
+			continue
+		}
+		sync(objects)
+	}
+}
+
+// pool applies the backends a watch wants to an openailb pool, touching
+// only the backends it added.
+type pool struct {
+	client  openailb.Client
+	cluster *Cluster
+	applied map[string]any // Backend name -> the spec it was added with.
+}
+
+// desired is a backend a watch wants in the pool. A backend whose spec
+// changed is replaced, unless only its weight did.
+type desired struct {
+	spec   any
+	config openailb.OpenaiClientConfig
+}
+
+func newPool(client openailb.Client, cluster *Cluster) *pool {
+	return &pool{client: client, cluster: cluster, applied: make(map[string]any)}
+}
+
+// sync adds the wanted backends missing from the pool, replaces the changed
+// ones and removes the others it added.
+func (p *pool) sync(want map[string]desired) {
+	for name, d := range want {
+		spec, ok := p.applied[name]
+		if ok && reflect.DeepEqual(spec, d.spec) {
+			continue
+		}
+		if ok && weightOnly(spec, d.spec) {
+			// Keep the backend, its breakers and its connections.
+			if err := p.client.SetWeight(name, max(d.config.Weight, 1)); err == nil {
+				p.applied[name] = d.spec
+				continue
+			}
+		}
+		if ok {
+			_ = p.client.RemoveBackend(name)
+			delete(p.applied, name)
+		}
+		cfg := d.config
+		cfg.Name = name
+		if _, err := p.client.AddBackend(cfg); err != nil {
+			p.cluster.warn("k8slb: backend not added", "backend", name, "error", err)
+			continue
+		}
+		p.applied[name] = d.spec
+	}
+	for name := range p.applied {
+		if _, ok := want[name]; !ok {
+			_ = p.client.RemoveBackend(name)
+			delete(p.applied, name)
+		}
+	}
+}
+
+// weightOnly reports whether two backend specs differ in their weight only.
+func weightOnly(old, new any) bool {
+	a, ok := old.(openailb.BackendConfig)
+	b, ok2 := new.(openailb.BackendConfig)
+	if !ok || !ok2 {
+		return false
+	}
+	a.Weight, b.Weight = 0, 0
+	return reflect.DeepEqual(a, b)
+}
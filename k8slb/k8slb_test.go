@@ -0,0 +1,193 @@
+package k8slb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	openailb "github.com/hi2code/openai-go-lb"
+	"github.com/openai/openai-go/v3"
+)
+
+// fakeAPIServer serves one list of objects at path, then the watch events
+// sent on its channel.
+type fakeAPIServer struct {
+	*httptest.Server
+	events chan any
+}
+
+func newFakeAPIServer(t *testing.T, path string, items ...any) *fakeAPIServer {
+	t.Helper()
+	s := &fakeAPIServer{events: make(chan any, 10)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path || r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unexpected request", http.StatusForbidden)
+			return
+		}
+		if r.URL.Query().Get("watch") != "true" {
+			_ = json.NewEncoder(w).Encode(map[string]any{"metadata": map[string]any{"resourceVersion": "1"}, "items": items})
+			return
+		}
+		for {
+			select {
+			case ev := <-s.events:
+				_ = json.NewEncoder(w).Encode(ev)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}))
+	return s
+}
+
+func (s *fakeAPIServer) cluster(t *testing.T) *Cluster {
+	t.Helper()
+	token := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(token, []byte("token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return &Cluster{Server: s.URL, Namespace: "default", TokenFile: token}
+}
+
+func newNamedServer(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "` + name + `"}}]}`))
+	}))
+}
+
+func slice(name string, port int, ready bool, addrs ...string) map[string]any {
+	return map[string]any{
+		"metadata":  map[string]any{"name": name},
+		"endpoints": []any{map[string]any{"addresses": addrs, "conditions": map[string]any{"ready": ready}}},
+		"ports":     []any{map[string]any{"name": "http", "port": port}},
+	}
+}
+
+func portOf(s *httptest.Server) int {
+	u, _ := url.Parse(s.URL)
+	var port int
+	fmt.Sscan(u.Port(), &port)
+	return port
+}
+
+func backendNames(client openailb.Client) []string {
+	var names []string
+	for _, h := range client.Health() {
+		names = append(names, h.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func waitForNames(t *testing.T, client openailb.Client, want ...string) {
+	t.Helper()
+	sort.Strings(want)
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if strings.Join(backendNames(client), ",") == strings.Join(want, ",") {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Expected the backends %v, got %v", want, backendNames(client))
+}
+
+func TestWatchEndpointSlices(t *testing.T) {
+	first, second := newNamedServer("first"), newNamedServer("second")
+	defer first.Close()
+	defer second.Close()
+	api := newFakeAPIServer(t, "/apis/discovery.k8s.io/v1/namespaces/default/endpointslices",
+		slice("vllm-a", portOf(first), true, "127.0.0.1"))
+	defer api.Close()
+
+	client := openailb.NewClient(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := api.cluster(t).WatchEndpointSlices(ctx, client, EndpointSlices{
+		Service:  "vllm",
+		Port:     "http",
+		Template: openailb.OpenaiClientConfig{APIKey: "none", BaseURL: "http://vllm/v1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstName := fmt.Sprintf("vllm@127.0.0.1:%d", portOf(first))
+	secondName := fmt.Sprintf("vllm@127.0.0.1:%d", portOf(second))
+	waitForNames(t, client, firstName)
+
+	api.events <- map[string]any{"type": "ADDED", "object": slice("vllm-b", portOf(second), false, "127.0.0.1")}
+	api.events <- map[string]any{"type": "MODIFIED", "object": slice("vllm-b", portOf(second), true, "127.0.0.1")}
+	waitForNames(t, client, firstName, secondName)
+
+	api.events <- map[string]any{"type": "DELETED", "object": slice("vllm-a", portOf(first), true, "127.0.0.1")}
+	waitForNames(t, client, secondName)
+	resp, err := client.Chat.Completions.New(context.Background(), openai.ChatCompletionNewParams{
+		Model:    "m",
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("test")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content := resp.Choices[0].Message.Content; content != "second" {
+		t.Errorf("Expected the remaining endpoint to serve, got %s", content)
+	}
+}
+
+func TestWatchConfigMap(t *testing.T) {
+	server := newNamedServer("ok")
+	defer server.Close()
+	configMap := func(backends string) map[string]any {
+		return map[string]any{
+			"metadata": map[string]any{"name": "openai-backends"},
+			"data":     map[string]any{DefaultConfigMapKey: backends},
+		}
+	}
+	api := newFakeAPIServer(t, "/api/v1/namespaces/default/configmaps", configMap(`
+- {name: east, api_key: k1, base_url: "`+server.URL+`"}
+- {name: west, api_key: k2, base_url: "`+server.URL+`"}
+`))
+	defer api.Close()
+
+	client := openailb.NewClient(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := api.cluster(t).WatchConfigMap(ctx, client, ConfigMap{Name: "openai-backends"}); err != nil {
+		t.Fatal(err)
+	}
+	waitForNames(t, client, "east", "west")
+	if err := client.TripBreakers("east", "incident"); err != nil {
+		t.Fatal(err)
+	}
+
+	api.events <- map[string]any{"type": "MODIFIED", "object": configMap(`
+- {name: east, api_key: k1, base_url: "` + server.URL + `", weight: 3}
+`)}
+	waitForNames(t, client, "east")
+	if h := client.Health()[0]; h.Weight != 3 || h.Status != openailb.StatusBreakerOpen {
+		t.Errorf("Expected the weight to change on the same backend, got weight %v, status %s", h.Weight, h.Status)
+	}
+
+	// An invalid list leaves the pool alone.
+	api.events <- map[string]any{"type": "MODIFIED", "object": configMap(`- {name: north, base_url: "` + server.URL + `"}`)}
+	time.Sleep(50 * time.Millisecond)
+	waitForNames(t, client, "east")
+	api.events <- map[string]any{"type": "MODIFIED", "object": configMap(`- {name: north, api_key: k3, base_url: "` + server.URL + `"}`)}
+	waitForNames(t, client, "north")
+
+	cluster := api.cluster(t)
+	err := cluster.WatchConfigMap(ctx, openailb.NewClient(nil), ConfigMap{Name: "openai-backends", Key: "missing.yaml"})
+	if err == nil || !strings.Contains(err.Error(), `no key "missing.yaml"`) {
+		t.Errorf("Expected the missing key to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package openailb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type labelSelectorKey struct{}
+
+// WithLabelSelector restricts the requests made with ctx to the backends
+// whose OpenaiClientConfig.Labels include every label of selector, e.g.
+// {"env": "prod", "gpu": "a100"}. Failover stays within those backends.
+func WithLabelSelector(ctx context.Context, selector map[string]string) context.Context {
+	return context.WithValue(ctx, labelSelectorKey{}, selector)
+}
+
+// LabelSelectorFromContext returns the selector set with WithLabelSelector, or nil.
+func LabelSelectorFromContext(ctx context.Context) map[string]string {
+	selector, _ := ctx.Value(labelSelectorKey{}).(map[string]string)
+	return selector
+}
+
+// hasLabels reports whether the backend carries every label of selector.
+func (c *SafeClient) hasLabels(selector map[string]string) bool {
+	for k, v := range selector {
+		if value, ok := c.Labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// formatLabels formats labels as sorted k=v pairs, e.g. for errors.
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,61 @@
+package openailb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestLabelSelector(t *testing.T) {
+	t.Parallel()
+
+	openaiServer := newNamedServer(t, "openai")
+	defer openaiServer.Close()
+	azureServer := newNamedServer(t, "azure")
+	defer azureServer.Close()
+
+	sink := &recordingSink{}
+	client := NewClient([]OpenaiClientConfig{
+		{Name: "openai", APIKey: "k1", BaseURL: openaiServer.URL, Labels: map[string]string{"provider": "openai", "env": "prod"}},
+		{Name: "azure-east", APIKey: "k2", BaseURL: azureServer.URL, Labels: map[string]string{"provider": "azure", "env": "prod"}},
+		{Name: "azure-west", APIKey: "k3", BaseURL: azureServer.URL, Labels: map[string]string{"provider": "azure", "env": "prod"}},
+	}, WithMetrics(sink))
+
+	// Requests stay within the selected backends.
+	ctx := WithLabelSelector(context.Background(), map[string]string{"provider": "azure"})
+	for i := 0; i < 3; i++ {
+		resp, err := client.Chat.Completions.New(ctx, chatParams("m"), option.WithMaxRetries(0))
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+		if content := resp.Choices[0].Message.Content; content != "azure" {
+			t.Errorf("Expected an Azure backend to answer, got %s", content)
+		}
+	}
+	stream := client.Chat.Completions.NewStreaming(WithLabelSelector(context.Background(), map[string]string{"provider": "openai"}), chatParams("m"))
+	for stream.Next() {
+	}
+	stream.Close()
+
+	sink.mu.Lock()
+	for _, m := range sink.done {
+		if m.Backend == "openai" && m.Labels["provider"] != "openai" {
+			t.Errorf("Expected the metrics to carry the backend's labels, got %v", m.Labels)
+		}
+		if m.Backend != "openai" && m.Labels["provider"] != "azure" {
+			t.Errorf("Expected only Azure backends besides the stream, got %s", m.Backend)
+		}
+	}
+	sink.mu.Unlock()
+
+	ctx = WithLabelSelector(context.Background(), map[string]string{"gpu": "a100", "env": "prod"})
+	_, err := client.Chat.Completions.New(ctx, chatParams("m"))
+	if err == nil || err.Error() != "no backend has the labels env=prod, gpu=a100" {
+		t.Errorf("Expected no backend to match, got %v", err)
+	}
+
+	if labels := client.Health()[2].Labels; labels["provider"] != "azure" {
+		t.Errorf("Expected the health snapshot to carry the labels, got %v", labels)
+	}
+}
@@ -0,0 +1,36 @@
+package openailb
+
+import "time"
+
+// latencyTrip is the configuration set by WithLatencyBreaker.
+type latencyTrip struct {
+	percentile float64
+	threshold  time.Duration
+	window     time.Duration
+	minSamples int
+}
+
+// observe records d and reports whether the configured percentile over the
+// window now exceeds the threshold.
+func (t *latencyTrip) observe(r *rollingHistogram, d time.Duration) bool {
+	now := time.Now()
+	r.observe(now, d)
+	h := r.window(now)
+	if h.total < uint64(t.minSamples) {
+		return false
+	}
+	return h.quantile(t.percentile) > t.threshold
+}
+
+// latencyFor returns (creating on first use) the latency histogram paired
+// with the breaker for key, over window.
+func (c *SafeClient) latencyFor(key breakerKey, window time.Duration) *rollingHistogram {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.latencies[key]
+	if !ok {
+		r = newRollingHistogram(window)
+		c.latencies[key] = r
+	}
+	return r
+}
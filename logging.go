@@ -0,0 +1,60 @@
+package openailb
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/sony/gobreaker/v2"
+)
+
+// streamStallThreshold is the gap between two chunks of a stream after which
+// the stream is logged as stalled.
+const streamStallThreshold = 30 * time.Second
+
+// discardHandler is the slog.Handler used without WithLogger.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// selected logs and publishes the backend picked for an attempt, and logs the
+// model mapping applied.
+func (lb *LoadBalancer) selected(ctx context.Context, a attempt) {
+	lb.events.publish(attemptEvent(EventRouteSelected, a))
+	lb.options.logger.DebugContext(ctx, "backend selected",
+		"backend", a.client.Name, "service", a.breaker.key.svc, "model", a.requested, "attempt", a.number)
+	if a.model != a.requested {
+		lb.options.logger.DebugContext(ctx, "model mapped",
+			"backend", a.client.Name, "model", a.requested, "mapped_model", a.model)
+	}
+}
+
+// logHealth logs a health event at a level matching its severity.
+func (lb *LoadBalancer) logHealth(ev HealthEvent) {
+	level := slog.LevelInfo
+	switch ev.Kind {
+	case HealthCheckFailed:
+		level = slog.LevelDebug
+	case HealthUnhealthy, HealthQuarantined, HealthEjected:
+		level = slog.LevelWarn
+	case HealthBreakerStateChange:
+		if ev.To == gobreaker.StateOpen.String() {
+			level = slog.LevelWarn
+		}
+	}
+
+	attrs := []any{"backend", ev.Backend}
+	if ev.Reason != "" {
+		attrs = append(attrs, "reason", ev.Reason)
+	}
+	if ev.Breaker != "" {
+		attrs = append(attrs, "breaker", ev.Breaker, "from", ev.From, "to", ev.To)
+	}
+	if !ev.Until.IsZero() {
+		attrs = append(attrs, "until", ev.Until)
+	}
+	lb.options.logger.Log(context.Background(), level, "backend health: "+string(ev.Kind), attrs...)
+}
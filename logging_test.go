@@ -0,0 +1,90 @@
+package openailb
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+// logBuffer collects log output from concurrent goroutines.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestLogger(t *testing.T) {
+	t.Parallel()
+
+	failServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failServer.Close()
+	okServer := newNamedServer(t, "ok")
+	defer okServer.Close()
+
+	var logs logBuffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: failServer.URL},
+		{APIKey: "k2", BaseURL: okServer.URL, ModelMap: map[string]string{"m": "mapped"}},
+	}, WithFailover(2), WithCBSettings(tripAfter(1)), WithLogger(logger))
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0)); err != nil {
+			t.Fatalf("Request %d should have failed over: %v", i, err)
+		}
+	}
+
+	out := logs.String()
+	for _, want := range []string{
+		`level=DEBUG msg="backend selected" backend=Client-0 service=chat model=m attempt=1`,
+		`level=DEBUG msg="model mapped" backend=Client-1 model=m mapped_model=mapped`,
+		`level=WARN msg="failing over" backend=Client-0`,
+		`level=WARN msg="backend health: breaker_state_change" backend=Client-0`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected log line %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestStreamStallLogged(t *testing.T) {
+	t.Parallel()
+
+	var gaps []time.Duration
+	body := &meteredBody{
+		ReadCloser: io.NopCloser(strings.NewReader("data: chunk\n\n")),
+		start:      time.Now().Add(-time.Minute),
+		ttft:       time.Second,
+		last:       time.Now().Add(-streamStallThreshold - time.Second),
+		done:       func(*meteredBody, ErrorClass, error) {},
+		stalled:    func(gap time.Duration) { gaps = append(gaps, gap) },
+	}
+	if _, err := io.ReadAll(body); err != nil {
+		t.Fatal(err)
+	}
+	if len(gaps) != 1 || gaps[0] <= streamStallThreshold {
+		t.Errorf("Expected one stall longer than %s, got %v", streamStallThreshold, gaps)
+	}
+}
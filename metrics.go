@@ -0,0 +1,302 @@
+package openailb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/sony/gobreaker/v2"
+)
+
+// MetricsSink receives the LB's measurements (WithMetrics), e.g. to export them
+// to Prometheus with the promlb package. Implementations must be safe for
+// concurrent use and should return quickly: they are called on the request path.
+type MetricsSink interface {
+	// RequestStarted is called when a request is sent to a backend.
+	RequestStarted(backend string, svc ServiceType, model string)
+	// RequestDone is called once that request finished.
+	RequestDone(m RequestMetrics)
+	// BreakerStateChanged is called on every breaker transition.
+	BreakerStateChanged(backend, breaker string, from, to gobreaker.State)
+}
+
+// RequestMetrics describes one finished backend request.
+type RequestMetrics struct {
+	Backend string
+	Service ServiceType
+	// Model is the model sent to the backend, after model mapping. It is empty
+	// for requests that don't name one, like polling a background response.
+	Model    string
+	Duration time.Duration
+	// TTFT is the time to the first streamed chunk; zero for non-streaming requests.
+	TTFT             time.Duration
+	Class            ErrorClass
+	PromptTokens     int64
+	CompletionTokens int64
+	// Labels are the backend's OpenaiClientConfig.Labels, e.g. to break the
+	// metrics down by provider.
+	Labels map[string]string
+}
+
+// ErrorClass groups request outcomes for metrics.
+type ErrorClass string
+
+const (
+	ClassOK       ErrorClass = "ok"
+	ClassCanceled ErrorClass = "canceled"
+	ClassTimeout  ErrorClass = "timeout"
+	ClassNetwork  ErrorClass = "network"
+	// ClassBreakerOpen is a request the backend's breaker turned away.
+	ClassBreakerOpen ErrorClass = "breaker_open"
+	ClassRateLimited ErrorClass = "rate_limited"
+	ClassAuth        ErrorClass = "auth"
+	ClassClient      ErrorClass = "client_error" // Other 4xx.
+	ClassServer      ErrorClass = "server_error" // 5xx.
+	// ClassRejected is a response without an error that WithIsSuccessful
+	// classified as a failure.
+	ClassRejected ErrorClass = "rejected"
+	ClassOther    ErrorClass = "other"
+)
+
+// classify returns the class of a request outcome.
+func classify(err error, success bool) ErrorClass {
+	if err == nil {
+		if success {
+			return ClassOK
+		}
+		return ClassRejected
+	}
+	var apiErr *openai.Error
+	var netErr net.Error
+	switch {
+	case errors.Is(err, gobreaker.ErrOpenState), errors.Is(err, gobreaker.ErrTooManyRequests):
+		return ClassBreakerOpen
+	case errors.Is(err, context.Canceled):
+		return ClassCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ClassTimeout
+	case errors.As(err, &apiErr):
+		return classifyStatus(apiErr.StatusCode)
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return ClassTimeout
+		}
+		return ClassNetwork
+	}
+	return ClassOther
+}
+
+// classifyStatus returns the class of an HTTP status code.
+func classifyStatus(code int) ErrorClass {
+	switch {
+	case code < 400:
+		return ClassOK
+	case code == http.StatusTooManyRequests:
+		return ClassRateLimited
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return ClassAuth
+	case code < 500:
+		return ClassClient
+	default:
+		return ClassServer
+	}
+}
+
+// usageOf returns the token usage reported in a response, if it has any.
+func usageOf(res any) (prompt, completion int64) {
+	switch r := res.(type) {
+	case *openai.ChatCompletion:
+		if r != nil {
+			return r.Usage.PromptTokens, r.Usage.CompletionTokens
+		}
+	case *openai.CreateEmbeddingResponse:
+		if r != nil {
+			return r.Usage.PromptTokens, 0
+		}
+	case *responses.Response:
+		if r != nil {
+			return r.Usage.InputTokens, r.Usage.OutputTokens
+		}
+	}
+	return 0, 0
+}
+
+// started reports an attempt sent to its backend to the stats, the metrics
+// sink and the hooks.
+func (lb *LoadBalancer) started(a attempt) {
+	a.client.stats.started()
+	if sink := lb.options.metrics; sink != nil {
+		sink.RequestStarted(a.client.Name, a.breaker.key.svc, a.model)
+	}
+	lb.hookRequest(a)
+}
+
+// finished prices an attempt passed to started, and reports how it ended.
+func (lb *LoadBalancer) finished(a attempt, r *ResponseInfo) {
+	r.Cost = a.client.priceOf(r.MappedModel).cost(r.PromptTokens, r.CompletionTokens)
+	a.client.setRateLimits(r.RateLimits)
+	now := time.Now()
+	a.client.stats.finished(now, *r)
+	a.client.settleTokens(now, a, *r)
+	a.client.adapt(now, *r)
+	lb.spend(now, a.client, r.Cost)
+	lb.checkAlerts(now, *r)
+	a.client.usage.record(now, *r)
+	if sink := lb.options.metrics; sink != nil {
+		sink.RequestDone(RequestMetrics{
+			Backend:          r.Backend,
+			Service:          r.Service,
+			Model:            r.MappedModel,
+			Duration:         r.Latency,
+			TTFT:             r.TTFT,
+			Class:            r.Class,
+			PromptTokens:     r.PromptTokens,
+			CompletionTokens: r.CompletionTokens,
+			Labels:           r.Labels,
+		})
+	}
+	lb.hookDone(*r)
+}
+
+// instrumentStream measures and traces a streaming attempt, which it reports
+// as started: the stream counts as done once its body is drained or closed,
+// its first chunk gives the TTFT, and its usage chunk (with include_usage) the
+// tokens. The returned context carries the stream's span. release is called
+// once the body is done.
+//
+// The SDK may retry the request before a body arrives; the attempt is still
+// reported once, ending either with the body or with failed, to be called with
+// the error of a stream that got none.
+func (lb *LoadBalancer) instrumentStream(ctx context.Context, a attempt, release func()) (_ context.Context, _ option.RequestOption, failed func(error)) {
+	ctx, capture := lb.withCapture(ctx)
+	ctx, span := lb.startSpan(ctx, a)
+	c, model := a.client, a.model
+	lb.started(a)
+	start := time.Now()
+	var once sync.Once
+	done := func(b *meteredBody, class ErrorClass, err error) {
+		once.Do(func() {
+			r := ResponseInfo{RequestInfo: a.info(), Latency: time.Since(start), Class: class, Err: err, RateLimits: capture.rateLimits}
+			if b != nil {
+				r.TTFT, r.PromptTokens, r.CompletionTokens = b.ttft, b.promptTokens, b.completionTokens
+			}
+			endSpan(span, nil, err, class)
+			lb.finished(a, &r)
+			lb.audit(ctx, capture, r, nil)
+		})
+	}
+	failed = func(err error) {
+		done(nil, classify(err, false), err)
+	}
+	return ctx, option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		res, err := next(req)
+		if err != nil {
+			c.recordError(err, classify(err, false))
+			return res, err
+		}
+		if res.StatusCode >= 400 {
+			c.recordError(errors.New("stream request failed: "+res.Status), classifyStatus(res.StatusCode))
+			return res, err
+		}
+		res.Body = &meteredBody{ReadCloser: res.Body, start: start, done: done, release: release, stalled: func(gap time.Duration) {
+			lb.options.logger.WarnContext(ctx, "stream stalled", "backend", c.Name, "model", model, "gap", gap)
+		}}
+		return res, nil
+	}), failed
+}
+
+// meteredBody reports when a streamed body delivered its first bytes, the
+// usage it announced, and when it ended.
+type meteredBody struct {
+	io.ReadCloser
+	start time.Time
+	ttft  time.Duration
+	last  time.Time // When the last bytes arrived.
+	line  []byte    // The incomplete SSE line read so far.
+	once  sync.Once
+	done  func(b *meteredBody, class ErrorClass, err error)
+	// release, if set, is called after done, e.g. to cancel the stream's timeout.
+	release func()
+
+	promptTokens     int64
+	completionTokens int64
+	// stalled is called when chunks arrive more than streamStallThreshold apart.
+	stalled func(gap time.Duration)
+}
+
+func (b *meteredBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		now := time.Now()
+		if b.ttft == 0 {
+			b.ttft = now.Sub(b.start)
+		} else if gap := now.Sub(b.last); gap > streamStallThreshold && b.stalled != nil {
+			b.stalled(gap)
+		}
+		b.last = now
+		b.scan(p[:n])
+	}
+	switch {
+	case err == io.EOF:
+		b.finish(ClassOK, nil)
+	case err != nil:
+		b.finish(classify(err, false), err)
+	}
+	return n, err
+}
+
+func (b *meteredBody) Close() error {
+	// A stream closed early by the caller still delivered what it was asked for.
+	b.finish(ClassOK, nil)
+	return b.ReadCloser.Close()
+}
+
+func (b *meteredBody) finish(class ErrorClass, err error) {
+	b.once.Do(func() {
+		b.done(b, class, err)
+		if b.release != nil {
+			b.release()
+		}
+	})
+}
+
+// scan looks for the usage chunk in the lines of p.
+func (b *meteredBody) scan(p []byte) {
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			b.line = append(b.line, p...)
+			return
+		}
+		b.line = append(b.line, p[:i]...)
+		b.parseLine(b.line)
+		b.line = b.line[:0]
+		p = p[i+1:]
+	}
+}
+
+// parseLine reads the token usage from an SSE data line that carries it.
+func (b *meteredBody) parseLine(line []byte) {
+	data, ok := bytes.CutPrefix(line, []byte("data:"))
+	if !ok || !bytes.Contains(data, []byte(`"usage"`)) {
+		return
+	}
+	var chunk struct {
+		Usage *struct {
+			PromptTokens     int64 `json:"prompt_tokens"`
+			CompletionTokens int64 `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	if json.Unmarshal(bytes.TrimSpace(data), &chunk) == nil && chunk.Usage != nil {
+		b.promptTokens, b.completionTokens = chunk.Usage.PromptTokens, chunk.Usage.CompletionTokens
+	}
+}
@@ -0,0 +1,100 @@
+package openailb
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/sony/gobreaker/v2"
+)
+
+// recordingSink is a MetricsSink that keeps everything it is told.
+type recordingSink struct {
+	mu       sync.Mutex
+	inFlight int
+	done     []RequestMetrics
+	breakers []string
+}
+
+func (s *recordingSink) RequestStarted(backend string, svc ServiceType, model string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlight++
+}
+
+func (s *recordingSink) RequestDone(m RequestMetrics) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlight--
+	s.done = append(s.done, m)
+}
+
+func (s *recordingSink) BreakerStateChanged(backend, breaker string, from, to gobreaker.State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.breakers = append(s.breakers, breaker+" "+to.String())
+}
+
+func TestMetricsSink(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), `"stream":true`) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: {\"choices\": [{\"delta\": {\"content\": \"Hi\"}}]}\n\ndata: [DONE]\n\n"))
+			return
+		}
+		if strings.Contains(string(body), `"model":"bad"`) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "Hello"}}], "usage": {"prompt_tokens": 5, "completion_tokens": 2}}`))
+	}))
+	defer server.Close()
+
+	sink := &recordingSink{}
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL, ModelMap: map[string]string{"m": "mapped"}}},
+		WithCBSettings(tripAfter(1)), WithMetrics(sink))
+
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m")); err != nil {
+		t.Fatal(err)
+	}
+	stream := client.Chat.Completions.NewStreaming(context.Background(), openai.ChatCompletionNewParams{
+		Model:    "m",
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("test")},
+	})
+	for stream.Next() {
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	stream.Close()
+	_, _ = client.Chat.Completions.New(context.Background(), chatParams("bad"), option.WithMaxRetries(0))
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.done) != 3 || sink.inFlight != 0 {
+		t.Fatalf("Expected 3 finished requests and none in flight, got %d and %d", len(sink.done), sink.inFlight)
+	}
+	if m := sink.done[0]; m.Backend != "Client-0" || m.Model != "mapped" || m.Class != ClassOK ||
+		m.PromptTokens != 5 || m.CompletionTokens != 2 || m.Duration <= 0 {
+		t.Errorf("Unexpected completion metrics: %+v", m)
+	}
+	if m := sink.done[1]; m.Class != ClassOK || m.TTFT <= 0 || m.Model != "mapped" {
+		t.Errorf("Unexpected stream metrics: %+v", m)
+	}
+	if m := sink.done[2]; m.Class != ClassRateLimited {
+		t.Errorf("Expected the 429 to be classified as rate_limited, got %s", m.Class)
+	}
+	if len(sink.breakers) != 1 || sink.breakers[0] != "Client-0/chat open" {
+		t.Errorf("Expected the breaker to be reported open, got %v", sink.breakers)
+	}
+}
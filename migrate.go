@@ -0,0 +1,215 @@
+package openailb
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Migration configures Client.MigrateWeight.
+type Migration struct {
+	// Duration is how long the shift takes, e.g. 2h.
+	Duration time.Duration
+	// Steps is how many even increments the weight moves in (default 20).
+	Steps int
+	// MaxErrorRate, if set, is the highest error rate (0-1) of the target
+	// backend over a step; above it the migration pauses, or rolls back with
+	// Rollback.
+	MaxErrorRate float64
+	// MinRequests is how many requests the target must have served during
+	// a step for its error rate to count (default 20).
+	MinRequests int64
+	Rollback    bool
+}
+
+// MigrationState is the state of a WeightMigration.
+type MigrationState string
+
+const (
+	MigrationRunning    MigrationState = "running"
+	MigrationPaused     MigrationState = "paused"
+	MigrationDone       MigrationState = "done"
+	MigrationRolledBack MigrationState = "rolled_back"
+	MigrationCanceled   MigrationState = "canceled" // The client was closed.
+)
+
+// WeightMigration is a migration started by Client.MigrateWeight.
+type WeightMigration struct {
+	lb       *LoadBalancer
+	from, to *SafeClient
+	total    float64 // The combined weight of both backends, kept throughout.
+	start    float64 // The target's share when the migration started.
+	cfg      Migration
+	done     chan struct{}
+
+	mu    sync.Mutex
+	step  int
+	state MigrationState
+	err   error
+}
+
+// MigrateWeight shifts traffic from the backend called from to the one
+// called to in the background, for a safe provider migration: over
+// m.Duration, the target's share of the two backends' combined weight grows
+// in m.Steps even increments from its current share to all of it. With
+// m.MaxErrorRate, a step in which the target fails too often pauses the
+// migration, or moves all the weight back to from with m.Rollback.
+func (c Client) MigrateWeight(from, to string, m Migration) (*WeightMigration, error) {
+	if from == to {
+		return nil, errors.New("the backends to migrate between must differ")
+	}
+	if m.Duration <= 0 {
+		return nil, fmt.Errorf("duration: must be positive, got %s", m.Duration)
+	}
+	if m.MaxErrorRate < 0 || m.MaxErrorRate > 1 {
+		return nil, fmt.Errorf("max error rate: must be in [0, 1], got %v", m.MaxErrorRate)
+	}
+	if m.Steps <= 0 {
+		m.Steps = 20
+	}
+	if m.MinRequests <= 0 {
+		m.MinRequests = 20
+	}
+	src, err := c.lb.clientByName(from)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := c.lb.clientByName(to)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &WeightMigration{lb: c.lb, from: src, to: dst, cfg: m, done: make(chan struct{}), state: MigrationRunning}
+	w.total = src.weight.Load() + dst.weight.Load()
+	if w.total > 0 {
+		w.start = dst.weight.Load() / w.total
+	} else {
+		w.total = 1
+	}
+	c.lb.options.logger.Info("weight migration started", "from", from, "to", to, "duration", m.Duration, "steps", m.Steps)
+	c.lb.background.Add(1)
+	go func() {
+		defer c.lb.background.Done()
+		w.run()
+	}()
+	return w, nil
+}
+
+// run advances the migration every step until it ends or the client is closed.
+func (w *WeightMigration) run() {
+	ticker := time.NewTicker(w.cfg.Duration / time.Duration(w.cfg.Steps))
+	defer ticker.Stop()
+	requests, failures := w.to.stats.totals()
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.done:
+			return
+		case <-w.lb.done.Done():
+			w.mu.Lock()
+			w.end(MigrationCanceled, nil)
+			w.mu.Unlock()
+			return
+		}
+		// Judge the target on the requests of the last step only.
+		prevRequests, prevFailures := requests, failures
+		requests, failures = w.to.stats.totals()
+		served, failed := requests-prevRequests, failures-prevFailures
+
+		w.mu.Lock()
+		if w.state != MigrationRunning {
+			w.mu.Unlock()
+			continue
+		}
+		if rate := float64(failed) / float64(max(served, 1)); w.cfg.MaxErrorRate > 0 && served >= w.cfg.MinRequests && rate > w.cfg.MaxErrorRate {
+			err := fmt.Errorf("%s: error rate %.2f over the last step is above %.2f", w.to.Name, rate, w.cfg.MaxErrorRate)
+			if w.cfg.Rollback {
+				w.apply(0)
+				w.end(MigrationRolledBack, err)
+				w.mu.Unlock()
+				w.lb.options.logger.Warn("weight migration rolled back", "from", w.from.Name, "to", w.to.Name, "error", err)
+				return
+			}
+			w.state, w.err = MigrationPaused, err
+			w.mu.Unlock()
+			w.lb.options.logger.Warn("weight migration paused", "from", w.from.Name, "to", w.to.Name, "error", err)
+			continue
+		}
+		w.step++
+		w.apply(w.start + (1-w.start)*float64(w.step)/float64(w.cfg.Steps))
+		if w.step < w.cfg.Steps {
+			w.mu.Unlock()
+			continue
+		}
+		w.end(MigrationDone, nil)
+		w.mu.Unlock()
+		w.lb.options.logger.Info("weight migration done", "from", w.from.Name, "to", w.to.Name)
+		return
+	}
+}
+
+// apply gives the target share of the combined weight to the target. The
+// caller holds w.mu.
+func (w *WeightMigration) apply(share float64) {
+	w.from.weight.Store(w.total * (1 - share))
+	w.to.weight.Store(w.total * share)
+}
+
+// end ends the migration in state, unless it ended already. The caller holds w.mu.
+func (w *WeightMigration) end(state MigrationState, err error) {
+	select {
+	case <-w.done:
+	default:
+		w.state, w.err = state, err
+		close(w.done)
+	}
+}
+
+// Pause holds the weights where they are until Resume.
+func (w *WeightMigration) Pause() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == MigrationRunning {
+		w.state = MigrationPaused
+	}
+}
+
+// Resume continues a migration paused by Pause or by the target's error rate.
+func (w *WeightMigration) Resume() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == MigrationPaused {
+		w.state, w.err = MigrationRunning, nil
+	}
+}
+
+// Rollback ends the migration, moving all the weight back to the source. It
+// does nothing once the migration ended.
+func (w *WeightMigration) Rollback() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == MigrationRunning || w.state == MigrationPaused {
+		w.apply(0)
+		w.end(MigrationRolledBack, nil)
+		w.lb.options.logger.Info("weight migration rolled back", "from", w.from.Name, "to", w.to.Name)
+	}
+}
+
+// State returns the state of the migration, and why it was paused or rolled
+// back by the target's error rate, if it was.
+func (w *WeightMigration) State() (MigrationState, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.state, w.err
+}
+
+// Progress returns the target's share of the combined weight, from 0 to 1.
+func (w *WeightMigration) Progress() float64 {
+	return w.to.weight.Load() / w.total
+}
+
+// Done is closed once the migration is done, rolled back or canceled.
+func (w *WeightMigration) Done() <-chan struct{} {
+	return w.done
+}
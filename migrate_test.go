@@ -0,0 +1,110 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestMigrateWeight(t *testing.T) {
+	t.Parallel()
+
+	a, b := newNamedServer(t, "a"), newNamedServer(t, "b")
+	defer a.Close()
+	defer b.Close()
+	client := NewClient([]OpenaiClientConfig{
+		{Name: "a", APIKey: "k1", BaseURL: a.URL, Weight: 3},
+		{Name: "b", APIKey: "k2", BaseURL: b.URL, Weight: 1},
+	})
+
+	m, err := client.MigrateWeight("a", "b", Migration{Duration: 100 * time.Millisecond, Steps: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := m.Progress(); p != 0.25 {
+		t.Errorf("Expected the migration to start from b's share, got %v", p)
+	}
+	select {
+	case <-m.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the migration to be done")
+	}
+	if state, err := m.State(); state != MigrationDone || err != nil {
+		t.Errorf("Expected the migration to be done, got %s %v", state, err)
+	}
+	if hits := countHits(t, client, 4); hits["b"] != 4 {
+		t.Errorf("Expected all the traffic on b, got %v", hits)
+	}
+	if w := client.lb.backends()[1].weight.Load(); w != 4 {
+		t.Errorf("Expected b to get the combined weight, got %v", w)
+	}
+
+	if _, err := client.MigrateWeight("a", "a", Migration{Duration: time.Second}); err == nil {
+		t.Error("Expected a migration to the same backend to be refused")
+	}
+	if _, err := client.MigrateWeight("a", "nope", Migration{Duration: time.Second}); err == nil || err.Error() != `no backend named "nope"` {
+		t.Errorf("Expected an unknown backend to be reported, got %v", err)
+	}
+}
+
+func TestMigrateWeightErrorRate(t *testing.T) {
+	t.Parallel()
+
+	a := newNamedServer(t, "a")
+	defer a.Close()
+	failServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failServer.Close()
+
+	for _, rollback := range []bool{false, true} {
+		client := NewClient([]OpenaiClientConfig{
+			{Name: "a", APIKey: "k1", BaseURL: a.URL},
+			{Name: "b", APIKey: "k2", BaseURL: failServer.URL},
+		}, WithCBSettings(tripAfter(100)))
+		m, err := client.MigrateWeight("a", "b", Migration{Duration: 400 * time.Millisecond, Steps: 2, MaxErrorRate: 0.5, MinRequests: 2, Rollback: rollback})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 4; i++ {
+			_, _ = client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+		}
+
+		if rollback {
+			select {
+			case <-m.Done():
+			case <-time.After(2 * time.Second):
+				t.Fatal("Expected the migration to be rolled back")
+			}
+			if state, err := m.State(); state != MigrationRolledBack || err == nil || !strings.Contains(err.Error(), "b: error rate 1.00") {
+				t.Errorf("Expected the failing target to roll the migration back, got %s %v", state, err)
+			}
+			if p := m.Progress(); p != 0 {
+				t.Errorf("Expected the weight back on a, got a share of %v on b", p)
+			}
+			continue
+		}
+		waitFor(t, 2*time.Second, func() bool {
+			state, _ := m.State()
+			return state == MigrationPaused
+		})
+		if _, err := m.State(); err == nil {
+			t.Error("Expected the pause to be explained")
+		}
+		if p := m.Progress(); p != 0.5 {
+			t.Errorf("Expected the paused migration to hold the weights, got %v", p)
+		}
+		m.Rollback()
+		if state, err := m.State(); state != MigrationRolledBack || err != nil || m.Progress() != 0 {
+			t.Errorf("Expected the caller to roll the migration back, got %s %v", state, err)
+		}
+		if err := client.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
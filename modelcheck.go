@@ -0,0 +1,126 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+// MissingModelsError is the ValidationResult.Err of a backend whose /models
+// list lacks models it is mapped to (Client.ValidateModels).
+type MissingModelsError struct {
+	Models []string
+}
+
+func (e *MissingModelsError) Error() string {
+	return fmt.Sprintf("mapped models missing from /models: %s", strings.Join(e.Models, ", "))
+}
+
+// ValidateModels checks in parallel that every backend lists, in its
+// /models, the models its ModelMap and the WithModelAlias aliases map to,
+// catching typos like "gpt-4o-mnii" before traffic does. Targets built from
+// regular expression submatches can't be checked and are skipped, as are
+// the backends mapping nothing. The error is a *ValidationError if any
+// backend failed, with a *MissingModelsError as the Err of those that
+// answered but lack models. Servers that don't list what they serve, like
+// Azure OpenAI deployments, fail it by design.
+func (c Client) ValidateModels(ctx context.Context) ([]ValidationResult, error) {
+	return c.lb.validateEach(ctx, (*SafeClient).checkModels)
+}
+
+// WithModelValidation runs Client.ValidateModels against the backends in the
+// background when the client is built and reports each backend missing
+// models with a HealthModelsMissing event, without taking it out of
+// rotation; backends whose list can't be fetched are logged.
+func WithModelValidation() LBOption {
+	return func(o *lbOptions) {
+		o.validateModels = true
+	}
+}
+
+// validateModelsInBackground checks the models of the initial backends, if
+// WithModelValidation is set.
+func (lb *LoadBalancer) validateModelsInBackground() {
+	if !lb.options.validateModels {
+		return
+	}
+	for _, c := range lb.backends() {
+		lb.background.Add(1)
+		go func(c *SafeClient) {
+			defer lb.background.Done()
+			ctx, cancel := context.WithTimeout(lb.done, c.healthCheck.Timeout)
+			defer cancel()
+			var missing *MissingModelsError
+			switch err := c.checkModels(ctx); {
+			case errors.As(err, &missing):
+				c.emitHealth(HealthEvent{Kind: HealthModelsMissing, Reason: err.Error()})
+			case err != nil && lb.done.Err() == nil:
+				lb.options.logger.Warn("model validation failed", "backend", c.Name, "error", err)
+			}
+		}(c)
+	}
+}
+
+// checkModels reports the models the backend is mapped to that its /models
+// list lacks.
+func (c *SafeClient) checkModels(ctx context.Context) error {
+	targets := c.mappedModels()
+	if len(targets) == 0 {
+		return nil
+	}
+	page, err := c.OpenAIClient().Models.List(ctx, option.WithMaxRetries(0))
+	if err != nil {
+		return fmt.Errorf("list models: %w", err)
+	}
+	listed := make(map[string]bool, len(page.Data))
+	for _, m := range page.Data {
+		listed[m.ID] = true
+	}
+	var missing []string
+	for _, model := range targets {
+		if !listed[model] {
+			missing = append(missing, model)
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingModelsError{Models: missing}
+	}
+	return nil
+}
+
+// mappedModels returns the models the backend's ModelMap and aliases map
+// to, sorted, except those of regular expressions with submatch references.
+func (c *SafeClient) mappedModels() []string {
+	set := make(map[string]bool)
+	if m := c.models; m != nil {
+		for _, to := range m.exact {
+			set[to] = true
+		}
+		for _, p := range m.prefixes {
+			set[p.target] = true
+		}
+		for _, p := range m.patterns {
+			if !strings.Contains(p.target, "$") {
+				set[p.target] = true
+			}
+		}
+		if m.fallback != nil {
+			set[*m.fallback] = true
+		}
+	}
+	for _, models := range c.lb.options.aliases {
+		if to := models[c.Name]; to != "" {
+			set[to] = true
+		}
+	}
+	targets := make([]string, 0, len(set))
+	for model := range set {
+		targets = append(targets, model)
+	}
+	sort.Strings(targets)
+	return targets
+}
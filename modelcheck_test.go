@@ -0,0 +1,55 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateModels(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object": "list", "data": [{"id": "gpt-4o-2024-08-06"}, {"id": "llama-3.1-8b"}]}`))
+	}))
+	defer server.Close()
+	events := make(chan HealthEvent, 4)
+	client := NewClient([]OpenaiClientConfig{
+		{Name: "openai", APIKey: "k1", BaseURL: server.URL, ModelMap: map[string]string{
+			"gpt-4o":      "gpt-4o-2024-08-06",
+			"gpt-4o-mini": "gpt-4o-mnii",
+			"/^x-(.+)$/":  "y-$1",
+		}},
+		{Name: "vllm", APIKey: "k2", BaseURL: server.URL},
+	}, WithModelAlias("fast-chat", map[string]string{"vllm": "llama-3.1-8b"}),
+		WithModelValidation(), WithOnHealthChange(func(ev HealthEvent) { events <- ev }))
+	defer client.Close(context.Background())
+
+	results, err := client.ValidateModels(context.Background())
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a *ValidationError, got %v", err)
+	}
+	var missing *MissingModelsError
+	if !errors.As(results[0].Err, &missing) || !slices.Equal(missing.Models, []string{"gpt-4o-mnii"}) {
+		t.Errorf("Expected the typo to be reported as missing, got %v", results[0].Err)
+	}
+	if results[1].Err != nil {
+		t.Errorf("Expected the alias target to be listed, got %v", results[1].Err)
+	}
+
+	select {
+	case ev := <-events:
+		if ev.Kind != HealthModelsMissing || ev.Backend != "openai" || !strings.Contains(ev.Reason, "gpt-4o-mnii") {
+			t.Errorf("Expected a models_missing event for openai, got %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the startup validation to report the missing model")
+	}
+}
@@ -0,0 +1,111 @@
+package openailb
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// modelMapper resolves the requested models through a ModelMap, whose keys
+// are exact names, prefixes ending in "*" such as "gpt-4*", or regular
+// expressions between slashes such as "/^gpt-4o-(.+)$/", whose target may
+// refer to their submatches as $1 or ${name}; "*" alone maps every other
+// model.
+type modelMapper struct {
+	exact    map[string]string
+	prefixes []modelPrefix  // Longest first.
+	patterns []modelPattern // In key order.
+	fallback *string        // The target of "*".
+}
+
+type modelPrefix struct {
+	prefix, target string
+}
+
+type modelPattern struct {
+	re     *regexp.Regexp
+	target string
+}
+
+// newModelMapper compiles m, skipping invalid regular expressions, which
+// ValidateConfigs reports.
+func newModelMapper(m map[string]string) *modelMapper {
+	mapper := &modelMapper{exact: make(map[string]string)}
+	for _, from := range sortedKeys(m) {
+		to := m[from]
+		if from == "*" {
+			mapper.fallback = &to
+			continue
+		}
+		if re, ok, err := modelRegexp(from); ok {
+			if err == nil {
+				mapper.patterns = append(mapper.patterns, modelPattern{re: re, target: to})
+			}
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(from, "*"); ok {
+			mapper.prefixes = append(mapper.prefixes, modelPrefix{prefix: prefix, target: to})
+			continue
+		}
+		mapper.exact[from] = to
+	}
+	slices.SortStableFunc(mapper.prefixes, func(a, b modelPrefix) int {
+		return len(b.prefix) - len(a.prefix)
+	})
+	return mapper
+}
+
+// modelRegexp compiles the ModelMap key from if it is a regular expression,
+// reporting whether it is one.
+func modelRegexp(from string) (*regexp.Regexp, bool, error) {
+	pattern, ok := strings.CutPrefix(from, "/")
+	if !ok || len(pattern) == 0 || !strings.HasSuffix(pattern, "/") {
+		return nil, false, nil
+	}
+	re, err := regexp.Compile(strings.TrimSuffix(pattern, "/"))
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid regular expression %q: %w", from, err)
+	}
+	return re, true, nil
+}
+
+// lookup returns the model name model maps to: by its exact name first, then
+// by the longest matching prefix, then by the first matching regular
+// expression, in the order of their keys, and last by "*".
+func (m *modelMapper) lookup(model string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	if to, ok := m.exact[model]; ok {
+		return to, true
+	}
+	for _, p := range m.prefixes {
+		if strings.HasPrefix(model, p.prefix) {
+			return p.target, true
+		}
+	}
+	for _, p := range m.patterns {
+		if match := p.re.FindStringSubmatchIndex(model); match != nil {
+			return string(p.re.ExpandString(nil, p.target, model, match)), true
+		}
+	}
+	if m.fallback != nil {
+		return *m.fallback, true
+	}
+	return "", false
+}
+
+// validateModelMap checks the keys and targets of m.
+func validateModelMap(m map[string]string) []error {
+	var errs []error
+	for _, from := range sortedKeys(m) {
+		if from == "" || m[from] == "" {
+			errs = append(errs, fmt.Errorf("has an empty model name in %q: %q", from, m[from]))
+		}
+		if _, _, err := modelRegexp(from); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,109 @@
+package openailb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newModelEchoServer answers chat completions with the model it was sent as
+// the content.
+func newModelEchoServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"model":   body.Model,
+			"choices": []map[string]any{{"message": map[string]any{"content": body.Model}}},
+		})
+	}))
+}
+
+func TestModelMapPatterns(t *testing.T) {
+	t.Parallel()
+
+	mapper := newModelMapper(map[string]string{
+		"gpt-4o":                            "exact",
+		"gpt-4*":                            "gpt-4-deployment",
+		"gpt-4o-mini*":                      "mini-deployment",
+		"/^gpt-4o-(\\d{4}-\\d{2}-\\d{2})$/": "azure-gpt-4o-$1",
+		"/^claude-(?P<tier>\\w+)$/":         "anthropic-${tier}",
+		"/[/":                               "invalid",
+	})
+	for model, want := range map[string]string{
+		"gpt-4o":            "exact",
+		"gpt-4o-mini-2024":  "mini-deployment",
+		"gpt-4-turbo":       "gpt-4-deployment",
+		"gpt-4o-2024-08-06": "gpt-4-deployment",
+		"claude-haiku":      "anthropic-haiku",
+		"llama-3.1-8b":      "",
+		"/[/":               "",
+	} {
+		if got, _ := mapper.lookup(model); got != want {
+			t.Errorf("Expected %s to map to %q, got %q", model, want, got)
+		}
+	}
+
+	regexOnly := newModelMapper(map[string]string{"/^gpt-4o-(\\d{4}-\\d{2}-\\d{2})$/": "azure-gpt-4o-$1"})
+	if got, _ := regexOnly.lookup("gpt-4o-2024-08-06"); got != "azure-gpt-4o-2024-08-06" {
+		t.Errorf("Expected the submatch in the target, got %q", got)
+	}
+}
+
+func TestModelMapWildcardRequest(t *testing.T) {
+	t.Parallel()
+
+	server := newModelEchoServer(t)
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL, ModelMap: map[string]string{"gpt-4*": "deployment"}}})
+
+	resp, err := client.Chat.Completions.New(context.Background(), chatParams("gpt-4o-2024-08-06"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Choices[0].Message.Content; got != "deployment" {
+		t.Errorf("Expected the wildcard to map the model, got %q", got)
+	}
+
+	err = ValidateConfigs([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL, ModelMap: map[string]string{"/gpt-(/": "x"}}})
+	if err == nil || !strings.Contains(err.Error(), "ModelMap: invalid regular expression") {
+		t.Errorf("Expected the invalid pattern to be reported, got %v", err)
+	}
+}
+
+func TestModelMapCatchAll(t *testing.T) {
+	t.Parallel()
+
+	mapper := newModelMapper(map[string]string{
+		"*":          "llama-3.1-8b",
+		"gpt-4*":     "gpt-4-deployment",
+		"/^claude-/": "anthropic",
+	})
+	for model, want := range map[string]string{
+		"gpt-4o":       "gpt-4-deployment",
+		"claude-haiku": "anthropic",
+		"mistral":      "llama-3.1-8b",
+	} {
+		if got, _ := mapper.lookup(model); got != want {
+			t.Errorf("Expected %s to map to %q, got %q", model, want, got)
+		}
+	}
+
+	server := newModelEchoServer(t)
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL, ModelMap: map[string]string{"*": "only-model"}}})
+	resp, err := client.Chat.Completions.New(context.Background(), chatParams("gpt-4o"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Choices[0].Message.Content; got != "only-model" {
+		t.Errorf("Expected the catch-all to map the model, got %q", got)
+	}
+}
@@ -0,0 +1,162 @@
+package openailb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+// WithResponseModelRewrite reports the requested model, rather than the one
+// a ModelMap sent to the backend, in responses and streamed chunks, so a
+// deployment name like "my-azure-deployment" doesn't leak to callers and
+// the transcripts they store. Only the top-level "model" fields naming the
+// model sent are rewritten, in the raw JSON too.
+func WithResponseModelRewrite() LBOption {
+	return func(o *lbOptions) {
+		o.rewriteModels = true
+	}
+}
+
+type modelRewriteKey struct{}
+
+// withModelRewrite returns a context in which rewriteModel reverts the model
+// mapping of a, with WithResponseModelRewrite.
+func (lb *LoadBalancer) withModelRewrite(ctx context.Context, a attempt) context.Context {
+	if !lb.options.rewriteModels || a.requested == "" || a.requested == a.model {
+		return ctx
+	}
+	return context.WithValue(ctx, modelRewriteKey{}, newModelRewriter(a.model, a.requested))
+}
+
+// modelRewriter replaces a model name in the top-level "model" field of JSON.
+type modelRewriter struct {
+	from, to []byte // Quoted as JSON strings.
+}
+
+func newModelRewriter(from, to string) *modelRewriter {
+	quotedFrom, _ := json.Marshal(from)
+	quotedTo, _ := json.Marshal(to)
+	return &modelRewriter{from: quotedFrom, to: quotedTo}
+}
+
+// scanner returns the function rewriting the lines of one body in turn.
+func (m *modelRewriter) scanner() func([]byte) []byte {
+	s := &modelScanner{modelRewriter: m}
+	return s.rewrite
+}
+
+// modelScanner follows the nesting of a body across its lines, which split
+// pretty-printed JSON, so that "model" keys of nested objects, like those of
+// tool call arguments or metadata, are left alone.
+type modelScanner struct {
+	*modelRewriter
+	depth             int
+	inString, escaped bool
+}
+
+func (s *modelScanner) rewrite(line []byte) []byte {
+	var out []byte
+	copied := 0
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if s.inString {
+			switch {
+			case s.escaped:
+				s.escaped = false
+			case c == '\\':
+				s.escaped = true
+			case c == '"':
+				s.inString = false
+			}
+			continue
+		}
+		switch c {
+		case '{', '[':
+			s.depth++
+		case '}', ']':
+			s.depth--
+		case '"':
+			if s.depth == 1 {
+				if start, end, ok := s.modelField(line, i); ok {
+					out = append(out, line[copied:start]...)
+					out = append(out, s.to...)
+					copied = end
+					i = end - 1
+					continue
+				}
+			}
+			s.inString = true
+		}
+	}
+	if out == nil {
+		return line
+	}
+	return append(out, line[copied:]...)
+}
+
+// modelField returns where the value is in line, if the string at i is a
+// "model" key naming the model rewritten.
+func (s *modelScanner) modelField(line []byte, i int) (start, end int, ok bool) {
+	rest, ok := bytes.CutPrefix(line[i:], []byte(`"model"`))
+	if !ok {
+		return 0, 0, false
+	}
+	rest = bytes.TrimLeft(rest, " \t")
+	if rest, ok = bytes.CutPrefix(rest, []byte(":")); !ok {
+		return 0, 0, false
+	}
+	rest = bytes.TrimLeft(rest, " \t")
+	if !bytes.HasPrefix(rest, s.from) {
+		return 0, 0, false
+	}
+	start = len(line) - len(rest)
+	return start, start + len(s.from), true
+}
+
+// rewriteModel is the backend middleware reverting the model mapping in JSON
+// and event stream responses, under withModelRewrite.
+func rewriteModel(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	rewriter, _ := req.Context().Value(modelRewriteKey{}).(*modelRewriter)
+	res, err := next(req)
+	if rewriter == nil || err != nil || res == nil {
+		return res, err
+	}
+	contentType := res.Header.Get("Content-Type")
+	if !strings.Contains(contentType, "json") && !strings.HasPrefix(contentType, "text/event-stream") {
+		return res, nil
+	}
+	res.Body = &rewrittenBody{ReadCloser: res.Body, lines: bufio.NewReader(res.Body), rewrite: rewriter.scanner()}
+	res.ContentLength = -1
+	res.Header.Del("Content-Length")
+	return res, nil
+}
+
+// rewrittenBody rewrites a body line by line as it is read, so streams flow
+// as before.
+type rewrittenBody struct {
+	io.ReadCloser
+	lines   *bufio.Reader
+	rewrite func([]byte) []byte
+	pending []byte
+	err     error
+}
+
+func (b *rewrittenBody) Read(p []byte) (int, error) {
+	for len(b.pending) == 0 {
+		if b.err != nil {
+			return 0, b.err
+		}
+		var line []byte
+		line, b.err = b.lines.ReadBytes('\n')
+		b.pending = b.rewrite(line)
+	}
+	n := copy(p, b.pending)
+	b.pending = b.pending[n:]
+	return n, nil
+}
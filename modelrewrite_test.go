@@ -0,0 +1,72 @@
+package openailb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponseModelRewrite(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model  string `json:"model"`
+			Stream bool   `json:"stream"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, content := range []string{"Hel", "lo"} {
+				fmt.Fprintf(w, "data: {\"choices\": [{\"delta\": {\"content\": %q, \"model\": %q}}], \"model\": %q}\n\n", content, body.Model, body.Model)
+			}
+			_, _ = w.Write([]byte("data: [DONE]\n\n"))
+			return
+		}
+		// Pretty-printed, with a nested "model" that isn't the response's.
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, "{\n  \"metadata\": {\"model\": %q},\n  \"model\": %q,\n  \"choices\": [{\"message\": {\"content\": %q}}]\n}\n", body.Model, body.Model, body.Model)
+	}))
+	defer server.Close()
+	configs := []OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL, ModelMap: map[string]string{"gpt-4o": "my-azure-deployment"}}}
+
+	client := NewClient(configs, WithResponseModelRewrite())
+	resp, err := client.Chat.Completions.New(context.Background(), chatParams("gpt-4o"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Model != "gpt-4o" || !strings.Contains(resp.RawJSON(), `"model": "gpt-4o"`) {
+		t.Errorf("Expected the requested model in the response, got %q in %s", resp.Model, resp.RawJSON())
+	}
+	if !strings.Contains(resp.RawJSON(), `{"model": "my-azure-deployment"}`) {
+		t.Errorf("Expected the nested model to be left alone, got %s", resp.RawJSON())
+	}
+	if got := resp.Choices[0].Message.Content; got != "my-azure-deployment" {
+		t.Errorf("Expected the backend to be sent the mapped model, got %q", got)
+	}
+
+	stream := client.Chat.Completions.NewStreaming(context.Background(), chatParams("gpt-4o"))
+	var content strings.Builder
+	for stream.Next() {
+		chunk := stream.Current()
+		if chunk.Model != "gpt-4o" || !strings.Contains(chunk.RawJSON(), `"content": "`+chunk.Choices[0].Delta.Content+`", "model": "my-azure-deployment"`) {
+			t.Errorf("Expected the requested model in every chunk, and only at its top level, got %s", chunk.RawJSON())
+		}
+		content.WriteString(chunk.Choices[0].Delta.Content)
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if content.String() != "Hello" {
+		t.Errorf("Expected the stream to be intact, got %q", content.String())
+	}
+
+	plain := NewClient(configs)
+	if resp, err := plain.Chat.Completions.New(context.Background(), chatParams("gpt-4o")); err != nil || resp.Model != "my-azure-deployment" {
+		t.Errorf("Expected the backend's model without the option, got %v, %v", resp, err)
+	}
+}
@@ -0,0 +1,158 @@
+package openailb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Notifier configures webhook notifications (WithNotifier) for backends
+// leaving rotation (open breakers, quarantines, ejections, failing health
+// checks) and recovering, and for the UsageAlertEvents of WithUsageAlerts.
+type Notifier struct {
+	// URL receives each notification as a JSON POST.
+	URL string
+	// Slack sends Slack-compatible {"text": ...} payloads instead of the
+	// NotifierEvent JSON, for incoming webhooks of Slack and compatible tools.
+	Slack bool
+	// MinInterval rate-limits the notifications: at most one per backend and
+	// kind of event per interval (default 1m). Notifications dropped in
+	// between are counted in the next one.
+	MinInterval time.Duration
+	// Timeout bounds each POST (default 10s).
+	Timeout time.Duration
+	// Client sends the POSTs (default http.DefaultClient).
+	Client *http.Client
+}
+
+// withDefaults fills in the zero fields.
+func (n Notifier) withDefaults() Notifier {
+	if n.MinInterval <= 0 {
+		n.MinInterval = time.Minute
+	}
+	if n.Timeout <= 0 {
+		n.Timeout = 10 * time.Second
+	}
+	if n.Client == nil {
+		n.Client = http.DefaultClient
+	}
+	return n
+}
+
+// NotifierEvent is the JSON body of a notification.
+type NotifierEvent struct {
+	HealthEvent
+	// Suppressed counts the notifications of the same backend and kind
+	// dropped by the rate limit since the previous one.
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+// notifier sends the notifications of one Client.
+type notifier struct {
+	cfg Notifier
+
+	mu   sync.Mutex
+	sent map[notifierKey]*notifierState
+}
+
+type notifierKey struct {
+	backend string
+	kind    HealthEventKind
+}
+
+type notifierState struct {
+	lastSent   time.Time
+	suppressed int
+}
+
+func newNotifier(cfg Notifier) *notifier {
+	return &notifier{cfg: cfg.withDefaults(), sent: make(map[notifierKey]*notifierState)}
+}
+
+// notifies reports whether ev is worth a notification: a backend leaving
+// rotation or coming back.
+func notifies(ev HealthEvent) bool {
+	switch ev.Kind {
+	case HealthUnhealthy, HealthQuarantined, HealthEjected, HealthRecovered:
+		return true
+	case HealthBreakerStateChange:
+		return ev.To == "open" || ev.To == "closed"
+	}
+	return false
+}
+
+// notify posts ev, unless the rate limit drops it. Errors are ignored: a
+// missing webhook must not affect the LB.
+func (n *notifier) notify(ev HealthEvent) {
+	if !notifies(ev) {
+		return
+	}
+	kind := ev.Kind
+	if kind == HealthBreakerStateChange {
+		kind += HealthEventKind(":" + ev.To)
+	}
+	key := notifierKey{backend: ev.Backend, kind: kind}
+
+	n.mu.Lock()
+	state, ok := n.sent[key]
+	if !ok {
+		state = &notifierState{}
+		n.sent[key] = state
+	}
+	if ev.At.Sub(state.lastSent) < n.cfg.MinInterval {
+		state.suppressed++
+		n.mu.Unlock()
+		return
+	}
+	payload := NotifierEvent{HealthEvent: ev, Suppressed: state.suppressed}
+	state.lastSent, state.suppressed = ev.At, 0
+	n.mu.Unlock()
+	n.post(payload, payload.text())
+}
+
+// alert posts the UsageAlertEvent ev. Alerts fire once per threshold and
+// period, so they aren't rate-limited.
+func (n *notifier) alert(ev UsageAlertEvent) {
+	n.post(ev, fmt.Sprintf("openailb: alert %s: %s at %v of %v (%g%%)", ev.Alert, ev.Metric, ev.Used, ev.Limit, ev.Threshold*100))
+}
+
+// post sends payload, or text in the Slack format.
+func (n *notifier) post(payload any, text string) {
+	body := payload
+	if n.cfg.Slack {
+		body = map[string]string{"text": text}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), n.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.cfg.URL, bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if res, err := n.cfg.Client.Do(req); err == nil {
+		res.Body.Close()
+	}
+}
+
+// text renders the event as a one-line chat message.
+func (e NotifierEvent) text() string {
+	msg := fmt.Sprintf("openailb: backend %s is %s", e.Backend, e.Kind)
+	if e.Kind == HealthBreakerStateChange {
+		msg = fmt.Sprintf("openailb: breaker %s went from %s to %s", e.Breaker, e.From, e.To)
+	}
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	if e.Suppressed > 0 {
+		msg += fmt.Sprintf(" (%d similar notifications suppressed)", e.Suppressed)
+	}
+	return msg
+}
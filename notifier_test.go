@@ -0,0 +1,71 @@
+package openailb
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotifier(t *testing.T) {
+	t.Parallel()
+
+	posts := make(chan []byte, 10)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		posts <- body
+	}))
+	defer webhook.Close()
+	next := func() []byte {
+		t.Helper()
+		select {
+		case body := <-posts:
+			return body
+		case <-time.After(time.Second):
+			t.Fatal("No notification")
+			return nil
+		}
+	}
+
+	backend := newNamedServer(t, "ok")
+	defer backend.Close()
+	configs := []OpenaiClientConfig{{APIKey: "k1", BaseURL: backend.URL}}
+	client := NewClient(configs, WithNotifier(Notifier{URL: webhook.URL, MinInterval: 100 * time.Millisecond}))
+
+	_ = client.MarkUnhealthy("Client-0", "maintenance")
+	var ev NotifierEvent
+	if err := json.Unmarshal(next(), &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Backend != "Client-0" || ev.Kind != HealthUnhealthy || ev.Reason != "maintenance" {
+		t.Errorf("Unexpected notification %+v", ev)
+	}
+
+	// Repeats within MinInterval are dropped, and counted in the next notification.
+	_ = client.MarkUnhealthy("Client-0", "maintenance")
+	_ = client.MarkUnhealthy("Client-0", "maintenance")
+	time.Sleep(150 * time.Millisecond)
+	_ = client.MarkUnhealthy("Client-0", "maintenance")
+	if err := json.Unmarshal(next(), &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Suppressed != 2 {
+		t.Errorf("Expected 2 suppressed notifications, got %+v", ev)
+	}
+
+	// Slack payloads carry a text message.
+	client = NewClient(configs, WithNotifier(Notifier{URL: webhook.URL, Slack: true}))
+	_ = client.MarkUnhealthy("Client-0", "maintenance")
+	var slack struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(next(), &slack); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(slack.Text, "Client-0 is unhealthy: maintenance") {
+		t.Errorf("Unexpected Slack text %q", slack.Text)
+	}
+}
@@ -2,9 +2,15 @@ package openailb
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
@@ -12,46 +18,260 @@ import (
 	"github.com/sony/gobreaker/v2"
 )
 
+// ServiceType identifies an upstream API family. Breaker state is kept per
+// (backend, service), so a backend whose image endpoint is down keeps serving chat.
+type ServiceType string
+
+const (
+	ServiceChat       ServiceType = "chat"
+	ServiceEmbeddings ServiceType = "embeddings"
+	ServiceAudio      ServiceType = "audio"
+	ServiceImages     ServiceType = "images"
+	ServiceResponses  ServiceType = "responses"
+	ServiceUploads    ServiceType = "uploads"
+)
+
 type LoadBalancer struct {
-	clients []*SafeClient
-	counter uint64
+	*lbState // Shared with the clients derived by Client.With, and the pools.
+	options  lbOptions
+	pool     string // The Pool.Name of a WithPools pool, or "".
 }
 
-// GetNextClient intelligently retrieves the next available client (skipping circuit-tripped nodes).
-func (lb *LoadBalancer) GetNextClient() (*SafeClient, error) {
-	total := len(lb.clients)
-	if total == 0 {
-		return nil, errors.New("no clients configured")
+// lbState is the pool and the state kept about it.
+type lbState struct {
+	clients atomic.Pointer[[]*SafeClient] // Replaced, never modified, on membership changes.
+	pools   []*poolRoute                  // Set with WithPools.
+	slots   slotSignal                    // Broadcast when a backend's request ends.
+
+	poolMu sync.Mutex // Serializes membership changes.
+	added  int        // Backends created so far, to name new ones.
+
+	mu              sync.Mutex // Guards the smooth weighted round-robin and ejection state of the clients.
+	lastOutlierEval time.Time
+
+	restored   map[string]BreakerSnapshot // breaker name -> state loaded from the StateStore
+	saveMu     sync.Mutex
+	stateDirty chan struct{} // Signaled when a transition is to be saved, by persistState.
+
+	ready     chan struct{} // Closed once a health check passed.
+	readyOnce sync.Once
+
+	events       eventBus
+	healthEvents healthQueue // For WithOnHealthChange and WithNotifier.
+
+	responseOwners owners // Of background responses, shared by the derived clients.
+	uploadOwners   owners // Of uploads, likewise.
+
+	closed     atomic.Bool
+	done       context.Context // Canceled by Close, stopping discovery.
+	closeDone  context.CancelFunc
+	background sync.WaitGroup // Discovery, and removed backends still draining.
+}
+
+// GetNextClient intelligently retrieves the next available client for svc and the
+// requested model (skipping nodes whose breaker for them is tripped).
+//
+// Clients are picked by smooth weighted round-robin, so equal weights give a
+// strict rotation and a client with weight 2 gets every other request of 3.
+func (lb *LoadBalancer) GetNextClient(svc ServiceType, model string) (*SafeClient, error) {
+	safeClient, release, err := lb.route(model).nextClient(svc, model, 0, nil, nil)
+	if err != nil {
+		return nil, err
 	}
+	// The caller's request isn't tracked, so it holds no slot.
+	release()
+	return safeClient, nil
+}
+
+// nextClient is GetNextClient among the backends of lb's pool carrying the
+// labels of selector with the TPM budget for tokens, skipping the backends in
+// tried. It takes one of the backend's slots for MaxInFlight, handed back by
+// the returned func.
+func (lb *LoadBalancer) nextClient(svc ServiceType, model string, tokens int64, selector map[string]string, tried map[*SafeClient]bool) (*SafeClient, func(), error) {
+	if lb.closed.Load() {
+		return nil, nil, ErrClosed
+	}
+	clients := lb.backends()
+	if len(clients) == 0 {
+		return nil, nil, errors.New("no clients configured")
+	}
+
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	now := time.Now()
+	lb.evaluateOutliers(now)
 
-	// Try at most 'total' times to avoid an infinite loop when all clients are down.
-	for i := 0; i < total; i++ {
-		current := atomic.AddUint64(&lb.counter, 1)
-		index := (current - 1) % uint64(total)
-		safeClient := lb.clients[index]
+	var best *SafeClient
+	total := 0.0
+	pooled, served, matched, limited, full, spent, fits := false, false, false, false, false, false, false
+	var largest int64
+	known := !lb.options.strictModels || model == "" || lb.pool != ""
+	var limitedFor, spentFor, downFor time.Duration // The soonest any such backend may take the request.
+	for _, safeClient := range clients {
+		if safeClient.lb.pool != lb.pool {
+			continue
+		}
+		pooled = true
+		known = known || safeClient.knows(model)
+		if target, ok := safeClient.aliasTarget(model); ok && target == "" {
+			continue
+		}
+		served = true
+		if !safeClient.hasLabels(selector) {
+			continue
+		}
+		matched = true
+		if !safeClient.fits(model, tokens) {
+			largest = max(largest, safeClient.tokenLimit(model))
+			continue
+		}
+		fits = true
+		if tried[safeClient] {
+			continue
+		}
+		if safeClient.ejected(now) {
+			downFor = soonest(downFor, safeClient.outlier.ejectedUntil.Sub(now))
+			continue
+		}
+		if quarantined, _ := safeClient.Quarantined(); quarantined {
+			continue
+		}
+		if safeClient.unhealthy() {
+			if safeClient.checked {
+				downFor = soonest(downFor, safeClient.healthCheck.Interval)
+			}
+			continue
+		}
+
+		breaker := safeClient.breakerFor(svc, mapModel(safeClient, model))
 
 		// Key: If the circuit breaker is in the StateOpen, it means the node is faulty, so skip it.
-		if safeClient.CB.State() == gobreaker.StateOpen {
+		if !safeClient.routable(breaker) {
+			downFor = soonest(downFor, breaker.cooldownUntil().Sub(now))
 			continue
 		}
 
-		return safeClient, nil
+		weight := safeClient.effectiveWeight(breaker)
+		if weight <= 0 {
+			continue
+		}
+		if safeClient.full() {
+			full = true
+			continue
+		}
+		if !safeClient.rpm.available(now, 1) || !safeClient.tpm.available(now, float64(tokens)) {
+			limited = true
+			limitedFor = soonest(limitedFor, max(safeClient.rpm.wait(now, 1), safeClient.tpm.wait(now, float64(tokens))))
+			continue
+		}
+		if safeClient.overBudget(now, model) {
+			spent = true
+			spentFor = soonest(spentFor, safeClient.budgetResetIn(now, model))
+			continue
+		}
+		safeClient.currentWeight += weight
+		total += weight
+		if best == nil || safeClient.currentWeight > best.currentWeight {
+			best = safeClient
+		}
 	}
 
-	return nil, errors.New("all clients are unavailable (circuit breakers open)")
+	if !pooled {
+		return nil, nil, lb.noBackendError(model)
+	}
+	if !known {
+		return nil, nil, &UnknownModelError{Model: model}
+	}
+	if !served {
+		return nil, nil, fmt.Errorf("no backend serves the alias %q", model)
+	}
+	if !matched {
+		return nil, nil, fmt.Errorf("no backend has the labels %s", formatLabels(selector))
+	}
+	if !fits {
+		return nil, nil, &RequestTooLargeError{Model: model, Tokens: tokens, Limit: largest}
+	}
+	if best == nil && full {
+		return nil, nil, errAtCapacity
+	}
+	if best == nil && limited {
+		return nil, nil, backpressure(ErrRateLimited, limitedFor, "all clients are unavailable or at their RPM or TPM limit")
+	}
+	if best == nil && spent {
+		return nil, nil, backpressure(ErrBudgetExceeded, spentFor, "all clients are unavailable or over budget")
+	}
+	if best == nil {
+		return nil, nil, backpressure(ErrAllBackendsDown, downFor, "circuit breakers open, ejected, quarantined or unhealthy")
+	}
+	best.currentWeight -= total
+	best.rpm.take(now, 1)
+	best.tpm.take(now, float64(tokens))
+	return best, best.acquire(), nil
 }
 
 type SafeClient struct {
+	// Client is the client the backend was created with. RotateKey replaces
+	// it; OpenAIClient returns the current one.
 	Client   *openai.Client
-	CB       *gobreaker.CircuitBreaker[*openai.ChatCompletion]
-	Name     string // Used for logging differentiation (e.g., the first few characters of the API key).
+	Name     string // OpenaiClientConfig.Name, or "Client-N".
 	ModelMap map[string]string
-	BaseURL  string // Used for testing and logging.
+	BaseURL  string            // Used for testing and logging.
+	Labels   map[string]string // OpenaiClientConfig.Labels.
+
+	lb              *LoadBalancer
+	breakerSettings gobreaker.Settings
+	weight          atomicFloat
+	currentWeight   float64 // Smooth weighted round-robin state, guarded by lb.mu.
+	outlier         outlierStats
+	sloCounter      *rollingCounter   // Set with WithSLO.
+	degradeCounter  *rollingCounter   // Set with WithDegradation.
+	degradeLatency  *rollingHistogram // Set with WithDegradation and a LatencyPercentile.
+	healthCheck     HealthCheck       // The resolved check, also used by Validate.
+	checked         bool              // Whether health checks run in the background.
+	httpClient      *http.Client
+	requestTimeout  time.Duration
+	models          *modelMapper // The compiled ModelMap.
+	rpm             *tokenBucket // Set with OpenaiClientConfig.RPM.
+	tpm             *tokenBucket // Set with OpenaiClientConfig.TPM.
+	maxInFlight     atomic.Int64 // OpenaiClientConfig.MaxInFlight, or the aimd limit.
+	aimd            *aimdLimit   // Set with WithAdaptiveConcurrency.
+	budget          *spendBudget // Set with OpenaiClientConfig.Budget.
+	active          atomic.Int64 // Requests holding one of the MaxInFlight slots.
+	prices          map[string]Price
+	stats           backendStats
+	usage           usageLedger
+	stop            chan struct{} // Closed once the backend was removed and drained.
+	retireOnce      sync.Once
+
+	mu               sync.Mutex
+	breakers         map[breakerKey]*trackedBreaker
+	latencies        map[breakerKey]*rollingHistogram
+	quarantinedAt    time.Time // Non-zero while quarantined by WithAuthQuarantine.
+	quarantineReason string
+	lastError        string
+	lastErrorAt      time.Time
+	lastErrorClass   ErrorClass
+	checks           checkState // Set by WithHealthChecks.
+	markedDownAt     time.Time  // Non-zero while marked down by MarkUnhealthy.
+	markedDownReason string
+	addrs            []string    // Last resolved addresses, with WithDNSRefresh.
+	rateLimits       *RateLimits // Last announced by the backend.
+	secret           *secretKey  // Set for an APIKeyRef.
+	client           atomic.Pointer[openai.Client]
+	config           OpenaiClientConfig // The config the backend was built from.
 }
 
 // Client is the outermost layer, mimicking openai.Client.
 type Client struct {
-	Chat *LBChatService
+	Chat       *LBChatService
+	Embeddings *LBEmbeddingsService
+	Images     *LBImagesService
+	Audio      *LBAudioService
+	Responses  *LBResponsesService
+	Uploads    *LBUploadsService
+
+	lb *LoadBalancer
 }
 
 // LBChatService mimics openai.ChatService.
@@ -66,79 +286,314 @@ type LBCompletionsService struct {
 
 // --- 3. Initialization Function ---
 type OpenaiClientConfig struct {
-	APIKey   string
-	BaseURL  string
-	ModelMap map[string]string // Optionally specify model mapping.
+	// Name identifies the backend in errors, logs, metrics, health snapshots
+	// and the admin APIs such as MarkUnhealthy. It must be unique in the pool.
+	// It defaults to "Client-N", N being the backend's index.
+	Name    string
+	APIKey  string
+	BaseURL string
+	// ModelMap, if set, maps the requested models to the names the backend
+	// knows them by. Keys are exact names, prefixes ending in "*" such as
+	// "gpt-4*", or regular expressions between slashes such as
+	// "/^gpt-4o-(.+)$/", whose target may refer to their submatches, e.g.
+	// "azure-gpt-4o-$1". Exact names win over prefixes, longer prefixes over
+	// shorter ones, and prefixes over regular expressions, tried in key order.
+	// A "*" key maps every other model, e.g. to the only model of a vLLM
+	// server.
+	ModelMap map[string]string
+
+	// APIKeyRef, instead of APIKey, refers to the key in the SecretProvider of
+	// WithSecretProvider, e.g. "secret/data/openai#api_key" for vaultlb.
+	APIKeyRef string
+
+	// Labels are arbitrary key/value pairs describing the backend, e.g.
+	// provider=azure, env=prod or gpu=a100. They are reported with its
+	// metrics, hooks and health, and WithLabelSelector routes by them.
+	Labels map[string]string
+
+	// Weight is the backend's share of traffic relative to the others (default 1).
+	Weight int
+
+	// Pool names the WithPools pool of the backend. The backends without one
+	// serve the models that no pool lists.
+	Pool string
+
+	// Azure, if set, makes the backend an Azure OpenAI resource at BaseURL,
+	// with ModelMap mapping models to deployment names.
+	Azure *Azure
+
+	// CBSettings optionally overrides the LB-wide circuit breaker settings
+	// (WithCBSettings) for this backend only.
+	CBSettings *gobreaker.Settings
+
+	// HalfOpenProbes, if set, overrides the breakers' MaxRequests: how many
+	// concurrent requests a half-open breaker lets through to probe the backend.
+	// High-throughput backends can probe with several, fragile ones with one.
+	HalfOpenProbes uint32
+	// CountsInterval, if set, overrides the breakers' Interval: how often a
+	// closed breaker clears its counts.
+	CountsInterval time.Duration
+	// HealthCheck overrides the non-zero fields of the WithHealthChecks config
+	// for this backend, e.g. a deployment-scoped probe for Azure. Setting it
+	// enables health checks for the backend even without WithHealthChecks.
+	HealthCheck *HealthCheck
+	// HealthCheckPath, if set, overrides HealthCheck.Path for this backend, e.g.
+	// CompletionCheckPath for a gateway that doesn't implement GET /models.
+	HealthCheckPath string
+	// Prices overrides WithPricing for this backend's models, by the model
+	// name sent to it, e.g. for a provider with discounted rates.
+	Prices map[string]Price
+	// Params, if set, adapts the chat completions sent to this backend to
+	// what it supports: capping max tokens, clamping the temperature, forcing
+	// a service tier or stripping parameters it rejects.
+	Params *ParamPolicy
+	// SystemPrompt, if set, is injected into every chat completion sent to
+	// this backend, after the one of WithSystemPrompt, e.g. locale or
+	// hardening instructions for a third-party reseller only.
+	SystemPrompt string
+	// ExtraBody, if set, adds provider-specific fields to the body of every
+	// chat completion and response sent to this backend, after Params and
+	// TransformParams, e.g. {"top_k": 20, "repetition_penalty": 1.1} for
+	// vLLM. A key may be a dotted path to set a nested field.
+	ExtraBody map[string]any
+	// TransformParams, if set, rewrites each chat completion sent to this
+	// backend, after its ModelMap, Params and SystemPrompt, for the gateway quirks no
+	// configuration covers: vendor fields (params.SetExtraFields), tool schema
+	// tweaks, renamed parameters. It must not modify params' slices and maps
+	// in place, as they are shared with the other attempts.
+	TransformParams func(params openai.ChatCompletionNewParams) openai.ChatCompletionNewParams
+	// RequestOptions are applied to every call to this backend, including
+	// health checks, before the options of the call itself: e.g. the extra
+	// auth header of a gateway like Helicone, an organization or a project.
+	RequestOptions []option.RequestOption
+
+	// HTTPClient replaces the backend's own HTTP client; ProxyURL, TLSConfig
+	// and DialTimeout configure the own one instead, e.g. with a private CA,
+	// an mTLS client certificate or InsecureSkipVerify for a lab server.
+	HTTPClient  *http.Client
+	ProxyURL    string
+	TLSConfig   *tls.Config
+	DialTimeout time.Duration
+
+	// RequestTimeout, if set, bounds each request on this backend, e.g. 120s
+	// for a slow self-hosted model and 30s for OpenAI. It covers reading the
+	// body of streams and raw responses too. A request that times out while
+	// the caller's context is still live counts against the breaker like a
+	// backend error, and fails over like one with WithFailover.
+	RequestTimeout time.Duration
+
+	// RPM, if set, caps the requests per minute sent to this backend, e.g. at
+	// its provider quota, so the requests it would reject with a 429 go to the
+	// other backends instead. RPMBurst is how many may be sent at once
+	// (default RPM/60, at least 1), as providers enforce quotas over seconds.
+	RPM      int
+	RPMBurst int
+	// TPM, if set, caps the tokens per minute sent to this backend. Requests
+	// are charged their prompt, estimated, plus their max tokens when sent,
+	// and settled to their actual usage once it is known; the backends whose
+	// budget can't cover a request are skipped.
+	TPM int
+	// ContextWindow, if set, is the most tokens, prompt and output together,
+	// the backend's models take, in place of their WithContextWindows entries.
+	// Requests estimated larger, or larger than its whole TPM, go to the other
+	// backends; if none can take them, they fail with a *RequestTooLargeError
+	// before anything is sent.
+	ContextWindow int
+	// MaxInFlight, if set, caps the requests in flight on this backend, streams
+	// included, e.g. for a self-hosted model that slows down past N. The
+	// excess goes to the other backends; when they are all full as well,
+	// requests wait for a slot until their context is done.
+	MaxInFlight int
+	// Budget, if set, is a spend ceiling for this backend, at its prices:
+	// once hit, it takes no requests until the next period. Budget.Reject
+	// doesn't apply.
+	Budget *Budget
 }
 
+// NewClient builds the load balancer over configs. It doesn't validate them;
+// use New to catch configuration mistakes up front.
 func NewClient(configs []OpenaiClientConfig, opts ...LBOption) Client {
 	// Initialize default options
 	options := lbOptions{
-		cbSettings: defaultCBSettings,
+		cbSettings:     defaultCBSettings,
+		breakerFactory: NewGobreaker,
+		isSuccessful:   DefaultIsSuccessful,
+		pollAttempts:   defaultPollAttempts,
+		pollBackoff:    defaultPollBackoff,
+		lookupHost:     net.DefaultResolver.LookupHost,
+		lookupSRV:      net.DefaultResolver.LookupSRV,
 	}
 	for _, o := range opts {
 		o(&options)
 	}
+	if options.logger == nil {
+		options.logger = slog.New(discardHandler{})
+	}
+	lb := &LoadBalancer{lbState: &lbState{ready: make(chan struct{}), healthEvents: healthQueue{wake: make(chan struct{}, 1)}, stateDirty: make(chan struct{}, 1), responseOwners: owners{ttl: responseOwnerTTL}, uploadOwners: owners{ttl: uploadOwnerTTL}}, options: options}
+	lb.done, lb.closeDone = context.WithCancel(context.Background())
+	lb.pools = newPools(lb)
+
 	// Initialize all real clients.
 	var clients []*SafeClient
-
 	for i, cfg := range configs {
-		c := openai.NewClient(
-			option.WithAPIKey(cfg.APIKey),
-			option.WithBaseURL(cfg.BaseURL),
-		)
-
-		// 3. Copy the configuration (Key Point)
-		// We must copy the settings because we are modifying the Name.
-		// Otherwise, all clients would share the same Name,
-		// or the next iteration of the loop would overwrite the previous Name.
-		currentSt := options.cbSettings
-		currentSt.Name = fmt.Sprintf("Client-%d", i)
-
-		// If the user has defined custom settings but has not set ReadyToTrip,
-		// we need to provide a fallback to prevent gobreaker from panicking or not working correctly.
-		if currentSt.ReadyToTrip == nil {
-			currentSt.ReadyToTrip = defaultCBSettings.ReadyToTrip
-		}
-
-		// Create the circuit breaker.
-		cb := gobreaker.NewCircuitBreaker[*openai.ChatCompletion](currentSt)
-
-		clients = append(clients, &SafeClient{
-			Client:   &c,
-			CB:       cb,
-			Name:     currentSt.Name,
-			ModelMap: cfg.ModelMap,
-			BaseURL:  cfg.BaseURL,
-		})
+		clients = append(clients, lb.newSafeClient(cfg, backendName(cfg, i)))
 	}
+	lb.setBackends(clients)
+	lb.added = len(clients)
+	lb.deliverHealthEvents()
+	lb.restoreState()
+	lb.persistState()
+	lb.subscribeSignals()
+	lb.startHealthChecks()
+	lb.validateModelsInBackground()
+
+	return newClientOf(lb)
+}
 
-	lb := &LoadBalancer{clients: clients}
-
+// newClientOf returns the Client whose requests go through lb.
+func newClientOf(lb *LoadBalancer) Client {
 	completionsSvc := &LBCompletionsService{lb: lb}
 	chatSvc := &LBChatService{Completions: completionsSvc}
 
 	return Client{
-		Chat: chatSvc,
+		Chat:       chatSvc,
+		Embeddings: &LBEmbeddingsService{lb: lb},
+		Images:     &LBImagesService{lb: lb},
+		Audio: &LBAudioService{
+			Transcriptions: &LBAudioTranscriptionsService{lb: lb},
+			Speech:         &LBAudioSpeechService{lb: lb},
+		},
+		Responses: &LBResponsesService{lb: lb},
+		Uploads:   newLBUploadsService(lb),
+		lb:        lb,
 	}
 }
 
-func applyModelMapping(client *SafeClient, params openai.ChatCompletionNewParams) openai.ChatCompletionNewParams {
-	if len(client.ModelMap) == 0 {
-		return params
+// backendName returns the name of the backend configured by cfg, the i-th backend created.
+func backendName(cfg OpenaiClientConfig, i int) string {
+	if cfg.Name != "" {
+		return cfg.Name
 	}
+	return fmt.Sprintf("Client-%d", i)
+}
 
-	// Get the requested model name.
-	reqModel := params.Model
+// newSafeClient builds the backend called name from cfg.
+func (lb *LoadBalancer) newSafeClient(cfg OpenaiClientConfig, name string) *SafeClient {
+	if pool, err := lb.poolNamed(cfg.Pool); err == nil {
+		lb = pool
+	}
+	options := lb.options
+	// Each backend gets its own connection pool, so it can be reset on its own.
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Transport: newTransport(cfg)}
+	}
+	var secret *secretKey
+	if cfg.APIKeyRef != "" {
+		secret = &secretKey{backend: name, ref: cfg.APIKeyRef, provider: options.secrets, logger: options.logger}
+	}
+	c := lb.newOpenAIClient(cfg, httpClient, secret)
+
+	// Copy the settings, since each backend's breakers get their own name.
+	currentSt := options.cbSettings
+	if cfg.CBSettings != nil {
+		currentSt = *cfg.CBSettings
+	} else if options.errorRate != nil {
+		options.errorRate.apply(&currentSt)
+	}
+	currentSt.Name = name
+	if cfg.HalfOpenProbes > 0 {
+		currentSt.MaxRequests = cfg.HalfOpenProbes
+	}
+	if cfg.CountsInterval > 0 {
+		currentSt.Interval = cfg.CountsInterval
+	}
 
-	// If a mapping exists, replace the model name.
-	if targetModel, ok := client.ModelMap[reqModel]; ok {
-		newParams := params
-		newParams.Model = targetModel
-		return newParams
+	// If the user has defined custom settings but has not set ReadyToTrip,
+	// we need to provide a fallback to prevent gobreaker from panicking or not working correctly.
+	if currentSt.ReadyToTrip == nil {
+		currentSt.ReadyToTrip = defaultCBSettings.ReadyToTrip
 	}
 
-	return params
+	healthCheck, checked := healthCheckFor(options.healthCheck, cfg)
+
+	// Breakers are created lazily, one per service (and model, if enabled).
+	sc := &SafeClient{
+		Client:          c,
+		Name:            currentSt.Name,
+		ModelMap:        cfg.ModelMap,
+		models:          newModelMapper(cfg.ModelMap),
+		BaseURL:         cfg.BaseURL,
+		Labels:          cfg.Labels,
+		lb:              lb,
+		breakerSettings: currentSt,
+		breakers:        make(map[breakerKey]*trackedBreaker),
+		latencies:       make(map[breakerKey]*rollingHistogram),
+		healthCheck:     healthCheck,
+		checked:         checked,
+		httpClient:      httpClient,
+		requestTimeout:  cfg.RequestTimeout,
+		rpm:             rpmLimiter(cfg),
+		tpm:             tpmLimiter(cfg),
+		prices:          cfg.Prices,
+		config:          cfg,
+		secret:          secret,
+		aimd:            newAIMDLimit(options.adaptive, cfg.MaxInFlight),
+		budget:          newSpendBudget(cfg.Budget),
+		stop:            make(chan struct{}),
+	}
+	sc.client.Store(c)
+	sc.maxInFlight.Store(int64(cfg.MaxInFlight))
+	if sc.aimd != nil {
+		sc.maxInFlight.Store(int64(sc.aimd.limit))
+	}
+	sc.weight.Store(float64(max(cfg.Weight, 1)))
+	sc.stats.recent = newRollingCounter(StatsWindow)
+	sc.stats.latencies = newRollingHistogram(StatsWindow)
+	if options.slo != nil {
+		sc.sloCounter = newRollingCounter(options.slo.Window)
+	}
+	if options.degradation != nil {
+		sc.degradeCounter = newRollingCounter(options.degradation.Window)
+		if options.degradation.LatencyPercentile > 0 {
+			sc.degradeLatency = newRollingHistogram(options.degradation.Window)
+		}
+	}
+	return sc
+}
+
+// newOpenAIClient builds the openai client of the backend configured by cfg.
+func (lb *LoadBalancer) newOpenAIClient(cfg OpenaiClientConfig, httpClient *http.Client, secret *secretKey) *openai.Client {
+	clientOpts := []option.RequestOption{
+		option.WithAPIKey(cfg.APIKey),
+		option.WithBaseURL(cfg.BaseURL),
+		option.WithHTTPClient(httpClient),
+	}
+	if cfg.Azure != nil {
+		clientOpts = append(clientOpts, cfg.Azure.options(cfg.BaseURL, cfg.APIKey)...)
+	}
+	if secret != nil {
+		clientOpts = append(clientOpts, secret.auth(cfg.Azure != nil))
+	}
+	clientOpts = append(clientOpts, cfg.RequestOptions...)
+	if lb.options.tracer != nil {
+		clientOpts = append(clientOpts, option.WithMiddleware(propagateTrace))
+	}
+	clientOpts = append(clientOpts, option.WithMiddleware(captureAttempt), option.WithMiddleware(rewriteModel))
+	c := openai.NewClient(clientOpts...)
+	return &c
+}
+
+// mapModel returns the model name the backend knows reqModel by.
+func mapModel(client *SafeClient, reqModel string) string {
+	if target, ok := client.aliasTarget(reqModel); ok && target != "" {
+		return target
+	}
+	// If a mapping exists, replace the model name.
+	if targetModel, ok := client.models.lookup(reqModel); ok {
+		return targetModel
+	}
+	return reqModel
 }
 
 // isFatalError determines whether to trip the circuit (400 errors don't, 401/429/5xx errors do).
@@ -156,69 +611,321 @@ func isFatalError(err error) bool {
 	return true
 }
 
-// New implementation (integrates circuit breaker + model mapping).
-func (s *LBCompletionsService) New(ctx context.Context, params openai.ChatCompletionNewParams, opts ...option.RequestOption) (*openai.ChatCompletion, error) {
-	// A. Get a healthy node.
-	safeClient, err := s.lb.GetNextClient()
-	if err != nil {
-		return nil, err
-	}
+// execute runs call on the next available backend for svc and the requested
+// model, inside that backend's breaker for them. With WithFailover, failed
+// attempts move on to the next backend.
+func execute[T any](ctx context.Context, lb *LoadBalancer, svc ServiceType, model string, call func(context.Context, *SafeClient) (T, error)) (T, error) {
+	lb = lb.route(model)
+	return executeAttempts(ctx, lb, svc, model, lb.options.maxAttempts, call)
+}
 
-	// B. Apply model mapping.
-	finalParams := applyModelMapping(safeClient, params)
+// executeOnce is execute without failover, for requests whose body can't be
+// sent twice, like file uploads read from an io.Reader.
+func executeOnce[T any](ctx context.Context, lb *LoadBalancer, svc ServiceType, model string, call func(context.Context, *SafeClient) (T, error)) (T, error) {
+	return executeAttempts(ctx, lb.route(model), svc, model, 1, call)
+}
 
-	// C. Execute the request within the circuit breaker.
-	res, err := safeClient.CB.Execute(func() (*openai.ChatCompletion, error) {
-		resp, reqErr := safeClient.Client.Chat.Completions.New(ctx, finalParams, opts...)
+func executeAttempts[T any](ctx context.Context, lb *LoadBalancer, svc ServiceType, model string, maxAttempts int, call func(context.Context, *SafeClient) (T, error)) (T, error) {
+	ctx, route := withRoute(ctx, model)
+	defer route.finish()
+	tokens := tokenEstimateFrom(ctx)
 
-		if reqErr != nil {
-			// If it's a fatal error, return the error to trigger the circuit breaker.
-			if isFatalError(reqErr) {
-				return nil, reqErr
+	// The request is admitted once, however many backends it tries; the
+	// admission is handed back with the slot of its last attempt.
+	admitted, err := lb.admit(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	admission := &sharedRelease{release: admitted}
+	defer admission.hold()()
+
+	var tried map[*SafeClient]bool
+	var lastErr error
+	for n := 1; ; n++ {
+		// A. Get a healthy node.
+		safeClient, release, err := lb.pick(ctx, svc, model, tokens, tried)
+		if err != nil {
+			var zero T
+			if lastErr != nil {
+				// Every backend left failed; the last failure says more than "unavailable".
+				return zero, lastErr
 			}
-			// If it's a non-fatal error (like a 400), return (nil, nil) to ignore it.
-			// (nil is a valid value for the *openai.ChatCompletion pointer type).
-			return nil, nil
+			return zero, err
 		}
-		return resp, nil
-	})
 
-	// Handle errors returned by the circuit breaker.
+		// B. Execute the request within the circuit breaker.
+		mapped := mapModel(safeClient, model)
+		held := admission.hold()
+		a := attempt{
+			client:    safeClient,
+			breaker:   safeClient.breakerFor(svc, mapped),
+			requested: model,
+			model:     mapped,
+			number:    n,
+			tag:       TagFromContext(ctx),
+			tokens:    tokens,
+			tenant:    TenantFromContext(ctx),
+			quota:     lb.tenantOf(ctx),
+			release: func() {
+				release()
+				held()
+			},
+		}
+		lb.selected(ctx, a)
+		res, done, err := executeWith(ctx, lb, a, call)
+		route.record(a, done)
+		if err == nil || !isFatalError(err) || n >= maxAttempts || ctx.Err() != nil {
+			return res, err
+		}
+		lb.options.logger.WarnContext(ctx, "failing over",
+			"backend", safeClient.Name, "service", svc, "model", model, "attempt", n, "error", err)
+		lb.hookFailover(done)
+		ev := attemptEvent(EventFailover, a)
+		ev.Err = err
+		lb.events.publish(ev)
+		if tried == nil {
+			tried = make(map[*SafeClient]bool)
+		}
+		tried[safeClient] = true
+		lastErr = err
+	}
+}
+
+// executeOn runs call on safeClient inside its breaker for svc and the (mapped) model.
+func executeOn[T any](ctx context.Context, lb *LoadBalancer, safeClient *SafeClient, svc ServiceType, model string, call func(context.Context, *SafeClient) (T, error)) (T, error) {
+	ctx, route := withRoute(ctx, model)
+	defer route.finish()
+	admitted, err := lb.admit(ctx)
 	if err != nil {
-		return nil, err
+		var zero T
+		return zero, err
 	}
+	release := safeClient.acquire()
+	a := attempt{client: safeClient, breaker: safeClient.breakerFor(svc, model), requested: model, model: model, number: 1, tag: TagFromContext(ctx), tenant: TenantFromContext(ctx), quota: lb.tenantOf(ctx), release: func() {
+		release()
+		admitted()
+	}}
+	res, done, err := executeWith(ctx, lb, a, call)
+	route.record(a, done)
+	return res, err
+}
+
+// attempt is one try of a request on one backend.
+type attempt struct {
+	client    *SafeClient
+	breaker   *trackedBreaker
+	requested string // The requested model.
+	model     string // The mapped model.
+	number    int    // 1 for the first try, 2 for the first failover, ...
+	stream    bool
+	tag       string  // Set with WithTag.
+	tokens    int64   // Estimated, for the TPM budgets.
+	tenant    string  // Set with WithTenant.
+	quota     *tenant // The tenant's, under WithTenantQuotas.
+	release   func()  // Hands back the backend's slot, if one was taken.
+}
 
-	// Handle the "non-fatal error" case (where res is nil and err is nil).
-	// This means a 400 error occurred, which the circuit breaker ignored,
-	// but we need to return the error to the user.
-	if res == nil {
-		// Re-run the request directly to get the original error (since it was ignored).
-		return safeClient.Client.Chat.Completions.New(ctx, finalParams, opts...)
+// executeWith runs call on the attempt's backend inside its breaker. Besides
+// call's results, it returns how the attempt ended.
+func executeWith[T any](ctx context.Context, lb *LoadBalancer, a attempt, call func(context.Context, *SafeClient) (T, error)) (res T, done ResponseInfo, err error) {
+	safeClient, breaker := a.client, a.breaker
+	done.RequestInfo = a.info()
+	ctx = lb.withModelRewrite(withBackend(ctx, a), a)
+	ctx, capture := lb.withCapture(ctx)
+	ctx, span := lb.startSpan(ctx, a)
+	release := a.release
+	if release == nil {
+		release = func() {}
+	}
+	settle, err := breaker.Allow()
+	if err != nil {
+		// Open, or out of half-open probes: back off until its cooldown ends.
+		err = backpressure(fmt.Errorf("%w: %w", ErrAllBackendsDown, err), time.Until(breaker.cooldownUntil()), "breaker "+breaker.name)
+		release()
+		safeClient.settleTokens(time.Now(), a, ResponseInfo{Err: err})
+		done.Class, done.Err = classify(err, false), err
+		endSpan(span, nil, err, done.Class)
+		lb.hookDone(done)
+		return res, done, err
+	}
+	parent := ctx
+	ctx, cancelTimeout := safeClient.withRequestTimeout(ctx)
+	cancel := func() {
+		cancelTimeout()
+		release()
+	}
+	lb.started(a)
+	start := time.Now()
+	defer func() {
+		// A panicking request still has to settle its breaker slot.
+		if e := recover(); e != nil {
+			cancel()
+			settle(false)
+			endSpan(span, nil, nil, ClassOther)
+			done.Latency, done.Class = time.Since(start), ClassOther
+			lb.finished(a, &done)
+			panic(e)
+		}
+	}()
+
+	key := safeClient.keyInUse()
+	withLabels(ctx, a, func(ctx context.Context) { res, err = call(ctx, safeClient) })
+	if lb.options.rotationRetry && isUnauthorized(err) && safeClient.keyRotated(ctx, key) {
+		lb.options.logger.InfoContext(ctx, "retrying with the rotated API key", "backend", safeClient.Name)
+		withLabels(ctx, a, func(ctx context.Context) { res, err = call(ctx, safeClient) })
+	}
+	latency := time.Since(start)
+	res = releaseWith(res, cancel)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && parent.Err() == nil {
+		err = fmt.Errorf("%s: no response within its RequestTimeout of %s: %w", safeClient.Name, safeClient.requestTimeout, err)
 	}
 
-	return res, nil
+	success := lb.options.isSuccessful(res, err)
+	if err != nil && lb.options.authQuarantine && isAuthError(err) {
+		safeClient.quarantine(err.Error())
+	}
+	if !success {
+		if err != nil {
+			safeClient.recordError(err, classify(err, false))
+		} else {
+			safeClient.recordError(errUnsuccessfulResponse, ClassRejected)
+		}
+	}
+	if lb.options.outlierDetection != nil {
+		safeClient.outlier.record(!success, latency)
+	}
+	if safeClient.sloCounter != nil {
+		safeClient.sloCounter.add(time.Now(), !success)
+	}
+	if safeClient.degradeCounter != nil {
+		safeClient.degradeCounter.observe(time.Now(), !success, latency)
+	}
+	if safeClient.degradeLatency != nil {
+		safeClient.degradeLatency.observe(time.Now(), latency)
+	}
+
+	// A backend that answers too slowly counts as failing even though this call succeeded.
+	if success && err == nil {
+		if trip := lb.options.latencyTrip; trip != nil && trip.observe(safeClient.latencyFor(breaker.key, trip.window), latency) {
+			success = false
+		}
+	}
+
+	settle(success)
+	done.Latency, done.Class, done.Err = latency, classify(err, success), err
+	done.PromptTokens, done.CompletionTokens = usageOf(res)
+	done.RateLimits = capture.rateLimits
+	endSpan(span, res, err, done.Class)
+	lb.finished(a, &done)
+	lb.audit(ctx, capture, done, res)
+	return res, done, err
+}
+
+// errUnsuccessfulResponse is recorded as a backend's last error when a response
+// without an error was classified as a failure by WithIsSuccessful.
+var errUnsuccessfulResponse = errors.New("response classified as unsuccessful")
+
+// DefaultIsSuccessful is the classification used without WithIsSuccessful: fatal
+// errors count against the breaker, while non-fatal ones (like a 400) are not
+// the node's fault and count as successes, but are still returned to the user.
+func DefaultIsSuccessful(res any, err error) bool {
+	return err == nil || !isFatalError(err)
+}
+
+// New implementation (integrates circuit breaker + model mapping).
+func (s *LBCompletionsService) New(ctx context.Context, params openai.ChatCompletionNewParams, opts ...option.RequestOption) (*openai.ChatCompletion, error) {
+	if cache := s.lb.options.cache; cache.keeps(params) {
+		return cache.completion(ctx, s.lb, params, opts, s.newCompletion)
+	}
+	return s.newCompletion(ctx, params, opts...)
+}
+
+// newCompletion is New past the response cache.
+func (s *LBCompletionsService) newCompletion(ctx context.Context, params openai.ChatCompletionNewParams, opts ...option.RequestOption) (*openai.ChatCompletion, error) {
+	ctx = s.lb.withTokenEstimate(ctx, func() int64 { return estimateChatTokens(params) })
+	return execute(ctx, s.lb, ServiceChat, params.Model, func(ctx context.Context, safeClient *SafeClient) (*openai.ChatCompletion, error) {
+		params, opts := safeClient.chatRequest(params, opts)
+		return safeClient.OpenAIClient().Chat.Completions.New(ctx, params, opts...)
+	})
 }
 
 // NewStreaming implementation (integrates status checking + model mapping).
 func (s *LBCompletionsService) NewStreaming(ctx context.Context, params openai.ChatCompletionNewParams, opts ...option.RequestOption) *ssestream.Stream[openai.ChatCompletionChunk] {
 	// A. Get a node.
-	safeClient, err := s.lb.GetNextClient()
+	lb := s.lb.route(params.Model)
+	ctx = lb.withTokenEstimate(ctx, func() int64 { return estimateChatTokens(params) })
+	tokens := tokenEstimateFrom(ctx)
+	admitted, err := lb.admit(ctx)
 	if err != nil {
-		// The streaming method signature cannot return an error. In a real scenario,
-		// it's recommended to modify the return signature or panic.
-		// For demonstration purposes, we can only return nil or an empty stream here.
-		return nil
+		// The signature can't return an error; the stream carries it instead.
+		return ssestream.NewStream[openai.ChatCompletionChunk](nil, err)
 	}
+	var safeClient *SafeClient
+	var release func()
+	for {
+		safeClient, release, err = lb.pick(ctx, ServiceChat, params.Model, tokens, nil)
+		if err != nil {
+			admitted()
+			return ssestream.NewStream[openai.ChatCompletionChunk](nil, err)
+		}
 
-	// B. Manually check the circuit breaker status (streams are hard to wrap with Execute).
-	if safeClient.CB.State() == gobreaker.StateOpen {
-		// If the current node's circuit is open, recursively try the next one.
-		return s.NewStreaming(ctx, params, opts...)
+		// B. Manually check the circuit breaker status (streams are hard to wrap with Execute).
+		if safeClient.routable(safeClient.breakerFor(ServiceChat, mapModel(safeClient, params.Model))) {
+			break
+		}
+		// If the current node's circuit is open, try the next one.
+		release()
+	}
+	picked := release
+	release = func() {
+		picked()
+		admitted()
 	}
 
-	// C. Apply model mapping.
-	finalParams := applyModelMapping(safeClient, params)
+	// C. Apply model mapping and the backend's parameter policy.
+	finalParams, opts := safeClient.chatRequest(params, opts)
+	a := attempt{
+		client:    safeClient,
+		breaker:   safeClient.breakerFor(ServiceChat, finalParams.Model),
+		requested: params.Model,
+		model:     finalParams.Model,
+		number:    1,
+		stream:    true,
+		tag:       TagFromContext(ctx),
+		tokens:    tokens,
+		tenant:    TenantFromContext(ctx),
+		quota:     lb.tenantOf(ctx),
+		release:   release,
+	}
+	lb.selected(ctx, a)
+	ctx, route := withRoute(ctx, params.Model)
+	defer route.finish()
+	ctx = lb.withModelRewrite(withBackend(ctx, a), a)
+	ctx, cancelTimeout := safeClient.withRequestTimeout(ctx)
+	cancel := func() {
+		cancelTimeout()
+		release()
+	}
+	ctx, instrument, failed := lb.instrumentStream(ctx, a, cancel)
+	opts = append(opts[:len(opts):len(opts)], instrument)
 
 	// D. Execute the request.
-	return safeClient.Client.Chat.Completions.NewStreaming(ctx, finalParams, opts...)
+	var stream *ssestream.Stream[openai.ChatCompletionChunk]
+	withLabels(ctx, a, func(ctx context.Context) {
+		stream = safeClient.OpenAIClient().Chat.Completions.NewStreaming(ctx, finalParams, opts...)
+	})
+	err = stream.Err()
+	if err != nil {
+		failed(err)
+		cancel()
+	}
+	route.record(a, ResponseInfo{
+		RequestInfo: a.info(),
+		Latency:     time.Since(route.start),
+		Class:       classify(err, err == nil),
+		Err:         err,
+		RateLimits:  captureFrom(ctx).rateLimits,
+	})
+	return stream
 }
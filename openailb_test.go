@@ -5,10 +5,12 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
 	"github.com/sony/gobreaker/v2"
 )
 
@@ -127,9 +129,9 @@ func TestLBCircuitBreakerWithDefaultOption(t *testing.T) {
 	}
 
 	//  Verify that the circuit breaker for the first Client (failServer) is open
-	failClient := lb.Chat.Completions.lb.clients[0]
-	if failClient.CB.State() != gobreaker.StateOpen {
-		t.Fatalf("Circuit breaker for failClient should be open, but it's %s", failClient.CB.State().String())
+	failClient := lb.Chat.Completions.lb.backends()[0]
+	if failClient.Breaker(ServiceChat).State() != gobreaker.StateOpen {
+		t.Fatalf("Circuit breaker for failClient should be open, but it's %s", failClient.Breaker(ServiceChat).State().String())
 	}
 	log.Println("breaker for failClient")
 
@@ -188,8 +190,8 @@ func TestLBCustomOptions(t *testing.T) {
 	// 6. Assert: The breaker should be OPEN immediately
 	// Access the internal client to check state (assuming you have access to internal fields for testing)
 
-	internalClient := client.Chat.Completions.lb.clients[0]
-	currentState := internalClient.CB.State()
+	internalClient := client.Chat.Completions.lb.backends()[0]
+	currentState := internalClient.Breaker(ServiceChat).State()
 
 	if currentState != gobreaker.StateOpen {
 		t.Errorf("Expected Circuit Breaker to be OPEN after 1 failure (Custom Option), but it is %s. Default settings might be active.", currentState.String())
@@ -197,3 +199,219 @@ func TestLBCustomOptions(t *testing.T) {
 		t.Log("Success: Circuit Breaker tripped after just 1 failure as configured.")
 	}
 }
+
+func TestLBBreakerPerServiceType(t *testing.T) {
+	t.Parallel()
+
+	// The backend serves chat fine but its image endpoint is down.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/images/generations" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "Hello"}}]}`))
+	}))
+	defer server.Close()
+
+	configs := []OpenaiClientConfig{
+		{APIKey: "mock-key", BaseURL: server.URL},
+	}
+	customSettings := gobreaker.Settings{
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= 1
+		},
+	}
+	client := NewClient(configs, WithCBSettings(customSettings))
+
+	_, err := client.Images.Generate(context.Background(), openai.ImageGenerateParams{Prompt: "a cat"},
+		option.WithMaxRetries(0))
+	if err == nil {
+		t.Fatal("Expected the image request to fail")
+	}
+
+	backend := client.Chat.Completions.lb.backends()[0]
+	if backend.Breaker(ServiceImages).State() != gobreaker.StateOpen {
+		t.Fatalf("Expected images breaker to be open, got %s", backend.Breaker(ServiceImages).State())
+	}
+	if backend.Breaker(ServiceChat).State() != gobreaker.StateClosed {
+		t.Fatalf("Expected chat breaker to stay closed, got %s", backend.Breaker(ServiceChat).State())
+	}
+
+	resp, err := client.Chat.Completions.New(context.Background(), openai.ChatCompletionNewParams{
+		Model:    "test_model",
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("test")},
+	})
+	if err != nil {
+		t.Fatalf("Chat request should still succeed: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "Hello" {
+		t.Fatalf("Expected response 'Hello', but got '%s'", resp.Choices[0].Message.Content)
+	}
+}
+
+func TestLBPerBackendCBSettings(t *testing.T) {
+	t.Parallel()
+
+	failServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failServer.Close()
+
+	// The first backend keeps the default settings (3 failures); the second trips after 1.
+	configs := []OpenaiClientConfig{
+		{APIKey: "default-key", BaseURL: failServer.URL},
+		{APIKey: "strict-key", BaseURL: failServer.URL, CBSettings: &gobreaker.Settings{
+			ReadyToTrip: func(counts gobreaker.Counts) bool {
+				return counts.ConsecutiveFailures >= 1
+			},
+		}},
+	}
+	client := NewClient(configs)
+
+	params := openai.ChatCompletionNewParams{
+		Model:    "test_model",
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("test")},
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := client.Chat.Completions.New(context.Background(), params, option.WithMaxRetries(0)); err == nil {
+			t.Fatalf("Request %d should have failed", i)
+		}
+	}
+
+	clients := client.Chat.Completions.lb.backends()
+	if state := clients[0].Breaker(ServiceChat).State(); state != gobreaker.StateClosed {
+		t.Errorf("Default backend should still be closed after 1 failure, got %s", state)
+	}
+	if state := clients[1].Breaker(ServiceChat).State(); state != gobreaker.StateOpen {
+		t.Errorf("Strict backend should be open after 1 failure, got %s", state)
+	}
+}
+
+func TestFailover(t *testing.T) {
+	t.Parallel()
+
+	var failHits atomic.Int32
+	failServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failHits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failServer.Close()
+	okServer := newNamedServer(t, "ok")
+	defer okServer.Close()
+
+	configs := []OpenaiClientConfig{
+		{APIKey: "fail-key", BaseURL: failServer.URL},
+		{APIKey: "ok-key", BaseURL: okServer.URL},
+	}
+	client := NewClient(configs, WithFailover(2))
+	for i := 0; i < 4; i++ {
+		resp, err := client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+		if err != nil {
+			t.Fatalf("Request %d should have failed over: %v", i, err)
+		}
+		if content := resp.Choices[0].Message.Content; content != "ok" {
+			t.Errorf("Expected the healthy backend's answer, got %s", content)
+		}
+	}
+	if hits := failHits.Load(); hits == 0 {
+		t.Error("Expected the failing backend to be tried")
+	}
+
+	// A request is admitted once, not again for each backend it tries.
+	client = NewClient(configs, WithFailover(2), WithMaxConcurrency(1, 0, 0))
+	for i := 0; i < 2; i++ {
+		if _, err := client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0)); err != nil {
+			t.Fatalf("Request %d should have failed over within its admission: %v", i, err)
+		}
+	}
+	if a := client.lb.options.admission; a.active != 0 {
+		t.Errorf("Expected the admission to be handed back, got %d held", a.active)
+	}
+
+	// A 400 is the caller's fault and is not retried elsewhere.
+	badRequest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer badRequest.Close()
+	client = NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: badRequest.URL},
+		{APIKey: "k2", BaseURL: okServer.URL},
+	}, WithFailover(2))
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m")); err == nil {
+		t.Error("Expected the 400 to be returned without failover")
+	}
+}
+
+func TestNamedBackends(t *testing.T) {
+	t.Parallel()
+
+	failServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failServer.Close()
+	okServer := newNamedServer(t, "ok")
+	defer okServer.Close()
+
+	sink := &recordingSink{}
+	client := NewClient([]OpenaiClientConfig{
+		{Name: "azure-east", APIKey: "k1", BaseURL: failServer.URL},
+		{APIKey: "k2", BaseURL: okServer.URL},
+	}, WithCBSettings(tripAfter(1)), WithMetrics(sink))
+	for i := 0; i < 2; i++ {
+		_, _ = client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+	}
+
+	health := client.Health()
+	if health[0].Name != "azure-east" || health[0].Breakers[0].Name != "azure-east/chat" || health[1].Name != "Client-1" {
+		t.Errorf("Expected the configured name and the default one, got %s (%s) and %s", health[0].Name, health[0].Breakers[0].Name, health[1].Name)
+	}
+	sink.mu.Lock()
+	if len(sink.done) == 0 || sink.done[0].Backend != "azure-east" {
+		t.Errorf("Expected metrics by the configured name, got %+v", sink.done)
+	}
+	sink.mu.Unlock()
+	if err := client.MarkUnhealthy("azure-east", "maintenance"); err != nil {
+		t.Errorf("Expected the admin API to take the configured name, got %v", err)
+	}
+	if _, err := client.AddBackend(OpenaiClientConfig{Name: "azure-east", APIKey: "k3", BaseURL: okServer.URL}); err == nil {
+		t.Error("Expected adding a duplicate name to fail")
+	}
+	if _, err := New([]OpenaiClientConfig{
+		{Name: "Client-1", APIKey: "k1", BaseURL: okServer.URL},
+		{APIKey: "k2", BaseURL: failServer.URL},
+	}); err == nil || err.Error() != `configs[1].Name: "Client-1" is already the name of configs[0]` {
+		t.Errorf("Expected New to reject duplicate names, got %v", err)
+	}
+}
+
+func TestBackendRequestOptions(t *testing.T) {
+	t.Parallel()
+
+	headers := make(chan http.Header, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := r.Header.Clone()
+		h.Set("X-Query", r.URL.Query().Get("tenant"))
+		headers <- h
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "ok"}}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient([]OpenaiClientConfig{{
+		APIKey:  "k1",
+		BaseURL: server.URL,
+		RequestOptions: []option.RequestOption{
+			option.WithHeader("Helicone-Auth", "Bearer h"),
+			option.WithOrganization("org-1"),
+			option.WithQuery("tenant", "a"),
+		},
+	}})
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithHeader("X-Call", "1")); err != nil {
+		t.Fatal(err)
+	}
+	h := <-headers
+	if h.Get("Helicone-Auth") != "Bearer h" || h.Get("OpenAI-Organization") != "org-1" || h.Get("X-Query") != "a" || h.Get("X-Call") != "1" {
+		t.Errorf("Expected the backend's and the call's options to apply, got %v", h)
+	}
+}
@@ -1,17 +1,78 @@
 package openailb
 
 import (
+	"context"
+	"log/slog"
+	"net"
 	"time"
 
 	"github.com/sony/gobreaker/v2"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type LBOption func(*lbOptions)
 
 type lbOptions struct {
-	cbSettings gobreaker.Settings
+	cbSettings         gobreaker.Settings
+	errorRate          *errorRate
+	breakerFactory     BreakerFactory
+	embeddingShardSize int
+	pollAttempts       int
+	pollBackoff        time.Duration
+	perModelBreakers   bool
+	latencyTrip        *latencyTrip
+	stateStore         StateStore
+	breakerJitter      float64
+	slowStart          *slowStart
+	outlierDetection   *OutlierDetection
+	authQuarantine     bool
+	slo                *SLO
+	syntheticProbe     *SyntheticProbe
+	isSuccessful       func(res any, err error) bool
+	healthCheck        *HealthCheck
+	onHealthChange     func(HealthEvent)
+	dnsRefresh         time.Duration
+	degradation        *Degradation
+	metrics            MetricsSink
+	maxAttempts        int
+	tracer             trace.Tracer
+	logger             *slog.Logger
+	hooks              []Hooks
+	prices             map[string]Price
+	audit              *Audit
+	notifier           *notifier
+	lookupHost         func(ctx context.Context, host string) ([]string, error)
+	lookupSRV          func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	secrets            SecretProvider
+	secretRefresh      time.Duration
+	rotationRetry      bool
+	adminAuth          AdminAuth
+	pools              []Pool
+	admission          *admission
+	tenants            *tenants
+	adaptive           *AdaptiveConcurrency
+	shedding           *shedder
+	budget             *spendBudget
+	alerts             []*alertState
+	rateLimiter        RateLimiter
+	signals            *breakerSignals
+	rewriteModels      bool
+	systemPrompt       string
+	aliases            map[string]map[string]string
+	contextWindows     map[string]int
+	truncateHistory    bool
+	strictModels       bool
+	validateModels     bool
+	cache              *responseCache
 }
 
+// Default retry policy for calls pinned to the backend that owns a background response.
+const (
+	defaultPollAttempts = 3
+	defaultPollBackoff  = 500 * time.Millisecond
+)
+
 // defaultCBSettings default settings for circuit breaker
 var defaultCBSettings = gobreaker.Settings{
 	Name:    "OpenAI-LB",
@@ -27,3 +88,288 @@ func WithCBSettings(settings gobreaker.Settings) LBOption {
 		o.cbSettings = settings
 	}
 }
+
+// WithEmbeddingSharding splits embeddings requests whose input array is larger than
+// shardSize into shards of at most shardSize inputs, dispatches the shards to the
+// healthy backends concurrently, as many at a time as there are backends, and
+// merges the vectors back in input order. Each shard is balanced on its own, so
+// a backend may get several at once. A shardSize of 0 (the default) disables
+// splitting.
+func WithEmbeddingSharding(shardSize int) LBOption {
+	return func(o *lbOptions) {
+		o.embeddingShardSize = shardSize
+	}
+}
+
+// WithPollRetry configures how Responses Get/Cancel calls, which must go to the
+// backend that created the response, retry transient failures: up to attempts
+// tries, waiting backoff before the second and doubling it after each failure.
+func WithPollRetry(attempts int, backoff time.Duration) LBOption {
+	return func(o *lbOptions) {
+		o.pollAttempts = attempts
+		o.pollBackoff = backoff
+	}
+}
+
+// WithPerModelBreakers tracks breaker state per (backend, service, model) instead
+// of per (backend, service), so a single broken deployment (e.g. one model out of
+// quota) doesn't take the whole backend out of rotation. Breakers are keyed by
+// the model name after model mapping.
+func WithPerModelBreakers() LBOption {
+	return func(o *lbOptions) {
+		o.perModelBreakers = true
+	}
+}
+
+// errorRateBuckets is how many buckets the rolling window of an error-rate breaker is split into.
+const errorRateBuckets = 10
+
+// WithErrorRateBreaker trips breakers on the failure rate over a rolling window
+// instead of consecutive failures: a breaker opens once at least minRequests
+// requests were seen within window and the share of failures reaches threshold
+// (e.g. 0.5 for 50%). It keeps the other fields set by WithCBSettings, in
+// whichever order the two are given.
+func WithErrorRateBreaker(threshold float64, window time.Duration, minRequests uint32) LBOption {
+	return func(o *lbOptions) {
+		o.errorRate = &errorRate{threshold: threshold, window: window, minRequests: minRequests}
+	}
+}
+
+// errorRate is the configuration set by WithErrorRateBreaker.
+type errorRate struct {
+	threshold   float64
+	window      time.Duration
+	minRequests uint32
+}
+
+// apply makes st trip on the error rate.
+func (e *errorRate) apply(st *gobreaker.Settings) {
+	st.Interval = e.window
+	st.BucketPeriod = e.window / errorRateBuckets
+	st.ReadyToTrip = func(counts gobreaker.Counts) bool {
+		if counts.Requests < e.minRequests {
+			return false
+		}
+		return float64(counts.TotalFailures)/float64(counts.Requests) >= e.threshold
+	}
+}
+
+// WithLatencyBreaker makes slow backends count as failing: once at least
+// minSamples calls were observed within window and their percentile-th (0-100)
+// latency exceeds threshold, each further successful call is reported to the
+// breaker as a failure, so ReadyToTrip can open it. The calls themselves still
+// return their responses to the caller.
+func WithLatencyBreaker(percentile float64, threshold, window time.Duration, minSamples int) LBOption {
+	return func(o *lbOptions) {
+		o.latencyTrip = &latencyTrip{
+			percentile: percentile,
+			threshold:  threshold,
+			window:     window,
+			minSamples: minSamples,
+		}
+	}
+}
+
+// WithBreakerFactory replaces the gobreaker-based breakers with breakers built
+// by factory, e.g. to use a company-standard resilience library. The factory
+// receives each backend's effective settings and may ignore them.
+func WithBreakerFactory(factory BreakerFactory) LBOption {
+	return func(o *lbOptions) {
+		o.breakerFactory = factory
+	}
+}
+
+// WithStateStore persists breaker state (open breakers, their cooldowns and
+// counters) to store on every breaker transition and restores it in NewClient,
+// so a restart doesn't forget which backends are down.
+func WithStateStore(store StateStore) LBOption {
+	return func(o *lbOptions) {
+		o.stateStore = store
+	}
+}
+
+// WithBreakerJitter randomizes each breaker's open timeout by up to ±fraction
+// (e.g. 0.2 for ±20%), so backends opened by the same upstream outage probe
+// again at different times instead of re-failing in lockstep.
+func WithBreakerJitter(fraction float64) LBOption {
+	return func(o *lbOptions) {
+		o.breakerJitter = fraction
+	}
+}
+
+// WithSlowStart ramps a recovered backend back into rotation: when one of its
+// breakers closes again, the backend's weight for that breaker starts at
+// initialFraction (e.g. 0.1) of its configured weight and grows linearly to
+// the full weight over window, instead of instantly taking a full share of
+// traffic that could re-trip it during a partial recovery.
+func WithSlowStart(window time.Duration, initialFraction float64) LBOption {
+	return func(o *lbOptions) {
+		o.slowStart = &slowStart{window: window, initialFraction: initialFraction}
+	}
+}
+
+// WithOutlierDetection enables outlier ejection: backends whose error rate or
+// latency stands out from the rest of the fleet are temporarily taken out of
+// rotation, independently of their breakers. See OutlierDetection.
+func WithOutlierDetection(cfg OutlierDetection) LBOption {
+	return func(o *lbOptions) {
+		cfg = cfg.withDefaults()
+		o.outlierDetection = &cfg
+	}
+}
+
+// WithAuthQuarantine quarantines a backend as soon as it answers 401 or 403:
+// the key is bad and won't recover on a breaker timeout, so the backend stays
+// out of rotation until Client.Reinstate is called.
+func WithAuthQuarantine() LBOption {
+	return func(o *lbOptions) {
+		o.authQuarantine = true
+	}
+}
+
+// WithSLO tracks each backend's success rate against slo and exposes the
+// remaining error budget through Client.ErrorBudgets. With ExhaustedWeight set,
+// backends that burned their budget are also down-weighted.
+func WithSLO(slo SLO) LBOption {
+	return func(o *lbOptions) {
+		o.slo = &slo
+	}
+}
+
+// WithSyntheticProbes keeps user requests away from half-open chat breakers:
+// instead of letting the next real request probe a possibly still broken
+// backend, the LB sends a tiny synthetic completion and only routes users to
+// the backend again once the probes closed the breaker.
+func WithSyntheticProbes(probe SyntheticProbe) LBOption {
+	return func(o *lbOptions) {
+		probe = probe.withDefaults()
+		o.syntheticProbe = &probe
+	}
+}
+
+// WithIsSuccessful decides which calls count as successes for breakers and
+// health tracking, replacing DefaultIsSuccessful. res is the call's result, e.g. a
+// *openai.ChatCompletion, so a 200 with finish_reason "content_filter" or no
+// choices can count as a failure. Callers still receive res and err unchanged.
+func WithIsSuccessful(isSuccessful func(res any, err error) bool) LBOption {
+	return func(o *lbOptions) {
+		o.isSuccessful = isSuccessful
+	}
+}
+
+// WithHealthChecks checks every backend in the background and takes the ones
+// failing their checks out of rotation until they pass again.
+func WithHealthChecks(check HealthCheck) LBOption {
+	return func(o *lbOptions) {
+		check = check.withDefaults()
+		o.healthCheck = &check
+	}
+}
+
+// WithOnHealthChange calls hook on every backend health transition: failed
+// health checks, backends becoming unhealthy or recovering, quarantines,
+// ejections and breaker state changes, e.g. to forward them to incident tooling.
+// Events are delivered in the order they happened, one at a time, on a
+// goroutine of the client's, so a slow hook delays the later ones (and the
+// WithNotifier notifications) but never a request.
+func WithOnHealthChange(hook func(HealthEvent)) LBOption {
+	return func(o *lbOptions) {
+		o.onHealthChange = hook
+	}
+}
+
+// WithDNSRefresh re-resolves every backend's hostname each interval, for
+// backends behind gateways whose IPs change. Failed lookups count as failed
+// health checks, and when the addresses change idle connections are dropped so
+// the backend recovers without a restart.
+func WithDNSRefresh(interval time.Duration) LBOption {
+	return func(o *lbOptions) {
+		o.dnsRefresh = interval
+	}
+}
+
+// WithDegradation adds a degraded state between healthy and down: backends
+// with elevated error rates or latency keep a reduced share of traffic.
+func WithDegradation(cfg Degradation) LBOption {
+	return func(o *lbOptions) {
+		cfg = cfg.withDefaults()
+		o.degradation = &cfg
+	}
+}
+
+// WithMetrics reports every backend request and breaker transition to sink.
+// It can be given several times to report to several sinks.
+func WithMetrics(sink MetricsSink) LBOption {
+	return func(o *lbOptions) {
+		o.metrics = addSink(o.metrics, sink)
+	}
+}
+
+// WithMeterProvider publishes OpenTelemetry metrics through mp: request counts
+// by outcome class, durations, time to first token, token counters, in-flight
+// requests and breaker transitions.
+func WithMeterProvider(mp metric.MeterProvider) LBOption {
+	return WithMetrics(newOtelSink(mp))
+}
+
+// WithFailover retries a request that failed with a fatal error (see
+// DefaultIsSuccessful) on other backends, trying up to maxAttempts backends in
+// total. Requests whose body is read from an io.Reader (image edits, audio
+// transcriptions, upload parts) and streams are never retried.
+func WithFailover(maxAttempts int) LBOption {
+	return func(o *lbOptions) {
+		o.maxAttempts = maxAttempts
+	}
+}
+
+// WithTracerProvider creates a span per backend attempt with tp, as a child of
+// the span in the request's context, and propagates the trace context to the
+// backend with the global OpenTelemetry propagator.
+func WithTracerProvider(tp trace.TracerProvider) LBOption {
+	return func(o *lbOptions) {
+		o.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// WithLogger logs the LB's decisions to logger: backend selection and model
+// mapping at debug level, failovers, breaker trips, health problems and stalled
+// streams at warn level, and recoveries at info level.
+func WithLogger(logger *slog.Logger) LBOption {
+	return func(o *lbOptions) {
+		o.logger = logger
+	}
+}
+
+// WithHooks calls hooks at the lifecycle points of every backend attempt. It
+// can be given several times; hooks are called in the order they were given.
+func WithHooks(hooks Hooks) LBOption {
+	return func(o *lbOptions) {
+		o.hooks = append(o.hooks, hooks)
+	}
+}
+
+// WithPricing sets the price of each model, by the model name sent to the
+// backend, for the cost accounting of Client.Stats and the hooks. Backends can
+// override it with OpenaiClientConfig.Prices.
+func WithPricing(prices map[string]Price) LBOption {
+	return func(o *lbOptions) {
+		o.prices = prices
+	}
+}
+
+// WithAudit writes a summary of every backend attempt (model, backend, tokens,
+// latency, outcome, and optionally the prompt and completion) to cfg.Writer.
+func WithAudit(cfg Audit) LBOption {
+	return func(o *lbOptions) {
+		cfg = cfg.withDefaults()
+		o.audit = &cfg
+	}
+}
+
+// WithNotifier POSTs a notification to cfg.URL when a backend leaves rotation
+// (breaker opened, quarantined, ejected, unhealthy) or recovers.
+func WithNotifier(cfg Notifier) LBOption {
+	return func(o *lbOptions) {
+		o.notifier = newNotifier(cfg)
+	}
+}
@@ -0,0 +1,126 @@
+package openailb
+
+import (
+	"context"
+
+	"github.com/sony/gobreaker/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// otelSink is a MetricsSink that records into OpenTelemetry instruments (WithMeterProvider).
+type otelSink struct {
+	requests    metric.Int64Counter
+	duration    metric.Float64Histogram
+	ttft        metric.Float64Histogram
+	tokens      metric.Int64Counter
+	inFlight    metric.Int64UpDownCounter
+	transitions metric.Int64Counter
+	cache       metric.Int64Counter
+}
+
+func newOtelSink(mp metric.MeterProvider) *otelSink {
+	meter := mp.Meter(tracerName)
+	s := &otelSink{}
+	var errs [7]error
+	s.requests, errs[0] = meter.Int64Counter("openailb.requests",
+		metric.WithDescription("Requests sent to backends, by outcome class."))
+	s.duration, errs[1] = meter.Float64Histogram("openailb.request.duration", metric.WithUnit("s"),
+		metric.WithDescription("Duration of backend requests; streams end when their body is drained."))
+	s.ttft, errs[2] = meter.Float64Histogram("openailb.time_to_first_token", metric.WithUnit("s"),
+		metric.WithDescription("Time to the first streamed chunk of streaming requests."))
+	s.tokens, errs[3] = meter.Int64Counter("openailb.tokens",
+		metric.WithDescription("Tokens reported by backends, by type (prompt or completion)."))
+	s.inFlight, errs[4] = meter.Int64UpDownCounter("openailb.requests.in_flight",
+		metric.WithDescription("Requests currently sent to a backend."))
+	s.transitions, errs[5] = meter.Int64Counter("openailb.breaker.transitions",
+		metric.WithDescription("Breaker state transitions."))
+	s.cache, errs[6] = meter.Int64Counter("openailb.cache.lookups",
+		metric.WithDescription("Response cache lookups, by result (hit or miss)."))
+	for _, err := range errs {
+		if err != nil {
+			// The instruments are still usable; report the problem like other OTel instrumentation does.
+			otel.Handle(err)
+		}
+	}
+	return s
+}
+
+func (s *otelSink) RequestStarted(backend string, svc ServiceType, model string) {
+	s.inFlight.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("backend", backend), attribute.String("service", string(svc))))
+}
+
+func (s *otelSink) RequestDone(m RequestMetrics) {
+	ctx := context.Background()
+	backend, svc, model := attribute.String("backend", m.Backend), attribute.String("service", string(m.Service)), attribute.String("model", m.Model)
+	s.inFlight.Add(ctx, -1, metric.WithAttributes(backend, svc))
+	attrs := []attribute.KeyValue{backend, svc, model}
+	for k, v := range m.Labels {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	s.requests.Add(ctx, 1, metric.WithAttributes(append(attrs, attribute.String("class", string(m.Class)))...))
+	s.duration.Record(ctx, m.Duration.Seconds(), metric.WithAttributes(attrs...))
+	if m.TTFT > 0 {
+		s.ttft.Record(ctx, m.TTFT.Seconds(), metric.WithAttributes(backend, model))
+	}
+	if m.PromptTokens > 0 {
+		s.tokens.Add(ctx, m.PromptTokens, metric.WithAttributes(backend, model, attribute.String("type", "prompt")))
+	}
+	if m.CompletionTokens > 0 {
+		s.tokens.Add(ctx, m.CompletionTokens, metric.WithAttributes(backend, model, attribute.String("type", "completion")))
+	}
+}
+
+func (s *otelSink) BreakerStateChanged(backend, breaker string, from, to gobreaker.State) {
+	s.transitions.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("backend", backend), attribute.String("breaker", breaker),
+		attribute.String("from", from.String()), attribute.String("to", to.String())))
+}
+
+func (s *otelSink) CacheLookup(svc ServiceType, model string, hit bool) {
+	s.cache.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("service", string(svc)), attribute.String("model", model), attribute.String("result", cacheResult(hit))))
+}
+
+// cacheResult is the result label of a cache lookup.
+func cacheResult(hit bool) string {
+	if hit {
+		return "hit"
+	}
+	return "miss"
+}
+
+// multiSink fans measurements out to several sinks.
+type multiSink []MetricsSink
+
+func (m multiSink) RequestStarted(backend string, svc ServiceType, model string) {
+	for _, s := range m {
+		s.RequestStarted(backend, svc, model)
+	}
+}
+
+func (m multiSink) RequestDone(r RequestMetrics) {
+	for _, s := range m {
+		s.RequestDone(r)
+	}
+}
+
+func (m multiSink) BreakerStateChanged(backend, breaker string, from, to gobreaker.State) {
+	for _, s := range m {
+		s.BreakerStateChanged(backend, breaker, from, to)
+	}
+}
+
+// addSink adds sink to the sinks already configured.
+func addSink(existing, sink MetricsSink) MetricsSink {
+	switch s := existing.(type) {
+	case nil:
+		return sink
+	case multiSink:
+		return append(s[:len(s):len(s)], sink)
+	default:
+		return multiSink{s, sink}
+	}
+}
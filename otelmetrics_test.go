@@ -0,0 +1,69 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openai/openai-go/v3/option"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestOtelMetrics(t *testing.T) {
+	t.Parallel()
+
+	failServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failServer.Close()
+	okServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "ok"}}], "usage": {"prompt_tokens": 5, "completion_tokens": 2}}`))
+	}))
+	defer okServer.Close()
+
+	reader := sdkmetric.NewManualReader()
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: failServer.URL},
+		{APIKey: "k2", BaseURL: okServer.URL},
+	}, WithCBSettings(tripAfter(1)), WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+
+	for i := 0; i < 2; i++ {
+		_, _ = client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	sums := make(map[string]int64)
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, p := range data.DataPoints {
+					sums[m.Name] += p.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, p := range data.DataPoints {
+					sums[m.Name] += int64(p.Count)
+				}
+			}
+		}
+	}
+
+	want := map[string]int64{
+		"openailb.requests":            2,
+		"openailb.request.duration":    2,
+		"openailb.tokens":              7,
+		"openailb.requests.in_flight":  0,
+		"openailb.breaker.transitions": 1,
+	}
+	for name, value := range want {
+		if sums[name] != value {
+			t.Errorf("Expected %s to be %d, got %d", name, value, sums[name])
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package openailb
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// OutlierDetection configures Envoy-style outlier ejection (WithOutlierDetection).
+// Independently of the breakers, every Interval the LB compares each backend's
+// error rate and mean latency over that interval against the fleet median and
+// temporarily ejects the outliers. Zero fields take the defaults noted below.
+type OutlierDetection struct {
+	// Interval is how often backends are evaluated, and the window the figures cover (default 10s).
+	Interval time.Duration
+	// BaseEjectionTime is how long a first ejection lasts; each repeated
+	// ejection of the same backend lasts that many times longer (default 30s).
+	BaseEjectionTime time.Duration
+	// MaxEjectionPercent caps the share of backends ejected at once (default 50).
+	// At least one backend is always left in rotation.
+	MaxEjectionPercent float64
+	// MinRequests is the traffic a backend needs within an interval to be evaluated (default 10).
+	MinRequests int
+	// ErrorRateMargin ejects backends whose error rate exceeds the fleet median
+	// by more than this many points, e.g. 0.2 for 20% (default 0.2).
+	ErrorRateMargin float64
+	// LatencyFactor ejects backends whose mean latency exceeds the fleet median
+	// by this factor, e.g. 3 for 3x. Zero disables latency-based ejection.
+	LatencyFactor float64
+}
+
+// withDefaults fills in the zero fields.
+func (o OutlierDetection) withDefaults() OutlierDetection {
+	if o.Interval <= 0 {
+		o.Interval = 10 * time.Second
+	}
+	if o.BaseEjectionTime <= 0 {
+		o.BaseEjectionTime = 30 * time.Second
+	}
+	if o.MaxEjectionPercent <= 0 {
+		o.MaxEjectionPercent = 50
+	}
+	if o.MinRequests <= 0 {
+		o.MinRequests = 10
+	}
+	if o.ErrorRateMargin <= 0 {
+		o.ErrorRateMargin = 0.2
+	}
+	return o
+}
+
+// outlierStats accumulates one backend's figures over the current interval.
+type outlierStats struct {
+	mu       sync.Mutex
+	requests int
+	failures int
+	latency  time.Duration
+
+	// Ejection state, guarded by LoadBalancer.mu.
+	ejectedUntil time.Time
+	ejections    int
+}
+
+func (s *outlierStats) record(failed bool, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	s.latency += latency
+	if failed {
+		s.failures++
+	}
+}
+
+// take returns the interval's figures and starts a new interval.
+func (s *outlierStats) take() (requests, failures int, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests, failures, latency = s.requests, s.failures, s.latency
+	s.requests, s.failures, s.latency = 0, 0, 0
+	return
+}
+
+// ejected reports whether the backend is currently ejected. Callers hold lb.mu.
+func (c *SafeClient) ejected(now time.Time) bool {
+	return now.Before(c.outlier.ejectedUntil)
+}
+
+// evaluateOutliers ejects outliers once per interval. Callers hold lb.mu.
+func (lb *LoadBalancer) evaluateOutliers(now time.Time) {
+	cfg := lb.options.outlierDetection
+	if cfg == nil || now.Sub(lb.lastOutlierEval) < cfg.Interval {
+		return
+	}
+	lb.lastOutlierEval = now
+
+	type figures struct {
+		client    *SafeClient
+		errorRate float64
+		latency   float64
+	}
+	var evaluated []figures
+	ejected := 0
+	for _, c := range lb.backends() {
+		requests, failures, latency := c.outlier.take()
+		if c.ejected(now) {
+			ejected++
+			continue
+		}
+		if requests < cfg.MinRequests {
+			continue
+		}
+		evaluated = append(evaluated, figures{
+			client:    c,
+			errorRate: float64(failures) / float64(requests),
+			latency:   float64(latency) / float64(requests),
+		})
+	}
+	if len(evaluated) < 2 {
+		// No fleet to compare against.
+		return
+	}
+
+	medianErrorRate := median(evaluated, func(f figures) float64 { return f.errorRate })
+	medianLatency := median(evaluated, func(f figures) float64 { return f.latency })
+
+	var outliers []figures
+	for _, f := range evaluated {
+		if f.errorRate > medianErrorRate+cfg.ErrorRateMargin ||
+			(cfg.LatencyFactor > 0 && f.latency > medianLatency*cfg.LatencyFactor) {
+			outliers = append(outliers, f)
+		} else if f.client.outlier.ejections > 0 {
+			// A healthy interval shortens the next ejection again.
+			f.client.outlier.ejections--
+		}
+	}
+	// Eject the worst offenders first when the cap doesn't allow ejecting all of them.
+	sort.Slice(outliers, func(a, b int) bool {
+		if outliers[a].errorRate != outliers[b].errorRate {
+			return outliers[a].errorRate > outliers[b].errorRate
+		}
+		return outliers[a].latency > outliers[b].latency
+	})
+
+	maxEjected := min(int(float64(len(lb.backends()))*cfg.MaxEjectionPercent/100), len(lb.backends())-1)
+	for _, f := range outliers {
+		if ejected >= maxEjected {
+			break
+		}
+		f.client.outlier.ejections++
+		f.client.outlier.ejectedUntil = now.Add(cfg.BaseEjectionTime * time.Duration(f.client.outlier.ejections))
+		f.client.emitHealth(HealthEvent{Kind: HealthEjected, At: now, Until: f.client.outlier.ejectedUntil})
+		ejected++
+	}
+}
+
+// median returns the median of value over items.
+func median[T any](items []T, value func(T) float64) float64 {
+	values := make([]float64, len(items))
+	for i, item := range items {
+		values[i] = value(item)
+	}
+	sort.Float64s(values)
+	mid := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[mid-1] + values[mid]) / 2
+	}
+	return values[mid]
+}
@@ -0,0 +1,49 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestOutlierEjection(t *testing.T) {
+	t.Parallel()
+
+	good1 := newNamedServer(t, "good1")
+	defer good1.Close()
+	good2 := newNamedServer(t, "good2")
+	defer good2.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer bad.Close()
+
+	// Keep the breakers out of the way so only outlier detection can remove the bad backend.
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: good1.URL},
+		{APIKey: "k2", BaseURL: good2.URL},
+		{APIKey: "k3", BaseURL: bad.URL},
+	}, WithCBSettings(tripAfter(1000)), WithOutlierDetection(OutlierDetection{
+		Interval:    50 * time.Millisecond,
+		MinRequests: 3,
+	}))
+
+	for i := 0; i < 9; i++ {
+		_, _ = client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	// The first pick after the interval evaluates and ejects the bad backend.
+	for i := 0; i < 10; i++ {
+		if _, err := client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0)); err != nil {
+			t.Fatalf("Request %d after ejection failed: %v", i, err)
+		}
+	}
+	if !client.Chat.Completions.lb.backends()[2].ejected(time.Now()) {
+		t.Error("Expected the bad backend to be ejected")
+	}
+}
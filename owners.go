@@ -0,0 +1,83 @@
+package openailb
+
+import (
+	"sync"
+	"time"
+)
+
+// responseOwnerTTL is how long the owner of a background response is
+// remembered, well past how long responses are polled for.
+const responseOwnerTTL = 24 * time.Hour
+
+// uploadOwnerTTL is how long the owner of an upload is remembered: uploads
+// expire an hour after they are created.
+const uploadOwnerTTL = time.Hour
+
+// owners remembers, for ttl, which backend created each of the resources
+// that only exist there, like background responses and uploads, so that the
+// calls on them can be routed back to it.
+type owners struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]owner // resource ID -> owner
+	swept   time.Time        // When the expired entries were last dropped.
+}
+
+type owner struct {
+	backend *SafeClient
+	expires time.Time
+}
+
+// store remembers backend as the owner of id.
+func (o *owners) store(id string, backend *SafeClient) {
+	now := time.Now()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.entries == nil {
+		o.entries = make(map[string]owner)
+	}
+	// IDs that are never polled to the end would otherwise pile up.
+	if now.Sub(o.swept) >= o.ttl {
+		for id, e := range o.entries {
+			if !now.Before(e.expires) {
+				delete(o.entries, id)
+			}
+		}
+		o.swept = now
+	}
+	o.entries[id] = owner{backend: backend, expires: now.Add(o.ttl)}
+}
+
+// load returns the owner of id, unless it is unknown or expired.
+func (o *owners) load(id string) (*SafeClient, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	e, ok := o.entries[id]
+	if !ok {
+		return nil, false
+	}
+	if !time.Now().Before(e.expires) {
+		delete(o.entries, id)
+		return nil, false
+	}
+	return e.backend, true
+}
+
+// delete forgets the owner of id.
+func (o *owners) delete(id string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.entries, id)
+}
+
+// purge forgets every resource owned by backend, once it left the pool.
+func (o *owners) purge(backend *SafeClient) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for id, e := range o.entries {
+		if e.backend == backend {
+			delete(o.entries, id)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package openailb
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// ParamPolicy adapts the chat completions sent to a backend
+// (OpenaiClientConfig.Params) to what it supports, so that heterogeneous
+// OpenAI-compatible servers don't reject otherwise valid requests.
+type ParamPolicy struct {
+	// MaxTokens, if set, caps max_tokens and max_completion_tokens.
+	MaxTokens int64 `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty"`
+	// MinTemperature and MaxTemperature, if set, clamp the temperature.
+	MinTemperature *float64 `json:"min_temperature,omitempty" yaml:"min_temperature,omitempty"`
+	MaxTemperature *float64 `json:"max_temperature,omitempty" yaml:"max_temperature,omitempty"`
+	// ServiceTier, if set, replaces the requested service tier, e.g. "flex".
+	ServiceTier string `json:"service_tier,omitempty" yaml:"service_tier,omitempty"`
+	// Strip lists the parameters removed from the request body, by JSON
+	// name, e.g. "logprobs" and "top_logprobs" for a server without them.
+	Strip []string `json:"strip,omitempty" yaml:"strip,omitempty"`
+}
+
+// validate checks p, to be reported under field.
+func (p *ParamPolicy) validate(field string) []error {
+	var errs []error
+	if p.MaxTokens < 0 {
+		errs = append(errs, fmt.Errorf("%s.max_tokens: must not be negative, got %d", field, p.MaxTokens))
+	}
+	if p.MinTemperature != nil && p.MaxTemperature != nil && *p.MinTemperature > *p.MaxTemperature {
+		errs = append(errs, fmt.Errorf("%s.min_temperature: must not exceed max_temperature, got %v > %v", field, *p.MinTemperature, *p.MaxTemperature))
+	}
+	for i, name := range p.Strip {
+		if name == "" {
+			errs = append(errs, fmt.Errorf("%s.strip[%d]: must not be empty", field, i))
+		}
+	}
+	return errs
+}
+
+// apply returns params adapted to the policy, with the options stripping
+// the parameters the policy removes.
+func (p *ParamPolicy) apply(params openai.ChatCompletionNewParams) (openai.ChatCompletionNewParams, []option.RequestOption) {
+	if p == nil {
+		return params, nil
+	}
+	if p.MaxTokens > 0 {
+		if params.MaxTokens.Valid() && params.MaxTokens.Value > p.MaxTokens {
+			params.MaxTokens = openai.Int(p.MaxTokens)
+		}
+		if params.MaxCompletionTokens.Valid() && params.MaxCompletionTokens.Value > p.MaxTokens {
+			params.MaxCompletionTokens = openai.Int(p.MaxTokens)
+		}
+	}
+	if params.Temperature.Valid() {
+		if p.MinTemperature != nil && params.Temperature.Value < *p.MinTemperature {
+			params.Temperature = openai.Float(*p.MinTemperature)
+		}
+		if p.MaxTemperature != nil && params.Temperature.Value > *p.MaxTemperature {
+			params.Temperature = openai.Float(*p.MaxTemperature)
+		}
+	}
+	if p.ServiceTier != "" {
+		params.ServiceTier = openai.ChatCompletionNewParamsServiceTier(p.ServiceTier)
+	}
+	var opts []option.RequestOption
+	for _, name := range p.Strip {
+		opts = append(opts, option.WithJSONDel(name))
+	}
+	return params, opts
+}
+
+// chatRequest returns params as sent to the backend, mapped by its ModelMap,
+// adapted to its ParamPolicy, with the system prompts injected, rewritten
+// by its TransformParams and trimmed to its context window with
+// WithHistoryTruncation, and opts followed by the options doing the rest,
+// its ExtraBody last.
+func (c *SafeClient) chatRequest(params openai.ChatCompletionNewParams, opts []option.RequestOption) (openai.ChatCompletionNewParams, []option.RequestOption) {
+	params.Model = mapModel(c, params.Model)
+	params, extra := c.config.Params.apply(params)
+	params.Messages = injectSystemPrompt(params.Messages, c.systemPrompt())
+	if transform := c.config.TransformParams; transform != nil {
+		params = transform(params)
+	}
+	if c.lb.options.truncateHistory {
+		var dropped int
+		if params, dropped = truncateHistory(params, c.contextWindow(params.Model)); dropped > 0 {
+			c.lb.options.logger.Debug("chat history truncated", "backend", c.Name, "model", params.Model, "dropped", dropped)
+		}
+	}
+	opts = append(opts[:len(opts):len(opts)], extra...)
+	return params, append(opts, c.extraBody()...)
+}
+
+// extraBody returns the options setting the backend's ExtraBody fields, in
+// the order of their keys.
+func (c *SafeClient) extraBody() []option.RequestOption {
+	keys := make([]string, 0, len(c.config.ExtraBody))
+	for key := range c.config.ExtraBody {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	opts := make([]option.RequestOption, 0, len(keys))
+	for _, key := range keys {
+		opts = append(opts, option.WithJSONSet(key, c.config.ExtraBody[key]))
+	}
+	return opts
+}
@@ -0,0 +1,142 @@
+package openailb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+)
+
+func TestParamPolicy(t *testing.T) {
+	t.Parallel()
+
+	bodies := make(chan map[string]any, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies <- body
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "ok"}}]}`))
+	}))
+	defer server.Close()
+	maxTemperature := 1.0
+	client := NewClient([]OpenaiClientConfig{{
+		APIKey:  "k1",
+		BaseURL: server.URL,
+		Params: &ParamPolicy{
+			MaxTokens:      1024,
+			MaxTemperature: &maxTemperature,
+			ServiceTier:    "flex",
+			Strip:          []string{"logprobs", "top_logprobs"},
+		},
+	}})
+
+	params := chatParams("m")
+	params.MaxTokens = openai.Int(4096)
+	params.Temperature = openai.Float(1.8)
+	params.Logprobs = openai.Bool(true)
+	params.TopLogprobs = openai.Int(5)
+	if _, err := client.Chat.Completions.New(context.Background(), params); err != nil {
+		t.Fatal(err)
+	}
+	body := <-bodies
+	if body["max_tokens"] != 1024.0 || body["temperature"] != 1.0 || body["service_tier"] != "flex" {
+		t.Errorf("Expected the policy to clamp and force the parameters, got %v", body)
+	}
+	if _, ok := body["logprobs"]; ok {
+		t.Errorf("Expected logprobs to be stripped, got %v", body)
+	}
+	if _, ok := body["top_logprobs"]; ok {
+		t.Errorf("Expected top_logprobs to be stripped, got %v", body)
+	}
+
+	stream := client.Chat.Completions.NewStreaming(context.Background(), params)
+	for stream.Next() {
+	}
+	if body := <-bodies; body["max_tokens"] != 1024.0 || body["logprobs"] != nil {
+		t.Errorf("Expected the policy to apply to streams, got %v", body)
+	}
+}
+
+func TestTransformParams(t *testing.T) {
+	t.Parallel()
+
+	bodies := make(chan map[string]any, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies <- body
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "ok"}}]}`))
+	}))
+	defer server.Close()
+	var seen string
+	client := NewClient([]OpenaiClientConfig{{
+		APIKey:   "k1",
+		BaseURL:  server.URL,
+		ModelMap: map[string]string{"m": "mapped"},
+		TransformParams: func(params openai.ChatCompletionNewParams) openai.ChatCompletionNewParams {
+			seen = params.Model
+			params.SetExtraFields(map[string]any{"top_k": 40})
+			return params
+		},
+	}})
+
+	params := chatParams("m")
+	if _, err := client.Chat.Completions.New(context.Background(), params); err != nil {
+		t.Fatal(err)
+	}
+	if body := <-bodies; body["top_k"] != 40.0 {
+		t.Errorf("Expected the transform's vendor field, got %v", body)
+	}
+	if seen != "mapped" {
+		t.Errorf("Expected the transform to see the mapped model, got %q", seen)
+	}
+	if params.ExtraFields() != nil {
+		t.Errorf("Expected the caller's params to be left alone, got %v", params.ExtraFields())
+	}
+}
+
+func TestExtraBody(t *testing.T) {
+	t.Parallel()
+
+	bodies := make(chan map[string]any, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		body["key"] = r.Header.Get("Authorization")
+		bodies <- body
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "ok"}}]}`))
+	}))
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "openai", BaseURL: server.URL},
+		{APIKey: "vllm", BaseURL: server.URL, ExtraBody: map[string]any{
+			"top_k":                                20,
+			"repetition_penalty":                   1.1,
+			"chat_template_kwargs.enable_thinking": false,
+		}},
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Chat.Completions.New(context.Background(), chatParams("m")); err != nil {
+			t.Fatal(err)
+		}
+		body := <-bodies
+		switch body["key"] {
+		case "Bearer openai":
+			if _, ok := body["top_k"]; ok {
+				t.Errorf("Expected no vendor fields for the other backend, got %v", body)
+			}
+		case "Bearer vllm":
+			kwargs, _ := body["chat_template_kwargs"].(map[string]any)
+			if body["top_k"] != 20.0 || body["repetition_penalty"] != 1.1 || kwargs["enable_thinking"] != false {
+				t.Errorf("Expected the backend's vendor fields, got %v", body)
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package openailb
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// drainPollInterval is how often a removed backend checks whether its
+// in-flight requests finished.
+const drainPollInterval = 100 * time.Millisecond
+
+// backends returns the current pool. The slice must not be modified.
+func (lb *LoadBalancer) backends() []*SafeClient {
+	if clients := lb.clients.Load(); clients != nil {
+		return *clients
+	}
+	return nil
+}
+
+// setBackends replaces the pool. Callers hold lb.poolMu, except in NewClient.
+func (lb *LoadBalancer) setBackends(clients []*SafeClient) {
+	lb.clients.Store(&clients)
+}
+
+// AddBackend adds a backend to the live pool. It takes traffic right away,
+// and is health checked like the others with WithHealthChecks. It is named
+// cfg.Name, which must not be taken, or else "Client-N", N counting every
+// backend added so far, and returns its name.
+func (c Client) AddBackend(cfg OpenaiClientConfig) (string, error) {
+	lb := c.lb
+	lb.poolMu.Lock()
+	defer lb.poolMu.Unlock()
+	if lb.closed.Load() {
+		return "", ErrClosed
+	}
+	name := backendName(cfg, lb.added)
+	if _, err := lb.clientByName(name); err == nil {
+		return "", fmt.Errorf("a backend named %q already exists", name)
+	}
+	if _, err := lb.poolNamed(cfg.Pool); err != nil {
+		return "", err
+	}
+	backend := lb.newSafeClient(cfg, name)
+	if lb.restored != nil {
+		for _, svc := range serviceTypes {
+			backend.Breaker(svc)
+		}
+	}
+	lb.added++
+
+	current := lb.backends()
+	clients := make([]*SafeClient, 0, len(current)+1)
+	lb.setBackends(append(append(clients, current...), backend))
+	backend.startHealthChecks()
+	lb.options.logger.Info("backend added", "backend", name, "base_url", cfg.BaseURL)
+	return name, nil
+}
+
+// RemoveBackend takes the backend called name out of the live pool. New
+// requests stop going to it at once, while the requests in flight on it run
+// to completion; then its health checks stop and its idle connections close.
+func (c Client) RemoveBackend(name string) error {
+	backend, err := c.lb.takeOut(name)
+	if err != nil {
+		return err
+	}
+	c.lb.options.logger.Info("backend removed", "backend", name, "in_flight", backend.stats.inFlight.Load())
+	c.lb.background.Add(1)
+	go func() {
+		defer c.lb.background.Done()
+		backend.drain()
+	}()
+	return nil
+}
+
+// DrainBackend takes the backend called name out of the live pool like
+// RemoveBackend, but waits up to timeout for its in-flight requests, streams
+// included, to finish before stopping its health checks and closing its idle
+// connections, e.g. before decommissioning a provider. A timeout of 0 waits
+// without a limit. If requests are still in flight at the timeout, it returns
+// an error wrapping context.DeadlineExceeded; they keep running, but the
+// backend is removed all the same.
+func (c Client) DrainBackend(name string, timeout time.Duration) error {
+	backend, err := c.lb.takeOut(name)
+	if err != nil {
+		return err
+	}
+	inFlight := backend.stats.inFlight.Load()
+	c.lb.options.logger.Info("draining backend", "backend", name, "in_flight", inFlight, "timeout", timeout)
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	drained := backend.waitIdle(ctx)
+	backend.retire()
+	if !drained {
+		return fmt.Errorf("%s: %d requests still in flight after %s: %w", name, backend.stats.inFlight.Load(), timeout, context.DeadlineExceeded)
+	}
+	c.lb.options.logger.Info("backend drained", "backend", name)
+	return nil
+}
+
+// takeOut removes the backend called name from the pool and returns it.
+func (lb *LoadBalancer) takeOut(name string) (*SafeClient, error) {
+	lb.poolMu.Lock()
+	defer lb.poolMu.Unlock()
+	backend, err := lb.clientByName(name)
+	if err != nil {
+		return nil, err
+	}
+	current := lb.backends()
+	clients := make([]*SafeClient, 0, len(current)-1)
+	for _, c := range current {
+		if c != backend {
+			clients = append(clients, c)
+		}
+	}
+	lb.setBackends(clients)
+	lb.responseOwners.purge(backend)
+	lb.uploadOwners.purge(backend)
+	return backend, nil
+}
+
+// syncBackends makes the backends named in owned those of desired, whose
+// configs are all named: it adds the ones missing from the pool and removes
+// the others. It returns the names owned now.
+func (c Client) syncBackends(owned map[string]bool, desired []OpenaiClientConfig) map[string]bool {
+	now := make(map[string]bool, len(desired))
+	for _, cfg := range desired {
+		if cfg.Name == "" {
+			c.lb.options.logger.Warn("discovered backend not added: it has no name", "base_url", cfg.BaseURL)
+			continue
+		}
+		if _, err := c.lb.clientByName(cfg.Name); err == nil && owned[cfg.Name] {
+			now[cfg.Name] = true
+			continue
+		}
+		if _, err := c.AddBackend(cfg); err != nil {
+			c.lb.options.logger.Warn("discovered backend not added", "backend", cfg.Name, "error", err)
+			continue
+		}
+		now[cfg.Name] = true
+	}
+	for name := range owned {
+		if !now[name] {
+			_ = c.RemoveBackend(name)
+		}
+	}
+	return now
+}
+
+// drain waits for the in-flight requests of a removed backend, then retires it.
+func (c *SafeClient) drain() {
+	c.waitIdle(context.Background())
+	c.retire()
+}
+
+// waitIdle waits until ctx is done for the backend's in-flight requests to
+// finish, and reports whether they did.
+func (c *SafeClient) waitIdle(ctx context.Context) bool {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for c.stats.inFlight.Load() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return c.stats.inFlight.Load() == 0
+		}
+	}
+	return true
+}
+
+// retire stops the background checks of a removed backend and closes its
+// idle connections. It may be called more than once.
+func (c *SafeClient) retire() {
+	c.retireOnce.Do(func() { close(c.stop) })
+	c.httpClient.CloseIdleConnections()
+}
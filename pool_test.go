@@ -0,0 +1,165 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestAddBackend(t *testing.T) {
+	t.Parallel()
+
+	a := newNamedServer(t, "a")
+	defer a.Close()
+	b := newNamedServer(t, "b")
+	defer b.Close()
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: a.URL}})
+
+	name, err := client.AddBackend(OpenaiClientConfig{APIKey: "k2", BaseURL: b.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "Client-1" {
+		t.Errorf("Expected the new backend to be Client-1, got %s", name)
+	}
+	if hits := countHits(t, client, 4); hits["a"] != 2 || hits["b"] != 2 {
+		t.Errorf("Expected the new backend to share traffic, got %v", hits)
+	}
+	if health := client.Health(); len(health) != 2 || health[1].Name != "Client-1" {
+		t.Errorf("Expected the new backend in the health snapshot, got %+v", health)
+	}
+}
+
+func TestRemoveBackendDrains(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	arrived := make(chan struct{}, 1)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "slow"}}]}`))
+	}))
+	defer slow.Close()
+	fast := newNamedServer(t, "fast")
+	defer fast.Close()
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: slow.URL},
+		{APIKey: "k2", BaseURL: fast.URL},
+	})
+	backend := client.lb.backends()[0]
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Chat.Completions.New(context.Background(), chatParams("m"))
+		done <- err
+	}()
+	<-arrived
+
+	if err := client.RemoveBackend("Client-0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.RemoveBackend("Client-0"); err == nil {
+		t.Error("Expected removing a removed backend to fail")
+	}
+	if hits := countHits(t, client, 3); hits["fast"] != 3 {
+		t.Errorf("Expected new requests to skip the removed backend, got %v", hits)
+	}
+
+	select {
+	case <-backend.stop:
+		t.Fatal("Expected the backend to wait for its in-flight request")
+	default:
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("Expected the in-flight request to complete, got %v", err)
+	}
+	select {
+	case <-backend.stop:
+	case <-time.After(time.Second):
+		t.Error("Expected the backend to stop once drained")
+	}
+}
+
+func TestDrainBackend(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	arrived := make(chan struct{}, 2)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		arrived <- struct{}{}
+		<-release
+		_, _ = w.Write([]byte("data: {\"choices\": [{\"delta\": {\"content\": \"slow\"}}]}\n\ndata: [DONE]\n\n"))
+	}))
+	defer slow.Close()
+	fast := newNamedServer(t, "fast")
+	defer fast.Close()
+	client := NewClient([]OpenaiClientConfig{
+		{Name: "slow", APIKey: "k1", BaseURL: slow.URL},
+		{Name: "fast", APIKey: "k2", BaseURL: fast.URL},
+	})
+	backend := client.lb.backends()[0]
+
+	// The stream is in flight until its body is read.
+	stream := client.Chat.Completions.NewStreaming(context.Background(), chatParams("m"))
+	<-arrived
+	drained := make(chan error, 1)
+	go func() { drained <- client.DrainBackend("slow", time.Second) }()
+	if !waitFor(t, time.Second, func() bool { return len(client.lb.backends()) == 1 }) {
+		t.Fatal("Expected the draining backend to leave the pool at once")
+	}
+	if hits := countHits(t, client, 2); hits["fast"] != 2 {
+		t.Errorf("Expected new requests to skip the draining backend, got %v", hits)
+	}
+	select {
+	case err := <-drained:
+		t.Fatalf("Expected the drain to wait for the open stream, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	for stream.Next() {
+	}
+	_ = stream.Close()
+	if err := <-drained; err != nil {
+		t.Errorf("Expected the drain to finish with the stream, got %v", err)
+	}
+	select {
+	case <-backend.stop:
+	default:
+		t.Error("Expected the drained backend to be stopped")
+	}
+
+	// A request outlasting the timeout is reported, and the backend removed anyway.
+	hung := make(chan struct{})
+	stuck := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-hung
+	}))
+	defer stuck.Close()
+	defer close(hung)
+	client = NewClient([]OpenaiClientConfig{{Name: "stuck", APIKey: "k3", BaseURL: stuck.URL}})
+	go func() {
+		_, _ = client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+	}()
+	<-arrived
+	err := client.DrainBackend("stuck", 50*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "stuck: 1 requests still in flight after 50ms") {
+		t.Errorf("Expected the timeout to be reported, got %v", err)
+	}
+	if len(client.lb.backends()) != 0 {
+		t.Error("Expected the backend to be removed after the timeout")
+	}
+	if err := client.DrainBackend("stuck", time.Second); err == nil {
+		t.Error("Expected draining a removed backend to fail")
+	}
+}
@@ -0,0 +1,157 @@
+package openailb
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Pool is a named group of backends serving a family of models, e.g. a
+// "chat-large" pool for gpt-4o and o1 on dedicated capacity, and an
+// "embeddings" pool for text-embedding-3-*. Backends join it by setting
+// OpenaiClientConfig.Pool to its name.
+type Pool struct {
+	Name string
+	// Models are the requested models routed to the pool, each an exact name
+	// or a prefix ending in "*", e.g. "gpt-4o*".
+	Models []string
+	// Options configure the pool's backends and requests on top of the
+	// client's options, e.g. WithFailover, WithCBSettings, WithHealthChecks or
+	// WithSlowStart. The options acting on the whole client (WithStateStore,
+	// WithOutlierDetection, WithLogger, WithMetrics, WithTracerProvider,
+	// WithAdminAuth, WithSecretProvider, WithDNSRefresh, WithOnHealthChange,
+	// WithNotifier, WithAudit, WithMaxConcurrency, WithTenantQuotas,
+	// WithLoadShedding, WithBudget, WithUsageAlerts, WithRateLimiter,
+	// WithBreakerSignals, WithModelAlias, WithModelValidation and
+	// WithResponseCache) are ignored.
+	Options []LBOption
+}
+
+// WithPools splits the client into pools. A request goes to the first pool
+// listing its model; the requests for other models go to the backends
+// without a Pool. Each pool balances only among its own backends.
+func WithPools(pools ...Pool) LBOption {
+	return func(o *lbOptions) {
+		o.pools = append(o.pools, pools...)
+	}
+}
+
+// poolRoute is a Pool together with the LoadBalancer of its backends.
+type poolRoute struct {
+	Pool
+	lb *LoadBalancer
+}
+
+// serves reports whether the pool lists model.
+func (p *poolRoute) serves(model string) bool {
+	for _, m := range p.Models {
+		if prefix, ok := strings.CutSuffix(m, "*"); (ok && strings.HasPrefix(model, prefix)) || m == model {
+			return true
+		}
+	}
+	return false
+}
+
+// newPools builds the pools of options over the pool state of base.
+func newPools(base *LoadBalancer) []*poolRoute {
+	var routes []*poolRoute
+	for _, p := range base.options.pools {
+		options := base.options
+		options.hooks = slices.Clip(options.hooks)
+		for _, o := range p.Options {
+			o(&options)
+		}
+		whole := base.options
+		options.stateStore = whole.stateStore
+		options.outlierDetection = whole.outlierDetection
+		options.logger = whole.logger
+		options.metrics = whole.metrics
+		options.tracer = whole.tracer
+		options.adminAuth = whole.adminAuth
+		options.secrets, options.secretRefresh = whole.secrets, whole.secretRefresh
+		options.dnsRefresh = whole.dnsRefresh
+		options.onHealthChange = whole.onHealthChange
+		options.notifier = whole.notifier
+		options.audit = whole.audit
+		options.pools = whole.pools
+		options.admission = whole.admission
+		options.tenants = whole.tenants
+		options.shedding = whole.shedding
+		options.budget = whole.budget
+		options.alerts = whole.alerts
+		options.rateLimiter = whole.rateLimiter
+		options.signals = whole.signals
+		options.aliases = whole.aliases
+		options.validateModels = whole.validateModels
+		options.cache = whole.cache
+		routes = append(routes, &poolRoute{Pool: p, lb: &LoadBalancer{lbState: base.lbState, options: options, pool: p.Name}})
+	}
+	return routes
+}
+
+// route returns the LoadBalancer of the pool serving model: lb itself if
+// lb is a pool's already or no pool lists model.
+func (lb *LoadBalancer) route(model string) *LoadBalancer {
+	if lb.pool != "" {
+		return lb
+	}
+	for _, p := range lb.pools {
+		if p.serves(model) {
+			return p.lb
+		}
+	}
+	return lb
+}
+
+// poolNamed returns the LoadBalancer of the pool called name, or lb itself
+// for name "".
+func (lb *LoadBalancer) poolNamed(name string) (*LoadBalancer, error) {
+	if name == "" {
+		return lb, nil
+	}
+	for _, p := range lb.pools {
+		if p.Name == name {
+			return p.lb, nil
+		}
+	}
+	return nil, fmt.Errorf("no pool named %q (WithPools)", name)
+}
+
+// noBackendError explains why lb has no backend at all for model.
+func (lb *LoadBalancer) noBackendError(model string) error {
+	switch {
+	case lb.pool != "":
+		return fmt.Errorf("pool %q has no backends", lb.pool)
+	case len(lb.pools) > 0:
+		return fmt.Errorf("no pool serves model %q, and no backend is outside the pools", model)
+	default:
+		return errors.New("no clients configured")
+	}
+}
+
+// validatePools reports the pools of opts without a name or with a taken
+// one, and the configs naming a pool missing from opts.
+func validatePools(configs []OpenaiClientConfig, opts []LBOption) error {
+	var options lbOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	var errs []error
+	names := make(map[string]bool)
+	for i, p := range options.pools {
+		switch {
+		case p.Name == "":
+			errs = append(errs, fmt.Errorf("pools[%d].Name: is required", i))
+		case names[p.Name]:
+			errs = append(errs, fmt.Errorf("pools[%d].Name: %q is already the name of another pool", i, p.Name))
+		}
+		names[p.Name] = true
+	}
+	for i, cfg := range configs {
+		if cfg.Pool != "" && !names[cfg.Pool] {
+			errs = append(errs, fmt.Errorf("configs[%d].Pool: no pool named %q (WithPools)", i, cfg.Pool))
+		}
+	}
+	return errors.Join(errs...)
+}
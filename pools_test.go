@@ -0,0 +1,82 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestPools(t *testing.T) {
+	t.Parallel()
+
+	large, small, other := newNamedServer(t, "large"), newNamedServer(t, "small"), newNamedServer(t, "other")
+	defer large.Close()
+	defer small.Close()
+	defer other.Close()
+	failServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failServer.Close()
+
+	client, err := New([]OpenaiClientConfig{
+		{Name: "large-down", APIKey: "k1", BaseURL: failServer.URL, Pool: "chat-large"},
+		{Name: "large", APIKey: "k2", BaseURL: large.URL, Pool: "chat-large"},
+		{Name: "small", APIKey: "k3", BaseURL: small.URL, Pool: "chat-small"},
+		{Name: "other", APIKey: "k4", BaseURL: other.URL},
+	}, WithPools(
+		Pool{Name: "chat-large", Models: []string{"gpt-4o", "o1*"}, Options: []LBOption{WithCBSettings(tripAfter(1))}},
+		Pool{Name: "chat-small", Models: []string{"gpt-4o-mini"}},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The pool's breakers trip after one failure, taking large-down out.
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("gpt-4o"), option.WithMaxRetries(0)); err == nil {
+		t.Fatal("Expected large-down to fail")
+	}
+	for model, want := range map[string]string{"gpt-4o": "large", "o1-preview": "large", "gpt-4o-mini": "small", "llama": "other"} {
+		for i := 0; i < 2; i++ {
+			resp, err := client.Chat.Completions.New(context.Background(), chatParams(model), option.WithMaxRetries(0))
+			if err != nil {
+				t.Fatalf("%s: %v", model, err)
+			}
+			if got := resp.Choices[0].Message.Content; got != want {
+				t.Errorf("Expected %s to be served by the %s backends, got %s", model, want, got)
+			}
+		}
+	}
+	if _, err := client.AddBackend(OpenaiClientConfig{APIKey: "k5", BaseURL: small.URL, Pool: "embeddings"}); err == nil || err.Error() != `no pool named "embeddings" (WithPools)` {
+		t.Errorf("Expected an unknown pool to be refused, got %v", err)
+	}
+	for _, h := range client.Health() {
+		if h.Name == "small" && h.Pool != "chat-small" {
+			t.Errorf("Expected the health of small to name its pool, got %q", h.Pool)
+		}
+	}
+
+	pooled := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: small.URL, Pool: "chat-small"}},
+		WithPools(Pool{Name: "chat-small", Models: []string{"gpt-4o-mini"}}))
+	_, err = pooled.Chat.Completions.New(context.Background(), openai.ChatCompletionNewParams{Model: "gpt-4o"})
+	if err == nil || err.Error() != `no pool serves model "gpt-4o", and no backend is outside the pools` {
+		t.Errorf("Expected a model without a pool to be reported, got %v", err)
+	}
+	if stream := pooled.Chat.Completions.NewStreaming(context.Background(), chatParams("gpt-4o-mini")); stream.Err() != nil {
+		t.Errorf("Expected streams to be routed to their pool, got %v", stream.Err())
+	}
+	if stream := pooled.Chat.Completions.NewStreaming(context.Background(), chatParams("gpt-4o")); stream.Err() == nil || stream.Err().Error() != err.Error() {
+		t.Errorf("Expected a stream without a pool to fail likewise, got %v", stream.Err())
+	}
+
+	_, err = New([]OpenaiClientConfig{{APIKey: "k1", BaseURL: small.URL, Pool: "nope"}}, WithPools(Pool{Models: []string{"m"}}))
+	for _, want := range []string{"pools[0].Name: is required", `configs[0].Pool: no pool named "nope"`} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in the error, got:\n%v", want, err)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package openailb
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// withLabels runs f with the pprof labels backend and model (after model
+// mapping) of a, so CPU and goroutine profiles of a service embedding the LB
+// can be sliced per backend, e.g. with `go tool pprof -tagfocus backend=azure`.
+// Goroutines the HTTP transport starts for the call, such as its dial and
+// connection readers, inherit the labels.
+func withLabels(ctx context.Context, a attempt, f func(context.Context)) {
+	pprof.Do(ctx, pprof.Labels("backend", a.client.Name, "model", a.model), f)
+}
@@ -0,0 +1,42 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestPprofLabels(t *testing.T) {
+	t.Parallel()
+
+	server := newNamedServer(t, "ok")
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL, ModelMap: map[string]string{"m": "mapped"}}})
+
+	labels := map[string]string{}
+	record := option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		pprof.ForLabels(req.Context(), func(key, value string) bool {
+			labels[key] = value
+			return true
+		})
+		return next(req)
+	})
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m"), record); err != nil {
+		t.Fatal(err)
+	}
+	if labels["backend"] != "Client-0" || labels["model"] != "mapped" {
+		t.Errorf("Expected backend and model labels, got %v", labels)
+	}
+
+	labels = map[string]string{}
+	stream := client.Chat.Completions.NewStreaming(context.Background(), chatParams("m"), record)
+	for stream.Next() {
+	}
+	stream.Close()
+	if labels["backend"] != "Client-0" || labels["model"] != "mapped" {
+		t.Errorf("Expected backend and model labels on streams, got %v", labels)
+	}
+}
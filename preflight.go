@@ -0,0 +1,37 @@
+package openailb
+
+import "fmt"
+
+// RequestTooLargeError is returned, without sending the request anywhere,
+// when its estimated tokens exceed the context window or the whole TPM of
+// every backend that could take it, so each would only reject it.
+type RequestTooLargeError struct {
+	Model  string
+	Tokens int64 // The request's estimate: its prompt plus its max tokens.
+	Limit  int64 // The largest request a backend takes.
+}
+
+func (e *RequestTooLargeError) Error() string {
+	return fmt.Sprintf("request for %q of about %d tokens exceeds the context window or TPM of every backend (at most %d)", e.Model, e.Tokens, e.Limit)
+}
+
+// tokenLimit returns the most tokens a request for model to the backend may
+// use, the smaller of its context window, unless WithHistoryTruncation trims
+// the requests to it, and TPM, or 0 without either.
+func (c *SafeClient) tokenLimit(model string) int64 {
+	var limit int64
+	if !c.lb.options.truncateHistory {
+		limit = c.contextWindow(mapModel(c, model))
+	}
+	if tpm := int64(c.config.TPM); tpm > 0 && (limit <= 0 || tpm < limit) {
+		limit = tpm
+	}
+	return max(limit, 0)
+}
+
+// fits reports whether a request for model of tokens, as estimated, fits the
+// backend.
+func (c *SafeClient) fits(model string, tokens int64) bool {
+	limit := c.tokenLimit(model)
+	return limit == 0 || tokens <= limit
+}
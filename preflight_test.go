@@ -0,0 +1,62 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+)
+
+func TestContextWindowReroutes(t *testing.T) {
+	t.Parallel()
+
+	small, large := newNamedServer(t, "small"), newNamedServer(t, "large")
+	defer small.Close()
+	defer large.Close()
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: small.URL, Weight: 100, ContextWindow: 100},
+		{APIKey: "k2", BaseURL: large.URL, ContextWindow: 10000},
+	})
+
+	params := chatParams("m")
+	params.MaxTokens = openai.Int(500)
+	resp, err := client.Chat.Completions.New(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Choices[0].Message.Content; got != "large" {
+		t.Errorf("Expected the request to go to the backend it fits, got %q", got)
+	}
+	if hits := countHits(t, client, 2); hits["small"] != 2 {
+		t.Errorf("Expected the small requests to keep the weighted backend, got %v", hits)
+	}
+}
+
+func TestRequestTooLarge(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	server := newNamedServer(t, "ok")
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: server.URL, ContextWindow: 1000},
+		{APIKey: "k2", BaseURL: server.URL, TPM: 2000},
+	}, WithHooks(Hooks{OnRequest: func(RequestInfo) { calls.Add(1) }}))
+
+	params := chatParams("m")
+	params.Messages = []openai.ChatCompletionMessageParamUnion{openai.UserMessage(strings.Repeat("word ", 2000))}
+	_, err := client.Chat.Completions.New(context.Background(), params)
+	var tooLarge *RequestTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("Expected a RequestTooLargeError, got %v", err)
+	}
+	if tooLarge.Limit != 2000 || tooLarge.Tokens <= 2000 {
+		t.Errorf("Expected the estimate over the largest limit of 2000, got %+v", tooLarge)
+	}
+	if calls.Load() != 0 {
+		t.Errorf("Expected nothing to be sent, got %d requests", calls.Load())
+	}
+}
@@ -0,0 +1,43 @@
+package openailb
+
+import "context"
+
+// Priority ranks the requests waiting in the queue of WithMaxConcurrency.
+type Priority int
+
+const (
+	// PriorityLow is for batch work that may be shed to make room.
+	PriorityLow Priority = iota - 1
+	// PriorityNormal is the priority of the requests without WithPriority.
+	PriorityNormal
+	// PriorityHigh is for interactive traffic, served first.
+	PriorityHigh
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	}
+	return "unknown"
+}
+
+type priorityKey struct{}
+
+// WithPriority sets the priority of the requests made with ctx. Under
+// saturation (WithMaxConcurrency), higher priorities leave the queue first,
+// and take the place of lower ones in a full queue, which then fail with
+// ErrOverloaded, so background jobs can't starve interactive traffic.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the priority set with WithPriority, or PriorityNormal.
+func PriorityFromContext(ctx context.Context) Priority {
+	p, _ := ctx.Value(priorityKey{}).(Priority)
+	return p
+}
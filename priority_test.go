@@ -0,0 +1,82 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestPriorityQueueing(t *testing.T) {
+	t.Parallel()
+
+	arrived, release := make(chan string, 4), make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- r.Header.Get("X-Priority")
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "slow"}}]}`))
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: slow.URL}}, WithMaxConcurrency(1, 2, 0))
+	a := client.lb.options.admission
+	queued := func(n int) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for {
+			a.mu.Lock()
+			l := len(a.queue)
+			a.mu.Unlock()
+			if l == n {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %d queued requests, got %d", n, l)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	errs := make(chan error, 3)
+	send := func(p Priority) {
+		go func() {
+			_, err := client.Chat.Completions.New(WithPriority(context.Background(), p), chatParams("m"), option.WithHeader("X-Priority", p.String()))
+			errs <- err
+		}()
+	}
+
+	send(PriorityNormal)
+	<-arrived
+	send(PriorityLow)
+	queued(1)
+	send(PriorityNormal)
+	queued(2)
+	send(PriorityHigh)
+	if err := <-errs; !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("Expected the low-priority request to be shed, got %v", err)
+	}
+	queued(2)
+	if _, err := client.Chat.Completions.New(WithPriority(context.Background(), PriorityLow), chatParams("m")); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("Expected a low-priority request to be turned away from a full queue, got %v", err)
+	}
+
+	release <- struct{}{}
+	if p := <-arrived; p != "high" {
+		t.Errorf("Expected the high-priority request to be served before the queued ones, got %s", p)
+	}
+	release <- struct{}{}
+	if p := <-arrived; p != "normal" {
+		t.Errorf("Expected the queued normal request to be served next, got %s", p)
+	}
+	release <- struct{}{}
+	for i := 0; i < 3; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
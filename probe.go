@@ -0,0 +1,81 @@
+package openailb
+
+import (
+	"context"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/sony/gobreaker/v2"
+)
+
+// SyntheticProbe configures the synthetic half-open probes (WithSyntheticProbes).
+type SyntheticProbe struct {
+	// Model is the (requested, pre-mapping) model to probe with. With per-model
+	// breakers, each breaker is probed with its own model instead.
+	Model string
+	// Prompt is the probe's user message (default "ping").
+	Prompt string
+	// Timeout bounds each probe (default 10s).
+	Timeout time.Duration
+}
+
+// withDefaults fills in the zero fields.
+func (p SyntheticProbe) withDefaults() SyntheticProbe {
+	if p.Prompt == "" {
+		p.Prompt = "ping"
+	}
+	if p.Timeout <= 0 {
+		p.Timeout = 10 * time.Second
+	}
+	return p
+}
+
+// routable reports whether user traffic may go through breaker. With synthetic
+// probes, a half-open chat breaker is kept away from user requests and probed
+// in the background instead; other services still probe with user traffic.
+func (c *SafeClient) routable(breaker *trackedBreaker) bool {
+	switch breaker.State() {
+	case gobreaker.StateOpen:
+		return false
+	case gobreaker.StateHalfOpen:
+		if c.lb.options.syntheticProbe != nil && breaker.key.svc == ServiceChat {
+			c.startProbe(breaker)
+			return false
+		}
+	}
+	return true
+}
+
+// startProbe sends one synthetic completion through the half-open breaker,
+// unless a probe is already in flight. A success counts toward closing it.
+func (c *SafeClient) startProbe(breaker *trackedBreaker) {
+	if !breaker.probing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer breaker.probing.Store(false)
+		err := c.probe(breaker)
+		c.lb.events.publish(Event{Kind: EventHealthProbe, Backend: c.Name, Breaker: breaker.name, Err: err})
+	}()
+}
+
+// probe sends the synthetic completion through breaker.
+func (c *SafeClient) probe(breaker *trackedBreaker) error {
+	cfg := c.lb.options.syntheticProbe
+	model := breaker.key.model
+	if model == "" {
+		model = mapModel(c, cfg.Model)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	_, _, err := executeWith(ctx, c.lb, attempt{client: c, breaker: breaker, requested: model, model: model, number: 1}, func(ctx context.Context, safeClient *SafeClient) (*openai.ChatCompletion, error) {
+		return safeClient.OpenAIClient().Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+			Model:               model,
+			Messages:            []openai.ChatCompletionMessageParamUnion{openai.UserMessage(cfg.Prompt)},
+			MaxCompletionTokens: openai.Int(1),
+		}, option.WithMaxRetries(0))
+	})
+	return err
+}
@@ -0,0 +1,74 @@
+package openailb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+	"github.com/sony/gobreaker/v2"
+)
+
+func TestSyntheticHalfOpenProbe(t *testing.T) {
+	t.Parallel()
+
+	var healthy atomic.Bool
+	var userHits, probeHits atomic.Int32
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Model == "probe-model" {
+			probeHits.Add(1)
+		} else {
+			userHits.Add(1)
+		}
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "flaky"}}]}`))
+	}))
+	defer flaky.Close()
+	steady := newNamedServer(t, "steady")
+	defer steady.Close()
+
+	settings := tripAfter(1)
+	settings.Timeout = 20 * time.Millisecond
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: flaky.URL},
+		{APIKey: "k2", BaseURL: steady.URL},
+	}, WithCBSettings(settings), WithSyntheticProbes(SyntheticProbe{Model: "probe-model"}))
+
+	_, _ = client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+	healthy.Store(true)
+	time.Sleep(30 * time.Millisecond)
+
+	// The half-open backend gets a probe, not this user request.
+	resp, err := client.Chat.Completions.New(context.Background(), chatParams("m"))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if content := resp.Choices[0].Message.Content; content != "steady" {
+		t.Errorf("Expected the user request on the steady backend, got %s", content)
+	}
+
+	breaker := client.Chat.Completions.lb.backends()[0].Breaker(ServiceChat)
+	deadline := time.Now().Add(time.Second)
+	for breaker.State() != gobreaker.StateClosed && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if breaker.State() != gobreaker.StateClosed {
+		t.Fatalf("Expected the probe to close the breaker, got %s", breaker.State())
+	}
+	if probeHits.Load() != 1 || userHits.Load() != 1 {
+		t.Errorf("Expected 1 probe and only the initial user request, got %d probes and %d user requests",
+			probeHits.Load(), userHits.Load())
+	}
+}
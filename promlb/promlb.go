@@ -0,0 +1,136 @@
+// Package promlb exports openailb metrics to Prometheus.
+//
+//	reg := prometheus.NewRegistry()
+//	client := openailb.NewClient(configs, promlb.WithPrometheus(reg))
+//	http.Handle("/metrics", promlb.Handler(reg))
+package promlb
+
+import (
+	"net/http"
+
+	openailb "github.com/hi2code/openai-go-lb"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sony/gobreaker/v2"
+)
+
+// Sink is an openailb.MetricsSink that records into Prometheus metrics.
+type Sink struct {
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	ttft         *prometheus.HistogramVec
+	tokens       *prometheus.CounterVec
+	inFlight     *prometheus.GaugeVec
+	breakerState *prometheus.GaugeVec
+	labels       *prometheus.GaugeVec
+	cache        *prometheus.CounterVec
+}
+
+// NewSink creates the metrics and registers them with reg.
+func NewSink(reg prometheus.Registerer) (*Sink, error) {
+	latencyBuckets := []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+	s := &Sink{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "openailb_requests_total",
+			Help: "Requests sent to backends, by outcome class.",
+		}, []string{"backend", "service", "model", "class"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "openailb_request_duration_seconds",
+			Help:    "Duration of backend requests; streams end when their body is drained.",
+			Buckets: latencyBuckets,
+		}, []string{"backend", "service", "model"}),
+		ttft: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "openailb_time_to_first_token_seconds",
+			Help:    "Time to the first streamed chunk of streaming requests.",
+			Buckets: latencyBuckets,
+		}, []string{"backend", "model"}),
+		tokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "openailb_tokens_total",
+			Help: "Tokens reported by backends, by type (prompt or completion).",
+		}, []string{"backend", "model", "type"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "openailb_in_flight_requests",
+			Help: "Requests currently sent to a backend.",
+		}, []string{"backend", "service"}),
+		breakerState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "openailb_breaker_state",
+			Help: "Breaker state: 0 closed, 1 half-open, 2 open.",
+		}, []string{"backend", "breaker"}),
+		labels: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "openailb_backend_label",
+			Help: "Always 1, one series per label of a backend, to join the other metrics on backend.",
+		}, []string{"backend", "label", "value"}),
+		cache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "openailb_cache_lookups_total",
+			Help: "Response cache lookups, by result (hit or miss).",
+		}, []string{"service", "model", "result"}),
+	}
+	for _, c := range []prometheus.Collector{s.requests, s.duration, s.ttft, s.tokens, s.inFlight, s.breakerState, s.labels, s.cache} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// WithPrometheus registers the metrics with reg and reports to them. It panics
+// if they can't be registered, e.g. because they already are; use NewSink and
+// openailb.WithMetrics to handle that error.
+func WithPrometheus(reg prometheus.Registerer) openailb.LBOption {
+	sink, err := NewSink(reg)
+	if err != nil {
+		panic("promlb: " + err.Error())
+	}
+	return openailb.WithMetrics(sink)
+}
+
+// Handler serves the metrics gathered by g, ready to mount at /metrics.
+func Handler(g prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(g, promhttp.HandlerOpts{})
+}
+
+func (s *Sink) RequestStarted(backend string, svc openailb.ServiceType, model string) {
+	s.inFlight.WithLabelValues(backend, string(svc)).Inc()
+}
+
+func (s *Sink) RequestDone(m openailb.RequestMetrics) {
+	s.inFlight.WithLabelValues(m.Backend, string(m.Service)).Dec()
+	s.requests.WithLabelValues(m.Backend, string(m.Service), m.Model, string(m.Class)).Inc()
+	s.duration.WithLabelValues(m.Backend, string(m.Service), m.Model).Observe(m.Duration.Seconds())
+	if m.TTFT > 0 {
+		s.ttft.WithLabelValues(m.Backend, m.Model).Observe(m.TTFT.Seconds())
+	}
+	if m.PromptTokens > 0 {
+		s.tokens.WithLabelValues(m.Backend, m.Model, "prompt").Add(float64(m.PromptTokens))
+	}
+	if m.CompletionTokens > 0 {
+		s.tokens.WithLabelValues(m.Backend, m.Model, "completion").Add(float64(m.CompletionTokens))
+	}
+	for k, v := range m.Labels {
+		s.labels.WithLabelValues(m.Backend, k, v).Set(1)
+	}
+}
+
+func (s *Sink) BreakerStateChanged(backend, breaker string, from, to gobreaker.State) {
+	s.breakerState.WithLabelValues(backend, breaker).Set(float64(stateValue(to)))
+}
+
+func (s *Sink) CacheLookup(svc openailb.ServiceType, model string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	s.cache.WithLabelValues(string(svc), model, result).Inc()
+}
+
+// stateValue maps a breaker state to the breaker_state gauge value.
+func stateValue(state gobreaker.State) int {
+	switch state {
+	case gobreaker.StateHalfOpen:
+		return 1
+	case gobreaker.StateOpen:
+		return 2
+	default:
+		return 0
+	}
+}
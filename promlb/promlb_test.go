@@ -0,0 +1,48 @@
+package promlb
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	openailb "github.com/hi2code/openai-go-lb"
+	"github.com/openai/openai-go/v3"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "Hello"}}], "usage": {"prompt_tokens": 5, "completion_tokens": 2}}`))
+	}))
+	defer server.Close()
+
+	reg := prometheus.NewRegistry()
+	client := openailb.NewClient([]openailb.OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL, Labels: map[string]string{"provider": "azure"}}}, WithPrometheus(reg))
+	_, err := client.Chat.Completions.New(context.Background(), openai.ChatCompletionNewParams{
+		Model:    "gpt-4o",
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("test")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	Handler(reg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	for _, want := range []string{
+		`openailb_requests_total{backend="Client-0",class="ok",model="gpt-4o",service="chat"} 1`,
+		`openailb_tokens_total{backend="Client-0",model="gpt-4o",type="prompt"} 5`,
+		`openailb_tokens_total{backend="Client-0",model="gpt-4o",type="completion"} 2`,
+		`openailb_in_flight_requests{backend="Client-0",service="chat"} 0`,
+		`openailb_request_duration_seconds_count{backend="Client-0",model="gpt-4o",service="chat"} 1`,
+		`openailb_backend_label{backend="Client-0",label="provider",value="azure"} 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Missing %s in:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package openailb
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// isAuthError reports whether err means the backend rejected the API key.
+func isAuthError(err error) bool {
+	var apiErr *openai.Error
+	return errors.As(err, &apiErr) &&
+		(apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden)
+}
+
+// quarantine takes the backend out of rotation until it is reinstated. Unlike
+// an open breaker, a quarantine never expires on its own: a rejected key won't
+// start working again after a timeout.
+func (c *SafeClient) quarantine(reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.quarantinedAt.IsZero() {
+		c.quarantinedAt = time.Now()
+		c.quarantineReason = reason
+		c.emitHealth(HealthEvent{Kind: HealthQuarantined, At: c.quarantinedAt, Reason: reason})
+	}
+}
+
+// reinstate lifts a quarantine.
+func (c *SafeClient) reinstate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.quarantinedAt.IsZero() {
+		c.emitHealth(HealthEvent{Kind: HealthRecovered, Reason: "reinstated"})
+	}
+	c.quarantinedAt = time.Time{}
+	c.quarantineReason = ""
+}
+
+// Quarantined reports whether the backend is quarantined, and why.
+func (c *SafeClient) Quarantined() (bool, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.quarantinedAt.IsZero(), c.quarantineReason
+}
+
+// clientByName returns the backend called name.
+func (lb *LoadBalancer) clientByName(name string) (*SafeClient, error) {
+	for _, c := range lb.backends() {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("no backend named %q", name)
+}
+
+// Reinstate puts a backend quarantined by WithAuthQuarantine back into rotation,
+// e.g. after its key was fixed.
+func (c Client) Reinstate(name string) error {
+	backend, err := c.lb.clientByName(name)
+	if err != nil {
+		return err
+	}
+	backend.reinstate()
+	return nil
+}
@@ -0,0 +1,51 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestAuthQuarantine(t *testing.T) {
+	t.Parallel()
+
+	badKey := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":{"message":"invalid api key"}}`))
+	}))
+	defer badKey.Close()
+	good := newNamedServer(t, "good")
+	defer good.Close()
+
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "bad-key", BaseURL: badKey.URL},
+		{APIKey: "good-key", BaseURL: good.URL},
+	}, WithAuthQuarantine())
+
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0)); err == nil {
+		t.Fatal("Expected the bad key to be rejected")
+	}
+	backend := client.Chat.Completions.lb.backends()[0]
+	if quarantined, reason := backend.Quarantined(); !quarantined || reason == "" {
+		t.Fatalf("Expected backend to be quarantined with a reason, got %v %q", quarantined, reason)
+	}
+
+	// A quarantined backend stays out of rotation for every service.
+	hits := countHits(t, client, 4)
+	if hits["good"] != 4 {
+		t.Errorf("Expected all traffic on the good backend, got %v", hits)
+	}
+
+	if err := client.Reinstate(backend.Name); err != nil {
+		t.Fatalf("Reinstate failed: %v", err)
+	}
+	if quarantined, _ := backend.Quarantined(); quarantined {
+		t.Error("Expected backend to be reinstated")
+	}
+	if err := client.Reinstate("missing"); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+}
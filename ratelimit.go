@@ -0,0 +1,185 @@
+package openailb
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// tokenBucket holds up to burst tokens, refilled continuously at rate tokens
+// per second.
+type tokenBucket struct {
+	rate, burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns a full bucket refilled with perMinute tokens a minute.
+func newTokenBucket(perMinute, burst int) *tokenBucket {
+	return &tokenBucket{rate: float64(perMinute) / 60, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// refill adds the tokens earned since the last call. The caller holds b.mu.
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// available reports whether n tokens can be taken at now. Taking more than
+// burst takes a full bucket.
+func (b *tokenBucket) available(now time.Time, n float64) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	return b.tokens >= min(n, b.burst)
+}
+
+// wait returns how long from now until n tokens can be taken, 0 for a nil bucket.
+func (b *tokenBucket) wait(now time.Time, n float64) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	missing := min(n, b.burst) - b.tokens
+	if missing <= 0 {
+		return 0
+	}
+	return time.Duration(missing / b.rate * float64(time.Second))
+}
+
+// take removes n tokens at now, going into debt if there are fewer.
+func (b *tokenBucket) take(now time.Time, n float64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	b.tokens -= n
+}
+
+// set replaces the tokens at now, e.g. with those of a shared bucket.
+func (b *tokenBucket) set(now time.Time, tokens float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens, b.last = min(tokens, b.burst), now
+}
+
+// rpmLimiter returns the request bucket of cfg, or nil without an RPM.
+func rpmLimiter(cfg OpenaiClientConfig) *tokenBucket {
+	if cfg.RPM <= 0 {
+		return nil
+	}
+	burst := cfg.RPMBurst
+	if burst <= 0 {
+		burst = max(cfg.RPM/60, 1)
+	}
+	return newTokenBucket(cfg.RPM, burst)
+}
+
+// tpmLimiter returns the token bucket of cfg, or nil without a TPM.
+func tpmLimiter(cfg OpenaiClientConfig) *tokenBucket {
+	if cfg.TPM <= 0 {
+		return nil
+	}
+	return newTokenBucket(cfg.TPM, cfg.TPM)
+}
+
+// remaining returns the tokens left at now, or nil for a nil bucket.
+func (b *tokenBucket) remaining(now time.Time) *int64 {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	n := int64(max(b.tokens, 0))
+	return &n
+}
+
+type tokenEstimateKey struct{}
+
+// withTokenEstimate attaches the tokens a request is expected to use, as
+// returned by estimate, to ctx for the TPM budgets and context windows, if
+// any backend has one.
+func (lb *LoadBalancer) withTokenEstimate(ctx context.Context, estimate func() int64) context.Context {
+	if !lb.estimated() {
+		return ctx
+	}
+	return context.WithValue(ctx, tokenEstimateKey{}, estimate())
+}
+
+// estimated reports whether any backend or tenant has a TPM budget, or any
+// backend a context window requests are routed by.
+func (lb *LoadBalancer) estimated() bool {
+	if lb.options.tenants.budgeted() {
+		return true
+	}
+	windows := !lb.options.truncateHistory
+	if windows && lb.options.contextWindows != nil {
+		return true
+	}
+	for _, c := range lb.backends() {
+		if c.tpm != nil || (windows && c.config.ContextWindow > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+func tokenEstimateFrom(ctx context.Context) int64 {
+	tokens, _ := ctx.Value(tokenEstimateKey{}).(int64)
+	return tokens
+}
+
+// estimateTokens estimates the tokens of a request as providers count them
+// against a TPM quota: its prompt, at about 4 bytes of JSON a token, plus
+// the most it may generate.
+func estimateTokens(params any, maxOutput int64) int64 {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return maxOutput
+	}
+	return int64(len(body)+3)/4 + maxOutput
+}
+
+// estimateChatTokens is estimateTokens for a chat completion.
+func estimateChatTokens(params openai.ChatCompletionNewParams) int64 {
+	return estimateTokens(params, max(params.MaxCompletionTokens.Value, params.MaxTokens.Value))
+}
+
+// settleTokens charges the TPM budgets of the backend (shared ones included)
+// and the tenant for the
+// tokens the attempt actually used in place of its estimate, once the usage
+// is known, and refunds the estimate of a failed attempt.
+func (c *SafeClient) settleTokens(now time.Time, a attempt, r ResponseInfo) {
+	if a.tokens <= 0 {
+		return
+	}
+	var tenant *tokenBucket
+	if a.quota != nil {
+		tenant = a.quota.tpm
+	}
+	var delta float64
+	switch used := r.PromptTokens + r.CompletionTokens; {
+	case used > 0:
+		delta = float64(used - a.tokens)
+	case r.Err != nil:
+		delta = float64(-a.tokens)
+	}
+	c.tpm.take(now, delta)
+	tenant.take(now, delta)
+	c.lb.settleShared(c, delta)
+}
@@ -0,0 +1,97 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+func TestRPMLimit(t *testing.T) {
+	t.Parallel()
+
+	limited, spare := newNamedServer(t, "limited"), newNamedServer(t, "spare")
+	defer limited.Close()
+	defer spare.Close()
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: limited.URL, Weight: 10, RPM: 60, RPMBurst: 2},
+		{APIKey: "k2", BaseURL: spare.URL},
+	})
+	if hits := countHits(t, client, 5); hits["limited"] != 2 || hits["spare"] != 3 {
+		t.Errorf("Expected the excess traffic to spill over to the spare backend, got %v", hits)
+	}
+
+	single := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: limited.URL, RPM: 60}})
+	if hits := countHits(t, single, 1); hits["limited"] != 1 {
+		t.Fatalf("Expected the first request to be sent, got %v", hits)
+	}
+	if _, err := single.Chat.Completions.New(context.Background(), chatParams("m")); err == nil || !strings.Contains(err.Error(), "at their RPM or TPM limit") {
+		t.Errorf("Expected the request over the limit to be refused, got %v", err)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	t.Parallel()
+
+	b := newTokenBucket(60, 2)
+	now := b.last
+	b.take(now, 1)
+	b.take(now, 1)
+	if b.available(now, 1) {
+		t.Error("Expected the emptied bucket to refuse")
+	}
+	if !b.available(now.Add(time.Second), 1) || b.available(now.Add(time.Second), 2) {
+		t.Error("Expected one token a second")
+	}
+	later := now.Add(time.Hour)
+	b.take(later, 2)
+	if b.available(later, 1) {
+		t.Error("Expected the bucket to hold at most its burst")
+	}
+}
+
+func TestTPMBudget(t *testing.T) {
+	t.Parallel()
+
+	budgeted := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "budgeted"}}], "usage": {"prompt_tokens": 10, "completion_tokens": 90, "total_tokens": 100}}`))
+	}))
+	defer budgeted.Close()
+	spare := newNamedServer(t, "spare")
+	defer spare.Close()
+	client := NewClient([]OpenaiClientConfig{
+		{Name: "budgeted", APIKey: "k1", BaseURL: budgeted.URL, Weight: 10, TPM: 250},
+		{Name: "spare", APIKey: "k2", BaseURL: spare.URL},
+	})
+
+	params := chatParams("m")
+	params.MaxTokens = openai.Int(100)
+	estimate := estimateChatTokens(params)
+	if estimate <= 100 || estimate > 130 {
+		t.Fatalf("Expected the estimate to add the prompt to the max tokens, got %d", estimate)
+	}
+	hits := make(map[string]int)
+	for i := 0; i < 3; i++ {
+		resp, err := client.Chat.Completions.New(context.Background(), params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hits[resp.Choices[0].Message.Content]++
+	}
+	// Each request is settled to its 100 tokens, so the third doesn't fit.
+	if hits["budgeted"] != 2 || hits["spare"] != 1 {
+		t.Errorf("Expected the request over the budget to go to the spare backend, got %v", hits)
+	}
+	stats := client.Stats()
+	if left := stats["budgeted"].TPMRemaining; left == nil || *left < 50 || *left >= estimate {
+		t.Errorf("Expected about 50 tokens left, got %v", left)
+	}
+	if stats["spare"].TPMRemaining != nil {
+		t.Error("Expected no budget for the backend without a TPM")
+	}
+}
@@ -0,0 +1,76 @@
+package openailb
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimits are the quota figures a backend announced in its
+// x-ratelimit-* response headers, as OpenAI and compatible providers send
+// them. Figures missing from the response are -1.
+type RateLimits struct {
+	LimitRequests     int64 `json:"limit_requests"`
+	RemainingRequests int64 `json:"remaining_requests"`
+	LimitTokens       int64 `json:"limit_tokens"`
+	RemainingTokens   int64 `json:"remaining_tokens"`
+	// ResetRequests and ResetTokens are how long until the quotas are fully
+	// replenished, as of At.
+	ResetRequests time.Duration `json:"reset_requests"`
+	ResetTokens   time.Duration `json:"reset_tokens"`
+	At            time.Time     `json:"at"`
+}
+
+// parseRateLimits reads the x-ratelimit-* headers of h. It reports false if
+// there are none.
+func parseRateLimits(h http.Header) (RateLimits, bool) {
+	found := false
+	count := func(name string) int64 {
+		v := h.Get(name)
+		if v == "" {
+			return -1
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return -1
+		}
+		found = true
+		return n
+	}
+	reset := func(name string) time.Duration {
+		v := h.Get(name)
+		if v == "" {
+			return -1
+		}
+		// e.g. "1s", "6m0s", "20ms"; some providers send plain seconds.
+		if d, err := time.ParseDuration(v); err == nil {
+			found = true
+			return d
+		}
+		if secs, err := strconv.ParseFloat(v, 64); err == nil {
+			found = true
+			return time.Duration(secs * float64(time.Second))
+		}
+		return -1
+	}
+	limits := RateLimits{
+		LimitRequests:     count("x-ratelimit-limit-requests"),
+		RemainingRequests: count("x-ratelimit-remaining-requests"),
+		LimitTokens:       count("x-ratelimit-limit-tokens"),
+		RemainingTokens:   count("x-ratelimit-remaining-tokens"),
+		ResetRequests:     reset("x-ratelimit-reset-requests"),
+		ResetTokens:       reset("x-ratelimit-reset-tokens"),
+		At:                time.Now(),
+	}
+	return limits, found
+}
+
+// setRateLimits keeps the latest limits announced by the backend.
+func (c *SafeClient) setRateLimits(limits *RateLimits) {
+	if limits == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rateLimits = limits
+}
@@ -0,0 +1,59 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimitHeaders(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("x-ratelimit-limit-requests", "500")
+		w.Header().Set("x-ratelimit-remaining-requests", "499")
+		w.Header().Set("x-ratelimit-limit-tokens", "30000")
+		w.Header().Set("x-ratelimit-remaining-tokens", "29000")
+		w.Header().Set("x-ratelimit-reset-requests", "120ms")
+		w.Header().Set("x-ratelimit-reset-tokens", "6m0s")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "ok"}}]}`))
+	}))
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL}})
+
+	var info RouteInfo
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m"), WithRouteInfo(&info)); err != nil {
+		t.Fatal(err)
+	}
+	limits := info.RateLimits
+	if limits == nil {
+		t.Fatal("Expected the route info to carry the rate limits")
+	}
+	if limits.LimitRequests != 500 || limits.RemainingRequests != 499 || limits.LimitTokens != 30000 || limits.RemainingTokens != 29000 {
+		t.Errorf("Unexpected rate limits %+v", limits)
+	}
+	if limits.ResetRequests != 120*time.Millisecond || limits.ResetTokens != 6*time.Minute {
+		t.Errorf("Unexpected resets %+v", limits)
+	}
+	if stats := client.Stats()["Client-0"]; stats.RateLimits == nil || stats.RateLimits.RemainingTokens != 29000 {
+		t.Errorf("Expected the stats to carry the latest rate limits, got %+v", stats.RateLimits)
+	}
+}
+
+func TestParseRateLimits(t *testing.T) {
+	t.Parallel()
+
+	if _, ok := parseRateLimits(http.Header{}); ok {
+		t.Error("Expected no rate limits without headers")
+	}
+	h := http.Header{}
+	h.Set("x-ratelimit-remaining-requests", "3")
+	h.Set("x-ratelimit-reset-requests", "1.5")
+	limits, ok := parseRateLimits(h)
+	if !ok || limits.RemainingRequests != 3 || limits.LimitRequests != -1 || limits.ResetRequests != 1500*time.Millisecond {
+		t.Errorf("Unexpected partial rate limits %+v", limits)
+	}
+}
@@ -0,0 +1,163 @@
+// Package redisstore provides Redis-backed implementations of the openailb
+// extension points that benefit from being shared across service replicas.
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	openailb "github.com/hi2code/openai-go-lb"
+	"github.com/redis/go-redis/v9"
+)
+
+// StateStore is an openailb.StateStore that keeps the snapshot under one Redis key.
+type StateStore struct {
+	client redis.UniversalClient
+	key    string
+}
+
+// NewStateStore returns a StateStore that saves the snapshot under key.
+func NewStateStore(client redis.UniversalClient, key string) *StateStore {
+	return &StateStore{client: client, key: key}
+}
+
+func (s *StateStore) Load(ctx context.Context) (*openailb.StateSnapshot, error) {
+	data, err := s.client.Get(ctx, s.key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshot openailb.StateSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+func (s *StateStore) Save(ctx context.Context, snapshot *openailb.StateSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, s.key, data, 0).Err()
+}
+
+// RateLimiter is an openailb.RateLimiter keeping each bucket in a Redis
+// hash under prefix+key, refilled with the Redis server's clock so that the
+// replicas' clocks don't matter.
+type RateLimiter struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRateLimiter returns a RateLimiter whose keys start with prefix, e.g.
+// "openailb:ratelimit:".
+func NewRateLimiter(client redis.UniversalClient, prefix string) *RateLimiter {
+	return &RateLimiter{client: client, prefix: prefix}
+}
+
+// takeScript refills the bucket since its last use, takes ARGV[1] tokens and
+// returns those left. The bucket expires once it would be full again.
+var takeScript = redis.NewScript(`
+local n, rate, burst = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) + tonumber(t[2]) / 1e6
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(state[1]) or burst
+local last = tonumber(state[2]) or now
+if now > last then
+  tokens = math.min(burst, tokens + (now - last) * rate)
+  last = now
+end
+tokens = tokens - n
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', tostring(last))
+redis.call('EXPIRE', KEYS[1], math.ceil((burst - math.min(tokens, burst)) / rate) + 1)
+return tostring(tokens)
+`)
+
+func (l *RateLimiter) Take(ctx context.Context, key string, n, rate, burst float64) (float64, error) {
+	return takeScript.Run(ctx, l.client, []string{l.prefix + key}, n, rate, burst).Float64()
+}
+
+// BreakerSignals is an openailb.BreakerSignals publishing the signals as
+// JSON on a Redis pub/sub channel.
+type BreakerSignals struct {
+	client  redis.UniversalClient
+	channel string
+}
+
+// NewBreakerSignals returns a BreakerSignals publishing on channel, e.g.
+// "openailb:breakers".
+func NewBreakerSignals(client redis.UniversalClient, channel string) *BreakerSignals {
+	return &BreakerSignals{client: client, channel: channel}
+}
+
+func (s *BreakerSignals) Publish(ctx context.Context, signal openailb.BreakerSignal) error {
+	data, err := json.Marshal(signal)
+	if err != nil {
+		return err
+	}
+	return s.client.Publish(ctx, s.channel, data).Err()
+}
+
+func (s *BreakerSignals) Subscribe(ctx context.Context, handle func(openailb.BreakerSignal)) error {
+	sub := s.client.Subscribe(ctx, s.channel)
+	defer sub.Close()
+	// Wait for the subscription to be confirmed, so a failure is reported.
+	if _, err := sub.Receive(ctx); err != nil {
+		return err
+	}
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-messages:
+			if !ok {
+				return errors.New("subscription closed")
+			}
+			var signal openailb.BreakerSignal
+			if err := json.Unmarshal([]byte(msg.Payload), &signal); err != nil {
+				// Not one of ours.
+				continue
+			}
+			handle(signal)
+		}
+	}
+}
+
+// Cache is an openailb.Cache keeping each response under prefix+key with
+// the TTL as its Redis expiry, so that every replica answers from it.
+type Cache struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewCache returns a Cache whose keys start with prefix, e.g.
+// "openailb:cache:".
+func NewCache(client redis.UniversalClient, prefix string) *Cache {
+	return &Cache{client: client, prefix: prefix}
+}
+
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	data, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+func (c *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.client.Set(ctx, c.prefix+key, value, ttl).Err()
+}
+
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	return c.client.Del(ctx, c.prefix+key).Err()
+}
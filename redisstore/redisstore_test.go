@@ -0,0 +1,209 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	openailb "github.com/hi2code/openai-go-lb"
+	"github.com/redis/go-redis/v9"
+)
+
+func newRedis(t *testing.T) (*miniredis.Miniredis, redis.UniversalClient) {
+	t.Helper()
+	m := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return m, client
+}
+
+func TestStateStore(t *testing.T) {
+	m, client := newRedis(t)
+	ctx := context.Background()
+	store := NewStateStore(client, "openailb:state")
+
+	if snapshot, err := store.Load(ctx); err != nil || snapshot != nil {
+		t.Fatalf("Expected no snapshot before the first save, got %+v, %v", snapshot, err)
+	}
+
+	opened := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	want := &openailb.StateSnapshot{
+		SavedAt: opened.Add(time.Second),
+		Breakers: []openailb.BreakerSnapshot{{
+			Name:                "Client-0/chat",
+			State:               "open",
+			OpenedAt:            opened,
+			OpenedBy:            "503 Service Unavailable",
+			CooldownUntil:       opened.Add(time.Minute),
+			Requests:            7,
+			TotalSuccesses:      2,
+			TotalFailures:       5,
+			ConsecutiveFailures: 5,
+		}, {
+			Name:                 "Client-1/embeddings",
+			State:                "closed",
+			Requests:             3,
+			TotalSuccesses:       3,
+			ConsecutiveSuccesses: 3,
+		}},
+	}
+	if err := store.Save(ctx, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the snapshot back, got %+v", got)
+	}
+	if ttl := m.TTL("openailb:state"); ttl != 0 {
+		t.Errorf("Expected the snapshot not to expire, got a TTL of %s", ttl)
+	}
+
+	m.Set("openailb:state", "not json")
+	if _, err := store.Load(ctx); err == nil {
+		t.Error("Expected an unreadable snapshot to be reported")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	m, client := newRedis(t)
+	ctx := context.Background()
+	limiter := NewRateLimiter(client, "openailb:ratelimit:")
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	m.SetTime(now)
+
+	take := func(n float64) float64 {
+		t.Helper()
+		left, err := limiter.Take(ctx, "Client-0/rpm", n, 10, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return left
+	}
+
+	// A new bucket starts full, and takes may leave it short.
+	for _, tc := range []struct{ n, want float64 }{{1, 4}, {3, 1}, {3, -2}} {
+		if left := take(tc.n); left != tc.want {
+			t.Errorf("Taking %v: expected %v left, got %v", tc.n, tc.want, left)
+		}
+	}
+	// The bucket expires once it would be full again: 7 tokens at 10/s, plus a second.
+	if ttl := m.TTL("openailb:ratelimit:Client-0/rpm"); ttl != 2*time.Second {
+		t.Errorf("Expected a TTL of 2s, got %s", ttl)
+	}
+
+	// Refilled at rate by the server's clock, up to burst.
+	m.SetTime(now.Add(500 * time.Millisecond))
+	if left := take(0); left != 3 {
+		t.Errorf("Expected 3 tokens after 500ms, got %v", left)
+	}
+	m.SetTime(now.Add(time.Minute))
+	if left := take(1); left != 4 {
+		t.Errorf("Expected a full bucket less one, got %v", left)
+	}
+	// A clock going backwards takes nothing back.
+	m.SetTime(now)
+	if left := take(1); left != 3 {
+		t.Errorf("Expected 3 tokens, got %v", left)
+	}
+
+	// Buckets are kept per key.
+	if left, err := limiter.Take(ctx, "Client-1/rpm", 2, 10, 5); err != nil || left != 3 {
+		t.Errorf("Expected a separate bucket, got %v, %v", left, err)
+	}
+
+	m.FastForward(time.Minute)
+	if m.Exists("openailb:ratelimit:Client-0/rpm") {
+		t.Fatal("Expected the bucket to expire")
+	}
+	if left := take(1); left != 4 {
+		t.Errorf("Expected an expired bucket to start full, got %v", left)
+	}
+}
+
+func TestBreakerSignals(t *testing.T) {
+	m, client := newRedis(t)
+	signals := NewBreakerSignals(client, "openailb:breakers")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan openailb.BreakerSignal, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- signals.Subscribe(ctx, func(signal openailb.BreakerSignal) { received <- signal })
+	}()
+	for deadline := time.Now().Add(time.Second); m.PubSubNumSub("openailb:breakers")["openailb:breakers"] == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected Subscribe to subscribe")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	m.Publish("openailb:breakers", "not a signal")
+	want := openailb.BreakerSignal{
+		Origin:        "replica-a",
+		Backend:       "Client-0",
+		Service:       openailb.ServiceChat,
+		Model:         "gpt-4o",
+		CooldownUntil: time.Date(2026, 10, 15, 12, 1, 0, 0, time.UTC),
+		Reason:        "503 Service Unavailable",
+	}
+	if err := signals.Publish(context.Background(), want); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-received:
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected the signal back, got %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the signal to be delivered")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected Subscribe to stop with its context, got %v", err)
+	}
+}
+
+func TestCache(t *testing.T) {
+	m, client := newRedis(t)
+	ctx := context.Background()
+	cache := NewCache(client, "openailb:cache:")
+
+	if _, ok, err := cache.Get(ctx, "chat:1"); ok || err != nil {
+		t.Fatalf("Expected a miss, got %v, %v", ok, err)
+	}
+
+	value := []byte("{\"id\": \"chatcmpl-1\"}\x00\xff")
+	if err := cache.Set(ctx, "chat:1", value, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := cache.Get(ctx, "chat:1")
+	if err != nil || !ok || string(got) != string(value) {
+		t.Errorf("Expected the value back, got %q, %v, %v", got, ok, err)
+	}
+	if ttl := m.TTL("openailb:cache:chat:1"); ttl != time.Minute {
+		t.Errorf("Expected the TTL as the key's expiry, got %s", ttl)
+	}
+
+	m.FastForward(time.Minute)
+	if _, ok, err := cache.Get(ctx, "chat:1"); ok || err != nil {
+		t.Errorf("Expected the value to expire, got %v, %v", ok, err)
+	}
+
+	if err := cache.Set(ctx, "chat:2", value, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Delete(ctx, "chat:2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := cache.Get(ctx, "chat:2"); ok || err != nil {
+		t.Errorf("Expected the value to be deleted, got %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,107 @@
+package openailb
+
+import (
+	"context"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// LBResponsesService mimics responses.ResponseService.
+//
+// Background responses only exist on the backend that created them, so the
+// service remembers the owner of every background response it creates, for a
+// day or until the backend is removed, and routes Get and Cancel for that ID
+// back to the same backend.
+type LBResponsesService struct {
+	lb *LoadBalancer
+}
+
+// New creates a model response on the next backend whose responses breaker is closed.
+func (s *LBResponsesService) New(ctx context.Context, params responses.ResponseNewParams, opts ...option.RequestOption) (*responses.Response, error) {
+	var owner *SafeClient
+	ctx = s.lb.withTokenEstimate(ctx, func() int64 { return estimateTokens(params, params.MaxOutputTokens.Value) })
+	res, err := execute(ctx, s.lb, ServiceResponses, params.Model, func(ctx context.Context, safeClient *SafeClient) (*responses.Response, error) {
+		owner = safeClient
+		p := params
+		p.Model = mapModel(safeClient, p.Model)
+		return safeClient.OpenAIClient().Responses.New(ctx, p, append(opts[:len(opts):len(opts)], safeClient.extraBody()...)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Background.Value && !isTerminalResponse(res) {
+		s.lb.responseOwners.store(res.ID, owner)
+	}
+	return res, nil
+}
+
+// Get retrieves a response from the backend that created it, retrying transient
+// failures according to WithPollRetry. Once the response reaches a terminal
+// status the LB forgets its owner.
+func (s *LBResponsesService) Get(ctx context.Context, responseID string, query responses.ResponseGetParams, opts ...option.RequestOption) (*responses.Response, error) {
+	res, err := s.pinned(ctx, responseID, func(ctx context.Context, safeClient *SafeClient) (*responses.Response, error) {
+		return safeClient.OpenAIClient().Responses.Get(ctx, responseID, query, opts...)
+	})
+	if err == nil && isTerminalResponse(res) {
+		s.lb.responseOwners.delete(responseID)
+	}
+	return res, err
+}
+
+// Cancel cancels a background response on the backend that created it.
+func (s *LBResponsesService) Cancel(ctx context.Context, responseID string, opts ...option.RequestOption) (*responses.Response, error) {
+	res, err := s.pinned(ctx, responseID, func(ctx context.Context, safeClient *SafeClient) (*responses.Response, error) {
+		return safeClient.OpenAIClient().Responses.Cancel(ctx, responseID, opts...)
+	})
+	if err == nil {
+		s.lb.responseOwners.delete(responseID)
+	}
+	return res, err
+}
+
+// pinned runs call on the owner of responseID with retry and backoff. IDs the LB
+// did not create (e.g. before a restart), or whose owner it forgot, fall back
+// to normal load balancing.
+func (s *LBResponsesService) pinned(ctx context.Context, responseID string, call func(context.Context, *SafeClient) (*responses.Response, error)) (*responses.Response, error) {
+	owner, ok := s.lb.responseOwners.load(responseID)
+	if !ok {
+		return execute(ctx, s.lb, ServiceResponses, "", call)
+	}
+
+	backoff := s.lb.options.pollBackoff
+	var lastErr error
+	for attempt := 0; attempt < max(s.lb.options.pollAttempts, 1); attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		res, err := executeOn(ctx, s.lb, owner, ServiceResponses, "", call)
+		if err == nil {
+			return res, nil
+		}
+		lastErr = err
+		// Only transient failures are worth retrying; a 4xx won't change on its own.
+		if !isFatalError(err) || ctx.Err() != nil {
+			return nil, err
+		}
+	}
+	return nil, lastErr
+}
+
+// isTerminalResponse reports whether a response has stopped progressing.
+func isTerminalResponse(res *responses.Response) bool {
+	switch res.Status {
+	case responses.ResponseStatusQueued, responses.ResponseStatusInProgress:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,108 @@
+package openailb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// newResponsesServer returns a server that only knows about the responses it created.
+func newResponsesServer(t *testing.T, name string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/responses":
+			_, _ = fmt.Fprintf(w, `{"id":"resp_%s","object":"response","status":"queued"}`, name)
+		case r.Method == http.MethodGet && r.URL.Path == "/responses/resp_"+name:
+			_, _ = fmt.Fprintf(w, `{"id":"resp_%s","object":"response","status":"completed"}`, name)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"message":"not found"}}`))
+		}
+	}))
+}
+
+func TestResponsesPollingPinnedToOwner(t *testing.T) {
+	t.Parallel()
+
+	server1 := newResponsesServer(t, "one")
+	defer server1.Close()
+	server2 := newResponsesServer(t, "two")
+	defer server2.Close()
+
+	configs := []OpenaiClientConfig{
+		{APIKey: "mock-key-1", BaseURL: server1.URL},
+		{APIKey: "mock-key-2", BaseURL: server2.URL},
+	}
+	client := NewClient(configs)
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		res, err := client.Responses.New(context.Background(), responses.ResponseNewParams{
+			Model:      "test_model",
+			Background: openai.Bool(true),
+		})
+		if err != nil {
+			t.Fatalf("Create %d failed: %v", i, err)
+		}
+		ids = append(ids, res.ID)
+	}
+
+	// Without pinning, round-robin would send each Get to the backend that doesn't know the ID.
+	for _, id := range ids {
+		res, err := client.Responses.Get(context.Background(), id, responses.ResponseGetParams{})
+		if err != nil {
+			t.Fatalf("Get %s should have been routed to its owner: %v", id, err)
+		}
+		if !strings.HasPrefix(res.ID, "resp_") || res.Status != responses.ResponseStatusCompleted {
+			t.Errorf("Unexpected response for %s: %s %s", id, res.ID, res.Status)
+		}
+		if _, ok := client.lb.responseOwners.load(id); ok {
+			t.Errorf("Owner of completed response %s should have been forgotten", id)
+		}
+	}
+}
+
+func TestResponseOwnersForgotten(t *testing.T) {
+	t.Parallel()
+
+	server := newResponsesServer(t, "one")
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{{Name: "one", APIKey: "k1", BaseURL: server.URL}, {Name: "two", APIKey: "k2", BaseURL: server.URL}})
+
+	res, err := client.Responses.New(context.Background(), responses.ResponseNewParams{Model: "m", Background: openai.Bool(true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner, ok := client.lb.responseOwners.load(res.ID); !ok || owner.Name != "one" {
+		t.Fatalf("Expected one to own %s", res.ID)
+	}
+	if err := client.RemoveBackend("one"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := client.lb.responseOwners.load(res.ID); ok {
+		t.Error("Expected the owner to be forgotten with its backend")
+	}
+
+	o := owners{ttl: 10 * time.Millisecond}
+	backend := client.lb.backends()[0]
+	o.store("resp_1", backend)
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := o.load("resp_1"); ok {
+		t.Error("Expected the owner to expire")
+	}
+	o.store("resp_2", backend)
+	time.Sleep(20 * time.Millisecond)
+	o.store("resp_3", backend)
+	if n := len(o.entries); n != 1 {
+		t.Errorf("Expected the expired owners to be swept, got %d entries", n)
+	}
+}
@@ -0,0 +1,72 @@
+package openailb
+
+import (
+	"sync"
+	"time"
+)
+
+// rollingBuckets is how many buckets a rolling window is split into.
+const rollingBuckets = 60
+
+// rollingCounter counts requests, failures and latency over a rolling time window.
+type rollingCounter struct {
+	mu      sync.Mutex
+	bucket  time.Duration
+	buckets [rollingBuckets]rollingBucket
+}
+
+// rollingBucket holds the counts of one slice of the window.
+type rollingBucket struct {
+	id       int64 // Which slice of time the counts belong to.
+	requests int64
+	failures int64
+	latency  time.Duration
+}
+
+func newRollingCounter(window time.Duration) *rollingCounter {
+	return &rollingCounter{bucket: max(window/rollingBuckets, time.Millisecond)}
+}
+
+// add records one request at now.
+func (r *rollingCounter) add(now time.Time, failed bool) {
+	r.observe(now, failed, 0)
+}
+
+// observe records one request at now that took latency.
+func (r *rollingCounter) observe(now time.Time, failed bool, latency time.Duration) {
+	id := now.UnixNano() / int64(r.bucket)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := &r.buckets[id%rollingBuckets]
+	if b.id != id {
+		*b = rollingBucket{id: id}
+	}
+	b.requests++
+	b.latency += latency
+	if failed {
+		b.failures++
+	}
+}
+
+// totals returns the counts over the window ending at now.
+func (r *rollingCounter) totals(now time.Time) (requests, failures int64) {
+	requests, failures, _ = r.stats(now)
+	return requests, failures
+}
+
+// stats returns the counts and summed latency over the window ending at now.
+func (r *rollingCounter) stats(now time.Time) (requests, failures int64, latency time.Duration) {
+	id := now.UnixNano() / int64(r.bucket)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, b := range r.buckets {
+		if b.id > id-rollingBuckets && b.id <= id {
+			requests += b.requests
+			failures += b.failures
+			latency += b.latency
+		}
+	}
+	return requests, failures, latency
+}
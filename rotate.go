@@ -0,0 +1,76 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// WithRotationRetry retries a request rejected with a 401 once on the same
+// backend if its key was rotated in the meantime, by RotateKey or, for an
+// APIKeyRef, in the secrets manager, which is asked again at once. Requests
+// sent with the old key just before it was revoked then succeed instead of
+// tripping the breaker or quarantining the backend. Streams aren't retried.
+func WithRotationRetry() LBOption {
+	return func(o *lbOptions) {
+		o.rotationRetry = true
+	}
+}
+
+// RotateKey makes the backend called name send newKey from now on, e.g. on a
+// schedule before the old key is revoked. Requests in flight finish with the
+// old key; see WithRotationRetry for those the revocation rejects. It also
+// reinstates a backend quarantined by WithAuthQuarantine. The key of a backend
+// with an APIKeyRef is rotated in its secrets manager instead.
+func (c Client) RotateKey(name, newKey string) error {
+	backend, err := c.lb.clientByName(name)
+	if err != nil {
+		return err
+	}
+	if newKey == "" {
+		return fmt.Errorf("%s: the new API key is empty", name)
+	}
+	if backend.secret != nil {
+		return fmt.Errorf("%s: the key is resolved from APIKeyRef %q; rotate it in the secrets manager", name, backend.secret.ref)
+	}
+	backend.mu.Lock()
+	backend.config.APIKey = newKey
+	// The new client keeps the backend's connections.
+	backend.client.Store(c.lb.newOpenAIClient(backend.config, backend.httpClient, nil))
+	backend.mu.Unlock()
+	backend.reinstate()
+	c.lb.options.logger.Info("API key rotated", "backend", name)
+	return nil
+}
+
+// OpenAIClient returns the client requests to the backend are sent with.
+func (c *SafeClient) OpenAIClient() *openai.Client {
+	return c.client.Load()
+}
+
+// keyInUse returns what identifies the key requests are sent with now.
+func (c *SafeClient) keyInUse() any {
+	if c.secret != nil {
+		return c.secret.value.Load()
+	}
+	return c.client.Load()
+}
+
+// keyRotated reports whether the backend's key changed since a request that
+// started when keyInUse returned used was rejected.
+func (c *SafeClient) keyRotated(ctx context.Context, used any) bool {
+	if c.secret != nil {
+		key, _ := used.(*string)
+		return c.secret.reresolve(ctx, key)
+	}
+	return c.client.Load() != used
+}
+
+// isUnauthorized reports whether err is a 401.
+func isUnauthorized(err error) bool {
+	var apiErr *openai.Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized
+}
@@ -0,0 +1,150 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+// keyServer answers requests sent with its valid key and rejects the others
+// with a 401. Requests sent with held wait for release first.
+type keyServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	valid   string
+	held    string
+	arrived chan struct{}
+	release chan struct{}
+}
+
+func newKeyServer(valid string) *keyServer {
+	s := &keyServer{valid: valid, arrived: make(chan struct{}, 1), release: make(chan struct{})}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		s.mu.Lock()
+		held := s.held
+		s.mu.Unlock()
+		if key == held {
+			s.arrived <- struct{}{}
+			<-s.release
+		}
+		s.mu.Lock()
+		valid := s.valid
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if key != valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": {"message": "invalid key", "code": "invalid_api_key"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "` + key + `"}}]}`))
+	}))
+	return s
+}
+
+func (s *keyServer) setValid(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.valid = key
+}
+
+func (s *keyServer) hold(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.held = key
+}
+
+func TestRotateKey(t *testing.T) {
+	t.Parallel()
+
+	server := newKeyServer("k1")
+	defer server.Close()
+
+	client := NewClient([]OpenaiClientConfig{{Name: "openai", APIKey: "k1", BaseURL: server.URL}}, WithAuthQuarantine())
+	server.setValid("k2")
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0)); err == nil {
+		t.Fatal("Expected the revoked key to be rejected")
+	}
+	if quarantined, _ := client.lb.backends()[0].Quarantined(); !quarantined {
+		t.Fatal("Expected the rejected backend to be quarantined")
+	}
+
+	if err := client.RotateKey("openai", "k2"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Chat.Completions.New(context.Background(), chatParams("m"))
+	if err != nil {
+		t.Fatalf("Expected the rotated key to be accepted and the backend reinstated, got %v", err)
+	}
+	if content := resp.Choices[0].Message.Content; content != "k2" {
+		t.Errorf("Expected the request to be sent with the new key, got %s", content)
+	}
+
+	if err := client.RotateKey("nope", "k3"); err == nil || err.Error() != `no backend named "nope"` {
+		t.Errorf("Expected an unknown backend to be reported, got %v", err)
+	}
+	if err := client.RotateKey("openai", ""); err == nil {
+		t.Error("Expected an empty key to be refused")
+	}
+	secrets := NewClient([]OpenaiClientConfig{{Name: "vault", APIKeyRef: "openai#key", BaseURL: server.URL}})
+	if err := secrets.RotateKey("vault", "k3"); err == nil || !strings.Contains(err.Error(), "rotate it in the secrets manager") {
+		t.Errorf("Expected a backend with an APIKeyRef to be refused, got %v", err)
+	}
+}
+
+func TestRotationRetry(t *testing.T) {
+	t.Parallel()
+
+	for _, retry := range []bool{true, false} {
+		server := newKeyServer("k1")
+		defer server.Close()
+		opts := []LBOption{}
+		if retry {
+			opts = append(opts, WithRotationRetry())
+		}
+		client := NewClient([]OpenaiClientConfig{{Name: "openai", APIKey: "k1", BaseURL: server.URL}}, opts...)
+
+		// The key is rotated and the old one revoked while a request is in flight.
+		server.hold("k1")
+		errs := make(chan error, 1)
+		go func() {
+			_, err := client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+			errs <- err
+		}()
+		<-server.arrived
+		if err := client.RotateKey("openai", "k2"); err != nil {
+			t.Fatal(err)
+		}
+		server.setValid("k2")
+		close(server.release)
+		if err := <-errs; retry && err != nil {
+			t.Errorf("Expected the rejected request to be retried with the new key, got %v", err)
+		} else if !retry && !isUnauthorized(err) {
+			t.Errorf("Expected the 401 without WithRotationRetry, got %v", err)
+		}
+	}
+
+	// A rejected APIKeyRef is resolved again at once.
+	server := newKeyServer("k1")
+	defer server.Close()
+	provider := &fakeSecrets{secrets: map[string]string{"openai#key": "k1"}}
+	client := NewClient([]OpenaiClientConfig{{Name: "vault", APIKeyRef: "openai#key", BaseURL: server.URL}},
+		WithSecretProvider(provider, 0), WithRotationRetry())
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m")); err != nil {
+		t.Fatal(err)
+	}
+	provider.set("openai#key", "k2", nil)
+	server.setValid("k2")
+	resp, err := client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+	if err != nil {
+		t.Fatalf("Expected the key to be resolved again and the request retried, got %v", err)
+	}
+	if content := resp.Choices[0].Message.Content; content != "k2" {
+		t.Errorf("Expected the retry to use the rotated key, got %s", content)
+	}
+}
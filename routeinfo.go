@@ -0,0 +1,128 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+// RouteInfo describes how the LB routed a request (WithRouteInfo).
+type RouteInfo struct {
+	// Backend is the backend that produced the answer, or the last one tried.
+	Backend string
+	BaseURL string
+	// Model is the requested model; MappedModel the one sent to Backend.
+	Model       string
+	MappedModel string
+	// Attempts lists the backends tried, in order: just one, unless the
+	// request failed over (WithFailover).
+	Attempts []AttemptInfo
+	// Latency is the time from the first attempt to the end of the last. For
+	// streams, it ends when the response headers arrived.
+	Latency time.Duration
+	// RateLimits are the quotas Backend announced in its response, if any.
+	RateLimits *RateLimits
+	// Cached is set for an answer from the response cache
+	// (WithResponseCache), which called no backend.
+	Cached bool
+	// Stale is set, with Cached, for an answer kept by ResponseCache.StaleFor
+	// and served because no backend could take the request.
+	Stale bool
+}
+
+// AttemptInfo is one backend attempt of a routed request.
+type AttemptInfo struct {
+	Backend     string
+	MappedModel string
+	Latency     time.Duration
+	Class       ErrorClass
+	Err         error
+	RateLimits  *RateLimits
+}
+
+// WithRouteInfo is a request option that fills in info once the call
+// returned, so the caller knows which backend produced an answer:
+//
+//	var info openailb.RouteInfo
+//	resp, err := client.Chat.Completions.New(ctx, params, openailb.WithRouteInfo(&info))
+//	log.Printf("answered by %s after %d attempts", info.Backend, len(info.Attempts))
+//
+// With WithFailover, info.Attempts also lists the backends that failed first.
+//
+// For a sharded embeddings request (WithEmbeddingSharding), info describes
+// one of the shards.
+func WithRouteInfo(info *RouteInfo) option.RequestOption {
+	return option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		if route, ok := req.Context().Value(routeKey{}).(*routeState); ok {
+			route.info = info
+		}
+		return next(req)
+	})
+}
+
+type routeKey struct{}
+
+// sharedRouteKey carries the mutex serializing writes to the RouteInfo that
+// the shards of a sharded embeddings request share.
+type sharedRouteKey struct{}
+
+// withSharedRoute returns a context for requests filling in one RouteInfo
+// concurrently.
+func withSharedRoute(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sharedRouteKey{}, new(sync.Mutex))
+}
+
+// routeState collects the attempts of one request, for the RouteInfo that
+// WithRouteInfo registers while the first attempt is sent.
+type routeState struct {
+	start    time.Time
+	model    string
+	attempts []attempt
+	results  []AttemptInfo
+	info     *RouteInfo
+	cached   bool
+	stale    bool
+	// shared is set, by withSharedRoute, when other requests write to info too.
+	shared *sync.Mutex
+}
+
+// withRoute returns a context in which WithRouteInfo registers with the returned state.
+func withRoute(ctx context.Context, model string) (context.Context, *routeState) {
+	route := &routeState{start: time.Now(), model: model}
+	route.shared, _ = ctx.Value(sharedRouteKey{}).(*sync.Mutex)
+	return context.WithValue(ctx, routeKey{}, route), route
+}
+
+// record adds an attempt and how it ended.
+func (r *routeState) record(a attempt, done ResponseInfo) {
+	r.attempts = append(r.attempts, a)
+	r.results = append(r.results, AttemptInfo{
+		Backend:     done.Backend,
+		MappedModel: done.MappedModel,
+		Latency:     done.Latency,
+		Class:       done.Class,
+		Err:         done.Err,
+		RateLimits:  done.RateLimits,
+	})
+}
+
+// finish fills in the registered RouteInfo, if any.
+func (r *routeState) finish() {
+	if r.info == nil {
+		return
+	}
+	info := RouteInfo{Model: r.model, Attempts: r.results, Latency: time.Since(r.start), Cached: r.cached, Stale: r.stale}
+	if n := len(r.attempts); n > 0 {
+		last := r.attempts[n-1]
+		info.Backend, info.BaseURL, info.MappedModel = last.client.Name, last.client.BaseURL, last.model
+		info.RateLimits = r.results[n-1].RateLimits
+	}
+	if r.shared != nil {
+		r.shared.Lock()
+		defer r.shared.Unlock()
+	}
+	*r.info = info
+}
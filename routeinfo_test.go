@@ -0,0 +1,73 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestRouteInfo(t *testing.T) {
+	t.Parallel()
+
+	failServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failServer.Close()
+	okServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == "text/event-stream" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: {\"choices\": [{\"delta\": {\"content\": \"Hi\"}}]}\n\ndata: [DONE]\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "ok"}}]}`))
+	}))
+	defer okServer.Close()
+
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: failServer.URL},
+		{APIKey: "k2", BaseURL: okServer.URL, ModelMap: map[string]string{"m": "mapped"}},
+	})
+
+	var failed RouteInfo
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0), WithRouteInfo(&failed)); err == nil {
+		t.Fatal("Expected the failing backend's error")
+	}
+	if len(failed.Attempts) != 1 {
+		t.Fatalf("Expected 1 attempt, got %+v", failed.Attempts)
+	}
+	if first := failed.Attempts[0]; first.Backend != "Client-0" || first.Class != ClassServer || first.Err == nil {
+		t.Errorf("Expected the attempt to fail on Client-0, got %+v", first)
+	}
+
+	var info RouteInfo
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0), WithRouteInfo(&info)); err != nil {
+		t.Fatal(err)
+	}
+	if info.Backend != "Client-1" || info.BaseURL != okServer.URL || info.Model != "m" || info.MappedModel != "mapped" {
+		t.Errorf("Unexpected route info %+v", info)
+	}
+	if len(info.Attempts) != 1 || info.Attempts[0].Class != ClassOK || info.Attempts[0].Err != nil {
+		t.Fatalf("Expected 1 successful attempt, got %+v", info.Attempts)
+	}
+	if info.Latency < info.Attempts[0].Latency {
+		t.Errorf("Expected the total latency to cover the attempt, got %s", info.Latency)
+	}
+
+	// Streams record the backend that answered the request.
+	var streamInfo RouteInfo
+	stream := client.Chat.Completions.NewStreaming(context.Background(), openai.ChatCompletionNewParams{
+		Model:    "m",
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("test")},
+	}, WithRouteInfo(&streamInfo))
+	for stream.Next() {
+	}
+	stream.Close()
+	if streamInfo.Backend == "" || len(streamInfo.Attempts) != 1 {
+		t.Errorf("Unexpected stream route info %+v", streamInfo)
+	}
+}
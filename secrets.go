@@ -0,0 +1,155 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+// SecretProvider resolves secret references to their values, e.g. API keys
+// kept in a secrets manager rather than in the config. The vaultlb and awssmlb
+// packages implement it for HashiCorp Vault and AWS Secrets Manager.
+type SecretProvider interface {
+	// Secret returns the current value of the secret ref refers to.
+	Secret(ctx context.Context, ref string) (string, error)
+}
+
+// DefaultSecretRefresh is how often WithSecretProvider resolves keys again
+// by default.
+const DefaultSecretRefresh = 5 * time.Minute
+
+// WithSecretProvider resolves the APIKeyRef of backends with p. A key is
+// resolved on the backend's first request, so a secrets manager that is down
+// at startup only fails the requests that need it, and then again every
+// refresh (default DefaultSecretRefresh), so rotated keys are picked up without
+// a restart. A failed refresh keeps the previous key.
+func WithSecretProvider(p SecretProvider, refresh time.Duration) LBOption {
+	return func(o *lbOptions) {
+		o.secrets = p
+		o.secretRefresh = refresh
+		if refresh <= 0 {
+			o.secretRefresh = DefaultSecretRefresh
+		}
+	}
+}
+
+// secretKey is a backend's API key resolved from a SecretProvider.
+type secretKey struct {
+	backend  string
+	ref      string
+	provider SecretProvider // Nil without WithSecretProvider.
+	logger   *slog.Logger
+	value    atomic.Pointer[string]
+	mu       sync.Mutex // Serializes resolving.
+	rejected time.Time  // When a rejected key was last resolved again, guarded by mu.
+}
+
+// minReresolve is the least time between resolving rejected keys again, so
+// a revoked key doesn't turn every request into a lookup.
+const minReresolve = time.Second
+
+// get returns the key, resolving it first if it wasn't yet.
+func (s *secretKey) get(ctx context.Context) (string, error) {
+	if key := s.value.Load(); key != nil {
+		return *key, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key := s.value.Load(); key != nil {
+		return *key, nil
+	}
+	return s.resolve(ctx)
+}
+
+// resolve resolves the key from the SecretProvider and stores it. s.mu must
+// be held.
+func (s *secretKey) resolve(ctx context.Context) (string, error) {
+	if s.provider == nil {
+		return "", fmt.Errorf("%s: APIKeyRef %q requires WithSecretProvider", s.backend, s.ref)
+	}
+	key, err := s.provider.Secret(ctx, s.ref)
+	if err == nil && key == "" {
+		err = errors.New("the secret is empty")
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: resolve APIKeyRef %q: %w", s.backend, s.ref, err)
+	}
+	if previous := s.value.Swap(&key); previous != nil && *previous != key {
+		s.logger.Info("API key rotated", "backend", s.backend)
+	}
+	return key, nil
+}
+
+// refresh resolves the key again, if it was resolved yet. A failure keeps
+// the current key.
+func (s *secretKey) refresh(ctx context.Context) {
+	if s.value.Load() == nil {
+		// Not needed yet; the first request resolves it.
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.resolve(ctx); err != nil {
+		s.logger.Warn("API key refresh failed; keeping the current key", "backend", s.backend, "error", err)
+	}
+}
+
+// reresolve resolves the key again after a request sent with used was
+// rejected, unless it changed since, and reports whether it now differs.
+func (s *secretKey) reresolve(ctx context.Context, used *string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if used == nil {
+		// The request resolved the key itself.
+		used = s.value.Load()
+	}
+	if current := s.value.Load(); current != used {
+		return current != nil && used != nil && *current != *used
+	}
+	if time.Since(s.rejected) < minReresolve {
+		return false
+	}
+	s.rejected = time.Now()
+	key, err := s.resolve(ctx)
+	return err == nil && used != nil && key != *used
+}
+
+// auth returns the middleware that authenticates each request with the
+// resolved key, as the api-key header for Azure.
+func (s *secretKey) auth(azure bool) option.RequestOption {
+	return option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		key, err := s.get(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		if azure {
+			req.Header.Set("Api-Key", key)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		return next(req)
+	})
+}
+
+// runSecretRefresh resolves the backend's key every interval.
+func (c *SafeClient) runSecretRefresh(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.stop:
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), c.healthCheck.Timeout)
+		c.secret.refresh(ctx)
+		cancel()
+	}
+}
@@ -0,0 +1,108 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+// fakeSecrets serves secrets from a map and counts the lookups.
+type fakeSecrets struct {
+	mu      sync.Mutex
+	secrets map[string]string
+	err     error
+	lookups int
+}
+
+func (p *fakeSecrets) Secret(ctx context.Context, ref string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lookups++
+	if p.err != nil {
+		return "", p.err
+	}
+	return p.secrets[ref], nil
+}
+
+func (p *fakeSecrets) set(ref, value string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.secrets[ref] = value
+	p.err = err
+}
+
+func TestSecretProvider(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get("Authorization"))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "ok"}}]}`))
+	}))
+	defer server.Close()
+	lastKey := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return keys[len(keys)-1]
+	}
+
+	secrets := &fakeSecrets{secrets: map[string]string{"openai#key": "sk-1"}}
+	configs := []OpenaiClientConfig{{Name: "vault", APIKeyRef: "openai#key", BaseURL: server.URL}}
+	if err := ValidateConfigs(configs); err != nil {
+		t.Fatalf("Expected an APIKeyRef to replace the APIKey, got %v", err)
+	}
+	client := NewClient(configs, WithSecretProvider(secrets, 20*time.Millisecond))
+	if secrets.lookups != 0 {
+		t.Errorf("Expected the key to be resolved lazily, got %d lookups", secrets.lookups)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := client.Chat.Completions.New(context.Background(), chatParams("m")); err != nil {
+			t.Fatal(err)
+		}
+		if key := lastKey(); key != "Bearer sk-1" {
+			t.Errorf("Expected the resolved key, got %q", key)
+		}
+	}
+
+	// A failed refresh keeps the key; a rotated one is picked up.
+	secrets.set("openai#key", "", errors.New("vault sealed"))
+	time.Sleep(50 * time.Millisecond)
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m")); err != nil {
+		t.Fatalf("Expected the current key to be kept, got %v", err)
+	}
+	secrets.set("openai#key", "sk-2", nil)
+	if !waitFor(t, time.Second, func() bool {
+		_, err := client.Chat.Completions.New(context.Background(), chatParams("m"))
+		return err == nil && lastKey() == "Bearer sk-2"
+	}) {
+		t.Error("Expected the rotated key to be used")
+	}
+
+	// Without a provider, or a key, the request fails and names the ref.
+	client = NewClient([]OpenaiClientConfig{{Name: "none", APIKeyRef: "openai#key", BaseURL: server.URL}})
+	_, err := client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+	if err == nil || !strings.Contains(err.Error(), `none: APIKeyRef "openai#key" requires WithSecretProvider`) {
+		t.Errorf("Expected the missing provider to be reported, got %v", err)
+	}
+	client = NewClient([]OpenaiClientConfig{{Name: "empty", APIKeyRef: "missing", BaseURL: server.URL}}, WithSecretProvider(secrets, 0))
+	_, err = client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+	if err == nil || !strings.Contains(err.Error(), `empty: resolve APIKeyRef "missing": the secret is empty`) {
+		t.Errorf("Expected the empty secret to be reported, got %v", err)
+	}
+
+	err = ValidateConfigs([]OpenaiClientConfig{{APIKey: "k", APIKeyRef: "openai#key", BaseURL: server.URL}})
+	if err == nil || !strings.Contains(err.Error(), "configs[0].APIKeyRef: must not be set with APIKey") {
+		t.Errorf("Expected APIKey and APIKeyRef to be exclusive, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// LBImagesService mimics openai.ImageService.
+type LBImagesService struct {
+	lb *LoadBalancer
+}
+
+// Generate creates an image on the next backend whose images breaker is closed.
+func (s *LBImagesService) Generate(ctx context.Context, params openai.ImageGenerateParams, opts ...option.RequestOption) (*openai.ImagesResponse, error) {
+	return execute(ctx, s.lb, ServiceImages, params.Model, func(ctx context.Context, safeClient *SafeClient) (*openai.ImagesResponse, error) {
+		p := params
+		p.Model = mapModel(safeClient, p.Model)
+		return safeClient.OpenAIClient().Images.Generate(ctx, p, opts...)
+	})
+}
+
+// Edit edits an image on the next backend whose images breaker is closed. The
+// image is read from a reader, so a failed edit is not retried elsewhere.
+func (s *LBImagesService) Edit(ctx context.Context, params openai.ImageEditParams, opts ...option.RequestOption) (*openai.ImagesResponse, error) {
+	return executeOnce(ctx, s.lb, ServiceImages, params.Model, func(ctx context.Context, safeClient *SafeClient) (*openai.ImagesResponse, error) {
+		p := params
+		p.Model = mapModel(safeClient, p.Model)
+		return safeClient.OpenAIClient().Images.Edit(ctx, p, opts...)
+	})
+}
+
+// LBAudioService mimics openai.AudioService.
+type LBAudioService struct {
+	Transcriptions *LBAudioTranscriptionsService
+	Speech         *LBAudioSpeechService
+}
+
+// LBAudioTranscriptionsService mimics openai.AudioTranscriptionService.
+type LBAudioTranscriptionsService struct {
+	lb *LoadBalancer
+}
+
+// New transcribes audio on the next backend whose audio breaker is closed. The
+// audio is read from a reader, so a failed transcription is not retried elsewhere.
+func (s *LBAudioTranscriptionsService) New(ctx context.Context, params openai.AudioTranscriptionNewParams, opts ...option.RequestOption) (*openai.AudioTranscriptionNewResponseUnion, error) {
+	return executeOnce(ctx, s.lb, ServiceAudio, params.Model, func(ctx context.Context, safeClient *SafeClient) (*openai.AudioTranscriptionNewResponseUnion, error) {
+		p := params
+		p.Model = mapModel(safeClient, p.Model)
+		return safeClient.OpenAIClient().Audio.Transcriptions.New(ctx, p, opts...)
+	})
+}
+
+// LBAudioSpeechService mimics openai.AudioSpeechService.
+type LBAudioSpeechService struct {
+	lb *LoadBalancer
+}
+
+// New generates speech on the next backend whose audio breaker is closed.
+// The caller is responsible for closing the returned response body.
+func (s *LBAudioSpeechService) New(ctx context.Context, params openai.AudioSpeechNewParams, opts ...option.RequestOption) (*http.Response, error) {
+	return execute(ctx, s.lb, ServiceAudio, params.Model, func(ctx context.Context, safeClient *SafeClient) (*http.Response, error) {
+		p := params
+		p.Model = mapModel(safeClient, p.Model)
+		return safeClient.OpenAIClient().Audio.Speech.New(ctx, p, opts...)
+	})
+}
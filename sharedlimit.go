@@ -0,0 +1,91 @@
+package openailb
+
+import (
+	"context"
+	"time"
+)
+
+// RateLimiter holds the RPM and TPM buckets of the backends on behalf of
+// several clients (WithRateLimiter), e.g. in Redis (redisstore.RateLimiter),
+// so that replicas each running their own client enforce
+// OpenaiClientConfig.RPM and TPM fleet-wide rather than each on their own.
+type RateLimiter interface {
+	// Take removes n tokens (adds them back if negative) from the bucket
+	// key, which holds up to burst tokens refilled at rate tokens a second
+	// and starts full, going into debt if there are fewer. It returns the
+	// tokens left, negative in debt.
+	Take(ctx context.Context, key string, n, rate, burst float64) (float64, error)
+}
+
+// WithRateLimiter shares the RPM and TPM budgets of the backends through
+// limiter, under keys made of the backend names, which must therefore match
+// across replicas. Each client still keeps a local copy of the buckets to
+// route by, updated with what limiter returns. A backend the fleet used up
+// meanwhile is skipped like a local one; when limiter fails, the local
+// buckets decide alone.
+func WithRateLimiter(limiter RateLimiter) LBOption {
+	return func(o *lbOptions) {
+		o.rateLimiter = limiter
+	}
+}
+
+// sharedTimeout bounds each call to the RateLimiter.
+const sharedTimeout = time.Second
+
+// takeShared charges a request of tokens to the shared buckets of the
+// backend, already charged to its local ones by nextClient. It reports
+// false, refunding the shared buckets, if the fleet left the backend
+// without the budget for it.
+func (lb *LoadBalancer) takeShared(ctx context.Context, c *SafeClient, tokens int64) bool {
+	limiter := lb.options.rateLimiter
+	if limiter == nil || (c.rpm == nil && c.tpm == nil) {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sharedTimeout)
+	defer cancel()
+	type charge struct {
+		key    string
+		bucket *tokenBucket
+		n      float64
+	}
+	var taken []charge
+	ok := true
+	for _, ch := range []charge{{"rpm:" + c.Name, c.rpm, 1}, {"tpm:" + c.Name, c.tpm, float64(tokens)}} {
+		if ch.bucket == nil || ch.n <= 0 {
+			continue
+		}
+		left, err := limiter.Take(ctx, ch.key, ch.n, ch.bucket.rate, ch.bucket.burst)
+		if err != nil {
+			lb.options.logger.WarnContext(ctx, "shared rate limiter failed", "backend", c.Name, "error", err)
+			continue
+		}
+		ch.bucket.set(time.Now(), left)
+		taken = append(taken, ch)
+		if left+ch.n < min(ch.n, ch.bucket.burst) {
+			ok = false
+		}
+	}
+	if !ok {
+		for _, ch := range taken {
+			if left, err := limiter.Take(ctx, ch.key, -ch.n, ch.bucket.rate, ch.bucket.burst); err == nil {
+				ch.bucket.set(time.Now(), left)
+			}
+		}
+	}
+	return ok
+}
+
+// settleShared is settleTokens for the shared TPM bucket of the backend.
+func (lb *LoadBalancer) settleShared(c *SafeClient, delta float64) {
+	limiter := lb.options.rateLimiter
+	if limiter == nil || c.tpm == nil || delta == 0 {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), sharedTimeout)
+		defer cancel()
+		if _, err := limiter.Take(ctx, "tpm:"+c.Name, delta, c.tpm.rate, c.tpm.burst); err != nil {
+			lb.options.logger.Warn("shared rate limiter failed", "backend", c.Name, "error", err)
+		}
+	}()
+}
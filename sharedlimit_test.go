@@ -0,0 +1,50 @@
+package openailb
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryLimiter is a RateLimiter shared in memory, standing in for Redis.
+type memoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func (m *memoryLimiter) Take(ctx context.Context, key string, n, rate, burst float64) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &tokenBucket{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+		m.buckets[key] = b
+	}
+	b.take(time.Now(), n)
+	return b.tokens, nil
+}
+
+func TestSharedRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	limited, spare := newNamedServer(t, "limited"), newNamedServer(t, "spare")
+	defer limited.Close()
+	defer spare.Close()
+	configs := []OpenaiClientConfig{
+		{Name: "limited", APIKey: "k1", BaseURL: limited.URL, Weight: 100, RPM: 2, RPMBurst: 2},
+		{Name: "spare", APIKey: "k2", BaseURL: spare.URL},
+	}
+	limiter := &memoryLimiter{buckets: make(map[string]*tokenBucket)}
+	replicas := []Client{NewClient(configs, WithRateLimiter(limiter)), NewClient(configs, WithRateLimiter(limiter))}
+
+	hits := make(map[string]int)
+	for _, client := range replicas {
+		for name, n := range countHits(t, client, 2) {
+			hits[name] += n
+		}
+	}
+	if hits["limited"] != 2 || hits["spare"] != 2 {
+		t.Errorf("Expected the replicas to share the backend's RPM, got %v", hits)
+	}
+}
@@ -0,0 +1,74 @@
+package openailb
+
+import (
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LoadShedding configures WithLoadShedding. The client is overloaded while
+// either threshold set is exceeded.
+type LoadShedding struct {
+	// QueueWait is the longest the last request admitted may have waited in
+	// the WithMaxConcurrency queue.
+	QueueWait time.Duration
+	// InFlight is the most requests in flight across all backends.
+	InFlight int
+	// For is how long the overload must last before shedding starts.
+	For time.Duration
+	// Fraction is the share of the low-priority requests rejected while
+	// shedding (default 0.5).
+	Fraction float64
+}
+
+// WithLoadShedding rejects a Fraction of the PriorityLow requests with
+// ErrOverloaded, before they are queued or sent, while the client has been
+// overloaded for cfg.For, rather than letting all requests time out slowly.
+func WithLoadShedding(cfg LoadShedding) LBOption {
+	return func(o *lbOptions) {
+		if cfg.Fraction <= 0 || cfg.Fraction > 1 {
+			cfg.Fraction = 0.5
+		}
+		o.shedding = &shedder{cfg: cfg}
+	}
+}
+
+// shedder tracks the overload signals of WithLoadShedding.
+type shedder struct {
+	cfg      LoadShedding
+	inFlight atomic.Int64
+
+	mu        sync.Mutex
+	queueWait time.Duration // Of the last request admitted.
+	since     time.Time     // When the overload started; zero without one.
+}
+
+// shed reports whether to reject a low-priority request at now.
+func (s *shedder) shed(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	over := (s.cfg.InFlight > 0 && s.inFlight.Load() > int64(s.cfg.InFlight)) ||
+		(s.cfg.QueueWait > 0 && s.queueWait > s.cfg.QueueWait)
+	if !over {
+		s.since = time.Time{}
+		return false
+	}
+	if s.since.IsZero() {
+		s.since = now
+	}
+	return now.Sub(s.since) >= s.cfg.For && rand.Float64() < s.cfg.Fraction
+}
+
+// admitted records a request admitted after waiting in the queue for
+// waited, and returns the func to call once it is done.
+func (s *shedder) admitted(waited time.Duration) func() {
+	if s == nil {
+		return func() {}
+	}
+	s.inFlight.Add(1)
+	s.mu.Lock()
+	s.queueWait = waited
+	s.mu.Unlock()
+	return sync.OnceFunc(func() { s.inFlight.Add(-1) })
+}
@@ -0,0 +1,54 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLoadShedding(t *testing.T) {
+	t.Parallel()
+
+	arrived, release := make(chan struct{}, 4), make(chan struct{})
+	slow := newBlockingServer(arrived, release)
+	defer slow.Close()
+	defer close(release)
+
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: slow.URL}},
+		WithLoadShedding(LoadShedding{InFlight: 1, For: 20 * time.Millisecond, Fraction: 1}))
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := client.Chat.Completions.New(context.Background(), chatParams("m"))
+			errs <- err
+		}()
+		<-arrived
+	}
+
+	if client.lb.options.shedding.shed(time.Now()) {
+		t.Error("Expected no shedding before the overload lasted For")
+	}
+	time.Sleep(20 * time.Millisecond)
+	low := WithPriority(context.Background(), PriorityLow)
+	if _, err := client.Chat.Completions.New(low, chatParams("m")); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("Expected the low-priority request to be shed, got %v", err)
+	}
+
+	release <- struct{}{}
+	release <- struct{}{}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+	go func() {
+		_, err := client.Chat.Completions.New(low, chatParams("m"))
+		errs <- err
+	}()
+	<-arrived
+	release <- struct{}{}
+	if err := <-errs; err != nil {
+		t.Errorf("Expected the shedding to stop with the overload, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package openailb
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// BreakerSignals carries breaker openings between clients
+// (WithBreakerSignals), e.g. over Redis pub/sub (redisstore.BreakerSignals),
+// so that when one replica finds a backend down the others stop sending it
+// traffic too.
+type BreakerSignals interface {
+	// Publish announces signal to every subscriber, the publisher included.
+	Publish(ctx context.Context, signal BreakerSignal) error
+	// Subscribe calls handle with every signal published until ctx is done
+	// or the subscription fails, and then returns.
+	Subscribe(ctx context.Context, handle func(BreakerSignal)) error
+}
+
+// BreakerSignal announces that a breaker opened.
+type BreakerSignal struct {
+	Origin        string      `json:"origin"` // Identifies the client that opened the breaker.
+	Backend       string      `json:"backend"`
+	Service       ServiceType `json:"service"`
+	Model         string      `json:"model,omitempty"` // Set for per-model breakers.
+	CooldownUntil time.Time   `json:"cooldown_until"`
+	Reason        string      `json:"reason,omitempty"` // The backend's last error when the breaker opened.
+}
+
+// WithBreakerSignals publishes every breaker opening of the client to
+// signals and holds the matching breakers open, until the cooldown
+// announced, when the other clients subscribed report theirs. Breakers are
+// matched by backend name, which must therefore match across replicas;
+// per-model openings are ignored by clients without WithPerModelBreakers.
+// Only openings are shared: a breaker opened by a peer probes again once
+// the peer's cooldown ends. Combine with WithStateStore for replicas started
+// after the signal was sent.
+func WithBreakerSignals(signals BreakerSignals) LBOption {
+	return func(o *lbOptions) {
+		o.signals = &breakerSignals{BreakerSignals: signals, origin: fmt.Sprintf("%016x", rand.Uint64())}
+	}
+}
+
+// breakerSignals is the BreakerSignals of the client, with the origin of the
+// signals it publishes.
+type breakerSignals struct {
+	BreakerSignals
+	origin string
+}
+
+// signalRetry is how long the client waits before subscribing again after
+// the subscription failed.
+const signalRetry = 5 * time.Second
+
+// subscribeSignals listens to the peers' breaker signals until the client is
+// closed, if WithBreakerSignals is set.
+func (lb *LoadBalancer) subscribeSignals() {
+	signals := lb.options.signals
+	if signals == nil {
+		return
+	}
+	lb.background.Add(1)
+	go func() {
+		defer lb.background.Done()
+		for {
+			err := signals.Subscribe(lb.done, lb.receiveSignal)
+			if lb.done.Err() != nil {
+				return
+			}
+			lb.options.logger.Warn("breaker signal subscription failed", "error", err)
+			select {
+			case <-lb.done.Done():
+				return
+			case <-time.After(signalRetry):
+			}
+		}
+	}()
+}
+
+// receiveSignal holds open the breaker a peer reported open.
+func (lb *LoadBalancer) receiveSignal(s BreakerSignal) {
+	if s.Origin == lb.options.signals.origin || !time.Now().Before(s.CooldownUntil) {
+		return
+	}
+	c, err := lb.clientByName(s.Backend)
+	if err != nil || (s.Model != "" && !c.lb.options.perModelBreakers) {
+		return
+	}
+	c.breakerFor(s.Service, s.Model).adopt(s)
+}
+
+// publishSignal announces to the peers that b opened, if WithBreakerSignals is set.
+func (lb *LoadBalancer) publishSignal(b *trackedBreaker, reason string) {
+	signals := lb.options.signals
+	if signals == nil {
+		return
+	}
+	s := BreakerSignal{
+		Origin:        signals.origin,
+		Backend:       b.owner.Name,
+		Service:       b.key.svc,
+		Model:         b.key.model,
+		CooldownUntil: b.cooldownUntil(),
+		Reason:        reason,
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), sharedTimeout)
+		defer cancel()
+		if err := signals.Publish(ctx, s); err != nil {
+			lb.options.logger.Warn("breaker signal not published", "backend", s.Backend, "error", err)
+		}
+	}()
+}
+
+// adopt holds the breaker open until the cooldown of a peer's signal ends.
+func (b *trackedBreaker) adopt(s BreakerSignal) {
+	b.mu.Lock()
+	if !s.CooldownUntil.After(b.holdUntil) {
+		b.mu.Unlock()
+		return
+	}
+	b.holdUntil = s.CooldownUntil
+	if s.Reason != "" {
+		b.openedBy = s.Reason
+	}
+	b.adopting = true
+	b.mu.Unlock()
+	b.observe()
+	b.mu.Lock()
+	b.adopting = false
+	b.mu.Unlock()
+}
@@ -0,0 +1,102 @@
+package openailb
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker/v2"
+)
+
+// memorySignals is a BreakerSignals shared in memory, standing in for Redis.
+type memorySignals struct {
+	mu        sync.Mutex
+	handlers  []func(BreakerSignal)
+	published []BreakerSignal
+}
+
+func (m *memorySignals) Publish(ctx context.Context, signal BreakerSignal) error {
+	m.mu.Lock()
+	m.published = append(m.published, signal)
+	handlers := m.handlers
+	m.mu.Unlock()
+	for _, handle := range handlers {
+		handle(signal)
+	}
+	return nil
+}
+
+func (m *memorySignals) Subscribe(ctx context.Context, handle func(BreakerSignal)) error {
+	m.mu.Lock()
+	m.handlers = append(m.handlers, handle)
+	m.mu.Unlock()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestBreakerSignals(t *testing.T) {
+	t.Parallel()
+
+	primary, spare := newNamedServer(t, "primary"), newNamedServer(t, "spare")
+	defer primary.Close()
+	defer spare.Close()
+	configs := []OpenaiClientConfig{
+		{Name: "primary", APIKey: "k1", BaseURL: primary.URL, Weight: 100},
+		{Name: "spare", APIKey: "k2", BaseURL: spare.URL},
+	}
+	signals := &memorySignals{}
+	opened := make(chan HealthEvent, len(serviceTypes))
+	first := NewClient(configs, WithBreakerSignals(signals), WithOnHealthChange(func(ev HealthEvent) {
+		if ev.Kind == HealthBreakerStateChange && ev.To == "open" {
+			opened <- ev
+		}
+	}))
+	second := NewClient(configs, WithBreakerSignals(signals))
+	defer second.Close(context.Background())
+	for {
+		signals.mu.Lock()
+		subscribed := len(signals.handlers)
+		signals.mu.Unlock()
+		if subscribed == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := first.TripBreakers("primary", "provider incident"); err != nil {
+		t.Fatal(err)
+	}
+	backend, _ := second.lb.clientByName("primary")
+	deadline := time.Now().Add(time.Second)
+	for backend.Breaker(ServiceChat).State() != gobreaker.StateOpen {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the peer's opening to open the breaker")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if hits := countHits(t, second, 2); hits["spare"] != 2 {
+		t.Errorf("Expected the traffic to go to the spare backend, got %v", hits)
+	}
+
+	signals.mu.Lock()
+	published := slices.Clone(signals.published)
+	signals.mu.Unlock()
+	if want := len(serviceTypes); len(published) != want {
+		t.Errorf("Expected only the tripped client to publish its %d breakers, got %d signals", want, len(published))
+	}
+	// The reason given, not the backend's last error, goes out everywhere.
+	for _, signal := range published {
+		if signal.Reason != "provider incident" {
+			t.Errorf("Expected the signal to carry the reason, got %+v", signal)
+		}
+	}
+	if ev := <-opened; ev.Reason != "provider incident" {
+		t.Errorf("Expected the health event to carry the reason, got %+v", ev)
+	}
+
+	if err := first.Close(context.Background()); err != nil {
+		t.Errorf("Expected Close to end the subscription, got %v", err)
+	}
+}
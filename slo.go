@@ -0,0 +1,76 @@
+package openailb
+
+import "time"
+
+// SLO is a success-rate objective tracked per backend (WithSLO).
+type SLO struct {
+	// Target is the success rate to hold, e.g. 0.995 for 99.5%.
+	Target float64
+	// Window is the rolling period the success rate is measured over, e.g. time.Hour.
+	Window time.Duration
+	// ExhaustedWeight, if set, scales the weight of backends that have burned
+	// their whole error budget, e.g. 0.25 to send them a quarter of their share.
+	ExhaustedWeight float64
+}
+
+// ErrorBudget is a backend's standing against the SLO over its window.
+type ErrorBudget struct {
+	Target      float64       `json:"target"`
+	Window      time.Duration `json:"window"`
+	Requests    int64         `json:"requests"`
+	Failures    int64         `json:"failures"`
+	SuccessRate float64       `json:"success_rate"`
+	// Remaining is the share of the error budget left: 1 when nothing failed,
+	// 0 once failures reach (1-Target) of requests, negative past that.
+	Remaining float64 `json:"remaining"`
+}
+
+// Exhausted reports whether the backend has burned its whole error budget.
+func (b ErrorBudget) Exhausted() bool {
+	return b.Remaining <= 0
+}
+
+// errorBudget computes the backend's current error budget.
+func (c *SafeClient) errorBudget(now time.Time) ErrorBudget {
+	slo := c.lb.options.slo
+	requests, failures := c.sloCounter.totals(now)
+	budget := ErrorBudget{
+		Target:      slo.Target,
+		Window:      slo.Window,
+		Requests:    requests,
+		Failures:    failures,
+		SuccessRate: 1,
+		Remaining:   1,
+	}
+	if requests > 0 {
+		budget.SuccessRate = 1 - float64(failures)/float64(requests)
+		if allowed := float64(requests) * (1 - slo.Target); allowed > 0 {
+			budget.Remaining = 1 - float64(failures)/allowed
+		} else if failures > 0 {
+			budget.Remaining = 0
+		}
+	}
+	return budget
+}
+
+// sloFactor scales the backend's weight down once its budget is exhausted.
+func (c *SafeClient) sloFactor() float64 {
+	slo := c.lb.options.slo
+	if slo == nil || slo.ExhaustedWeight <= 0 || !c.errorBudget(time.Now()).Exhausted() {
+		return 1
+	}
+	return slo.ExhaustedWeight
+}
+
+// ErrorBudgets returns every backend's error budget by name. It is empty unless WithSLO is set.
+func (c Client) ErrorBudgets() map[string]ErrorBudget {
+	budgets := make(map[string]ErrorBudget)
+	if c.lb.options.slo == nil {
+		return budgets
+	}
+	now := time.Now()
+	for _, backend := range c.lb.backends() {
+		budgets[backend.Name] = backend.errorBudget(now)
+	}
+	return budgets
+}
@@ -0,0 +1,46 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestErrorBudget(t *testing.T) {
+	t.Parallel()
+
+	good := newNamedServer(t, "good")
+	defer good.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer bad.Close()
+
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: good.URL},
+		{APIKey: "k2", BaseURL: bad.URL},
+	}, WithCBSettings(tripAfter(1000)), WithSLO(SLO{Target: 0.9, Window: time.Minute, ExhaustedWeight: 0.01}))
+
+	for i := 0; i < 4; i++ {
+		_, _ = client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+	}
+
+	// The bad backend's first failure burns its budget, so it already gets less traffic.
+	budgets := client.ErrorBudgets()
+	if b := budgets["Client-0"]; b.Requests != 3 || b.Remaining != 1 || b.Exhausted() {
+		t.Errorf("Unexpected budget for the good backend: %+v", b)
+	}
+	if b := budgets["Client-1"]; b.Failures != 1 || b.SuccessRate != 0 || !b.Exhausted() {
+		t.Errorf("Unexpected budget for the bad backend: %+v", b)
+	}
+
+	// The exhausted backend is down-weighted to a trickle.
+	hits := countHits(t, client, 10)
+	if hits["good"] != 10 {
+		t.Errorf("Expected traffic to avoid the exhausted backend, got %v", hits)
+	}
+}
@@ -0,0 +1,250 @@
+package openailb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sony/gobreaker/v2"
+)
+
+// stateStoreTimeout bounds each load and save done on behalf of the LB.
+const stateStoreTimeout = 5 * time.Second
+
+// StateStore persists breaker state across restarts, so a deploy doesn't
+// instantly resume hammering a provider that is already known to be down.
+type StateStore interface {
+	// Load returns the last saved snapshot, or (nil, nil) if there is none.
+	Load(ctx context.Context) (*StateSnapshot, error)
+	Save(ctx context.Context, snapshot *StateSnapshot) error
+}
+
+// StateSnapshot is the persisted state of every breaker in the pool.
+type StateSnapshot struct {
+	SavedAt  time.Time         `json:"saved_at"`
+	Breakers []BreakerSnapshot `json:"breakers"`
+}
+
+// BreakerSnapshot is the persisted state of one breaker.
+type BreakerSnapshot struct {
+	Name                 string    `json:"name"` // e.g. "Client-0/chat"
+	State                string    `json:"state"`
+	OpenedAt             time.Time `json:"opened_at,omitempty"`
+	OpenedBy             string    `json:"opened_by,omitempty"`      // The backend's last error when the breaker opened.
+	CooldownUntil        time.Time `json:"cooldown_until,omitempty"` // When an open breaker lets a probe through.
+	Requests             uint32    `json:"requests"`
+	TotalSuccesses       uint32    `json:"total_successes"`
+	TotalFailures        uint32    `json:"total_failures"`
+	ConsecutiveSuccesses uint32    `json:"consecutive_successes"`
+	ConsecutiveFailures  uint32    `json:"consecutive_failures"`
+}
+
+// snapshot captures the breaker's current state.
+func (b *trackedBreaker) snapshot() BreakerSnapshot {
+	state := b.State()
+	cooldown := b.cooldownUntil()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	snap := BreakerSnapshot{
+		Name:                 b.name,
+		State:                state.String(),
+		CooldownUntil:        cooldown,
+		Requests:             b.counts.Requests,
+		TotalSuccesses:       b.counts.TotalSuccesses,
+		TotalFailures:        b.counts.TotalFailures,
+		ConsecutiveSuccesses: b.counts.ConsecutiveSuccesses,
+		ConsecutiveFailures:  b.counts.ConsecutiveFailures,
+	}
+	if state == gobreaker.StateOpen {
+		snap.OpenedAt = b.openedAt
+		snap.OpenedBy = b.openedBy
+	}
+	return snap
+}
+
+// restore applies a persisted snapshot to a freshly created breaker: an
+// unexpired cooldown keeps the breaker open until it ends, and the counters
+// carry over. The wrapped breaker itself starts closed once the cooldown ends.
+func (b *trackedBreaker) restore(snap BreakerSnapshot) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.counts = gobreaker.Counts{
+		Requests:             snap.Requests,
+		TotalSuccesses:       snap.TotalSuccesses,
+		TotalFailures:        snap.TotalFailures,
+		ConsecutiveSuccesses: snap.ConsecutiveSuccesses,
+		ConsecutiveFailures:  snap.ConsecutiveFailures,
+	}
+	if snap.State == gobreaker.StateOpen.String() && time.Now().Before(snap.CooldownUntil) {
+		b.state = gobreaker.StateOpen
+		b.openedAt = snap.OpenedAt
+		b.openedBy = snap.OpenedBy
+		b.holdUntil = snap.CooldownUntil
+	}
+}
+
+// restoreState loads the persisted snapshot, if a StateStore is configured.
+// Breakers are created lazily, so the snapshot is applied as each one is created;
+// breakers that were open are created right away so routing skips them.
+func (lb *LoadBalancer) restoreState() {
+	if lb.options.stateStore == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), stateStoreTimeout)
+	defer cancel()
+	snapshot, err := lb.options.stateStore.Load(ctx)
+	if err != nil || snapshot == nil {
+		// A missing or unreadable snapshot just means starting from scratch.
+		return
+	}
+
+	lb.restored = make(map[string]BreakerSnapshot, len(snapshot.Breakers))
+	for _, snap := range snapshot.Breakers {
+		lb.restored[snap.Name] = snap
+	}
+	for _, c := range lb.backends() {
+		for _, svc := range serviceTypes {
+			c.Breaker(svc)
+		}
+	}
+}
+
+// serviceTypes lists every service a backend can have a breaker for.
+var serviceTypes = []ServiceType{ServiceChat, ServiceEmbeddings, ServiceAudio, ServiceImages, ServiceResponses, ServiceUploads}
+
+// snapshotState captures the state of every breaker created so far.
+func (lb *LoadBalancer) snapshotState() *StateSnapshot {
+	snapshot := &StateSnapshot{SavedAt: time.Now()}
+	for _, c := range lb.backends() {
+		for _, b := range c.breakerList() {
+			snapshot.Breakers = append(snapshot.Breakers, b.snapshot())
+		}
+	}
+	return snapshot
+}
+
+// saveState writes the current snapshot to the StateStore.
+func (lb *LoadBalancer) saveState(ctx context.Context) error {
+	if lb.options.stateStore == nil {
+		return errors.New("no state store configured")
+	}
+	lb.saveMu.Lock()
+	defer lb.saveMu.Unlock()
+	return lb.options.stateStore.Save(ctx, lb.snapshotState())
+}
+
+// onBreakerStateChange is called whenever a breaker changes state.
+func (lb *LoadBalancer) onBreakerStateChange(b *trackedBreaker, from, to gobreaker.State) {
+	var reason string
+	var adopted bool
+	if to == gobreaker.StateOpen {
+		b.mu.Lock()
+		reason, adopted = b.openedBy, b.adopting
+		b.mu.Unlock()
+	}
+	b.owner.emitHealth(HealthEvent{Kind: HealthBreakerStateChange, Breaker: b.name, From: from.String(), To: to.String(), Reason: reason})
+	ev := Event{Kind: EventBreakerStateChange, Backend: b.owner.Name, Breaker: b.name, From: from.String(), To: to.String()}
+	lb.events.publish(ev)
+	if to == gobreaker.StateOpen {
+		ev.Kind, ev.Until = EventCooldownStarted, b.cooldownUntil()
+		lb.events.publish(ev)
+		if !adopted {
+			lb.publishSignal(b, reason)
+		}
+	}
+	if sink := lb.options.metrics; sink != nil {
+		sink.BreakerStateChanged(b.owner.Name, b.name, from, to)
+	}
+	if lb.options.stateStore != nil {
+		// Persist transitions right away, coalescing those of a flapping
+		// backend; a failed save is retried on the next one.
+		select {
+		case lb.stateDirty <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// persistState saves the state whenever a transition marks it dirty, one
+// save at a time, until the client is closed, if a StateStore is configured.
+// Close saves the final state itself.
+func (lb *LoadBalancer) persistState() {
+	if lb.options.stateStore == nil {
+		return
+	}
+	lb.background.Add(1)
+	go func() {
+		defer lb.background.Done()
+		for {
+			select {
+			case <-lb.stateDirty:
+			case <-lb.done.Done():
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), stateStoreTimeout)
+			err := lb.saveState(ctx)
+			cancel()
+			if err != nil {
+				lb.options.logger.Warn("saving breaker state failed", "error", err)
+			}
+		}
+	}()
+}
+
+// SaveState writes the current breaker state to the configured StateStore.
+// Transitions are saved automatically; call this on shutdown to also persist
+// the latest counters.
+func (c Client) SaveState(ctx context.Context) error {
+	return c.lb.saveState(ctx)
+}
+
+// FileStateStore is a StateStore that keeps the snapshot in a JSON file.
+type FileStateStore struct {
+	Path string
+}
+
+// NewFileStateStore returns a StateStore backed by the JSON file at path.
+func NewFileStateStore(path string) *FileStateStore {
+	return &FileStateStore{Path: path}
+}
+
+func (s *FileStateStore) Load(ctx context.Context) (*StateSnapshot, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshot StateSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// Save writes the snapshot to a temporary file and renames it into place, so
+// a crash mid-write never leaves a truncated snapshot behind.
+func (s *FileStateStore) Save(ctx context.Context, snapshot *StateSnapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
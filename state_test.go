@@ -0,0 +1,103 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+	"github.com/sony/gobreaker/v2"
+)
+
+func TestStateStoreRestoresOpenBreakers(t *testing.T) {
+	t.Parallel()
+
+	failServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failServer.Close()
+
+	store := NewFileStateStore(filepath.Join(t.TempDir(), "state.json"))
+	configs := []OpenaiClientConfig{{APIKey: "fail-key", BaseURL: failServer.URL}}
+
+	client := NewClient(configs, WithCBSettings(tripAfter(1)), WithStateStore(store))
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0)); err == nil {
+		t.Fatal("Expected the request to fail")
+	}
+	if err := client.SaveState(context.Background()); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+
+	// A restarted client must remember the backend is down until its cooldown ends.
+	restarted := NewClient(configs, WithCBSettings(tripAfter(1)), WithStateStore(store))
+	backend := restarted.Chat.Completions.lb.backends()[0]
+	if state := backend.Breaker(ServiceChat).State(); state != gobreaker.StateOpen {
+		t.Fatalf("Expected restored breaker to be open, got %s", state)
+	}
+	if state := backend.Breaker(ServiceEmbeddings).State(); state != gobreaker.StateClosed {
+		t.Errorf("Expected untouched breaker to stay closed, got %s", state)
+	}
+	if _, err := restarted.Chat.Completions.New(context.Background(), chatParams("m")); err == nil {
+		t.Error("Expected the restored open breaker to reject requests")
+	}
+}
+
+// slowStore is a StateStore whose saves take a while, counting how many
+// overlap and whether any ends after closed is set.
+type slowStore struct {
+	mu                   sync.Mutex
+	saving, most, saves  int
+	closed, savedAfterIt bool
+}
+
+func (s *slowStore) Load(context.Context) (*StateSnapshot, error) { return nil, nil }
+
+func (s *slowStore) Save(context.Context, *StateSnapshot) error {
+	s.mu.Lock()
+	s.saving++
+	s.saves++
+	s.most = max(s.most, s.saving)
+	s.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saving--
+	s.savedAfterIt = s.savedAfterIt || s.closed
+	return nil
+}
+
+func TestStateStoreCoalescesSaves(t *testing.T) {
+	t.Parallel()
+
+	store := &slowStore{}
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1"}}, WithStateStore(store))
+	// A flapping backend: every transition asks for a save.
+	for i := 0; i < 20; i++ {
+		_ = client.TripBreakers("Client-0", "flapping")
+		_ = client.ResetBreakers("Client-0")
+	}
+	if err := client.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	store.mu.Lock()
+	store.closed = true
+	saves, most := store.saves, store.most
+	store.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+
+	if most != 1 {
+		t.Errorf("Expected one save at a time, got %d at once", most)
+	}
+	if saves >= 20*len(serviceTypes) {
+		t.Errorf("Expected the transitions' saves to be coalesced, got %d saves", saves)
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.savedAfterIt {
+		t.Error("Expected no save after Close returned")
+	}
+}
@@ -0,0 +1,153 @@
+package openailb
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StatsWindow is the recent window over which BackendStats computes rates and
+// latency percentiles.
+const StatsWindow = time.Minute
+
+// BackendStats are the runtime figures of one backend, as returned by
+// Client.Stats, for dashboards and autoscalers to poll.
+type BackendStats struct {
+	// Totals since the client was created. Failures count every attempt that
+	// didn't end with ClassOK, including client errors.
+	Requests         int64 `json:"requests"`
+	Failures         int64 `json:"failures"`
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+
+	// Figures over the last StatsWindow.
+	RPS       float64       `json:"rps"`
+	ErrorRate float64       `json:"error_rate"`
+	P50       time.Duration `json:"p50"`
+	P95       time.Duration `json:"p95"`
+	P99       time.Duration `json:"p99"`
+
+	InFlight            int64 `json:"in_flight"`
+	ConsecutiveFailures int64 `json:"consecutive_failures"`
+	// MaxInFlight is the backend's concurrency limit, as configured or set by
+	// WithAdaptiveConcurrency; 0 if it has none.
+	MaxInFlight int64 `json:"max_in_flight,omitempty"`
+
+	// Cost is the total cost of the backend's tokens, at the prices set with
+	// WithPricing and OpenaiClientConfig.Prices. Models breaks the usage down
+	// by the model sent to the backend, Tags by the callers' WithTag.
+	Cost   float64          `json:"cost"`
+	Models map[string]Usage `json:"models"`
+	Tags   map[string]Usage `json:"tags"`
+
+	// TPMRemaining is how many tokens the backend's OpenaiClientConfig.TPM
+	// budget has left, if it has one.
+	TPMRemaining *int64 `json:"tpm_remaining,omitempty"`
+
+	// RateLimits are the quotas the backend last announced, if it ever did.
+	RateLimits *RateLimits `json:"rate_limits,omitempty"`
+
+	// The backend's most recent failure, as in BackendHealth.
+	LastError      string     `json:"last_error,omitempty"`
+	LastErrorClass ErrorClass `json:"last_error_class,omitempty"`
+	LastErrorAt    time.Time  `json:"last_error_at,omitempty"`
+}
+
+// backendStats accumulates a backend's BackendStats.
+type backendStats struct {
+	inFlight  atomic.Int64
+	recent    *rollingCounter
+	latencies *rollingHistogram
+
+	mu                  sync.Mutex
+	requests            int64
+	failures            int64
+	promptTokens        int64
+	completionTokens    int64
+	consecutiveFailures int64
+}
+
+func (s *backendStats) started() {
+	s.inFlight.Add(1)
+}
+
+// finished records an attempt passed to started.
+func (s *backendStats) finished(now time.Time, r ResponseInfo) {
+	s.inFlight.Add(-1)
+	failed := r.Class != ClassOK
+	s.recent.observe(now, failed, r.Latency)
+	s.latencies.observe(now, r.Latency)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	s.promptTokens += r.PromptTokens
+	s.completionTokens += r.CompletionTokens
+	if failed {
+		s.failures++
+		s.consecutiveFailures++
+	} else {
+		s.consecutiveFailures = 0
+	}
+}
+
+// totals returns the requests and failures since the client was created.
+func (s *backendStats) totals() (requests, failures int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests, s.failures
+}
+
+func (s *backendStats) snapshot(now time.Time) BackendStats {
+	s.mu.Lock()
+	stats := BackendStats{
+		Requests:            s.requests,
+		Failures:            s.failures,
+		PromptTokens:        s.promptTokens,
+		CompletionTokens:    s.completionTokens,
+		ConsecutiveFailures: s.consecutiveFailures,
+	}
+	s.mu.Unlock()
+	stats.InFlight = s.inFlight.Load()
+
+	requests, failures := s.recent.totals(now)
+	stats.RPS = float64(requests) / StatsWindow.Seconds()
+	if requests > 0 {
+		stats.ErrorRate = float64(failures) / float64(requests)
+	}
+
+	h := s.latencies.window(now)
+	stats.P50, stats.P95, stats.P99 = h.quantile(50), h.quantile(95), h.quantile(99)
+	return stats
+}
+
+// LatencyPercentile returns the p-th percentile (0-100) of the latency of the
+// backend called name over the last StatsWindow, or 0 without traffic.
+// Percentiles are accurate to within about 2%.
+func (c Client) LatencyPercentile(name string, p float64) (time.Duration, error) {
+	backend, err := c.lb.clientByName(name)
+	if err != nil {
+		return 0, err
+	}
+	return backend.stats.latencies.window(time.Now()).quantile(p), nil
+}
+
+// Stats returns the runtime figures of every backend, by name.
+func (c Client) Stats() map[string]BackendStats {
+	now := time.Now()
+	stats := make(map[string]BackendStats, len(c.lb.backends()))
+	for _, backend := range c.lb.backends() {
+		s := backend.stats.snapshot(now)
+		var total Usage
+		total, s.Models, s.Tags = backend.usage.snapshot()
+		s.Cost = total.Cost
+		s.TPMRemaining = backend.tpm.remaining(now)
+		s.MaxInFlight = backend.maxInFlight.Load()
+		s.LastError, s.LastErrorClass, s.LastErrorAt = backend.lastErr()
+		backend.mu.Lock()
+		s.RateLimits = backend.rateLimits
+		backend.mu.Unlock()
+		stats[backend.Name] = s
+	}
+	return stats
+}
@@ -0,0 +1,56 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	okServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "ok"}}], "usage": {"prompt_tokens": 3, "completion_tokens": 5}}`))
+	}))
+	defer okServer.Close()
+	failServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failServer.Close()
+
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: okServer.URL},
+		{APIKey: "k2", BaseURL: failServer.URL},
+	}, WithCBSettings(tripAfter(100)))
+	for i := 0; i < 4; i++ {
+		_, _ = client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+	}
+
+	stats := client.Stats()
+	ok, failing := stats["Client-0"], stats["Client-1"]
+	if ok.Requests != 2 || ok.Failures != 0 || ok.ErrorRate != 0 {
+		t.Errorf("Unexpected stats for the healthy backend: %+v", ok)
+	}
+	if ok.PromptTokens != 6 || ok.CompletionTokens != 10 {
+		t.Errorf("Expected 6 prompt and 10 completion tokens, got %d and %d", ok.PromptTokens, ok.CompletionTokens)
+	}
+	if ok.P50 <= 0 || ok.P99 < ok.P50 || ok.RPS <= 0 {
+		t.Errorf("Expected recent latency percentiles and rate, got %+v", ok)
+	}
+	if failing.Requests != 2 || failing.Failures != 2 || failing.ConsecutiveFailures != 2 || failing.ErrorRate != 1 {
+		t.Errorf("Unexpected stats for the failing backend: %+v", failing)
+	}
+	if p50, err := client.LatencyPercentile("Client-0", 50); err != nil || p50 != ok.P50 {
+		t.Errorf("Expected LatencyPercentile to match the stats' p50 %s, got %s (%v)", ok.P50, p50, err)
+	}
+	if _, err := client.LatencyPercentile("nope", 50); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+	if ok.InFlight != 0 || failing.InFlight != 0 {
+		t.Errorf("Expected no requests in flight, got %d and %d", ok.InFlight, failing.InFlight)
+	}
+}
@@ -0,0 +1,146 @@
+// Package statsdlb exports openailb metrics to StatsD, with DogStatsD tags as
+// understood by the Datadog agent and Telegraf.
+//
+//	sink, err := statsdlb.NewSink("127.0.0.1:8125", "openailb")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer sink.Close()
+//	client := openailb.NewClient(configs, openailb.WithMetrics(sink))
+package statsdlb
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	openailb "github.com/hi2code/openai-go-lb"
+	"github.com/sony/gobreaker/v2"
+)
+
+// Sink is an openailb.MetricsSink that sends every measurement as a StatsD
+// datagram. Sends are fire-and-forget: a missing agent never slows requests.
+type Sink struct {
+	conn   net.Conn
+	prefix string
+
+	inFlight sync.Map // backend and service -> *atomic.Int64
+}
+
+// NewSink sends the metrics over UDP to the StatsD agent at addr, with names
+// starting with prefix, e.g. "openailb".
+func NewSink(addr, prefix string) (*Sink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, ".") {
+		prefix += "."
+	}
+	return &Sink{conn: conn, prefix: prefix}, nil
+}
+
+// Close closes the connection to the agent.
+func (s *Sink) Close() error {
+	return s.conn.Close()
+}
+
+// RequestStarted implements openailb.MetricsSink.
+func (s *Sink) RequestStarted(backend string, svc openailb.ServiceType, model string) {
+	n := s.inFlightOf(backend, svc).Add(1)
+	s.send("requests.in_flight", strconv.FormatInt(n, 10), "g", "backend", backend, "service", string(svc))
+}
+
+// RequestDone implements openailb.MetricsSink.
+func (s *Sink) RequestDone(m openailb.RequestMetrics) {
+	n := s.inFlightOf(m.Backend, m.Service).Add(-1)
+	s.send("requests.in_flight", strconv.FormatInt(n, 10), "g", "backend", m.Backend, "service", string(m.Service))
+
+	tags := []string{"backend", m.Backend, "service", string(m.Service), "model", m.Model}
+	// The backend's labels tag its requests too, sorted for stable datagrams.
+	keys := make([]string, 0, len(m.Labels))
+	for k := range m.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		tags = append(tags, k, m.Labels[k])
+	}
+	s.send("requests", "1", "c", append(tags, "class", string(m.Class))...)
+	if m.Class != openailb.ClassOK {
+		s.send("errors", "1", "c", append(tags, "class", string(m.Class))...)
+	}
+	s.send("request.duration", millis(m.Duration), "ms", tags...)
+	if m.TTFT > 0 {
+		s.send("time_to_first_token", millis(m.TTFT), "ms", "backend", m.Backend, "model", m.Model)
+	}
+	if m.PromptTokens > 0 {
+		s.send("tokens", strconv.FormatInt(m.PromptTokens, 10), "c", "backend", m.Backend, "model", m.Model, "type", "prompt")
+	}
+	if m.CompletionTokens > 0 {
+		s.send("tokens", strconv.FormatInt(m.CompletionTokens, 10), "c", "backend", m.Backend, "model", m.Model, "type", "completion")
+	}
+}
+
+// BreakerStateChanged implements openailb.MetricsSink. The state is sent as a
+// gauge: 0 closed, 1 half-open, 2 open.
+func (s *Sink) BreakerStateChanged(backend, breaker string, from, to gobreaker.State) {
+	var v string
+	switch to {
+	case gobreaker.StateClosed:
+		v = "0"
+	case gobreaker.StateHalfOpen:
+		v = "1"
+	case gobreaker.StateOpen:
+		v = "2"
+	}
+	s.send("breaker.state", v, "g", "backend", backend, "breaker", breaker)
+	s.send("breaker.transitions", "1", "c", "backend", backend, "breaker", breaker, "from", from.String(), "to", to.String())
+}
+
+// CacheLookup implements openailb.CacheMetricsSink.
+func (s *Sink) CacheLookup(svc openailb.ServiceType, model string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	s.send("cache.lookups", "1", "c", "service", string(svc), "model", model, "result", result)
+}
+
+func (s *Sink) inFlightOf(backend string, svc openailb.ServiceType) *atomic.Int64 {
+	key := backend + "\x00" + string(svc)
+	if n, ok := s.inFlight.Load(key); ok {
+		return n.(*atomic.Int64)
+	}
+	n, _ := s.inFlight.LoadOrStore(key, new(atomic.Int64))
+	return n.(*atomic.Int64)
+}
+
+// tagReplacer drops the characters that would break a DogStatsD datagram.
+var tagReplacer = strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", "_")
+
+// send writes one datagram; kv are the tags, as name, value pairs.
+func (s *Sink) send(name, value, kind string, kv ...string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s%s:%s|%s", s.prefix, name, value, kind)
+	for i := 0; i+1 < len(kv); i += 2 {
+		if i == 0 {
+			b.WriteString("|#")
+		} else {
+			b.WriteByte(',')
+		}
+		b.WriteString(kv[i])
+		b.WriteByte(':')
+		b.WriteString(tagReplacer.Replace(kv[i+1]))
+	}
+	_, _ = s.conn.Write([]byte(b.String()))
+}
+
+func millis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+}
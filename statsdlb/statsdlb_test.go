@@ -0,0 +1,68 @@
+package statsdlb
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	openailb "github.com/hi2code/openai-go-lb"
+	"github.com/openai/openai-go/v3"
+)
+
+func TestStatsDMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "Hello"}}], "usage": {"prompt_tokens": 5, "completion_tokens": 2}}`))
+	}))
+	defer server.Close()
+
+	agent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer agent.Close()
+	sink, err := NewSink(agent.LocalAddr().String(), "openailb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	client := openailb.NewClient([]openailb.OpenaiClientConfig{{
+		APIKey: "k1", BaseURL: server.URL, Labels: map[string]string{"provider": "azure", "env": "prod"},
+	}}, openailb.WithMetrics(sink))
+	_, err = client.Chat.Completions.New(context.Background(), openai.ChatCompletionNewParams{
+		Model:    "gpt-4o",
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("test")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	buf := make([]byte, 1024)
+	_ = agent.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for len(got) < 6 {
+		n, _, err := agent.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("Got only %d datagrams: %v", len(got), err)
+		}
+		got = append(got, string(buf[:n]))
+	}
+	all := strings.Join(got, "\n")
+	for _, want := range []string{
+		"openailb.requests.in_flight:1|g|#backend:Client-0,service:chat",
+		"openailb.requests.in_flight:0|g|#backend:Client-0,service:chat",
+		"openailb.requests:1|c|#backend:Client-0,service:chat,model:gpt-4o,env:prod,provider:azure,class:ok",
+		"openailb.request.duration:",
+		"openailb.tokens:5|c|#backend:Client-0,model:gpt-4o,type:prompt",
+		"openailb.tokens:2|c|#backend:Client-0,model:gpt-4o,type:completion",
+	} {
+		if !strings.Contains(all, want) {
+			t.Errorf("Missing %s in:\n%s", want, all)
+		}
+	}
+}
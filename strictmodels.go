@@ -0,0 +1,34 @@
+package openailb
+
+import "fmt"
+
+// UnknownModelError is returned by WithStrictModels, without sending the
+// request anywhere, for a model no backend that could take it maps.
+type UnknownModelError struct {
+	Model string
+}
+
+func (e *UnknownModelError) Error() string {
+	return fmt.Sprintf("model %q is not in the ModelMap of any backend nor a model alias", e.Model)
+}
+
+// WithStrictModels rejects the requests for a model that no backend maps,
+// in its ModelMap ("*" included) or as a WithModelAlias, with an
+// *UnknownModelError, instead of passing the name through to every backend
+// to fail there in as many ways. Requests routed to a WithPools pool by its
+// Models are known to it and pass, as do those without a model, like file
+// uploads.
+func WithStrictModels() LBOption {
+	return func(o *lbOptions) {
+		o.strictModels = true
+	}
+}
+
+// knows reports whether the backend maps model, by alias or ModelMap.
+func (c *SafeClient) knows(model string) bool {
+	if _, ok := c.aliasTarget(model); ok {
+		return true
+	}
+	_, ok := c.models.lookup(model)
+	return ok
+}
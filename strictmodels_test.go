@@ -0,0 +1,40 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestStrictModels(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	server := newModelEchoServer(t)
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{
+		{Name: "openai", APIKey: "k1", BaseURL: server.URL, ModelMap: map[string]string{"gpt-4o": "gpt-4o-2024-08-06"}},
+		{Name: "vllm", APIKey: "k2", BaseURL: server.URL, ModelMap: map[string]string{"llama-*": "llama-3.1-70b"}},
+	}, WithStrictModels(), WithModelAlias("fast-chat", map[string]string{"vllm": "llama-3.1-8b"}),
+		WithHooks(Hooks{OnRequest: func(RequestInfo) { calls.Add(1) }}))
+
+	for _, model := range []string{"gpt-4o", "llama-3", "fast-chat"} {
+		if _, err := client.Chat.Completions.New(context.Background(), chatParams(model)); err != nil {
+			t.Errorf("Expected %q to be served, got %v", model, err)
+		}
+	}
+	calls.Store(0)
+	_, err := client.Chat.Completions.New(context.Background(), chatParams("gpt-4o-mini"))
+	var unknown *UnknownModelError
+	if !errors.As(err, &unknown) || unknown.Model != "gpt-4o-mini" {
+		t.Fatalf("Expected an UnknownModelError for gpt-4o-mini, got %v", err)
+	}
+	stream := client.Chat.Completions.NewStreaming(context.Background(), chatParams("gpt-4o-mini"))
+	if stream.Next() || !errors.As(stream.Err(), &unknown) {
+		t.Errorf("Expected a stream failing with an UnknownModelError, got %v", stream.Err())
+	}
+	if calls.Load() != 0 {
+		t.Errorf("Expected nothing to be sent, got %d requests", calls.Load())
+	}
+}
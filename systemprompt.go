@@ -0,0 +1,68 @@
+package openailb
+
+import (
+	"slices"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/param"
+)
+
+// WithSystemPrompt injects prompt into every chat completion, ahead of the
+// backends' own OpenaiClientConfig.SystemPrompt: prepended to the request's
+// first system (or developer) message, or sent as a new system message
+// before the others if it has none.
+func WithSystemPrompt(prompt string) LBOption {
+	return func(o *lbOptions) {
+		o.systemPrompt = prompt
+	}
+}
+
+// systemPrompt returns the prompt injected into the chat completions sent to
+// the backend.
+func (c *SafeClient) systemPrompt() string {
+	global, own := c.lb.options.systemPrompt, c.config.SystemPrompt
+	switch {
+	case global == "":
+		return own
+	case own == "":
+		return global
+	default:
+		return global + "\n\n" + own
+	}
+}
+
+// injectSystemPrompt returns messages with prompt prepended to the first
+// system or developer message, or in a new system message first. The
+// caller's messages are left alone.
+func injectSystemPrompt(messages []openai.ChatCompletionMessageParamUnion, prompt string) []openai.ChatCompletionMessageParamUnion {
+	if prompt == "" {
+		return messages
+	}
+	for i, m := range messages {
+		switch {
+		case m.OfSystem != nil:
+			system := *m.OfSystem
+			prependPrompt(&system.Content.OfString, &system.Content.OfArrayOfContentParts, prompt)
+			messages = slices.Clone(messages)
+			messages[i] = openai.ChatCompletionMessageParamUnion{OfSystem: &system}
+			return messages
+		case m.OfDeveloper != nil:
+			developer := *m.OfDeveloper
+			prependPrompt(&developer.Content.OfString, &developer.Content.OfArrayOfContentParts, prompt)
+			messages = slices.Clone(messages)
+			messages[i] = openai.ChatCompletionMessageParamUnion{OfDeveloper: &developer}
+			return messages
+		}
+	}
+	return slices.Insert(slices.Clone(messages), 0, openai.SystemMessage(prompt))
+}
+
+// prependPrompt prepends prompt to the content of a system or developer
+// message, text or parts, copying the parts.
+func prependPrompt(text *param.Opt[string], parts *[]openai.ChatCompletionContentPartTextParam, prompt string) {
+	if *parts != nil {
+		*parts = slices.Insert(slices.Clone(*parts), 0, openai.ChatCompletionContentPartTextParam{Text: prompt})
+		return
+	}
+	*text = openai.String(prompt + "\n\n" + text.Value)
+}
@@ -0,0 +1,51 @@
+package openailb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+)
+
+func TestSystemPrompt(t *testing.T) {
+	t.Parallel()
+
+	type message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	bodies := make(chan []message, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []message `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies <- body.Messages
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "ok"}}]}`))
+	}))
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL, SystemPrompt: "Answer in French."}}, WithSystemPrompt("Be safe."))
+
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m")); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-bodies; len(got) != 2 || got[0] != (message{"system", "Be safe.\n\nAnswer in French."}) {
+		t.Errorf("Expected a system message to be added first, got %v", got)
+	}
+
+	params := chatParams("m")
+	params.Messages = []openai.ChatCompletionMessageParamUnion{openai.SystemMessage("You are terse."), openai.UserMessage("test")}
+	if _, err := client.Chat.Completions.New(context.Background(), params); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-bodies; len(got) != 2 || got[0] != (message{"system", "Be safe.\n\nAnswer in French.\n\nYou are terse."}) {
+		t.Errorf("Expected the prompt to be prepended to the system message, got %v", got)
+	}
+	if got := params.Messages[0].OfSystem.Content.OfString.Value; got != "You are terse." {
+		t.Errorf("Expected the caller's messages to be left alone, got %q", got)
+	}
+}
@@ -0,0 +1,154 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTenantQuota is returned for the requests of a tenant over its
+// TenantQuota.
+var ErrTenantQuota = errors.New("tenant quota exceeded")
+
+type tenantKey struct{}
+
+// WithTenant marks the requests made with ctx as the tenant's, e.g. the
+// calling team, for WithTenantQuotas and the fair share of the
+// WithMaxConcurrency queue.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant set with WithTenant, or "".
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// TenantQuota limits the requests of a tenant across all backends. Zero
+// fields are unlimited.
+type TenantQuota struct {
+	// MaxInFlight caps the tenant's requests in flight, streams included.
+	MaxInFlight int
+	// RPM caps the tenant's requests per minute.
+	RPM int
+	// TPM is the tenant's tokens-per-minute budget, charged like
+	// OpenaiClientConfig.TPM.
+	TPM int
+}
+
+// WithTenantQuotas enforces quotas on the tenants set with WithTenant:
+// quotas[tenant] if present, defaults otherwise. Requests over their
+// tenant's quota fail at once with ErrTenantQuota, so one noisy tenant
+// can't take the whole pool. Requests without a tenant have no quota.
+func WithTenantQuotas(defaults TenantQuota, quotas map[string]TenantQuota) LBOption {
+	return func(o *lbOptions) {
+		o.tenants = &tenants{defaults: defaults, quotas: quotas, byName: make(map[string]*tenant)}
+	}
+}
+
+// tenants holds the state of WithTenantQuotas, created on first use.
+type tenants struct {
+	defaults TenantQuota
+	quotas   map[string]TenantQuota
+
+	mu     sync.Mutex
+	byName map[string]*tenant
+}
+
+// tenant is the usage of one tenant against its quota.
+type tenant struct {
+	name        string
+	maxInFlight int64
+	active      atomic.Int64
+	rpm, tpm    *tokenBucket
+}
+
+// get returns the state of the named tenant.
+func (ts *tenants) get(name string) *tenant {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if t, ok := ts.byName[name]; ok {
+		return t
+	}
+	q, ok := ts.quotas[name]
+	if !ok {
+		q = ts.defaults
+	}
+	t := &tenant{name: name, maxInFlight: int64(q.MaxInFlight)}
+	if q.RPM > 0 {
+		t.rpm = newTokenBucket(q.RPM, max(q.RPM/60, 1))
+	}
+	if q.TPM > 0 {
+		t.tpm = newTokenBucket(q.TPM, q.TPM)
+	}
+	ts.byName[name] = t
+	return t
+}
+
+// budgeted reports whether any tenant may have a TPM budget.
+func (ts *tenants) budgeted() bool {
+	if ts == nil {
+		return false
+	}
+	if ts.defaults.TPM > 0 {
+		return true
+	}
+	for _, q := range ts.quotas {
+		if q.TPM > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// tenantOf returns the state of the tenant of ctx, or nil if it has none
+// or there are no quotas.
+func (lb *LoadBalancer) tenantOf(ctx context.Context) *tenant {
+	name := TenantFromContext(ctx)
+	if lb.options.tenants == nil || name == "" {
+		return nil
+	}
+	return lb.options.tenants.get(name)
+}
+
+// take charges a request of tokens to the tenant's quota, if it fits, and
+// returns the func handing back its in-flight slot.
+func (t *tenant) take(now time.Time, tokens int64) (func(), error) {
+	if t == nil {
+		return func() {}, nil
+	}
+	if t.maxInFlight > 0 && t.active.Add(1) > t.maxInFlight {
+		t.active.Add(-1)
+		return nil, backpressure(ErrTenantQuota, 0, fmt.Sprintf("%s has %d requests in flight", t.name, t.maxInFlight))
+	}
+	if !t.rpm.available(now, 1) {
+		t.release()
+		return nil, backpressure(ErrTenantQuota, t.rpm.wait(now, 1), t.name+" is over its RPM")
+	}
+	if !t.tpm.available(now, float64(tokens)) {
+		t.release()
+		return nil, backpressure(ErrTenantQuota, t.tpm.wait(now, float64(tokens)), t.name+" is over its TPM")
+	}
+	t.rpm.take(now, 1)
+	t.tpm.take(now, float64(tokens))
+	return sync.OnceFunc(t.release), nil
+}
+
+func (t *tenant) release() {
+	if t.maxInFlight > 0 {
+		t.active.Add(-1)
+	}
+}
+
+// refund hands back the rate of a request of tokens that wasn't sent.
+func (t *tenant) refund(now time.Time, tokens int64) {
+	if t == nil {
+		return
+	}
+	t.rpm.take(now, -1)
+	t.tpm.take(now, float64(-tokens))
+}
@@ -0,0 +1,138 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestTenantQuotas(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "ok"}}], "usage": {"prompt_tokens": 10, "completion_tokens": 90, "total_tokens": 100}}`))
+	}))
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL}},
+		WithTenantQuotas(TenantQuota{TPM: 250}, map[string]TenantQuota{"chat": {}}))
+
+	params := chatParams("m")
+	params.MaxTokens = openai.Int(100)
+	batch := WithTenant(context.Background(), "batch")
+	for i := 0; i < 2; i++ {
+		if _, err := client.Chat.Completions.New(batch, params); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Each request is settled to its 100 tokens, so the third doesn't fit.
+	if _, err := client.Chat.Completions.New(batch, params); !errors.Is(err, ErrTenantQuota) {
+		t.Errorf("Expected ErrTenantQuota over the tenant's TPM, got %v", err)
+	}
+	for _, ctx := range []context.Context{WithTenant(context.Background(), "chat"), context.Background()} {
+		for i := 0; i < 3; i++ {
+			if _, err := client.Chat.Completions.New(ctx, params); err != nil {
+				t.Errorf("Expected the other tenants to keep their own quota, got %v", err)
+			}
+		}
+	}
+}
+
+func TestTenantMaxInFlight(t *testing.T) {
+	t.Parallel()
+
+	arrived, release := make(chan struct{}, 4), make(chan struct{})
+	slow := newBlockingServer(arrived, release)
+	defer slow.Close()
+	defer close(release)
+
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: slow.URL}},
+		WithTenantQuotas(TenantQuota{MaxInFlight: 1}, nil))
+	batch := WithTenant(context.Background(), "batch")
+	errs := make(chan error, 1)
+	go func() {
+		_, err := client.Chat.Completions.New(batch, chatParams("m"))
+		errs <- err
+	}()
+	<-arrived
+	if _, err := client.Chat.Completions.New(batch, chatParams("m")); !errors.Is(err, ErrTenantQuota) {
+		t.Errorf("Expected ErrTenantQuota over the tenant's MaxInFlight, got %v", err)
+	}
+	release <- struct{}{}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_, err := client.Chat.Completions.New(batch, chatParams("m"))
+		errs <- err
+	}()
+	<-arrived
+	release <- struct{}{}
+	if err := <-errs; err != nil {
+		t.Errorf("Expected the tenant's slot to be handed back, got %v", err)
+	}
+}
+
+func TestTenantFairQueueing(t *testing.T) {
+	t.Parallel()
+
+	arrived, release := make(chan string, 4), make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- r.Header.Get("X-Tenant")
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "slow"}}]}`))
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: slow.URL}}, WithMaxConcurrency(1, 4, 0))
+	a := client.lb.options.admission
+	errs := make(chan error, 4)
+	send := func(tenant string, queued int) {
+		t.Helper()
+		go func() {
+			_, err := client.Chat.Completions.New(WithTenant(context.Background(), tenant), chatParams("m"), option.WithHeader("X-Tenant", tenant))
+			errs <- err
+		}()
+		deadline := time.Now().Add(time.Second)
+		for {
+			a.mu.Lock()
+			n := len(a.queue)
+			a.mu.Unlock()
+			if n == queued {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %d queued requests, got %d", queued, n)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	send("batch", 0)
+	<-arrived
+	send("batch", 1)
+	send("batch", 2)
+	send("chat", 3)
+	var got []string
+	for i := 0; i < 3; i++ {
+		release <- struct{}{}
+		got = append(got, <-arrived)
+	}
+	release <- struct{}{}
+	if got[0] != "batch" || got[1] != "chat" || got[2] != "batch" {
+		t.Errorf("Expected the tenants to take turns in the queue, got %v", got)
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package openailb
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// withRequestTimeout bounds ctx by the backend's RequestTimeout. The returned
+// cancel func is never nil.
+func (c *SafeClient) withRequestTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.requestTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.requestTimeout)
+}
+
+// releaseWith calls cancel once res is done with its context: right away,
+// or for a raw *http.Response once its body is closed.
+func releaseWith[T any](res T, cancel context.CancelFunc) T {
+	if r, ok := any(res).(*http.Response); ok && r != nil && r.Body != nil {
+		r.Body = &cancelOnClose{ReadCloser: r.Body, cancel: cancel}
+		return res
+	}
+	cancel()
+	return res
+}
+
+// cancelOnClose cancels a response's context when its body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,109 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	t.Parallel()
+
+	slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Reading the body lets the server notice the client hanging up.
+		_, _ = io.ReadAll(r.Body)
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer slowServer.Close()
+	okServer := newNamedServer(t, "ok")
+	defer okServer.Close()
+
+	// The slow backend's timeout trips its breaker, so the next request goes
+	// to the other one.
+	client := NewClient([]OpenaiClientConfig{
+		{Name: "slow", APIKey: "k1", BaseURL: slowServer.URL, RequestTimeout: 50 * time.Millisecond},
+		{Name: "fast", APIKey: "k2", BaseURL: okServer.URL, RequestTimeout: time.Second},
+	}, WithCBSettings(tripAfter(1)))
+	start := time.Now()
+	_, err := client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the slow backend to be given up on after 50ms, took %s", elapsed)
+	}
+	// The timeout is returned and names the backend.
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected a deadline error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "slow: no response within its RequestTimeout of 50ms") {
+		t.Errorf("Expected the error to name the backend's timeout, got %v", err)
+	}
+	resp, err := client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content := resp.Choices[0].Message.Content; content != "ok" {
+		t.Errorf("Expected the fast backend's answer, got %s", content)
+	}
+
+	// The caller's own deadline is returned as is.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	client = NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: slowServer.URL, RequestTimeout: time.Second},
+	})
+	_, err = client.Chat.Completions.New(ctx, chatParams("m"), option.WithMaxRetries(0))
+	if err == nil || strings.Contains(err.Error(), "RequestTimeout") {
+		t.Errorf("Expected the caller's deadline, got %v", err)
+	}
+}
+
+func TestRequestTimeoutBodies(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), `"stream":true`) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: {\"choices\": [{\"delta\": {\"content\": \"Hi\"}}]}\n\ndata: [DONE]\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "audio/mpeg")
+		_, _ = w.Write([]byte("audio"))
+	}))
+	defer server.Close()
+
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: server.URL, RequestTimeout: time.Second}})
+
+	// A raw response stays readable after the call returns.
+	resp, err := client.Audio.Speech.New(context.Background(), openai.AudioSpeechNewParams{Model: "tts", Input: "hi", Voice: "alloy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(audio) != "audio" {
+		t.Errorf("Expected the speech body to be readable, got %q, %v", audio, err)
+	}
+
+	stream := client.Chat.Completions.NewStreaming(context.Background(), chatParams("m"))
+	var content string
+	for stream.Next() {
+		if choices := stream.Current().Choices; len(choices) > 0 {
+			content += choices[0].Delta.Content
+		}
+	}
+	if err := stream.Err(); err != nil || content != "Hi" {
+		t.Errorf("Expected the stream to be read in full, got %q, %v", content, err)
+	}
+	stream.Close()
+}
@@ -0,0 +1,62 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/openai/openai-go/v3/option"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the LB's spans.
+const tracerName = "github.com/hi2code/openai-go-lb"
+
+// startSpan starts the span of one attempt, if tracing is enabled. The span is
+// nil otherwise.
+func (lb *LoadBalancer) startSpan(ctx context.Context, a attempt) (context.Context, trace.Span) {
+	if lb.options.tracer == nil {
+		return ctx, nil
+	}
+	return lb.options.tracer.Start(ctx, "openailb "+string(a.breaker.key.svc),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("openailb.backend", a.client.Name),
+			attribute.String("openailb.base_url", a.client.BaseURL),
+			attribute.String("gen_ai.request.model", a.model),
+			attribute.Int("openailb.attempt", a.number),
+			attribute.String("openailb.breaker.state", a.breaker.State().String()),
+		))
+}
+
+// endSpan records the attempt's outcome on span and ends it.
+func endSpan(span trace.Span, res any, err error, class ErrorClass) {
+	if span == nil {
+		return
+	}
+	if prompt, completion := usageOf(res); prompt > 0 || completion > 0 {
+		span.SetAttributes(
+			attribute.Int64("gen_ai.usage.input_tokens", prompt),
+			attribute.Int64("gen_ai.usage.output_tokens", completion),
+		)
+	}
+	if class != ClassOK {
+		span.SetAttributes(attribute.String("error.type", string(class)))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			span.SetStatus(codes.Error, string(class))
+		}
+	}
+	span.End()
+}
+
+// propagateTrace injects the request context's trace into the backend request's headers.
+func propagateTrace(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	otel.GetTextMapPropagator().Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+	return next(req)
+}
@@ -0,0 +1,87 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/openai/openai-go/v3/option"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var propagated atomic.Int32
+	check := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("traceparent") != "" {
+				propagated.Add(1)
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+	failServer := httptest.NewServer(check(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})))
+	defer failServer.Close()
+	okServer := httptest.NewServer(check(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "ok"}}], "usage": {"prompt_tokens": 5, "completion_tokens": 2}}`))
+	})))
+	defer okServer.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: failServer.URL},
+		{APIKey: "k2", BaseURL: okServer.URL},
+	}, WithTracerProvider(tp))
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "caller")
+	if _, err := client.Chat.Completions.New(ctx, chatParams("m"), option.WithMaxRetries(0)); err == nil {
+		t.Fatal("Expected the failing backend's error")
+	}
+	if _, err := client.Chat.Completions.New(ctx, chatParams("m"), option.WithMaxRetries(0)); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 2 request spans and the caller's, got %d", len(spans))
+	}
+	if propagated.Load() != 2 {
+		t.Errorf("Expected the trace context on both backend requests, got %d", propagated.Load())
+	}
+
+	failed, ok := spans[0], spans[1]
+	for _, span := range []tracetest.SpanStub{failed, ok} {
+		if span.Name != "openailb chat" || span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("Expected a child span of the caller, got %s with parent %s", span.Name, span.Parent.SpanID())
+		}
+	}
+	attrs := func(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+		m := make(map[attribute.Key]attribute.Value)
+		for _, kv := range span.Attributes {
+			m[kv.Key] = kv.Value
+		}
+		return m
+	}
+	if a := attrs(failed); a["openailb.backend"].AsString() != "Client-0" || a["openailb.attempt"].AsInt64() != 1 ||
+		a["error.type"].AsString() != string(ClassServer) || failed.Status.Code != codes.Error {
+		t.Errorf("Unexpected failed span: %v %v", a, failed.Status)
+	}
+	if a := attrs(ok); a["openailb.backend"].AsString() != "Client-1" || a["openailb.attempt"].AsInt64() != 1 ||
+		a["openailb.breaker.state"].AsString() != "closed" || a["gen_ai.request.model"].AsString() != "m" ||
+		a["gen_ai.usage.input_tokens"].AsInt64() != 5 || a["gen_ai.usage.output_tokens"].AsInt64() != 2 {
+		t.Errorf("Unexpected successful span: %v", a)
+	}
+}
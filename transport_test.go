@@ -0,0 +1,108 @@
+package openailb
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+func newTLSServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "tls"}}]}`))
+	}))
+}
+
+func TestBackendTLS(t *testing.T) {
+	t.Parallel()
+
+	server := newTLSServer(t)
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	for name, tc := range map[string]struct {
+		config OpenaiClientConfig
+		ok     bool
+	}{
+		"system roots":  {OpenaiClientConfig{}, false},
+		"private CA":    {OpenaiClientConfig{TLSConfig: &tls.Config{RootCAs: roots}}, true},
+		"skip verify":   {OpenaiClientConfig{TLSConfig: &tls.Config{InsecureSkipVerify: true}}, true},
+		"custom client": {OpenaiClientConfig{HTTPClient: server.Client()}, true},
+	} {
+		cfg := tc.config
+		cfg.APIKey, cfg.BaseURL = "k1", server.URL
+		client := NewClient([]OpenaiClientConfig{cfg})
+		_, err := client.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0))
+		if tc.ok && err != nil {
+			t.Errorf("%s: expected the request to succeed, got %v", name, err)
+		}
+		if !tc.ok && err == nil {
+			t.Errorf("%s: expected the certificate to be rejected", name)
+		}
+	}
+}
+
+func TestConfigTLSFiles(t *testing.T) {
+	t.Parallel()
+
+	server := newTLSServer(t)
+	defer server.Close()
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewClientFromConfig(&Config{Backends: []BackendConfig{
+		{APIKey: "k1", BaseURL: server.URL, TLS: &TLSConfig{CAFile: ca}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits := countHits(t, client, 1); hits["tls"] != 1 {
+		t.Errorf("Expected the CA file to be trusted, got %v", hits)
+	}
+
+	err = (&Config{Backends: []BackendConfig{
+		{APIKey: "k1", BaseURL: server.URL, TLS: &TLSConfig{CertFile: ca}, ProxyURL: "proxy:3128"},
+	}}).Validate()
+	for _, want := range []string{
+		"backends[0].tls: cert_file and key_file must be set together",
+		`backends[0].proxy_url: "proxy:3128" must be an absolute URL`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in the error, got %v", want, err)
+		}
+	}
+}
+
+func TestBackendProxy(t *testing.T) {
+	t.Parallel()
+
+	var proxied atomic.Value
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied.Store(r.Host)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "proxied"}}]}`))
+	}))
+	defer proxy.Close()
+
+	client := NewClient([]OpenaiClientConfig{{APIKey: "k1", BaseURL: "http://backend.invalid/v1/", ProxyURL: proxy.URL}})
+	if hits := countHits(t, client, 1); hits["proxied"] != 1 {
+		t.Errorf("Expected the request to go through the proxy, got %v", hits)
+	}
+	if host, _ := proxied.Load().(string); host != "backend.invalid" {
+		t.Errorf("Expected the proxy to be asked for backend.invalid, got %q", host)
+	}
+}
@@ -0,0 +1,86 @@
+package openailb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// LBUploadsService mimics openai.UploadService.
+//
+// An upload and all of its parts must live on the same backend, so the backend
+// chosen by New is pinned for every later Parts.New, Complete and Cancel call
+// on that upload ID, for the hour the upload lasts or until the backend is
+// removed.
+type LBUploadsService struct {
+	Parts *LBUploadPartsService
+
+	lb *LoadBalancer
+}
+
+// LBUploadPartsService mimics openai.UploadPartService.
+type LBUploadPartsService struct {
+	uploads *LBUploadsService
+}
+
+func newLBUploadsService(lb *LoadBalancer) *LBUploadsService {
+	s := &LBUploadsService{lb: lb}
+	s.Parts = &LBUploadPartsService{uploads: s}
+	return s
+}
+
+// New creates an upload on the next backend whose uploads breaker is closed.
+func (s *LBUploadsService) New(ctx context.Context, params openai.UploadNewParams, opts ...option.RequestOption) (*openai.Upload, error) {
+	var owner *SafeClient
+	res, err := execute(ctx, s.lb, ServiceUploads, "", func(ctx context.Context, safeClient *SafeClient) (*openai.Upload, error) {
+		owner = safeClient
+		return safeClient.OpenAIClient().Uploads.New(ctx, params, opts...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.lb.uploadOwners.store(res.ID, owner)
+	return res, nil
+}
+
+// Complete completes the upload on the backend that created it.
+func (s *LBUploadsService) Complete(ctx context.Context, uploadID string, params openai.UploadCompleteParams, opts ...option.RequestOption) (*openai.Upload, error) {
+	res, err := pinnedUpload(ctx, s, uploadID, func(ctx context.Context, safeClient *SafeClient) (*openai.Upload, error) {
+		return safeClient.OpenAIClient().Uploads.Complete(ctx, uploadID, params, opts...)
+	})
+	if err == nil {
+		s.lb.uploadOwners.delete(uploadID)
+	}
+	return res, err
+}
+
+// Cancel cancels the upload on the backend that created it.
+func (s *LBUploadsService) Cancel(ctx context.Context, uploadID string, opts ...option.RequestOption) (*openai.Upload, error) {
+	res, err := pinnedUpload(ctx, s, uploadID, func(ctx context.Context, safeClient *SafeClient) (*openai.Upload, error) {
+		return safeClient.OpenAIClient().Uploads.Cancel(ctx, uploadID, opts...)
+	})
+	if err == nil {
+		s.lb.uploadOwners.delete(uploadID)
+	}
+	return res, err
+}
+
+// New adds a part to the upload on the backend that created it.
+func (s *LBUploadPartsService) New(ctx context.Context, uploadID string, params openai.UploadPartNewParams, opts ...option.RequestOption) (*openai.UploadPart, error) {
+	return pinnedUpload(ctx, s.uploads, uploadID, func(ctx context.Context, safeClient *SafeClient) (*openai.UploadPart, error) {
+		return safeClient.OpenAIClient().Uploads.Parts.New(ctx, uploadID, params, opts...)
+	})
+}
+
+// pinnedUpload runs call on the backend that owns uploadID. Part bodies are
+// streamed readers and can't be replayed, so there is no retry here.
+func pinnedUpload[T any](ctx context.Context, s *LBUploadsService, uploadID string, call func(context.Context, *SafeClient) (T, error)) (T, error) {
+	owner, ok := s.lb.uploadOwners.load(uploadID)
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("upload %s was not created through this load balancer, or its backend was removed", uploadID)
+	}
+	return executeOn(ctx, s.lb, owner, ServiceUploads, "", call)
+}
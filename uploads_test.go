@@ -0,0 +1,104 @@
+package openailb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+)
+
+func TestUploadsPinnedToOwner(t *testing.T) {
+	t.Parallel()
+
+	var partHits [2]int32
+	newServer := func(i int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			uploadID := fmt.Sprintf("upload_%d", i)
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Path {
+			case "/uploads":
+				_, _ = fmt.Fprintf(w, `{"id":"%s","object":"upload","status":"pending"}`, uploadID)
+			case "/uploads/" + uploadID + "/parts":
+				atomic.AddInt32(&partHits[i], 1)
+				_, _ = fmt.Fprintf(w, `{"id":"part_%d","object":"upload.part","upload_id":"%s"}`, i, uploadID)
+			case "/uploads/" + uploadID + "/complete":
+				_, _ = fmt.Fprintf(w, `{"id":"%s","object":"upload","status":"completed"}`, uploadID)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"error":{"message":"not found"}}`))
+			}
+		}))
+	}
+	server0 := newServer(0)
+	defer server0.Close()
+	server1 := newServer(1)
+	defer server1.Close()
+
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "mock-key-0", BaseURL: server0.URL},
+		{APIKey: "mock-key-1", BaseURL: server1.URL},
+	})
+
+	upload, err := client.Uploads.New(context.Background(), openai.UploadNewParams{
+		Bytes:    6,
+		Filename: "data.jsonl",
+		MimeType: "text/jsonl",
+		Purpose:  openai.FilePurposeBatch,
+	})
+	if err != nil {
+		t.Fatalf("Create upload failed: %v", err)
+	}
+
+	// Every part must land on the backend that created the upload, not round-robin.
+	for i := 0; i < 3; i++ {
+		_, err := client.Uploads.Parts.New(context.Background(), upload.ID, openai.UploadPartNewParams{
+			Data: strings.NewReader("ab"),
+		})
+		if err != nil {
+			t.Fatalf("Part %d failed: %v", i, err)
+		}
+	}
+	if got := atomic.LoadInt32(&partHits[0]) + atomic.LoadInt32(&partHits[1]); got != 3 {
+		t.Fatalf("Expected 3 parts on the owner, got %d", got)
+	}
+
+	done, err := client.Uploads.Complete(context.Background(), upload.ID, openai.UploadCompleteParams{
+		PartIDs: []string{"part"},
+	})
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if done.Status != openai.UploadStatusCompleted {
+		t.Errorf("Expected completed upload, got %s", done.Status)
+	}
+	if _, err := client.Uploads.Cancel(context.Background(), upload.ID); err == nil {
+		t.Error("Expected an error for an upload the LB no longer tracks")
+	}
+
+	// Removing a backend forgets its uploads.
+	upload, err = client.Uploads.New(context.Background(), openai.UploadNewParams{
+		Bytes:    6,
+		Filename: "data.jsonl",
+		MimeType: "text/jsonl",
+		Purpose:  openai.FilePurposeBatch,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner, ok := client.lb.uploadOwners.load(upload.ID)
+	if !ok {
+		t.Fatalf("Expected an owner for %s", upload.ID)
+	}
+	if err := client.RemoveBackend(owner.Name); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Uploads.Parts.New(context.Background(), upload.ID, openai.UploadPartNewParams{Data: strings.NewReader("ab")})
+	if err == nil || !strings.Contains(err.Error(), "its backend was removed") {
+		t.Errorf("Expected the upload of a removed backend to be refused, got %v", err)
+	}
+}
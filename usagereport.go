@@ -0,0 +1,177 @@
+package openailb
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// UsageRetention is how long the usage behind Client.UsageReport is kept,
+// long enough for a monthly report.
+const UsageRetention = 32 * 24 * time.Hour
+
+// usageHistory keeps a backend's usage per hour, per mapped model and tag,
+// for UsageRetention. It is guarded by usageLedger.mu.
+type usageHistory struct {
+	hours map[int64]map[usageKey]*Usage
+}
+
+type usageKey struct {
+	model, tag string
+}
+
+// add adds r, finished at now, to its hour and drops the hours past retention.
+func (h *usageHistory) add(now time.Time, r ResponseInfo) {
+	hour := now.Unix() / 3600
+	if h.hours == nil {
+		h.hours = make(map[int64]map[usageKey]*Usage)
+	}
+	entries, ok := h.hours[hour]
+	if !ok {
+		entries = make(map[usageKey]*Usage)
+		h.hours[hour] = entries
+		oldest := now.Add(-UsageRetention).Unix() / 3600
+		for hour := range h.hours {
+			if hour < oldest {
+				delete(h.hours, hour)
+			}
+		}
+	}
+	key := usageKey{model: r.MappedModel, tag: r.Tag}
+	u, ok := entries[key]
+	if !ok {
+		u = &Usage{}
+		entries[key] = u
+	}
+	u.add(r)
+}
+
+// since returns the usage per model and tag of the hours that ended after from.
+func (l *usageLedger) since(from time.Time) map[usageKey]Usage {
+	first := from.Unix() / 3600
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make(map[usageKey]Usage)
+	for hour, entries := range l.history.hours {
+		if hour < first {
+			continue
+		}
+		for key, u := range entries {
+			sum := out[key]
+			sum.merge(*u)
+			out[key] = sum
+		}
+	}
+	return out
+}
+
+func (u *Usage) merge(o Usage) {
+	u.Requests += o.Requests
+	u.Errors += o.Errors
+	u.PromptTokens += o.PromptTokens
+	u.CompletionTokens += o.CompletionTokens
+	u.Cost += o.Cost
+}
+
+// UsageReport is the usage and estimated cost of every backend over a
+// window, as returned by Client.UsageReport, e.g. to reconcile invoices.
+type UsageReport struct {
+	From     time.Time      `json:"from"`
+	To       time.Time      `json:"to"`
+	Total    Usage          `json:"total"`
+	Backends []BackendUsage `json:"backends"`
+	// Entries break the usage down by backend, model and tag, sorted.
+	Entries []UsageEntry `json:"entries"`
+}
+
+// BackendUsage is one backend's usage in a UsageReport, in total and by
+// the model sent to the backend and by the callers' WithTag.
+type BackendUsage struct {
+	Backend string `json:"backend"`
+	Usage
+	Models map[string]Usage `json:"models"`
+	Tags   map[string]Usage `json:"tags"`
+}
+
+// UsageEntry is the usage of one backend, model and tag in a UsageReport.
+// Tag is "" for untagged requests.
+type UsageEntry struct {
+	Backend string `json:"backend"`
+	Model   string `json:"model"`
+	Tag     string `json:"tag"`
+	Usage
+}
+
+// UsageReport returns the usage of every backend over the last window. Usage
+// is kept per hour, so the report starts at the beginning of the hour window
+// ago, and for at most UsageRetention.
+func (c Client) UsageReport(window time.Duration) UsageReport {
+	now := time.Now()
+	window = min(window, UsageRetention)
+	from := now.Add(-window).Truncate(time.Hour)
+	report := UsageReport{From: from, To: now, Backends: []BackendUsage{}, Entries: []UsageEntry{}}
+	for _, backend := range c.lb.backends() {
+		b := BackendUsage{Backend: backend.Name, Models: map[string]Usage{}, Tags: map[string]Usage{}}
+		for key, u := range backend.usage.since(from) {
+			report.Entries = append(report.Entries, UsageEntry{Backend: backend.Name, Model: key.model, Tag: key.tag, Usage: u})
+			b.merge(u)
+			model := b.Models[key.model]
+			model.merge(u)
+			b.Models[key.model] = model
+			if key.tag != "" {
+				tag := b.Tags[key.tag]
+				tag.merge(u)
+				b.Tags[key.tag] = tag
+			}
+		}
+		report.Total.merge(b.Usage)
+		report.Backends = append(report.Backends, b)
+	}
+	sort.Slice(report.Backends, func(i, j int) bool { return report.Backends[i].Backend < report.Backends[j].Backend })
+	sort.Slice(report.Entries, func(i, j int) bool {
+		a, b := report.Entries[i], report.Entries[j]
+		if a.Backend != b.Backend {
+			return a.Backend < b.Backend
+		}
+		if a.Model != b.Model {
+			return a.Model < b.Model
+		}
+		return a.Tag < b.Tag
+	})
+	return report
+}
+
+// WriteJSON writes the report to w as indented JSON.
+func (r UsageReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV writes the report's entries to w as CSV, one row per backend,
+// model and tag after a header row.
+func (r UsageReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"backend", "model", "tag", "requests", "errors", "prompt_tokens", "completion_tokens", "cost"}); err != nil {
+		return err
+	}
+	for _, e := range r.Entries {
+		if err := cw.Write([]string{
+			e.Backend,
+			e.Model,
+			e.Tag,
+			strconv.FormatInt(e.Requests, 10),
+			strconv.FormatInt(e.Errors, 10),
+			strconv.FormatInt(e.PromptTokens, 10),
+			strconv.FormatInt(e.CompletionTokens, 10),
+			strconv.FormatFloat(e.Cost, 'f', -1, 64),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,100 @@
+package openailb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUsageReport(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Model == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "Hello"}}], "usage": {"prompt_tokens": 1000, "completion_tokens": 500}}`))
+	}))
+	defer server.Close()
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: server.URL, ModelMap: map[string]string{"m": "mapped"}},
+	}, WithPricing(map[string]Price{"mapped": {Prompt: 2, Completion: 8}}))
+
+	ctx := WithTag(context.Background(), "team-a")
+	for i := 0; i < 2; i++ {
+		if _, err := client.Chat.Completions.New(ctx, chatParams("m")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Chat.Completions.New(ctx, chatParams("bad")); err == nil {
+		t.Fatal("Expected the bad request to fail")
+	}
+
+	report := client.UsageReport(24 * time.Hour)
+	if report.Total.Requests != 4 || report.Total.Errors != 1 || report.Total.PromptTokens != 3000 || !near(report.Total.Cost, 0.018) {
+		t.Errorf("Unexpected total %+v", report.Total)
+	}
+	if len(report.Backends) != 1 || report.Backends[0].Backend != "Client-0" {
+		t.Fatalf("Unexpected backends %+v", report.Backends)
+	}
+	b := report.Backends[0]
+	if u := b.Models["mapped"]; u.Requests != 3 || u.Errors != 0 {
+		t.Errorf("Unexpected usage for the mapped model: %+v", u)
+	}
+	if u := b.Tags["team-a"]; u.Requests != 3 || u.Errors != 1 || !near(u.Cost, 0.012) {
+		t.Errorf("Unexpected usage for the tag: %+v", u)
+	}
+
+	var csv bytes.Buffer
+	if err := report.WriteCSV(&csv); err != nil {
+		t.Fatal(err)
+	}
+	want := "backend,model,tag,requests,errors,prompt_tokens,completion_tokens,cost\n" +
+		"Client-0,bad,team-a,1,1,0,0,0\n" +
+		"Client-0,mapped,,1,0,1000,500,0.006\n" +
+		"Client-0,mapped,team-a,2,0,2000,1000,0.012\n"
+	if csv.String() != want {
+		t.Errorf("Expected CSV\n%s\ngot\n%s", want, csv.String())
+	}
+
+	var js bytes.Buffer
+	if err := report.WriteJSON(&js); err != nil {
+		t.Fatal(err)
+	}
+	var decoded UsageReport
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil || len(decoded.Entries) != 3 || decoded.Backends[0].Requests != 4 {
+		t.Errorf("Unexpected JSON report (%v):\n%s", err, js.String())
+	}
+	if !strings.Contains(js.String(), `"errors": 1`) {
+		t.Errorf("Expected error counts in the JSON report:\n%s", js.String())
+	}
+}
+
+func TestUsageHistoryRetention(t *testing.T) {
+	t.Parallel()
+
+	var l usageLedger
+	now := time.Now()
+	l.record(now.Add(-UsageRetention-2*time.Hour), ResponseInfo{RequestInfo: RequestInfo{MappedModel: "old"}, Class: ClassOK})
+	l.record(now, ResponseInfo{RequestInfo: RequestInfo{MappedModel: "new"}, Class: ClassOK})
+	if len(l.history.hours) != 1 {
+		t.Errorf("Expected the hour past retention to be dropped, got %d hours", len(l.history.hours))
+	}
+	if got := l.since(now.Add(-time.Hour)); len(got) != 1 || got[usageKey{model: "new"}].Requests != 1 {
+		t.Errorf("Unexpected usage %+v", got)
+	}
+}
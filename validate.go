@@ -0,0 +1,100 @@
+package openailb
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ValidationResult is the outcome of validating one backend (Client.Validate).
+type ValidationResult struct {
+	Name    string
+	BaseURL string
+	// Err is nil if the backend is reachable and accepted its key.
+	Err     error
+	Latency time.Duration
+}
+
+// ValidationError is returned by Client.Validate when backends failed validation.
+type ValidationError struct {
+	Results []ValidationResult
+}
+
+func (e *ValidationError) Error() string {
+	var failed []string
+	for _, r := range e.Results {
+		if r.Err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%s): %v", r.Name, r.BaseURL, r.Err))
+		}
+	}
+	return fmt.Sprintf("%d of %d backends failed validation: %s", len(failed), len(e.Results), strings.Join(failed, "; "))
+}
+
+// Validate checks every backend in parallel: its BaseURL must parse and
+// resolve, the endpoint must answer the health-check request (GET /models
+// unless configured otherwise) and accept the API key. As with the health
+// checks, a rejected check request, like a 400, still passes. Call it at startup so a
+// typo'd base URL or key fails fast instead of surfacing as runtime errors.
+// The error is a *ValidationError if any backend failed.
+func (c Client) Validate(ctx context.Context) ([]ValidationResult, error) {
+	return c.lb.validateEach(ctx, (*SafeClient).validate)
+}
+
+// validateEach runs check against every backend in parallel.
+func (lb *LoadBalancer) validateEach(ctx context.Context, check func(*SafeClient, context.Context) error) ([]ValidationResult, error) {
+	results := make([]ValidationResult, len(lb.backends()))
+	var wg sync.WaitGroup
+	for i, backend := range lb.backends() {
+		wg.Add(1)
+		go func(i int, backend *SafeClient) {
+			defer wg.Done()
+			start := time.Now()
+			results[i] = ValidationResult{
+				Name:    backend.Name,
+				BaseURL: backend.BaseURL,
+				Err:     check(backend, ctx),
+				Latency: time.Since(start),
+			}
+		}(i, backend)
+	}
+	wg.Wait()
+
+	for _, r := range results {
+		if r.Err != nil {
+			return results, &ValidationError{Results: results}
+		}
+	}
+	return results, nil
+}
+
+// NewValidatedClient is NewClient followed by Client.Validate.
+func NewValidatedClient(ctx context.Context, configs []OpenaiClientConfig, opts ...LBOption) (Client, []ValidationResult, error) {
+	client := NewClient(configs, opts...)
+	results, err := client.Validate(ctx)
+	return client, results, err
+}
+
+// validate runs the validation steps against the backend.
+func (c *SafeClient) validate(ctx context.Context) error {
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("invalid base URL %q: expected http(s)://host[/path]", c.BaseURL)
+	}
+	if _, err := c.lb.options.lookupHost(ctx, u.Hostname()); err != nil {
+		return fmt.Errorf("resolve %s: %w", u.Hostname(), err)
+	}
+
+	if err := pingFailure(c.ping(ctx)); err != nil {
+		if isAuthError(err) {
+			return fmt.Errorf("API key rejected: %w", err)
+		}
+		return fmt.Errorf("endpoint check failed: %w", err)
+	}
+	return nil
+}
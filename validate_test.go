@@ -0,0 +1,57 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	ok := newNamedServer(t, "ok")
+	defer ok.Close()
+	badKey := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer badKey.Close()
+	badRequest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer badRequest.Close()
+	fakeLookup := func(o *lbOptions) {
+		o.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+			if host == "backend.internal" {
+				return nil, errors.New("no such host")
+			}
+			return []string{host}, nil
+		}
+	}
+
+	_, results, err := NewValidatedClient(context.Background(), []OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: ok.URL},
+		{APIKey: "k2", BaseURL: badKey.URL},
+		{APIKey: "k3", BaseURL: "api.openai.com/v1"},
+		{APIKey: "k4", BaseURL: "http://backend.internal/v1"},
+		{APIKey: "k5", BaseURL: badRequest.URL},
+	}, fakeLookup)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a *ValidationError, got %v", err)
+	}
+
+	if results[0].Err != nil || results[4].Err != nil {
+		t.Errorf("Expected the healthy backends to pass, got %v and %v", results[0].Err, results[4].Err)
+	}
+	for i, want := range map[int]string{1: "API key rejected", 2: "invalid base URL", 3: "resolve backend.internal"} {
+		if results[i].Err == nil || !strings.Contains(results[i].Err.Error(), want) {
+			t.Errorf("Expected backend %d to fail with %q, got %v", i, want, results[i].Err)
+		}
+	}
+	if !strings.Contains(err.Error(), "3 of 5 backends failed validation") {
+		t.Errorf("Unexpected error message: %v", err)
+	}
+}
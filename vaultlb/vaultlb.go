@@ -0,0 +1,120 @@
+// Package vaultlb resolves openailb API key references from HashiCorp Vault.
+// A reference is a secret's path and the field holding the key, e.g.
+// "secret/data/openai#api_key" for a KV v2 engine mounted at secret/. It uses
+// Vault's HTTP API and reads KV v1 and v2 secrets alike.
+//
+//	client, err := openailb.New([]openailb.OpenaiClientConfig{
+//		{APIKeyRef: "secret/data/openai#api_key", BaseURL: "https://api.openai.com/v1"},
+//	}, openailb.WithSecretProvider(&vaultlb.Provider{}, 0))
+package vaultlb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DefaultAddress is the address of a local Vault server.
+const DefaultAddress = "http://127.0.0.1:8200"
+
+// Provider is an openailb.SecretProvider over Vault.
+type Provider struct {
+	// Address is the Vault server's URL (default $VAULT_ADDR, then
+	// DefaultAddress).
+	Address string
+	// Token authenticates the reads (default $VAULT_TOKEN).
+	Token string
+	// Namespace, if set, is the Vault Enterprise namespace of the secrets
+	// (default $VAULT_NAMESPACE).
+	Namespace string
+	// HTTPClient sends the requests (default http.DefaultClient).
+	HTTPClient *http.Client
+}
+
+// Secret implements openailb.SecretProvider. ref is "path#field"; the field
+// may be omitted for a secret with a single field.
+func (p *Provider) Secret(ctx context.Context, ref string) (string, error) {
+	path, field, _ := strings.Cut(ref, "#")
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", fmt.Errorf("vaultlb: %q has no secret path", ref)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(p.address(), "/")+"/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	if token := envDefault(p.Token, "VAULT_TOKEN"); token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if namespace := envDefault(p.Namespace, "VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	httpClient := p.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return "", fmt.Errorf("vaultlb: read %s: %s: %s", path, res.Status, strings.TrimSpace(string(msg)))
+	}
+	var secret struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&secret); err != nil {
+		return "", fmt.Errorf("vaultlb: read %s: %w", path, err)
+	}
+	data := secret.Data
+	// KV v2 nests the fields, next to the version's metadata.
+	if inner, ok := data["data"].(map[string]any); ok && data["metadata"] != nil {
+		data = inner
+	}
+	if field == "" {
+		if len(data) != 1 {
+			return "", fmt.Errorf("vaultlb: %s has %d fields (%s); give one as %s#field", path, len(data), fieldNames(data), path)
+		}
+		for name := range data {
+			field = name
+		}
+	}
+	value, ok := data[field].(string)
+	if !ok {
+		return "", fmt.Errorf("vaultlb: %s has no string field %q", path, field)
+	}
+	return value, nil
+}
+
+// address returns the Vault server's URL.
+func (p *Provider) address() string {
+	if addr := envDefault(p.Address, "VAULT_ADDR"); addr != "" {
+		return addr
+	}
+	return DefaultAddress
+}
+
+// envDefault returns value, or else the environment variable key.
+func envDefault(value, key string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(key)
+}
+
+// fieldNames lists the fields of data, sorted.
+func fieldNames(data map[string]any) string {
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,52 @@
+package vaultlb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProvider(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			http.Error(w, `{"errors": ["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/openai":
+			_, _ = w.Write([]byte(`{"data": {"data": {"api_key": "sk-v2", "org": "org-1"}, "metadata": {"version": 3}}}`))
+		case "/v1/kv/openai":
+			_, _ = w.Write([]byte(`{"data": {"api_key": "sk-v1"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer vault.Close()
+
+	p := &Provider{Address: vault.URL, Token: "s.token"}
+	for ref, want := range map[string]string{
+		"secret/data/openai#api_key": "sk-v2",
+		"kv/openai#api_key":          "sk-v1",
+		"/kv/openai":                 "sk-v1",
+	} {
+		got, err := p.Secret(context.Background(), ref)
+		if err != nil || got != want {
+			t.Errorf("Secret(%q) = %q, %v; want %q", ref, got, err, want)
+		}
+	}
+
+	for ref, want := range map[string]string{
+		"secret/data/openai":         "has 2 fields (api_key, org); give one as secret/data/openai#field",
+		"secret/data/openai#missing": `has no string field "missing"`,
+		"nope#api_key":               "404 Not Found",
+	} {
+		if _, err := p.Secret(context.Background(), ref); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Secret(%q): expected an error containing %q, got %v", ref, want, err)
+		}
+	}
+	if _, err := (&Provider{Address: vault.URL, Token: "wrong"}).Secret(context.Background(), "kv/openai"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("Expected the denied read to be reported, got %v", err)
+	}
+}
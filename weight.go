@@ -0,0 +1,114 @@
+package openailb
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// slowStart is the configuration set by WithSlowStart.
+type slowStart struct {
+	window          time.Duration
+	initialFraction float64
+}
+
+// effectiveWeight is the weight the balancer uses for c when routing through
+// breaker: the configured weight, reduced while the breaker is recovering,
+// while the backend's error budget is exhausted and while it is degraded.
+func (c *SafeClient) effectiveWeight(breaker *trackedBreaker) float64 {
+	return c.weight.Load() * c.lb.options.slowStart.factor(breaker) * c.sloFactor() * c.degradedFactor()
+}
+
+// factor ramps linearly from initialFraction to 1 over window after the
+// breaker last closed.
+func (s *slowStart) factor(breaker *trackedBreaker) float64 {
+	if s == nil || s.window <= 0 {
+		return 1
+	}
+	breaker.mu.Lock()
+	closedAt := breaker.closedAt
+	breaker.mu.Unlock()
+	if closedAt.IsZero() {
+		return 1
+	}
+
+	elapsed := time.Since(closedAt)
+	if elapsed >= s.window {
+		return 1
+	}
+	return s.initialFraction + (1-s.initialFraction)*float64(elapsed)/float64(s.window)
+}
+
+// SetWeight changes the weight of the backend called name from its next
+// request on, e.g. for an autoscaler or a cost controller steering traffic.
+// Unlike OpenaiClientConfig.Weight, a weight of 0 means no traffic at all:
+// the backend leaves the rotation but keeps being health checked.
+func (c Client) SetWeight(name string, weight int) error {
+	if weight < 0 {
+		return fmt.Errorf("%s: weight must not be negative, got %d", name, weight)
+	}
+	backend, err := c.lb.clientByName(name)
+	if err != nil {
+		return err
+	}
+	from := backend.weight.Load()
+	backend.weight.Store(float64(weight))
+	c.lb.options.logger.Info("backend weight changed", "backend", name, "from", from, "to", weight)
+	return nil
+}
+
+// Switchover moves all the traffic of the backend called from to the one
+// called to at once, for a blue/green provider change: to takes over the
+// larger of the two weights and from drops to 0, between two requests. from
+// keeps being health checked, so Switchover(to, from) reverses the change
+// just as instantly. It refuses a target that is unhealthy or quarantined.
+func (c Client) Switchover(from, to string) error {
+	if from == to {
+		return errors.New("the backends to switch over between must differ")
+	}
+	src, err := c.lb.clientByName(from)
+	if err != nil {
+		return err
+	}
+	dst, err := c.lb.clientByName(to)
+	if err != nil {
+		return err
+	}
+
+	// Under the routing lock, so no request sees both backends or neither.
+	c.lb.mu.Lock()
+	dst.mu.Lock()
+	unhealthy, reason := dst.unhealthyLocked()
+	dst.mu.Unlock()
+	if quarantined, why := dst.Quarantined(); quarantined {
+		unhealthy, reason = true, why
+	}
+	if unhealthy {
+		c.lb.mu.Unlock()
+		return fmt.Errorf("%s: not switching over to an unhealthy backend: %s", to, reason)
+	}
+	weight := max(src.weight.Load(), dst.weight.Load())
+	if weight == 0 {
+		weight = 1
+	}
+	src.weight.Store(0)
+	dst.weight.Store(weight)
+	c.lb.mu.Unlock()
+	c.lb.options.logger.Info("backends switched over", "from", from, "to", to, "weight", weight)
+	return nil
+}
+
+// atomicFloat is a float64 that may be read and written concurrently.
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) Load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+func (f *atomicFloat) Store(v float64) {
+	f.bits.Store(math.Float64bits(v))
+}
@@ -0,0 +1,155 @@
+package openailb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newNamedServer returns a server answering every chat completion with name.
+func newNamedServer(t *testing.T, name string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"content": "` + name + `"}}]}`))
+	}))
+}
+
+// countHits sends n chat completions and counts the responses by content.
+func countHits(t *testing.T, client Client, n int) map[string]int {
+	t.Helper()
+	hits := make(map[string]int)
+	for i := 0; i < n; i++ {
+		resp, err := client.Chat.Completions.New(context.Background(), chatParams("m"))
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+		hits[resp.Choices[0].Message.Content]++
+	}
+	return hits
+}
+
+func TestWeightedRoundRobin(t *testing.T) {
+	t.Parallel()
+
+	heavy := newNamedServer(t, "heavy")
+	defer heavy.Close()
+	light := newNamedServer(t, "light")
+	defer light.Close()
+
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: heavy.URL, Weight: 3},
+		{APIKey: "k2", BaseURL: light.URL},
+	})
+
+	hits := countHits(t, client, 8)
+	if hits["heavy"] != 6 || hits["light"] != 2 {
+		t.Errorf("Expected a 6/2 split for weights 3/1, got %v", hits)
+	}
+}
+
+func TestSlowStartAfterRecovery(t *testing.T) {
+	t.Parallel()
+
+	recovered := newNamedServer(t, "recovered")
+	defer recovered.Close()
+	steady := newNamedServer(t, "steady")
+	defer steady.Close()
+
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: recovered.URL},
+		{APIKey: "k2", BaseURL: steady.URL},
+	}, WithSlowStart(time.Hour, 0.1))
+
+	// Pretend the first backend's chat breaker has just closed again.
+	breaker := client.Chat.Completions.lb.backends()[0].breakerFor(ServiceChat, "m")
+	breaker.mu.Lock()
+	breaker.closedAt = time.Now()
+	breaker.mu.Unlock()
+
+	hits := countHits(t, client, 11)
+	if hits["recovered"] != 1 || hits["steady"] != 10 {
+		t.Errorf("Expected the recovering backend to get ~10%% of traffic, got %v", hits)
+	}
+}
+
+func TestSetWeight(t *testing.T) {
+	t.Parallel()
+
+	a, b := newNamedServer(t, "a"), newNamedServer(t, "b")
+	defer a.Close()
+	defer b.Close()
+	client := NewClient([]OpenaiClientConfig{
+		{Name: "a", APIKey: "k1", BaseURL: a.URL},
+		{Name: "b", APIKey: "k2", BaseURL: b.URL},
+	})
+
+	if err := client.SetWeight("b", 3); err != nil {
+		t.Fatal(err)
+	}
+	if hits := countHits(t, client, 8); hits["a"] != 2 || hits["b"] != 6 {
+		t.Errorf("Expected a 1:3 split, got %v", hits)
+	}
+	if err := client.SetWeight("b", 0); err != nil {
+		t.Fatal(err)
+	}
+	if hits := countHits(t, client, 4); hits["a"] != 4 {
+		t.Errorf("Expected a weight of 0 to take the backend out of rotation, got %v", hits)
+	}
+	if health := client.Health(); health[1].Weight != 0 {
+		t.Errorf("Expected the health snapshot to show the new weight, got %v", health[1].Weight)
+	}
+
+	if err := client.SetWeight("b", -1); err == nil || err.Error() != "b: weight must not be negative, got -1" {
+		t.Errorf("Expected a negative weight to be refused, got %v", err)
+	}
+	if err := client.SetWeight("c", 1); err == nil {
+		t.Error("Expected an unknown backend to be reported")
+	}
+}
+
+func TestSwitchover(t *testing.T) {
+	t.Parallel()
+
+	blue, green := newNamedServer(t, "blue"), newNamedServer(t, "green")
+	defer blue.Close()
+	defer green.Close()
+	client := NewClient([]OpenaiClientConfig{
+		{Name: "blue", APIKey: "k1", BaseURL: blue.URL, Weight: 2},
+		{Name: "green", APIKey: "k2", BaseURL: green.URL, Weight: 0},
+	})
+	if err := client.SetWeight("green", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Switchover("blue", "green"); err != nil {
+		t.Fatal(err)
+	}
+	if hits := countHits(t, client, 3); hits["green"] != 3 {
+		t.Errorf("Expected all the traffic on green, got %v", hits)
+	}
+	if health := client.Health(); health[0].Weight != 0 || health[1].Weight != 2 {
+		t.Errorf("Expected green to take over blue's weight, got %v and %v", health[0].Weight, health[1].Weight)
+	}
+	if err := client.Switchover("green", "blue"); err != nil {
+		t.Fatal(err)
+	}
+	if hits := countHits(t, client, 3); hits["blue"] != 3 {
+		t.Errorf("Expected the switchover to be reversed, got %v", hits)
+	}
+
+	if err := client.MarkUnhealthy("green", "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Switchover("blue", "green"); err == nil || err.Error() != "green: not switching over to an unhealthy backend: maintenance" {
+		t.Errorf("Expected an unhealthy target to be refused, got %v", err)
+	}
+	if hits := countHits(t, client, 2); hits["blue"] != 2 {
+		t.Errorf("Expected the refused switchover to leave the traffic on blue, got %v", hits)
+	}
+	if err := client.Switchover("blue", "blue"); err == nil {
+		t.Error("Expected a switchover to the same backend to be refused")
+	}
+}
@@ -0,0 +1,36 @@
+package openailb
+
+import "slices"
+
+// With returns a client sharing c's backends, breakers, health checks and
+// statistics, whose requests follow opts on top of c's options, so each part
+// of an application can get its own policy against the same pool:
+//
+//	batch := client.With(WithFailover(5), WithEmbeddingSharding(512))
+//	interactive := client.With(WithFailover(1), WithIsSuccessful(nonEmpty))
+//
+// Only the options that shape a request take effect: WithFailover,
+// WithIsSuccessful, WithEmbeddingSharding, WithPollRetry, WithRotationRetry
+// and WithHooks, whose hooks run after c's. The others configure the pool and
+// are ignored, and requests routed to a WithPools pool follow the pool's
+// options instead. Deriving a client is cheap and starts no background work.
+// Membership changes, breaker trips and Close act on the shared pool, so
+// closing any of the clients closes them all.
+func (c Client) With(opts ...LBOption) Client {
+	base := c.lb.options
+	derived := base
+	derived.hooks = slices.Clip(derived.hooks)
+	for _, o := range opts {
+		o(&derived)
+	}
+
+	options := base
+	options.maxAttempts = derived.maxAttempts
+	options.isSuccessful = derived.isSuccessful
+	options.embeddingShardSize = derived.embeddingShardSize
+	options.pollAttempts = derived.pollAttempts
+	options.pollBackoff = derived.pollBackoff
+	options.rotationRetry = derived.rotationRetry
+	options.hooks = derived.hooks
+	return newClientOf(&LoadBalancer{lbState: c.lb.lbState, options: options})
+}
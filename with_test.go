@@ -0,0 +1,60 @@
+package openailb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+func TestWith(t *testing.T) {
+	t.Parallel()
+
+	failServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failServer.Close()
+	okServer := newNamedServer(t, "ok")
+	defer okServer.Close()
+
+	client := NewClient([]OpenaiClientConfig{
+		{APIKey: "k1", BaseURL: failServer.URL},
+		{APIKey: "k2", BaseURL: okServer.URL},
+	}, WithCBSettings(tripAfter(1)))
+	var failovers atomic.Int32
+	derived := client.With(WithFailover(2), WithHooks(Hooks{OnFailover: func(ResponseInfo) { failovers.Add(1) }}),
+		WithCBSettings(tripAfter(100)))
+
+	if _, err := derived.Chat.Completions.New(context.Background(), chatParams("m"), option.WithMaxRetries(0)); err != nil {
+		t.Fatalf("Expected the derived client to fail over, got %v", err)
+	}
+	if n := failovers.Load(); n != 1 {
+		t.Errorf("Expected the derived hook to see 1 failover, got %d", n)
+	}
+
+	// The failure tripped the shared breaker, with the base client's settings.
+	if hits := countHits(t, client, 3); hits["ok"] != 3 {
+		t.Errorf("Expected the base client to skip the tripped backend, got %v", hits)
+	}
+	if client.lb.options.maxAttempts != 0 || len(client.lb.options.hooks) != 0 {
+		t.Error("Expected the base client's options to be unchanged")
+	}
+
+	if _, err := derived.AddBackend(OpenaiClientConfig{Name: "b", APIKey: "k3", BaseURL: okServer.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(client.DebugReport().Backends); n != 3 {
+		t.Errorf("Expected the added backend to be shared, got %d backends", n)
+	}
+
+	if err := derived.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Chat.Completions.New(context.Background(), chatParams("m")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected closing the derived client to close the base one, got %v", err)
+	}
+}